@@ -0,0 +1,233 @@
+package dxfparser
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// tableSourceACAD prefixes the entity handle in the table source of tables read from ACAD_TABLE entities
+const tableSourceACAD = "acad_table"
+
+// ACADTable is the cell text of an ACAD_TABLE entity, row by row. Cells covered by a merged
+// cell are empty.
+type ACADTable struct {
+	Handle string
+	Cells  [][]string
+}
+
+// readStructuredTables reads the tables a drawing stores as data instead of loose text in one
+// pass over the content the text was parsed from with opts: the eBOM in the first of
+// dictionaries found (nil if none, or dictionaries is empty) and the ACAD_TABLE entities of the
+// ENTITIES section in the layout of opts, in file order. Content that failed the strict check
+// of opts never gets here; content cut short is read up to the truncation, as with opts.Recover.
+func readStructuredTables(content []byte, opts ParseOptions, dictionaries []string) (*EBOMTables, []ACADTable, error) {
+	opts.Workers = 1
+	parser := NewDXFParserWithOptions(opts)
+	if truncation, err := findTruncation(bytes.NewReader(content), int64(len(content))); err != nil {
+		return nil, nil, err
+	} else if truncation != nil {
+		content = content[:truncation.Offset]
+	}
+
+	var ebom *ebomCollector
+	if len(dictionaries) > 0 {
+		ebom = newEBOMCollector(parser)
+	}
+	var tables []ACADTable
+	parser.OnEntity("ACAD_TABLE", func(entity Entity) error {
+		if entity.Section == "ENTITIES" && parser.keepsEntity(entity) {
+			tables = append(tables, parseACADTable(entity, opts.RawMText))
+		}
+		return nil
+	})
+	if err := parser.ParseEntities(bytes.NewReader(content)); err != nil {
+		return nil, nil, fmt.Errorf("error reading structured tables: %w", err)
+	}
+	if ebom == nil {
+		return nil, tables, nil
+	}
+	return ebom.tables(dictionaries), tables, nil
+}
+
+// parseACADTable reads the cells of an ACAD_TABLE. The table groups give the row (91) and column
+// (92) counts; the cells follow row by row, each starting with its cell type (171). The text of
+// a cell is in 1 (before AutoCAD 2008) or 302 (the cell value), longer texts are preceded by
+// chunks in 2 or 303. Cell text is MTEXT and loses its formatting unless raw is set.
+func parseACADTable(entity Entity, raw bool) ACADTable {
+	table := ACADTable{}
+	rows, columns := 0, 0
+	var cells []string
+	chunks := ""
+	for _, g := range entity.Groups {
+		inCell := len(cells) > 0
+		switch {
+		case g.Code == 171:
+			cells = append(cells, "")
+			chunks = ""
+		case !inCell && g.Code == 5:
+			table.Handle = strings.ToUpper(g.Value)
+		case !inCell && g.Code == 91:
+			rows, _ = strconv.Atoi(g.Value)
+		case !inCell && g.Code == 92:
+			columns, _ = strconv.Atoi(g.Value)
+		case inCell && (g.Code == 2 || g.Code == 303):
+			chunks += g.Value
+		case inCell && (g.Code == 1 || g.Code == 302):
+			text := acadCellText(chunks+g.Value, raw)
+			chunks = ""
+			if text != "" {
+				cells[len(cells)-1] = text
+			}
+		}
+	}
+
+	if columns <= 0 {
+		columns = len(cells)
+	}
+	if rows <= 0 && columns > 0 {
+		rows = (len(cells) + columns - 1) / columns
+	}
+	for r := 0; r < rows; r++ {
+		row := make([]string, columns)
+		for c := range row {
+			if i := r*columns + c; i < len(cells) {
+				row[c] = cells[i]
+			}
+		}
+		table.Cells = append(table.Cells, row)
+	}
+	return table
+}
+
+// acadCellText returns the plain text of a cell on one line, or its MTEXT with the formatting
+// codes if raw is set
+func acadCellText(value string, raw bool) string {
+	text := decodeText(value)
+	if !raw {
+		text = strings.Join(strings.Fields(stripMTextFormat(text)), " ")
+	}
+	return strings.TrimSpace(text)
+}
+
+// acadTableExtraction builds the table titled title from the ACAD_TABLEs of a drawing, in the
+// output columns of the eBOM tables. A table belongs to it if a cell matches the title; the
+// next row with text is the header, its cells are matched to columns like eBOM keys, the rows
+// after it are data. Tables split over several ACAD_TABLEs with the same title are joined.
+// ERECTION MATERIALS get the category rows (text only in the first column) moved to CATEGORY
+// and the total rows reduced to weight and label, as the reconstruction from text does.
+// Returns the table and its source, "acad_table:<handle>" of the first table used.
+func acadTableExtraction(tables []ACADTable, title string, columns, header []string) (TableExtraction, string) {
+	extraction := TableExtraction{Header: header}
+	source := ""
+	category := ebomColumn("CATEGORY", columns)
+	weight := ebomColumn("WEIGHT", columns)
+	qty := ebomColumn("QTY", columns)
+	for _, table := range tables {
+		titleRow := -1
+		for r, row := range table.Cells {
+			if acadRowMatches(row, title) {
+				titleRow = r
+				break
+			}
+		}
+		if titleRow < 0 {
+			continue
+		}
+
+		headerRow := -1
+		for r := titleRow + 1; r < len(table.Cells); r++ {
+			if !acadRowEmpty(table.Cells[r]) {
+				headerRow = r
+				break
+			}
+		}
+		if headerRow < 0 {
+			continue
+		}
+		mapping := make([]int, len(table.Cells[headerRow]))
+		mapped := false
+		for c, name := range table.Cells[headerRow] {
+			mapping[c] = -1
+			if name == "" {
+				continue
+			}
+			if column := ebomColumn(name, columns); column >= 0 {
+				mapping[c] = column
+				mapped = true
+			} else {
+				extraction.Warnings = append(extraction.Warnings, fmt.Sprintf("ACAD_TABLE %s: unknown column '%s'", title, name))
+			}
+		}
+		if !mapped {
+			continue
+		}
+		if source == "" {
+			source = tableSourceACAD + ":" + table.Handle
+		}
+
+		current := ""
+		for _, cells := range table.Cells[headerRow+1:] {
+			if acadRowEmpty(cells) || strings.Join(cells, "\x00") == strings.Join(table.Cells[headerRow], "\x00") {
+				continue
+			}
+			filled := acadFilledCells(cells)
+			if category >= 0 && len(filled) == 1 && filled[0] == 0 {
+				current = cells[0]
+				continue
+			}
+			if category >= 0 && weight >= 0 && strings.HasPrefix(strings.ToUpper(cells[filled[0]]), "TOTAL") {
+				row := make([]string, len(columns))
+				row[category] = cells[filled[0]]
+				if len(filled) > 1 {
+					row[weight] = cells[filled[len(filled)-1]]
+				}
+				extraction.Rows = append(extraction.Rows, row)
+				continue
+			}
+
+			row := make([]string, len(columns))
+			for c, value := range cells {
+				if c < len(mapping) && mapping[c] >= 0 {
+					row[mapping[c]] = value
+				}
+			}
+			if category >= 0 && row[category] == "" {
+				row[category] = current
+			}
+			if qty >= 0 {
+				row[qty] = strings.TrimSuffix(row[qty], "M") // pipe lengths like "2.4M"
+			}
+			markPieceNumber(title, row)
+			extraction.Rows = append(extraction.Rows, row)
+		}
+	}
+	return extraction, source
+}
+
+// acadRowMatches reports whether a cell of row holds the table title
+func acadRowMatches(row []string, title string) bool {
+	for _, cell := range row {
+		if cell != "" && matchesTableTitle(cell, title) {
+			return true
+		}
+	}
+	return false
+}
+
+// acadRowEmpty reports whether no cell of row has text
+func acadRowEmpty(row []string) bool {
+	return len(acadFilledCells(row)) == 0
+}
+
+// acadFilledCells returns the indexes of the cells of row with text
+func acadFilledCells(row []string) []int {
+	var filled []int
+	for c, cell := range row {
+		if cell != "" {
+			filled = append(filled, c)
+		}
+	}
+	return filled
+}
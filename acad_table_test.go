@@ -0,0 +1,54 @@
+package dxfparser
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// acadTable returns the DXF records of a one-row ACAD_TABLE with handle and the given cells, in
+// paper space layout if it is not ""
+func acadTable(handle, layout string, cells ...string) string {
+	records := "0\nACAD_TABLE\n5\n" + handle + "\n8\n0\n"
+	if layout != "" {
+		records += "67\n1\n410\n" + layout + "\n"
+	}
+	records += "91\n1\n92\n" + strconv.Itoa(len(cells)) + "\n"
+	for _, cell := range cells {
+		records += "171\n1\n1\n" + cell + "\n"
+	}
+	return records
+}
+
+func TestReadStructuredTables(t *testing.T) {
+	content := []byte("0\nSECTION\n2\nENTITIES\n" +
+		acadTable("1A", "", `{\fArial|b1;PIPE}`, "2") +
+		acadTable("2B", "Sheet1", "PIPE", "2") +
+		"0\nENDSEC\n0\nEOF\n")
+
+	cases := []struct {
+		name string
+		opts ParseOptions
+		want []ACADTable
+	}{
+		{"model space", ParseOptions{}, []ACADTable{{"1A", [][]string{{"PIPE", "2"}}}}},
+		{"layout", ParseOptions{Layout: "sheet1"}, []ACADTable{{"2B", [][]string{{"PIPE", "2"}}}}},
+		{"all layouts, raw MTEXT", ParseOptions{Layout: LayoutAll, RawMText: true}, []ACADTable{
+			{"1A", [][]string{{`{\fArial|b1;PIPE}`, "2"}}},
+			{"2B", [][]string{{"PIPE", "2"}}},
+		}},
+	}
+	for _, c := range cases {
+		ebom, tables, err := readStructuredTables(content, c.opts, nil)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if ebom != nil {
+			t.Errorf("%s: eBOM %+v without dictionaries", c.name, ebom)
+		}
+		if !reflect.DeepEqual(tables, c.want) {
+			t.Errorf("%s: tables = %q, want %q", c.name, tables, c.want)
+		}
+	}
+}
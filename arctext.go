@@ -0,0 +1,85 @@
+package dxfparser
+
+import "math"
+
+// arcState holds the arc of an ARCALIGNEDTEXT, the Express Tools text written along an arc
+type arcState struct {
+	centerX, centerY, centerZ float64
+	radius                    float64
+	offset                    float64 // distance of the text from the arc (44)
+	start, end                float64 // angles of the arc in degrees (50, 51)
+	reversed                  bool    // character order reversed (70), the text reads clockwise
+	concave                   bool    // text on the concave side (73 = 2), inside the arc
+}
+
+// setArcGroup applies one group of an ARCALIGNEDTEXT and reports whether it used it: the arc
+// center (10/20/30), radius (40), angles (50, 51), text offset (44), height (42) and the order
+// (70) and side (73) of the characters. The font names (2, 3) and font flags are dropped; the
+// text (1), style (7), layer and color are read as for other text.
+func (e *TextEntity) setArcGroup(code, value string) bool {
+	if e.arc == nil {
+		e.arc = &arcState{}
+	}
+	a := e.arc
+	switch code {
+	case "10", "20", "30", "40", "42", "44", "50", "51":
+		f, ok := parseGroupFloat(code, value)
+		if !ok {
+			return true
+		}
+		switch code {
+		case "10":
+			a.centerX = f
+		case "20":
+			a.centerY = f
+		case "30":
+			a.centerZ = f
+		case "40":
+			a.radius = f
+		case "42":
+			e.Height = f
+		case "44":
+			a.offset = f
+		case "50":
+			a.start = f
+		case "51":
+			a.end = f
+		}
+	case "70":
+		a.reversed = value == "1"
+	case "73":
+		a.concave = value == "2"
+	case "2", "3", "41", "43", "45", "46", "71", "72", "74", "75", "76", "77", "78", "79", "90", "280":
+	default:
+		return false
+	}
+	return true
+}
+
+// finishArc places an ARCALIGNEDTEXT at the middle of its arc, the approximate center of the
+// text, turned along the tangent there so it reads like the text on the sheet
+func (e *TextEntity) finishArc() {
+	if e.arc == nil {
+		return
+	}
+	a := e.arc
+	end := a.end
+	if end < a.start {
+		end += 360
+	}
+	middle := (a.start + end) / 2
+	radius := a.radius + a.offset
+	if a.concave {
+		radius = a.radius - a.offset
+	}
+	rad := middle * math.Pi / 180
+	e.X = a.centerX + radius*math.Cos(rad)
+	e.Y = a.centerY + radius*math.Sin(rad)
+	e.Z = a.centerZ
+	// Text on the convex side reads clockwise, along the tangent turned back from the radius
+	if a.reversed != a.concave {
+		e.Rotation = normalizeRotation(middle + 90)
+	} else {
+		e.Rotation = normalizeRotation(middle - 90)
+	}
+}
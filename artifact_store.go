@@ -0,0 +1,206 @@
+package dxfparser
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// artifactFormat is part of every artifact key; raise it when the content of an artifact kind
+// changes, so development builds do not load artifacts of an older format
+const artifactFormat = 17
+
+// Artifact kinds
+const (
+	artifactEntities = "entities" // text entities with the warnings of the parse
+	artifactSegments = "segments" // weld candidate polyline segments
+)
+
+// artifactStoreEnv names the environment variable with the store directory of all commands
+const artifactStoreEnv = "DXF_PARSER_STORE"
+
+// artifactStore is the store shared by all commands, set from DXF_PARSER_STORE or bom -store;
+// nil disables it
+var artifactStore *ArtifactStore
+
+// ArtifactStore keeps the parse results of drawings in a directory, keyed by the SHA-256 of the
+// file content, so a drawing parsed once by any command is not parsed again while it is
+// unchanged, whatever its name or location. Every artifact also depends on the settings that
+// produced it (tool version, parser options, weld lengths); other settings miss. Tables and weld
+// symbols are computed from the stored artifacts as they depend on the project config.
+type ArtifactStore struct {
+	dir string
+}
+
+// openArtifactStore creates the store directory if needed
+func openArtifactStore(dir string) (*ArtifactStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating artifact store: %w", err)
+	}
+	return &ArtifactStore{dir: dir}, nil
+}
+
+// useArtifactStore sets the store of the process; "" disables it
+func useArtifactStore(dir string) error {
+	if dir == "" {
+		artifactStore = nil
+		return nil
+	}
+	store, err := openArtifactStore(dir)
+	if err != nil {
+		return err
+	}
+	artifactStore = store
+	return nil
+}
+
+// contentHash returns the key of content in the store
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// path returns the file of an artifact: <dir>/ab/abcd.../<kind>-<settings hash>.json
+func (s *ArtifactStore) path(hash, kind string, settings interface{}) string {
+	key, _ := json.Marshal(struct {
+		Format   int         `json:"format"`
+		Version  string      `json:"version"`
+		Settings interface{} `json:"settings"`
+	}{artifactFormat, getToolVersion(), settings})
+	return filepath.Join(s.dir, hash[:2], hash, kind+"-"+contentHash(key)[:16]+".json")
+}
+
+// load reads the artifact into v and reports whether it was found. A damaged artifact is a miss.
+func (s *ArtifactStore) load(hash, kind string, settings, v interface{}) bool {
+	data, err := os.ReadFile(s.path(hash, kind, settings))
+	if err != nil {
+		return false
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		debugPrint(fmt.Sprintf("[DEBUG] Ignoring damaged %s artifact of %s: %v", kind, hash[:12], err))
+		return false
+	}
+	debugPrint(fmt.Sprintf("[DEBUG] Loaded %s of %s from the artifact store", kind, hash[:12]))
+	return true
+}
+
+// save writes the artifact. A store that cannot be written only costs the next parse, so
+// errors are not returned.
+func (s *ArtifactStore) save(hash, kind string, settings, v interface{}) {
+	path := s.path(hash, kind, settings)
+	data, err := json.Marshal(v)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+	}
+	if err == nil {
+		err = writeOutputFile(path, data)
+	}
+	if err != nil {
+		debugPrint(fmt.Sprintf("[DEBUG] Could not store %s of %s: %v", kind, hash[:12], err))
+	}
+}
+
+// entityArtifact is the result of a parse as stored
+type entityArtifact struct {
+	Entities          []TextEntity     `json:"entities"`
+	Warnings          []string         `json:"warnings,omitempty"`
+	Truncation        *TruncationError `json:"truncation,omitempty"`
+	ParseWarnings     []ParseWarning   `json:"parse_warnings,omitempty"`
+	ParseWarningCount int              `json:"parse_warning_count,omitempty"`
+	ProxyEntities     int              `json:"proxy_entities,omitempty"`
+}
+
+// parseSettings are the parser options that change the entities of a parse
+func (p *DXFParser) parseSettings() interface{} {
+	return struct {
+		Encoding       string          `json:"encoding"`
+		RawMText       bool            `json:"raw_mtext"`
+		ScanBuffer     int             `json:"scan_buffer"`
+		Recover        bool            `json:"recover"`
+		Strict         bool            `json:"strict,omitempty"`
+		BlockDepth     int             `json:"block_depth"`
+		ExcludeColors  []int           `json:"exclude_colors,omitempty"`
+		XData          bool            `json:"xdata,omitempty"`
+		KeepWhitespace bool            `json:"keep_whitespace,omitempty"`
+		RawContent     bool            `json:"raw_content,omitempty"`
+		IncludeHidden  bool            `json:"include_hidden,omitempty"`
+		Layout         string          `json:"layout,omitempty"`
+		Scope          ExtractionScope `json:"scope,omitempty"`
+	}{p.encoding, p.rawMText, p.scanBuffer, p.recover, p.strict, p.blockDepth, p.excludeColors, p.xdata, p.whitespace, p.rawContent, p.includeHidden, p.layout, p.scope}
+}
+
+// parseFileStored is parser.ParseFileContext through the artifact store
+func parseFileStored(ctx context.Context, parser *DXFParser, path string) ([]TextEntity, error) {
+	if artifactStore == nil {
+		return parser.ParseFileContext(ctx, path)
+	}
+	input, err := loadInput(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer input.Close()
+	return parseContentStored(ctx, parser, path, input.Data, contentHash(input.Data))
+}
+
+// parseContentStored returns the entities of content, the content of path, from the artifact
+// store, or parses and stores them. The warnings and truncation of the stored parse are set on
+// parser as if it had parsed the content. hash "" parses content without the store, nil content
+// parses path.
+func parseContentStored(ctx context.Context, parser *DXFParser, path string, content []byte, hash string) ([]TextEntity, error) {
+	if content == nil {
+		return parser.ParseFileContext(ctx, path)
+	}
+	if artifactStore == nil || hash == "" {
+		return parser.parseReaderAt(ctx, bytes.NewReader(content), int64(len(content)))
+	}
+	settings := parser.parseSettings()
+	var artifact entityArtifact
+	if artifactStore.load(hash, artifactEntities, settings, &artifact) {
+		parser.restoreResult(artifact.Warnings, artifact.Truncation, artifact.ParseWarnings, artifact.ParseWarningCount, artifact.ProxyEntities)
+		return artifact.Entities, nil
+	}
+
+	entities, err := parser.parseReaderAt(ctx, bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return entities, err
+	}
+	artifactStore.save(hash, artifactEntities, settings, entityArtifact{
+		Entities:          entities,
+		Warnings:          parser.Warnings(),
+		Truncation:        parser.Truncation(),
+		ParseWarnings:     parser.ParseWarnings(),
+		ParseWarningCount: parser.ParseWarningCount(),
+		ProxyEntities:     parser.ProxyEntities(),
+	})
+	return entities, nil
+}
+
+// segmentsStored is parsePolylineSegmentsOptimized through the artifact store; the segments
+// depend on the weld lengths of the active weld configuration and the block depth
+func segmentsStored(content []byte, hash string, run *runConfig) ([]PolylineSegment, error) {
+	if artifactStore == nil || hash == "" {
+		return parsePolylineSegmentsOptimized(content, run)
+	}
+	settings := struct {
+		Weld       WeldConfig      `json:"weld"`
+		BlockDepth int             `json:"block_depth"`
+		Layout     string          `json:"layout,omitempty"`
+		Scope      ExtractionScope `json:"scope,omitempty"`
+		Units      string          `json:"units,omitempty"`
+	}{weldConfig, blockDepthLimit(run.parse.BlockDepth), run.parse.Layout, run.parse.Scope, run.units}
+	var segments []PolylineSegment
+	if artifactStore.load(hash, artifactSegments, settings, &segments) {
+		return segments, nil
+	}
+	segments, err := parsePolylineSegmentsOptimized(content, run)
+	if err != nil {
+		return nil, err
+	}
+	artifactStore.save(hash, artifactSegments, settings, segments)
+	return segments, nil
+}
@@ -0,0 +1,100 @@
+package dxfparser
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// outputFile is an output being written to a temporary file next to its final name. Commit
+// renames it into place, so a crash or a failed write never leaves a truncated file under the
+// final name for a loader to pick up.
+type outputFile struct {
+	*os.File
+	path      string // final name
+	sync      bool   // flush to disk before the rename (bom -fsync)
+	committed bool
+}
+
+// createOutput creates a temporary file for the output path in the same directory, which
+// keeps the rename on one file system
+func createOutput(path string) (*outputFile, error) {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	file, err := os.CreateTemp(dir, "."+base+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	return &outputFile{File: file, path: path}, nil
+}
+
+// Commit closes the file and renames it to its final name, replacing an existing file.
+// With sync the content and the directory entry are flushed to disk first.
+func (f *outputFile) Commit() error {
+	if f.sync {
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.File.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	// CreateTemp files are private; outputs get the permissions of os.Create
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), f.path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	f.committed = true
+	if f.sync {
+		syncDir(filepath.Dir(f.path))
+	}
+	return nil
+}
+
+// Close discards the output unless it was committed; deferred after createOutput it removes
+// the temporary file of a failed write
+func (f *outputFile) Close() error {
+	if f.committed {
+		return nil
+	}
+	f.File.Close()
+	return os.Remove(f.Name())
+}
+
+// writeOutputFile writes data to path through a temporary file like createOutput
+func writeOutputFile(path string, data []byte) error {
+	return writeOutput(path, data, false)
+}
+
+// writeOutput is writeOutputFile flushing the output to disk with sync
+func writeOutput(path string, data []byte, sync bool) error {
+	file, err := createOutput(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	file.sync = sync
+
+	if _, err := file.Write(data); err != nil {
+		return err
+	}
+	return file.Commit()
+}
+
+// syncDir flushes the directory entries of dir, making a rename durable. Not every platform
+// can sync a directory (Windows cannot), so errors are ignored.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	defer d.Close()
+	d.Sync()
+}
@@ -0,0 +1,370 @@
+package dxfparser
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/jeffcall-ch/dxf_parser_go/internal/geometry"
+)
+
+// defaultBlockDepth is the deepest chain of nested block references expanded when
+// ParseOptions.BlockDepth is not set; deeper references are dropped with a warning
+const defaultBlockDepth = 16
+
+// blockDepthLimit returns the nesting limit of a block depth option, 0 for the default
+func blockDepthLimit(depth int) int {
+	if depth <= 0 {
+		return defaultBlockDepth
+	}
+	return depth
+}
+
+// blockRecord holds the groups of a BLOCK, ENDBLK or INSERT record, which the scanner passes on
+// in a TextEntity for the block expansion, or of a LAYER record, whose name and color it takes
+type blockRecord struct {
+	Name                   string  // block or layer name (2)
+	ScaleX, ScaleY, ScaleZ float64 // 41, 42, 43 of an INSERT
+	Rotation               float64 // 50 of an INSERT, in degrees
+}
+
+// isBlockRecord reports whether a record of the given type is passed on for the block expansion
+func isBlockRecord(recordType string) bool {
+	return recordType == "BLOCK" || recordType == "ENDBLK" || recordType == "INSERT"
+}
+
+// newBlockRecord returns the entity carrying a BLOCK, ENDBLK, INSERT or LAYER record of recordType
+func newBlockRecord(recordType string) TextEntity {
+	return TextEntity{EntityType: recordType, Color: colorByLayer, block: &blockRecord{ScaleX: 1, ScaleY: 1, ScaleZ: 1}}
+}
+
+// setBlockGroup applies one group of a BLOCK, ENDBLK or INSERT and reports whether it used it:
+// the block name (2), scale (41/42/43) and rotation (50). The base or insertion point (10/20/30),
+// handle, space, layout, layer, visibility and color are left to setGroup; other groups are dropped.
+func (e *TextEntity) setBlockGroup(code, value string) bool {
+	switch code {
+	case "2":
+		e.block.Name = value
+	case "41":
+		e.block.ScaleX, _ = parseGroupFloat(code, value)
+	case "42":
+		e.block.ScaleY, _ = parseGroupFloat(code, value)
+	case "43":
+		e.block.ScaleZ, _ = parseGroupFloat(code, value)
+	case "50":
+		e.block.Rotation, _ = parseGroupFloat(code, value)
+	case "5", "8", "10", "20", "30", "60", "62", "67", "410", "420":
+		return false
+	}
+	return true
+}
+
+// place returns e, given in the coordinates of a block with base point baseX, baseY, baseZ, in
+// the coordinates the INSERT e is placed in: moved to the insertion point, scaled and rotated.
+// Text on layer 0 takes the layer of the INSERT and ByBlock color its color, as AutoCAD draws them.
+// The text takes the handle, space and layout of the INSERT, so nested block text ends up with
+// those of the outermost one.
+func (insert TextEntity) place(e TextEntity, baseX, baseY, baseZ float64) TextEntity {
+	e.X, e.Y = insert.transform(e.X, e.Y, baseX, baseY)
+	e.Z = insert.elevate(e.Z, baseZ)
+	if len(e.Arrow) == 2 {
+		x, y := insert.transform(e.Arrow[0], e.Arrow[1], baseX, baseY)
+		e.Arrow = []float64{x, y}
+	}
+	e.Height *= math.Abs(insert.block.ScaleY)
+	if e.WidthFactor != 0 && insert.block.ScaleY != 0 {
+		e.WidthFactor *= math.Abs(insert.block.ScaleX / insert.block.ScaleY)
+	}
+	// The text direction follows the scale, which mirrors it for a negative factor, and the rotation
+	sin, cos := math.Sincos(e.Rotation * math.Pi / 180)
+	e.Rotation = normalizeRotation(geometry.Angle(cos*insert.block.ScaleX, sin*insert.block.ScaleY) + insert.block.Rotation)
+	if e.Layer == "0" {
+		e.Layer = insert.Layer
+	}
+	if e.Color == 0 {
+		e.Color, e.TrueColor = insert.Color, insert.TrueColor
+	}
+	e.Invisible = e.Invisible || insert.Invisible
+	e.Handle = insert.Handle
+	e.PaperSpace, e.Layout = insert.PaperSpace, insert.Layout
+	return e
+}
+
+// placeSegment is place for a polyline segment of a block
+func (insert TextEntity) placeSegment(s PolylineSegment, baseX, baseY, baseZ float64) PolylineSegment {
+	s.X1, s.Y1 = insert.transform(s.X1, s.Y1, baseX, baseY)
+	s.X2, s.Y2 = insert.transform(s.X2, s.Y2, baseX, baseY)
+	s.Z1, s.Z2 = insert.elevate(s.Z1, baseZ), insert.elevate(s.Z2, baseZ)
+	s.Length = geometry.Distance(s.X1, s.Y1, s.X2, s.Y2)
+	if s.Layer == "0" {
+		s.Layer = insert.Layer
+	}
+	s.Handle = insert.Handle
+	return s
+}
+
+// transform returns the point x, y of a block with base point baseX, baseY in the coordinates
+// the INSERT is placed in
+func (insert TextEntity) transform(x, y, baseX, baseY float64) (float64, float64) {
+	x, y = (x-baseX)*insert.block.ScaleX, (y-baseY)*insert.block.ScaleY
+	sin, cos := math.Sincos(insert.block.Rotation * math.Pi / 180)
+	return insert.X + x*cos - y*sin, insert.Y + x*sin + y*cos
+}
+
+// elevate returns the elevation z of a block with base elevation baseZ at the INSERT; the
+// rotation is about the Z axis, so only the Z scale applies
+func (insert TextEntity) elevate(z, baseZ float64) float64 {
+	return insert.Z + (z-baseZ)*insert.block.ScaleZ
+}
+
+// blockNesting follows the chain of blocks being expanded. It refuses a block that is already
+// in the chain, i.e. inserts itself directly or through other blocks, and a chain longer than
+// the limit, with one warning per block.
+type blockNesting struct {
+	limit    int
+	chain    []string // names of the blocks being expanded, outermost first
+	warned   map[string]bool
+	warnings []string
+}
+
+// newBlockNesting returns a nesting following chains of at most limit blocks
+func newBlockNesting(limit int) blockNesting {
+	return blockNesting{limit: limit, warned: make(map[string]bool)}
+}
+
+// enter reports whether the block name is expanded inside the current chain and adds it; an
+// entered block is left with leave
+func (n *blockNesting) enter(name string) bool {
+	for i, outer := range n.chain {
+		if strings.EqualFold(outer, name) {
+			n.warn(name, fmt.Sprintf("block %s inserts itself (%s > %s), not expanded", name, strings.Join(n.chain[i:], " > "), name))
+			return false
+		}
+	}
+	if len(n.chain) >= n.limit {
+		n.warn(name, fmt.Sprintf("block %s exceeds the nesting depth of %d, not expanded (raise -block-depth)", name, n.limit))
+		return false
+	}
+	n.chain = append(n.chain, name)
+	return true
+}
+
+// leave removes the innermost block from the chain
+func (n *blockNesting) leave() {
+	n.chain = n.chain[:len(n.chain)-1]
+}
+
+// warn records the warning of a block unless it has one
+func (n *blockNesting) warn(name, warning string) {
+	if key := strings.ToUpper(name); !n.warned[key] {
+		n.warned[key] = true
+		n.warnings = append(n.warnings, warning)
+	}
+}
+
+// blockDefinition is the base point and content of a BLOCK: its text entities and INSERTs
+type blockDefinition struct {
+	baseX, baseY, baseZ float64
+	content             []TextEntity
+}
+
+// blockExpander takes the text entities and block records of a file in file order and emits
+// the text entities as they appear in the drawing. The text of a block definition is kept and
+// emitted at every INSERT of the block, in world coordinates; nested INSERTs are followed up to
+// the depth limit. Layout blocks (*Model_Space, *Paper_Space...) are drawn, not inserted, so
+// their text is emitted as it is, and so are the ATTDEF defaults of block definitions.
+type blockExpander struct {
+	emit    func(TextEntity) error
+	blocks  map[string]*blockDefinition
+	current *blockDefinition // block being read; nil outside BLOCK / ENDBLK and in layouts
+	nesting blockNesting
+	layers  layerColors // the LAYER table, which precedes the blocks and entities
+}
+
+// newBlockExpander returns an expander emitting to emit, with ByLayer colors resolved, that follows depth levels of nested
+// blocks, 0 for the default
+func newBlockExpander(emit func(TextEntity) error, depth int) *blockExpander {
+	b := &blockExpander{blocks: make(map[string]*blockDefinition), nesting: newBlockNesting(blockDepthLimit(depth)), layers: make(layerColors)}
+	b.emit = func(e TextEntity) error {
+		return emit(b.layers.resolve(e))
+	}
+	return b
+}
+
+// Warnings returns the blocks not expanded as they insert themselves or are nested too deep
+func (b *blockExpander) Warnings() []string {
+	return b.nesting.warnings
+}
+
+// add takes the next entity or block record of the file
+func (b *blockExpander) add(e TextEntity) error {
+	switch {
+	case e.block == nil && (b.current == nil || e.EntityType == "ATTDEF"):
+		return b.emit(e)
+	case e.block == nil:
+		b.current.content = append(b.current.content, e)
+	case isLayerRecord(e.EntityType):
+		b.layers.add(e)
+	case e.EntityType == "BLOCK":
+		b.current = nil
+		if !isLayoutBlock(e.block.Name) {
+			b.current = &blockDefinition{baseX: e.X, baseY: e.Y, baseZ: e.Z}
+			b.blocks[strings.ToUpper(e.block.Name)] = b.current
+		}
+	case e.EntityType == "ENDBLK":
+		b.current = nil
+	case b.current != nil: // INSERT inside a block definition
+		b.current.content = append(b.current.content, e)
+	default:
+		return b.expand(e, func(e TextEntity) TextEntity { return e })
+	}
+	return nil
+}
+
+// expand emits the text of the block insert refers to; outer places the coordinates of
+// insert in the world
+func (b *blockExpander) expand(insert TextEntity, outer func(TextEntity) TextEntity) error {
+	definition := b.blocks[strings.ToUpper(insert.block.Name)]
+	if definition == nil || !b.nesting.enter(insert.block.Name) {
+		return nil
+	}
+	defer b.nesting.leave()
+	inner := func(e TextEntity) TextEntity {
+		return outer(insert.place(e, definition.baseX, definition.baseY, definition.baseZ))
+	}
+	for _, e := range definition.content {
+		if e.block != nil {
+			if err := b.expand(e, inner); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := b.emit(inner(e)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expandBlocks returns entities, the scan result of a whole file, with the blocks expanded to
+// depth levels, and the warnings of the expansion
+func expandBlocks(entities []TextEntity, depth int) ([]TextEntity, []string) {
+	expanded := newEntities(len(entities))
+	expander := newBlockExpander(func(e TextEntity) error {
+		expanded = append(expanded, e)
+		return nil
+	}, depth)
+	for _, e := range entities {
+		expander.add(e)
+	}
+	return expanded, expander.Warnings()
+}
+
+// segmentBlocks places the polyline segments of block definitions at the INSERTs of the blocks,
+// the way blockExpander places their text. It reads the block records from the groups of a
+// segment scan: startRecord at a BLOCK, ENDBLK or INSERT, setGroup for its groups and
+// endRecord at the next record. Segment lengths change with the insert scale, so the segments
+// of a definition are kept unfiltered and emit decides on the placed ones.
+type segmentBlocks struct {
+	emit    func(segment PolylineSegment, handle string)
+	blocks  map[string]*segmentBlock
+	current *segmentBlock // block being read; nil outside BLOCK / ENDBLK and in layouts
+	record  *TextEntity   // block record being read
+	nesting blockNesting
+	layout  string // INSERTs and polylines outside block definitions are only placed in this layout
+}
+
+// segmentBlock is the base point and content of a BLOCK for the segment scan: its segments
+// with the handles of their polylines, and its INSERTs
+type segmentBlock struct {
+	baseX, baseY, baseZ float64
+	segments            []PolylineSegment
+	handles             []string
+	inserts             []TextEntity
+}
+
+// newSegmentBlocks returns segment blocks emitting to emit the segments drawn in layout (see
+// ParseOptions.Layout) that follow depth levels of nested blocks, 0 for the default
+func newSegmentBlocks(emit func(segment PolylineSegment, handle string), depth int, layout string) *segmentBlocks {
+	return &segmentBlocks{emit: emit, blocks: make(map[string]*segmentBlock), nesting: newBlockNesting(blockDepthLimit(depth)), layout: layout}
+}
+
+// drawn reports whether a polyline with paper space flag paper (67) and layout name (410) is
+// taken: always in a block definition, where its INSERTs decide, otherwise if it is in the layout
+func (s *segmentBlocks) drawn(paper bool, name string) bool {
+	return s.current != nil || inLayout(paper, name, s.layout)
+}
+
+// startRecord starts a block record of recordType
+func (s *segmentBlocks) startRecord(recordType string) {
+	record := newBlockRecord(recordType)
+	s.record = &record
+}
+
+// setGroup applies a group to the block record being read and reports whether there is one
+func (s *segmentBlocks) setGroup(code, value string) bool {
+	if s.record == nil {
+		return false
+	}
+	s.record.setGroup(code, value)
+	return true
+}
+
+// endRecord ends the block record being read, if any: a BLOCK starts a definition, an INSERT
+// is kept in the definition being read or placed in the drawing
+func (s *segmentBlocks) endRecord() {
+	record := s.record
+	s.record = nil
+	switch {
+	case record == nil:
+	case record.EntityType == "BLOCK":
+		s.current = nil
+		if !isLayoutBlock(record.block.Name) {
+			s.current = &segmentBlock{baseX: record.X, baseY: record.Y, baseZ: record.Z}
+			s.blocks[strings.ToUpper(record.block.Name)] = s.current
+		}
+	case record.EntityType == "ENDBLK":
+		s.current = nil
+	case s.current != nil:
+		s.current.inserts = append(s.current.inserts, *record)
+	case !record.InLayout(s.layout):
+	default:
+		s.expand(*record, func(segment PolylineSegment) PolylineSegment { return segment })
+	}
+}
+
+// add takes a segment of the polyline with handle: kept in the definition being read, or
+// emitted as it is outside block definitions
+func (s *segmentBlocks) add(segment PolylineSegment, handle string) {
+	if s.current == nil {
+		s.emit(segment, handle)
+		return
+	}
+	s.current.segments = append(s.current.segments, segment)
+	s.current.handles = append(s.current.handles, handle)
+}
+
+// expand emits the segments of the block insert refers to; outer places the coordinates of
+// insert in the world
+func (s *segmentBlocks) expand(insert TextEntity, outer func(PolylineSegment) PolylineSegment) {
+	definition := s.blocks[strings.ToUpper(insert.block.Name)]
+	if definition == nil || !s.nesting.enter(insert.block.Name) {
+		return
+	}
+	defer s.nesting.leave()
+	inner := func(segment PolylineSegment) PolylineSegment {
+		return outer(insert.placeSegment(segment, definition.baseX, definition.baseY, definition.baseZ))
+	}
+	for i, segment := range definition.segments {
+		s.emit(inner(segment), definition.handles[i])
+	}
+	for _, nested := range definition.inserts {
+		s.expand(nested, inner)
+	}
+}
+
+// isLayoutBlock reports whether a block holds the entities of model space or a layout
+func isLayoutBlock(name string) bool {
+	upper := strings.ToUpper(name)
+	return strings.HasPrefix(upper, "*MODEL_SPACE") || strings.HasPrefix(upper, "*PAPER_SPACE")
+}
@@ -1,7 +1,8 @@
-package main
+package dxfparser
 
 import (
 	"fmt"
+	"regexp"
 	"sort"
 	"strings"
 	"time"
@@ -19,6 +20,80 @@ func isTextRemark(text string) bool {
 	return !isPieceNumber(textStr) && !isNumber(textStr)
 }
 
+// cutLengthColumns is the layout of the CUT PIPE LENGTH table: two blocks of
+// PIECE NO | CUT LENGTH | N.S. (MM) | REMARKS side by side
+const cutLengthColumns = 8
+
+// splitCutLengthRowByColumns places the cells of a CUT PIPE LENGTH row into the columns whose
+// header they are below, joining several fragments in one column (e.g. two remark texts).
+// A cell belongs to column i from a quarter of the gap left of header i up to the same point
+// before header i+1, since values are often centered under a left-aligned header text.
+// Returns false if the header does not have the expected layout or the piece columns do not
+// hold piece numbers, so the caller can fall back to validateAndCorrectCutLengthRow.
+func splitCutLengthRowByColumns(cells []TableCell, headerXs []float64) ([]string, bool) {
+	if len(headerXs) != cutLengthColumns || len(cells) == 0 {
+		return nil, false
+	}
+	for i := 1; i < len(headerXs); i++ {
+		if headerXs[i] <= headerXs[i-1] {
+			return nil, false
+		}
+	}
+
+	// Left boundary of each column
+	bounds := make([]float64, cutLengthColumns)
+	for i := range bounds {
+		gap := headerXs[1] - headerXs[0]
+		if i > 0 {
+			gap = headerXs[i] - headerXs[i-1]
+		}
+		bounds[i] = headerXs[i] - gap/4
+	}
+
+	fragments := make([][]string, cutLengthColumns)
+	for _, cell := range cells {
+		text := strings.TrimSpace(cell.Text)
+		if text == "" {
+			continue
+		}
+		col := 0
+		for col+1 < cutLengthColumns && cell.X >= bounds[col+1] {
+			col++
+		}
+		fragments[col] = append(fragments[col], text)
+	}
+
+	corrected := make([]string, cutLengthColumns)
+	for i, parts := range fragments {
+		corrected[i] = strings.Join(parts, " ")
+	}
+
+	// Piece columns must be empty or a single piece number, length and N.S. columns numeric
+	for _, base := range []int{0, 4} {
+		piece := corrected[base]
+		if piece == "" {
+			if corrected[base+1] != "" || corrected[base+2] != "" {
+				return nil, false
+			}
+			continue
+		}
+		if !isPieceNumber(piece) {
+			return nil, false
+		}
+		for _, col := range []int{base + 1, base + 2} {
+			if corrected[col] != "" && !isNumber(corrected[col]) {
+				return nil, false
+			}
+		}
+	}
+	if corrected[0] == "" && corrected[4] == "" {
+		return nil, false
+	}
+
+	debugPrint(fmt.Sprintf("[DEBUG] Row placed by header columns: %v", corrected))
+	return corrected, true
+}
+
 func validateAndCorrectCutLengthRow(row []string) []string {
 	if len(row) == 0 {
 		return row
@@ -32,7 +107,7 @@ func validateAndCorrectCutLengthRow(row []string) []string {
 		index int
 		value string
 	}{}
-	
+
 	for i, cell := range row {
 		cellStr := strings.TrimSpace(cell)
 		if isPieceNumber(cellStr) {
@@ -122,7 +197,39 @@ func validateAndCorrectCutLengthRow(row []string) []string {
 	return corrected
 }
 
-func findPipeClass(textEntities []TextEntity) string {
+// Title block attribute tags holding the drawing number and the pipe class, compared without
+// separators (DWG_NO, Drawing-No)
+var (
+	drawingNoTags = []string{"DWGNO", "DRAWINGNO", "KKS"}
+	pipeClassTags = []string{"PIPECLASS", "PIPINGCLASS"}
+)
+
+// attributeMatch returns the first match of pattern in the value of an ATTRIB with one of tags
+func attributeMatch(textEntities []TextEntity, tags []string, pattern *regexp.Regexp) string {
+	for _, entity := range textEntities {
+		if entity.EntityType != "ATTRIB" || entity.Tag == "" {
+			continue
+		}
+		tag := strings.ToUpper(strings.NewReplacer("_", "", "-", "", ".", "", " ", "").Replace(entity.Tag))
+		for _, t := range tags {
+			if tag != t {
+				continue
+			}
+			if match := pattern.FindString(strings.TrimSpace(entity.Content)); match != "" {
+				debugPrint(fmt.Sprintf("[DEBUG] Found '%s' in attribute %s", match, entity.Tag))
+				return match
+			}
+		}
+	}
+	return ""
+}
+
+func findPipeClass(textEntities []TextEntity, patterns *Patterns) string {
+	// A title block attribute names the pipe class directly
+	if match := attributeMatch(textEntities, pipeClassTags, patterns.PipeClass); match != "" {
+		return match
+	}
+
 	// Look for 'Pipe class:' label first
 	var pipeClassLabelY, pipeClassLabelX *float64
 
@@ -153,7 +260,7 @@ func findPipeClass(textEntities []TextEntity) string {
 				entity.X > *pipeClassLabelX && // To the right of label
 				abs(entity.X-*pipeClassLabelX) < 200 { // Not too far horizontally
 				textClean := strings.TrimSpace(entity.Content)
-				match := pipeClassPattern.FindString(textClean)
+				match := patterns.PipeClass.FindString(textClean)
 				if match != "" {
 					distance := abs(entity.X - *pipeClassLabelX)
 					candidates = append(candidates, candidate{match, distance})
@@ -188,7 +295,7 @@ func findPipeClass(textEntities []TextEntity) string {
 		for _, entity := range textEntities {
 			if entity.Y < *designDataY && entity.Y > *designDataY-150 { // Within 150 units below DESIGN DATA
 				textClean := strings.TrimSpace(entity.Content)
-				match := pipeClassPattern.FindString(textClean)
+				match := patterns.PipeClass.FindString(textClean)
 				if match != "" {
 					pipeClass := match
 					debugPrint(fmt.Sprintf("[DEBUG] Found pipe class in DESIGN DATA area: '%s' at X=%f, Y=%f", pipeClass, entity.X, entity.Y))
@@ -225,7 +332,7 @@ func findPipeClass(textEntities []TextEntity) string {
 
 	for _, entity := range bottomEntities {
 		if entity.X < 500 { // Avoid far right area where revision notes typically are
-			match := pipeClassPattern.FindString(strings.TrimSpace(entity.Content))
+			match := patterns.PipeClass.FindString(strings.TrimSpace(entity.Content))
 			if match != "" {
 				centerCandidates = append(centerCandidates, centerCandidate{match, entity.X, entity.Y})
 				debugPrint(fmt.Sprintf("[DEBUG] Center area pipe class candidate: '%s' at X=%f, Y=%f", match, entity.X, entity.Y))
@@ -247,14 +354,42 @@ func findPipeClass(textEntities []TextEntity) string {
 	return ""
 }
 
-func findDrawingNo(textEntities []TextEntity) string {
+// titleStyleEntities returns the entities in the title_styles of the project config if any of
+// them matches pattern, so a drawing number in the title block wins over the same kind of code
+// in annotation text; otherwise, and without title_styles, all entities
+func titleStyleEntities(textEntities []TextEntity, pattern *regexp.Regexp) []TextEntity {
+	if len(titleStyles) == 0 {
+		return textEntities
+	}
+	var titled []TextEntity
+	found := false
+	for _, entity := range textEntities {
+		if !entity.InStyle(titleStyles) {
+			continue
+		}
+		titled = append(titled, entity)
+		found = found || pattern.MatchString(entity.Content)
+	}
+	if !found {
+		debugPrint("[DEBUG] No drawing number in the title styles, using text of all styles")
+		return textEntities
+	}
+	return titled
+}
+
+func findDrawingNo(textEntities []TextEntity, patterns *Patterns) string {
 	// Find KKS code with pattern 1AAA11BR111 (1=digit, A=capital letter, BR=fixed)
 	// Located in bottom right corner, below and to the right of ERECTION MATERIALS
 
+	// A title block attribute names the drawing number directly
+	if match := attributeMatch(textEntities, drawingNoTags, patterns.DrawingNo); match != "" {
+		return match
+	}
+
 	// First find ERECTION MATERIALS position to establish search area
 	var erectionX, erectionY *float64
 	for _, entity := range textEntities {
-		if strings.Contains(strings.ToUpper(entity.Content), "ERECTION MATERIALS") {
+		if matchesTableTitle(entity.Content, "ERECTION MATERIALS") {
 			erectionX = &entity.X
 			erectionY = &entity.Y
 			debugPrint(fmt.Sprintf("[DEBUG] Found ERECTION MATERIALS at X=%f, Y=%f", entity.X, entity.Y))
@@ -270,8 +405,8 @@ func findDrawingNo(textEntities []TextEntity) string {
 	}
 	candidates := []candidate{}
 
-	for _, entity := range textEntities {
-		match := kksPattern.FindString(entity.Content)
+	for _, entity := range titleStyleEntities(textEntities, patterns.DrawingNo) {
+		match := patterns.DrawingNo.FindString(entity.Content)
 		if match != "" {
 			// If we found ERECTION MATERIALS, filter by position (below and to the right)
 			if erectionX != nil && erectionY != nil {
@@ -322,22 +457,24 @@ func abs(x float64) float64 {
 	return x
 }
 
-// Extract pipe descriptions from material table (PIPE category only)
-func extractPipeDescriptions(matRows [][]string) []string {
-	var pipeDescriptions []string
-	
+// Extract pipe rows from material table (PIPE category only)
+func extractPipeRows(matRows [][]string) []PipeRow {
+	var pipes []PipeRow
+
 	for _, row := range matRows {
 		if len(row) >= 6 && row[5] == "PIPE" { // Category is in column F (index 5)
-			if len(row) >= 2 && row[1] != "" { // Description is in column B (index 1)
-				pipeDescriptions = append(pipeDescriptions, row[1])
+			if row[1] != "" { // Description is in column B (index 1)
+				pipes = append(pipes, PipeRow{Description: row[1], NS: row[2], Qty: parseQuantity(row[3])})
 			}
 		}
 	}
-	
-	return pipeDescriptions
+
+	return pipes
 }
 
-func convertCutLengthToSingleRowFormat(header []string, rows [][]string, drawingNo, pipeClass string, pipeDescriptions []string) ([]string, [][]string) {
+// convertCutLengthToSingleRowFormat splits the two-piece CUT PIPE LENGTH rows into one row per
+// piece. With several pipes on the drawing, the PIPE DESCRIPTION follows the pipe policy.
+func convertCutLengthToSingleRowFormat(header []string, rows [][]string, drawingNo, pipeClass string, pipes []PipeRow, policy PipePolicy) ([]string, [][]string) {
 	if len(rows) == 0 {
 		return []string{"PIECE NO", "CUT LENGTH", "N.S. (MM)", "REMARKS", "PIPE DESCRIPTION", "MULTIPLE PIPE DESCRIPTIONS", "Drawing-No.", "Pipe Class"}, [][]string{}
 	}
@@ -345,13 +482,13 @@ func convertCutLengthToSingleRowFormat(header []string, rows [][]string, drawing
 	// Determine pipe description to use
 	pipeDesc := ""
 	multipleDesc := "NO"
-	if len(pipeDescriptions) == 0 {
+	if len(pipes) == 0 {
 		pipeDesc = "No pipe description found"
-	} else if len(pipeDescriptions) == 1 {
-		pipeDesc = pipeDescriptions[0]
+	} else if len(pipes) == 1 {
+		pipeDesc = pipes[0].Description
 	} else {
-		// Join all pipe descriptions with " | " separator
-		pipeDesc = strings.Join(pipeDescriptions, " | ")
+		// Selected pipe descriptions joined with " | "; the flag still reports the choice
+		pipeDesc = describePipes(selectPipes(pipes, policy), policy, " | ")
 		multipleDesc = "YES"
 	}
 
@@ -414,7 +551,76 @@ func convertCutLengthToSingleRowFormat(header []string, rows [][]string, drawing
 	return newHeader, newRows
 }
 
-func processDXFFile(filepath string) DXFResult {
+// checkPieceNumbers validates that the piece numbers of a drawing's cut length rows (single-row
+// format, piece number in column 0) run from <1> to <N> without gaps or repeats.
+// Returns "" for a drawing without cut lengths, "OK", or the problems found, e.g.
+// "missing <3>, <5>-<7>; duplicate <2>; invalid 'A1'".
+func checkPieceNumbers(cutRows [][]string) string {
+	if len(cutRows) == 0 {
+		return ""
+	}
+
+	counts := make(map[int]int)
+	maxPiece := 0
+	var invalid []string
+	for _, row := range cutRows {
+		if len(row) == 0 {
+			continue
+		}
+		text := strings.TrimSpace(row[0])
+		if !isPieceNumber(text) {
+			invalid = append(invalid, fmt.Sprintf("'%s'", text))
+			continue
+		}
+		var n int
+		fmt.Sscanf(strings.Trim(text, "<>"), "%d", &n)
+		counts[n]++
+		if n > maxPiece {
+			maxPiece = n
+		}
+	}
+
+	var missing, duplicate []string
+	for n := 1; n <= maxPiece; n++ {
+		if counts[n] > 1 {
+			duplicate = append(duplicate, fmt.Sprintf("<%d>", n))
+		}
+		if counts[n] > 0 {
+			continue
+		}
+		// Collapse runs of missing numbers into a range
+		end := n
+		for end+1 <= maxPiece && counts[end+1] == 0 {
+			end++
+		}
+		if end > n {
+			missing = append(missing, fmt.Sprintf("<%d>-<%d>", n, end))
+		} else {
+			missing = append(missing, fmt.Sprintf("<%d>", n))
+		}
+		n = end
+	}
+	if counts[0] > 0 {
+		invalid = append(invalid, "'<0>'")
+	}
+
+	var problems []string
+	if len(missing) > 0 {
+		problems = append(problems, "missing "+strings.Join(missing, ", "))
+	}
+	if len(duplicate) > 0 {
+		problems = append(problems, "duplicate "+strings.Join(duplicate, ", "))
+	}
+	if len(invalid) > 0 {
+		problems = append(problems, "invalid "+strings.Join(invalid, ", "))
+	}
+	if len(problems) == 0 {
+		return "OK"
+	}
+	return strings.Join(problems, "; ")
+}
+
+func processDXFFile(filepath string, run *runConfig) DXFResult {
 	start := time.Now()
 	result := DXFResult{
 		Filename: filepath,
@@ -424,16 +630,21 @@ func processDXFFile(filepath string) DXFResult {
 	debugPrint(fmt.Sprintf("[DEBUG] Opening DXF file: %s", filepath))
 
 	// Use our existing Go DXF parser
-	parser := NewDXFParser(1) // Use single worker for individual file processing
+	// Use single worker for individual file processing
+	parser := NewDXFParserWithOptions(run.parse)
 	textEntities, err := parser.ParseFile(filepath)
 	if err != nil {
 		result.Error = fmt.Sprintf("Failed to parse DXF file: %v", err)
 		result.ProcessingTime = time.Since(start).Seconds()
 		return result
 	}
+	if !run.parse.IncludeHidden {
+		textEntities = visibleEntities(textEntities)
+	}
 
-	drawingNo := findDrawingNo(textEntities)
-	pipeClass := findPipeClass(textEntities)
+	patterns := orActivePatterns(run.patterns)
+	drawingNo := findDrawingNo(textEntities, patterns)
+	pipeClass := findPipeClass(textEntities, patterns)
 
 	matHeader, matRows := extractTable(textEntities, "ERECTION MATERIALS")
 	cutHeader, cutRows := extractTable(textEntities, "CUT PIPE LENGTH")
@@ -448,15 +659,19 @@ func processDXFFile(filepath string) DXFResult {
 	}
 
 	if len(cutRows) > 0 {
-		// Extract pipe descriptions from material table for cut length table
-		pipeDescriptions := extractPipeDescriptions(matRows)
-		
+		// Extract pipe rows from material table for cut length table
+		pipes := extractPipeRows(matRows)
+
 		// Convert to single-row format with pipe descriptions
-		result.CutHeader, result.CutRows = convertCutLengthToSingleRowFormat(cutHeader, cutRows, drawingNo, pipeClass, pipeDescriptions)
+		result.CutHeader, result.CutRows = convertCutLengthToSingleRowFormat(cutHeader, cutRows, drawingNo, pipeClass, pipes, run.pipePolicy)
 	}
+	result.PieceCheck = checkPieceNumbers(result.CutRows)
+	result.MatConfidence = scoreTable("ERECTION MATERIALS", result.MatHeader, result.MatRows)
+	result.CutConfidence = scoreTable("CUT PIPE LENGTH", result.CutHeader, result.CutRows)
 
 	result.DrawingNo = drawingNo
 	result.PipeClass = pipeClass
+	result.Revision = findRevision(filepath, patterns)
 	result.ProcessingTime = time.Since(start).Seconds()
 
 	debugPrint(fmt.Sprintf("[DEBUG] Extracted %d material rows and %d cut length rows from %s", len(result.MatRows), len(result.CutRows), filepath))
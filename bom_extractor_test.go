@@ -0,0 +1,87 @@
+package dxfparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// cutLengthHeaderXs are the X positions of the CUT PIPE LENGTH header texts: PIECE NO,
+// CUT LENGTH, N.S. (MM), REMARKS of the left block, then the same of the right block
+var cutLengthHeaderXs = []float64{10, 30, 50, 70, 110, 130, 150, 170}
+
+func TestSplitCutLengthRowByColumns(t *testing.T) {
+	cases := []struct {
+		name  string
+		cells []TableCell
+		want  []string
+	}{
+		{
+			name:  "both blocks",
+			cells: []TableCell{{10, "<1>"}, {30, "1200"}, {50, "50"}, {110, "<2>"}, {130, "800"}, {150, "80"}},
+			want:  []string{"<1>", "1200", "50", "", "<2>", "800", "80", ""},
+		},
+		{
+			name:  "two remark fragments",
+			cells: []TableCell{{10, "<1>"}, {30, "1200"}, {50, "50"}, {70, "SEE"}, {78, "NOTE 3"}, {110, "<2>"}, {130, "800"}, {150, "50"}},
+			want:  []string{"<1>", "1200", "50", "SEE NOTE 3", "<2>", "800", "50", ""},
+		},
+		{
+			name:  "numeric remark",
+			cells: []TableCell{{10, "<3>"}, {30, "450.5"}, {50, "25"}, {70, "SEE NOTE"}, {88, "3"}},
+			want:  []string{"<3>", "450.5", "25", "SEE NOTE 3", "", "", "", ""},
+		},
+		{
+			name:  "remark of the right block only",
+			cells: []TableCell{{10, "<4>"}, {30, "300"}, {50, "50"}, {110, "<5>"}, {130, "75"}, {150, "50"}, {170, "FIELD"}, {180, "WELD"}},
+			want:  []string{"<4>", "300", "50", "", "<5>", "75", "50", "FIELD WELD"},
+		},
+		{
+			name:  "values centered under left-aligned headers",
+			cells: []TableCell{{12, "<6>"}, {36, "1500"}, {54, "100"}, {114, "<7>"}, {138, "620"}, {156, "100"}},
+			want:  []string{"<6>", "1500", "100", "", "<7>", "620", "100", ""},
+		},
+		{
+			name:  "right block only",
+			cells: []TableCell{{110, "<8>"}, {130, "90"}, {150, "15"}},
+			want:  []string{"", "", "", "", "<8>", "90", "15", ""},
+		},
+		{
+			name:  "blank fragments",
+			cells: []TableCell{{10, "<9>"}, {30, " 2000 "}, {50, "40"}, {70, "  "}},
+			want:  []string{"<9>", "2000", "40", "", "", "", "", ""},
+		},
+	}
+	for _, c := range cases {
+		got, ok := splitCutLengthRowByColumns(c.cells, cutLengthHeaderXs)
+		if !ok {
+			t.Errorf("%s: not placed, want %q", c.name, c.want)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestSplitCutLengthRowByColumnsFallback(t *testing.T) {
+	row := []TableCell{{10, "<1>"}, {30, "1200"}, {50, "50"}}
+	cases := []struct {
+		name     string
+		cells    []TableCell
+		headerXs []float64
+	}{
+		{"no cells", nil, cutLengthHeaderXs},
+		{"header of another layout", row, []float64{10, 30, 50, 70}},
+		{"header not left to right", row, []float64{10, 30, 50, 70, 110, 130, 130, 170}},
+		{"text in a piece column", []TableCell{{10, "PIPE"}, {30, "1200"}, {50, "50"}}, cutLengthHeaderXs},
+		{"text in a length column", []TableCell{{10, "<1>"}, {30, "SEE NOTE"}, {50, "50"}}, cutLengthHeaderXs},
+		{"length without a piece", []TableCell{{10, "<1>"}, {30, "1200"}, {50, "50"}, {130, "800"}}, cutLengthHeaderXs},
+		{"two pieces in one column", []TableCell{{10, "<1>"}, {14, "<2>"}, {30, "1200"}}, cutLengthHeaderXs},
+		{"remarks only", []TableCell{{70, "SEE NOTE 3"}}, cutLengthHeaderXs},
+	}
+	for _, c := range cases {
+		if got, ok := splitCutLengthRowByColumns(c.cells, c.headerXs); ok {
+			t.Errorf("%s: placed as %q, want the fallback", c.name, got)
+		}
+	}
+}
@@ -1,7 +1,10 @@
-package main
+package dxfparser
 
 import (
+	"bytes"
+	"context"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,6 +12,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -19,22 +23,38 @@ var debugMode = false
 var (
 	pieceNumberPattern = regexp.MustCompile(`^<\d+>$`)
 	numberPattern      = regexp.MustCompile(`^\d+(\.\d+)?$`)
-	kksPattern         = regexp.MustCompile(`\b\d[A-Z]{3}\d{2}BR\d{3}\b`)
-	pipeClassPattern   = regexp.MustCompile(`\b[A-Z]{4}\b`)
 )
 
 // DXFResult represents the extracted data from a single DXF file
 type DXFResult struct {
-	DrawingNo      string      `json:"drawing_no"`
-	PipeClass      string      `json:"pipe_class"`
-	MatHeader      []string    `json:"mat_header"`
-	MatRows        [][]string  `json:"mat_rows"`
-	CutHeader      []string    `json:"cut_header"`
-	CutRows        [][]string  `json:"cut_rows"`
-	Error          string      `json:"error"`
-	ProcessingTime float64     `json:"processing_time"`
-	Filename       string      `json:"filename"`
-	FilePath       string      `json:"file_path"`
+	DrawingNo      string           `json:"drawing_no"`
+	PipeClass      string           `json:"pipe_class"`
+	Revision       string           `json:"revision"`
+	MatHeader      []string         `json:"mat_header"`
+	MatRows        [][]string       `json:"mat_rows"`
+	CutHeader      []string         `json:"cut_header"`
+	CutRows        [][]string       `json:"cut_rows"`
+	Error          string           `json:"error"`
+	ProcessingTime float64          `json:"processing_time"`
+	Filename       string           `json:"filename"`
+	FilePath       string           `json:"file_path"`
+	MatProvenance  []RowProvenance  `json:"mat_provenance,omitempty"`
+	CutProvenance  []RowProvenance  `json:"cut_provenance,omitempty"`
+	Warnings       []string         `json:"warnings,omitempty"`
+	PieceCheck     string           `json:"piece_check,omitempty"` // piece number continuity, see checkPieceNumbers
+	MatConfidence  *TableConfidence `json:"mat_confidence,omitempty"`
+	CutConfidence  *TableConfidence `json:"cut_confidence,omitempty"`
+	Source         string           `json:"source,omitempty"`     // input directory or file the drawing was found through
+	MatSource      string           `json:"mat_source,omitempty"` // "ebom:<dictionary>" or "acad_table:<handle>" for structured tables, "" for text
+	CutSource      string           `json:"cut_source,omitempty"`
+	Partial        bool             `json:"partial,omitempty"`        // extracted from the intact part of a truncated file (-recover)
+	Units          string           `json:"units,omitempty"`          // units of the drawing, converted to millimeters
+	ParseWarnings  int              `json:"parse_warnings,omitempty"` // malformed group code / value pairs skipped
+	Proxies        int              `json:"proxies,omitempty"`        // proxy entities, whose graphics are not read
+	Version        string           `json:"version,omitempty"`        // $ACADVER with its release, e.g. "AC1015 (2000)"
+	Timing         FileTiming       `json:"-"`                        // stage times for -perf-stats
+	RawMatRows     []RawTableRow    `json:"-"`
+	RawCutRows     []RawTableRow    `json:"-"`
 }
 
 // SummaryRow for the summary CSV output
@@ -49,6 +69,15 @@ type SummaryRow struct {
 	CutMissing     bool    `json:"cut_missing"`
 	Error          string  `json:"error"`
 	ProcessingTime float64 `json:"processing_time"`
+	Warnings       string  `json:"warnings"`
+	PieceCheck     string  `json:"piece_check"`
+	MatConfidence  string  `json:"mat_confidence"`
+	CutConfidence  string  `json:"cut_confidence"`
+	ParseWarnings  int     `json:"parse_warnings"`
+	Proxies        int     `json:"proxies"`
+	Units          string  `json:"units"`
+	Version        string  `json:"version"`
+	Source         string  `json:"source"`
 }
 
 func debugPrint(message string) {
@@ -58,61 +87,61 @@ func debugPrint(message string) {
 }
 
 // Write all output files
-func writeOutputFiles(directory string, materialRows, cutRows [][]string, summary []SummaryRow, matHeader, cutHeader []string) error {
-	
+func writeOutputFiles(run *runConfig, directory string, materialRows, cutRows [][]string, summary []SummaryRow, matHeader, cutHeader []string) error {
+	out := &run.outputs
+
 	// Write ERECTION MATERIALS CSV
 	if len(materialRows) > 0 {
-		matFilename := filepath.Join(directory, "0001_ERECTION_MATERIALS.csv")
-		if err := writeCSV(matFilename, matHeader, materialRows); err != nil {
+		matFilename := out.path(directory, "0001_ERECTION_MATERIALS.csv")
+		if err := writeCSV(out, matFilename, matHeader, materialRows); err != nil {
 			return fmt.Errorf("error writing materials CSV: %v", err)
 		}
-		fmt.Printf("Wrote ERECTION MATERIALS data to: %s (%d rows)\n", matFilename, len(materialRows))
-		
+		fmt.Println(msg("bom.wrote_materials", matFilename, len(materialRows)))
+
 		// Post-process to fix missing N.S. columns
-		if err := fixMissingNSColumns(matFilename); err != nil {
+		if err := fixMissingNSColumns(out, matFilename); err != nil {
 			return fmt.Errorf("error fixing missing N.S. columns: %v", err)
 		}
 	}
 
 	// Write CUT PIPE LENGTH CSV
 	if len(cutRows) > 0 {
-		cutFilename := filepath.Join(directory, "0002_CUT_PIPE_LENGTH.csv")
-		if err := writeCSV(cutFilename, cutHeader, cutRows); err != nil {
+		cutFilename := out.path(directory, "0002_CUT_PIPE_LENGTH.csv")
+		if err := writeCSV(out, cutFilename, cutHeader, cutRows); err != nil {
 			return fmt.Errorf("error writing cut pipe CSV: %v", err)
 		}
-		fmt.Printf("Wrote CUT PIPE LENGTH data to: %s (%d rows)\n", cutFilename, len(cutRows))
+		fmt.Println(msg("bom.wrote_cut", cutFilename, len(cutRows)))
 	}
 
 	// Write AGGREGATED MATERIALS CSV
 	if len(materialRows) > 0 {
-		aggHeader, aggRows := createAggregatedMaterials(materialRows, matHeader)
-		aggFilename := filepath.Join(directory, "0003_AGGREGATED_MATERIALS.csv")
-		if err := writeCSV(aggFilename, aggHeader, aggRows); err != nil {
+		aggHeader, aggRows := createAggregatedMaterials(materialRows, matHeader, run.translit)
+		aggFilename := out.path(directory, "0003_AGGREGATED_MATERIALS.csv")
+		if err := writeCSV(out, aggFilename, aggHeader, aggRows); err != nil {
 			return fmt.Errorf("error writing aggregated materials CSV: %v", err)
 		}
-		fmt.Printf("Wrote AGGREGATED MATERIALS data to: %s (%d rows)\n", aggFilename, len(aggRows))
+		fmt.Println(msg("bom.wrote_aggregated", aggFilename, len(aggRows)))
 	}
 
 	// Write summary CSV
-	summaryFilename := filepath.Join(directory, "0004_SUMMARY.csv")
-	if err := writeSummaryCSV(summaryFilename, summary); err != nil {
+	summaryFilename := out.path(directory, "0004_SUMMARY.csv")
+	if err := writeSummaryCSV(out, summaryFilename, summary); err != nil {
 		return fmt.Errorf("error writing summary CSV: %v", err)
 	}
-	fmt.Printf("Wrote processing summary to: %s (%d files)\n", summaryFilename, len(summary))
+	fmt.Println(msg("bom.wrote_summary", summaryFilename, len(summary)))
 
 	return nil
 }
 
 // Write a generic CSV file
-func writeCSV(filename string, header []string, rows [][]string) error {
-	file, err := os.Create(filename)
+func writeCSV(out *runOutputs, filename string, header []string, rows [][]string) error {
+	file, err := out.create(filename)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 
 	writer := csv.NewWriter(file)
-	defer writer.Flush()
 
 	// Write header
 	if err := writer.Write(header); err != nil {
@@ -126,25 +155,28 @@ func writeCSV(filename string, header []string, rows [][]string) error {
 		}
 	}
 
-	return nil
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return file.Commit()
 }
 
 // Write summary CSV
-func writeSummaryCSV(filename string, summary []SummaryRow) error {
-	file, err := os.Create(filename)
+func writeSummaryCSV(out *runOutputs, filename string, summary []SummaryRow) error {
+	file, err := out.create(filename)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 
 	writer := csv.NewWriter(file)
-	defer writer.Flush()
 
 	// Write header
 	header := []string{
-		"FilePath", "Filename", "DrawingNo", "PipeClass", 
-		"MatRows", "CutRows", "MatMissing", "CutMissing", 
-		"Error", "ProcessingTime",
+		"FilePath", "Filename", "DrawingNo", "PipeClass",
+		"MatRows", "CutRows", "MatMissing", "CutMissing",
+		"Error", "ProcessingTime", "Warnings", "PieceCheck", "MatConfidence", "CutConfidence", "ParseWarnings", "Proxies", "Units", "Version", "Source",
 	}
 	if err := writer.Write(header); err != nil {
 		return err
@@ -163,169 +195,252 @@ func writeSummaryCSV(filename string, summary []SummaryRow) error {
 			strconv.FormatBool(row.CutMissing),
 			row.Error,
 			fmt.Sprintf("%.3f", row.ProcessingTime),
+			row.Warnings,
+			row.PieceCheck,
+			row.MatConfidence,
+			row.CutConfidence,
+			strconv.Itoa(row.ParseWarnings),
+			strconv.Itoa(row.Proxies),
+			row.Units,
+			row.Version,
+			row.Source,
 		}
 		if err := writer.Write(csvRow); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return file.Commit()
 }
 
 // Process files sequentially
-func processFilesSequential(files []string, debug bool) []DXFResult {
+func processFilesSequential(files []string, debug bool, run *runConfig) []DXFResult {
 	results := make([]DXFResult, 0, len(files))
-	
+
 	for i, filePath := range files {
 		if debug {
 			fmt.Printf("[%d/%d] Processing: %s\n", i+1, len(files), filepath.Base(filePath))
 		} else {
-			fmt.Printf("Processing file %d/%d: %s\n", i+1, len(files), filepath.Base(filePath))
+			fmt.Println(msg("bom.progress_start", i+1, len(files), filepath.Base(filePath)))
 		}
-		
-		result := processDXFFile(filePath)
+
+		result := processDXFFile(filePath, run)
 		results = append(results, result)
 	}
-	
+
 	return results
 }
 
 // Process files in parallel
-func processFilesParallel(files []string, workers int, debug bool) []DXFResult {
+func processFilesParallel(files []string, workers int, debug bool, run *runConfig) []DXFResult {
 	jobs := make(chan string, len(files))
 	results := make(chan DXFResult, len(files))
-	
+
 	// Start workers
 	for w := 0; w < workers; w++ {
 		go func() {
 			for filePath := range jobs {
-				result := processDXFFile(filePath)
+				result := processDXFFile(filePath, run)
 				results <- result
 			}
 		}()
 	}
-	
+
 	// Send jobs
 	for _, filePath := range files {
 		jobs <- filePath
 	}
 	close(jobs)
-	
+
 	// Collect results
 	var allResults []DXFResult
 	for i := 0; i < len(files); i++ {
 		result := <-results
 		allResults = append(allResults, result)
-		
+
 		if debug {
 			fmt.Printf("[%d/%d] Completed: %s\n", i+1, len(files), filepath.Base(result.FilePath))
 		} else {
-			fmt.Printf("Completed file %d/%d: %s\n", i+1, len(files), filepath.Base(result.FilePath))
+			fmt.Println(msg("bom.progress_done", i+1, len(files), filepath.Base(result.FilePath)))
 		}
 	}
-	
+
 	return allResults
 }
 
 // Print final summary
 func printFinalSummary(totalFiles, successfulFiles int, totalTime, totalProcessingTime float64, workers, matRows, cutRows int, directory string) {
-	
+
 	fmt.Println("\n" + strings.Repeat("=", 60))
-	fmt.Println("PROCESSING COMPLETE")
+	fmt.Println(msg("summary.complete"))
 	fmt.Println(strings.Repeat("=", 60))
-	
-	fmt.Printf("Directory: %s\n", directory)
-	fmt.Printf("Total Files: %d\n", totalFiles)
-	fmt.Printf("Successful: %d\n", successfulFiles)
-	fmt.Printf("Failed: %d\n", totalFiles-successfulFiles)
-	fmt.Printf("Workers: %d\n", workers)
-	fmt.Printf("Total Material Rows: %d\n", matRows)
-	fmt.Printf("Total Cut Pipe Rows: %d\n", cutRows)
-	fmt.Printf("Wall Clock Time: %.3f seconds\n", totalTime)
-	fmt.Printf("Total Processing Time: %.3f seconds\n", totalProcessingTime)
-	
+
+	fmt.Println(msg("summary.directory", directory))
+	fmt.Println(msg("summary.total_files", totalFiles))
+	fmt.Println(msg("summary.successful", successfulFiles))
+	fmt.Println(msg("summary.failed", totalFiles-successfulFiles))
+	fmt.Println(msg("summary.workers", workers))
+	fmt.Println(msg("summary.material_rows", matRows))
+	fmt.Println(msg("summary.cut_rows", cutRows))
+	fmt.Println(msg("summary.wall_time", totalTime))
+	fmt.Println(msg("summary.proc_time", totalProcessingTime))
+
 	if workers > 1 && totalProcessingTime > 0 {
 		efficiency := (totalProcessingTime / totalTime) * 100 / float64(workers)
-		fmt.Printf("Parallel Efficiency: %.1f%%\n", efficiency)
+		fmt.Println(msg("summary.efficiency", efficiency))
 	}
-	
+
 	if successfulFiles > 0 {
 		avgTime := totalProcessingTime / float64(successfulFiles)
-		fmt.Printf("Average Time per File: %.3f seconds\n", avgTime)
+		fmt.Println(msg("summary.avg_time", avgTime))
 	}
-	
+
 	fmt.Println(strings.Repeat("=", 60))
 }
 
 // AggregatedItem represents an aggregated material item
 type AggregatedItem struct {
-	Description string
-	NS          string
-	TotalQty    float64
-	Weight      string
-	Category    string
+	Description  string          `json:"description"`
+	NS           string          `json:"ns"`
+	TotalQty     float64         `json:"total_qty"`
+	Weight       string          `json:"weight"`      // unit weight as printed (first occurrence)
+	UnitWeight   float64         `json:"unit_weight"` // Weight parsed, 0 if not numeric
+	Category     string          `json:"category"`
+	Drawings     []string        `json:"drawings"` // drawing numbers the item occurs on
+	MixedScripts bool            `json:"mixed_scripts"`
+	Scripts      map[string]bool `json:"-"` // writing systems of the merged descriptions
 }
 
-// createAggregatedMaterials combines materials by description and organizes by category
-func createAggregatedMaterials(materialRows [][]string, matHeader []string) ([]string, [][]string) {
-	// Map to store aggregated items by description
-	itemMap := make(map[string]*AggregatedItem)
-	
-	// Process each material row (skip total rows)
-	for _, row := range materialRows {
-		if len(row) < 6 {
-			continue
+// AggregateOptions controls how Aggregate groups material rows
+type AggregateOptions struct {
+	Transliterate bool // group Cyrillic and Latin spellings of the same description
+}
+
+// materialAggregator combines ERECTION MATERIALS rows by description and N.S.
+type materialAggregator struct {
+	transliterate bool
+	items         map[string]*AggregatedItem
+}
+
+func newMaterialAggregator(transliterate bool) *materialAggregator {
+	return &materialAggregator{transliterate: transliterate, items: make(map[string]*AggregatedItem)}
+}
+
+// add merges one material row (PT NO | DESCRIPTION | N.S. | QTY | WEIGHT | CATEGORY | ...)
+func (a *materialAggregator) add(row []string, drawingNo string) {
+	if len(row) < 6 {
+		return
+	}
+
+	// Skip total rows
+	if strings.Contains(row[4], "TOTAL") || row[1] == "" {
+		return
+	}
+
+	description := row[1] // Column B - Component Description
+	ns := row[2]          // Column C - N.S.
+	qtyStr := row[3]      // Column D - QTY
+	weight := row[4]      // Column E - WEIGHT
+	category := row[5]    // Column F - CATEGORY
+
+	if description == "" || category == "" {
+		return
+	}
+
+	// Parse quantity (handle various formats)
+	qty := parseQuantity(qtyStr)
+
+	// Create unique key based on description and N.S.
+	key := aggregationKey(description, a.transliterate) + "|" + ns
+	script := detectScript(description)
+
+	item, exists := a.items[key]
+	if !exists {
+		unitWeight, _ := parseDXFFloat(strings.TrimSpace(weight))
+		item = &AggregatedItem{
+			Description: description,
+			NS:          ns,
+			Weight:      weight, // Use weight from first occurrence
+			UnitWeight:  unitWeight,
+			Category:    category,
+			Scripts:     map[string]bool{},
 		}
-		
-		// Skip total rows
-		if len(row) >= 5 && (strings.Contains(row[4], "TOTAL") || row[1] == "") {
+		a.items[key] = item
+	}
+	item.TotalQty += qty
+	item.Scripts[script] = true
+	item.MixedScripts = len(item.Scripts) > 1 || item.Scripts[ScriptMixed]
+	if drawingNo != "" && (len(item.Drawings) == 0 || item.Drawings[len(item.Drawings)-1] != drawingNo) {
+		item.Drawings = append(item.Drawings, drawingNo)
+	}
+}
+
+// sorted returns the aggregated items ordered by category priority and description
+func (a *materialAggregator) sorted() []*AggregatedItem {
+	items := make([]*AggregatedItem, 0, len(a.items))
+	for _, item := range a.items {
+		items = append(items, item)
+	}
+	sortItemsByCategory(items)
+	return items
+}
+
+// Aggregate combines the ERECTION MATERIALS rows of all results by description and N.S.
+// like 0003_AGGREGATED_MATERIALS.csv, returning typed items for embedding applications.
+func Aggregate(results []DXFResult, opts AggregateOptions) ([]AggregatedItem, error) {
+	aggregator := newMaterialAggregator(opts.Transliterate)
+	for _, result := range results {
+		if len(result.MatRows) == 0 {
 			continue
 		}
-		
-		description := row[1]  // Column B - Component Description
-		ns := row[2]          // Column C - N.S.
-		qtyStr := row[3]      // Column D - QTY
-		weight := row[4]      // Column E - WEIGHT
-		category := row[5]    // Column F - CATEGORY
-		
-		if description == "" || category == "" {
-			continue
+		if len(result.MatHeader) < 6 || result.MatHeader[5] != "CATEGORY" {
+			return nil, fmt.Errorf("%s: unexpected ERECTION MATERIALS header %v", result.FilePath, result.MatHeader)
 		}
-		
-		// Parse quantity (handle various formats)
-		qty := parseQuantity(qtyStr)
-		
-		// Create unique key based on description and N.S.
-		key := description + "|" + ns
-		
-		if item, exists := itemMap[key]; exists {
-			// Add to existing item
-			item.TotalQty += qty
-		} else {
-			// Create new item
-			itemMap[key] = &AggregatedItem{
-				Description: description,
-				NS:          ns,
-				TotalQty:    qty,
-				Weight:      weight, // Use weight from first occurrence
-				Category:    category,
-			}
+		for _, row := range result.MatRows {
+			aggregator.add(row, result.DrawingNo)
 		}
 	}
-	
-	// Convert map to slice and organize by category
-	var items []*AggregatedItem
-	for _, item := range itemMap {
-		items = append(items, item)
+
+	sorted := aggregator.sorted()
+	items := make([]AggregatedItem, len(sorted))
+	for i, item := range sorted {
+		items[i] = *item
 	}
-	
-	// Sort by category priority and then by description
-	sortItemsByCategory(items)
-	
+	return items, nil
+}
+
+// createAggregatedMaterials combines materials by description and organizes by category; with
+// translit Cyrillic and Latin spellings of a description are combined
+func createAggregatedMaterials(materialRows [][]string, matHeader []string, translit bool) ([]string, [][]string) {
+	drawingCol := -1
+	for i, col := range matHeader {
+		if col == "Drawing-No." {
+			drawingCol = i
+		}
+	}
+
+	aggregator := newMaterialAggregator(translit)
+	for _, row := range materialRows {
+		drawingNo := ""
+		if drawingCol >= 0 && drawingCol < len(row) {
+			drawingNo = row[drawingCol]
+		}
+		aggregator.add(row, drawingNo)
+	}
+	items := aggregator.sorted()
+
 	// Create header and rows
 	header := []string{"DESCRIPTION", "N.S.", "TOTAL QTY", "UNIT WEIGHT", "CATEGORY"}
+	if translit {
+		// Flag groups where Cyrillic and Latin spellings were merged
+		header = append(header, "MIXED SCRIPTS")
+	}
 	var rows [][]string
-	
+
 	for _, item := range items {
 		qtyStr := formatQuantity(item.TotalQty)
 		row := []string{
@@ -335,9 +450,16 @@ func createAggregatedMaterials(materialRows [][]string, matHeader []string) ([]s
 			item.Weight,
 			item.Category,
 		}
+		if translit {
+			mixed := ""
+			if item.MixedScripts {
+				mixed = "YES"
+			}
+			row = append(row, mixed)
+		}
 		rows = append(rows, row)
 	}
-	
+
 	return header, rows
 }
 
@@ -346,17 +468,17 @@ func parseQuantity(qtyStr string) float64 {
 	if qtyStr == "" || qtyStr == "---" {
 		return 0
 	}
-	
+
 	// Remove units and spaces
 	cleaned := strings.TrimSpace(qtyStr)
 	cleaned = strings.ReplaceAll(cleaned, "M", "")
 	cleaned = strings.ReplaceAll(cleaned, "m", "")
-	
+
 	// Try to parse as float
 	if qty, err := strconv.ParseFloat(cleaned, 64); err == nil {
 		return qty
 	}
-	
+
 	return 0
 }
 
@@ -371,13 +493,13 @@ func formatQuantity(qty float64) string {
 // sortItemsByCategory sorts items by category priority and description
 func sortItemsByCategory(items []*AggregatedItem) {
 	categoryOrder := map[string]int{
-		"PIPE":                        1,
-		"FITTINGS":                   2,
-		"VALVES / IN-LINE ITEMS":     3,
-		"SUPPORTS":                   4,
-		"MISCELLANEOUS COMPONENTS":   5,
+		"PIPE":                     1,
+		"FITTINGS":                 2,
+		"VALVES / IN-LINE ITEMS":   3,
+		"SUPPORTS":                 4,
+		"MISCELLANEOUS COMPONENTS": 5,
 	}
-	
+
 	sort.Slice(items, func(i, j int) bool {
 		// First sort by category
 		orderI := categoryOrder[items[i].Category]
@@ -388,11 +510,11 @@ func sortItemsByCategory(items []*AggregatedItem) {
 		if orderJ == 0 {
 			orderJ = 999
 		}
-		
+
 		if orderI != orderJ {
 			return orderI < orderJ
 		}
-		
+
 		// Then sort by description
 		return items[i].Description < items[j].Description
 	})
@@ -401,7 +523,7 @@ func sortItemsByCategory(items []*AggregatedItem) {
 // fixMissingNSColumns post-processes the ERECTION MATERIALS CSV to fix missing N.S. columns
 // and clean QTY values by removing "M" suffixes.
 // It looks for rows where PT NO has a value but WEIGHT is empty, indicating missing N.S. column
-func fixMissingNSColumns(filename string) error {
+func fixMissingNSColumns(out *runOutputs, filename string) error {
 	// Read the CSV file
 	file, err := os.Open(filename)
 	if err != nil {
@@ -424,13 +546,13 @@ func fixMissingNSColumns(filename string) error {
 
 	header := records[0]
 	rows := records[1:]
-	
+
 	// Find column indices
 	ptNoIdx := -1
 	nsIdx := -1
 	qtyIdx := -1
 	weightIdx := -1
-	
+
 	for i, col := range header {
 		switch strings.TrimSpace(col) {
 		case "PT NO":
@@ -443,37 +565,37 @@ func fixMissingNSColumns(filename string) error {
 			weightIdx = i
 		}
 	}
-	
+
 	if ptNoIdx == -1 || nsIdx == -1 || qtyIdx == -1 || weightIdx == -1 {
 		debugPrint("[DEBUG] Could not find required columns for N.S. correction")
 		return nil // Can't process without proper column structure
 	}
-	
+
 	correctedRows := [][]string{}
 	correctionCount := 0
-	
+
 	for _, row := range rows {
 		// Ensure row has enough columns
 		for len(row) <= weightIdx {
 			row = append(row, "")
 		}
-		
+
 		// Check if this is a component row (PT NO has value) AND WEIGHT is empty
 		ptNo := strings.TrimSpace(row[ptNoIdx])
 		weight := strings.TrimSpace(row[weightIdx])
-		
+
 		if ptNo != "" && weight == "" {
 			// This row has missing N.S. column - shift columns right
 			debugPrint(fmt.Sprintf("[DEBUG] Fixing missing N.S. column for PT NO '%s'", ptNo))
-			
+
 			newRow := make([]string, len(row))
 			copy(newRow, row)
-			
+
 			// Shift: N.S. → QTY, QTY → WEIGHT, leave N.S. empty
 			newRow[weightIdx] = row[qtyIdx] // Move QTY to WEIGHT
 			newRow[qtyIdx] = row[nsIdx]     // Move N.S. to QTY
 			newRow[nsIdx] = ""              // Clear N.S. (it was missing)
-			
+
 			correctedRows = append(correctedRows, newRow)
 			correctionCount++
 		} else {
@@ -481,7 +603,7 @@ func fixMissingNSColumns(filename string) error {
 			correctedRows = append(correctedRows, row)
 		}
 	}
-	
+
 	// Clean QTY column - remove "M" suffixes and ensure numeric values
 	qtyCleanCount := 0
 	for i, row := range correctedRows {
@@ -501,7 +623,7 @@ func fixMissingNSColumns(filename string) error {
 			}
 		}
 	}
-	
+
 	if correctionCount > 0 || qtyCleanCount > 0 {
 		if correctionCount > 0 {
 			debugPrint(fmt.Sprintf("[DEBUG] Fixed %d rows with missing N.S. columns", correctionCount))
@@ -509,57 +631,202 @@ func fixMissingNSColumns(filename string) error {
 		if qtyCleanCount > 0 {
 			debugPrint(fmt.Sprintf("[DEBUG] Cleaned %d QTY values (removed 'M' suffixes)", qtyCleanCount))
 		}
-		
+
 		// Write back the corrected CSV
 		allRecords := [][]string{header}
 		allRecords = append(allRecords, correctedRows...)
-		
-		return writeCSV(filename, header, correctedRows)
+
+		return writeCSV(out, filename, header, correctedRows)
 	}
-	
+
 	return nil // No corrections needed
 }
 
-// Process a single DXF file with optional caching for weld detection
-func processDXFFileWithCaching(filepath string, weldFlag bool) (DXFResult, *FileCache) {
+// Process a single DXF file with the settings of run, with optional caching for weld detection.
+// If ctx is done while the file is parsed, the result has an error; callers check ctx to tell
+// an interruption from a bad file.
+func processDXFFileWithCaching(ctx context.Context, filepath string, weldFlag bool, run *runConfig) (DXFResult, *FileCache) {
 	start := time.Now()
+	lap := lapTimer()
+	patterns := orActivePatterns(run.patterns)
 	result := DXFResult{
 		Filename: filepath,
 		FilePath: filepath,
 	}
-	
+
+	// The content is loaded once, mapped for plain files, for the weld segments, the HEADER, the
+	// text and the artifact store key
+	var content []byte
+	hash := ""
+	input, readErr := loadInput(filepath)
+	if readErr == nil {
+		defer input.Close()
+		content = input.Data
+		if artifactStore != nil {
+			hash = contentHash(content)
+		}
+	}
+	if content != nil {
+		result.Timing.Bytes = int64(len(content))
+	} else if info, err := os.Stat(filepath); err == nil {
+		result.Timing.Bytes = info.Size()
+	}
+	result.Timing.Read = lap()
+
 	var cache *FileCache
 	if weldFlag {
 		cache = &FileCache{}
-		// Read raw content for weld detection
-		if rawContent, err := os.ReadFile(filepath); err == nil {
-			cache.RawContent = rawContent
+		// Keep only the weld candidate segments
+		if readErr == nil {
+			if segments, err := segmentsStored(content, hash, run); err != nil {
+				cache.SegmentError = err.Error()
+			} else {
+				cache.Segments = segments
+			}
+		} else {
+			cache.SegmentError = readErr.Error()
 		}
 	}
+	result.Timing.Segments = lap()
 
 	debugPrint(fmt.Sprintf("[DEBUG] Opening DXF file: %s", filepath))
 
 	// Use our existing Go DXF parser
-	parser := NewDXFParser(1) // Use single worker for individual file processing
-	textEntities, err := parser.ParseFile(filepath)
+	// Use single worker for individual file processing
+	parser := NewDXFParserWithOptions(run.parse)
+	// The HEADER gives the units and the DXF version, which is also reported for files that
+	// fail to parse
+	header, err := func() (*DXFHeader, error) {
+		if content != nil {
+			return readHeader(bytes.NewReader(content), parser.scanBuffer)
+		}
+		return parser.ParseHeader(filepath)
+	}()
+	if err != nil {
+		header = nil
+	}
+	result.Version = header.VersionName()
+
+	textEntities, err := parseContentStored(ctx, parser, filepath, content, hash)
+	result.Timing.Parse = lap()
 	if err != nil {
 		result.Error = fmt.Sprintf("Failed to parse DXF file: %v", err)
+		var truncation *TruncationError
+		var parseError *ParseError
+		if errors.As(err, &parseError) {
+			result.Error += " (rejected by -strict)"
+		} else if errors.As(err, &truncation) {
+			result.Error += " (-recover extracts the part before it)"
+		}
 		result.ProcessingTime = time.Since(start).Seconds()
 		return result, cache
 	}
-	
+
+	// Without -weld nothing keeps the entities of the file once its rows are built, and their
+	// slices go back to the pool for the next file (see ReleaseEntities)
+	if !weldFlag {
+		defer ReleaseEntities(textEntities)
+	}
+
+	// Coordinates and lengths are compared in millimeters, whatever the drawing units
+	units := detectUnits(run.units, func() (*DXFHeader, error) { return header, nil })
+	if units.Millimeters != 1 {
+		textEntities = scaleEntities(textEntities, units)
+		if !weldFlag {
+			defer ReleaseEntities(textEntities)
+		}
+	}
+	result.Units = units.Name
+
+	if !run.parse.IncludeHidden {
+		visible := visibleEntities(textEntities)
+		if dropped := len(textEntities) - len(visible); dropped > 0 {
+			debugPrint(fmt.Sprintf("[DEBUG] Skipped %d invisible text entities", dropped))
+		}
+		if !weldFlag {
+			defer ReleaseEntities(visible)
+		}
+		textEntities = visible
+	}
+
+	if run.translit {
+		annotateScripts(textEntities)
+	}
+
 	// Cache text entities for weld detection if needed
 	if weldFlag {
 		cache.TextEntities = textEntities
-		cache.DrawingNo = findDrawingNo(textEntities)
-		cache.PipeClass = findPipeClass(textEntities)
 	}
 
-	drawingNo := findDrawingNo(textEntities)
-	pipeClass := findPipeClass(textEntities)
-
-	matHeader, matRows := extractTable(textEntities, "ERECTION MATERIALS")
-	cutHeader, cutRows := extractTable(textEntities, "CUT PIPE LENGTH")
+	drawingNo := findDrawingNo(textEntities, patterns)
+	pipeClass := findPipeClass(textEntities, patterns)
+	if weldFlag {
+		cache.DrawingNo = drawingNo
+		cache.PipeClass = pipeClass
+	}
+
+	// Tables stored as structured data, in the drawing's dictionaries or as ACAD_TABLE entities,
+	// replace the reconstruction. They are read from the loaded content with the options of the
+	// text parse; without content the file could not be read for the text either.
+	lap()
+	var ebom *EBOMTables
+	var acadTables []ACADTable
+	var tablesErr error
+	if content != nil {
+		ebom, acadTables, tablesErr = readStructuredTables(content, run.parse, ebomDictionaries)
+	}
+	tableText := tableEntities(textEntities, run.rotatedText)
+	var matTable, cutTable TableExtraction
+	if ebom != nil && len(ebom.Materials.Rows) > 0 {
+		matTable, result.MatSource = ebom.Materials, tableSourceEBOM+":"+ebom.Dictionary
+	} else if acad, source := acadTableExtraction(acadTables, "ERECTION MATERIALS", ebomMaterialColumns, ebomMaterialHeader); len(acad.Rows) > 0 {
+		matTable, result.MatSource = acad, source
+	} else {
+		matTable = extractTableDetailed(tableText, "ERECTION MATERIALS")
+	}
+	if ebom != nil && len(ebom.CutLengths.Rows) > 0 {
+		cutTable, result.CutSource = ebom.CutLengths, tableSourceEBOM+":"+ebom.Dictionary
+	} else if acad, source := acadTableExtraction(acadTables, "CUT PIPE LENGTH", ebomCutColumns, ebomCutHeader); len(acad.Rows) > 0 {
+		cutTable, result.CutSource = acad, source
+	} else {
+		cutTable = extractTableDetailed(tableText, "CUT PIPE LENGTH")
+	}
+	matHeader, matRows := matTable.Header, matTable.Rows
+	cutHeader, cutRows := cutTable.Header, cutTable.Rows
+	result.Timing.Tables = lap()
+	result.Warnings = append(matTable.Warnings, cutTable.Warnings...)
+	if tablesErr != nil {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("structured tables not read: %v", tablesErr))
+	}
+	debugPrint(fmt.Sprintf("[DEBUG] Table sources of %s: materials %q, cut lengths %q", filepath, result.MatSource, result.CutSource))
+	if encoding := transcodedFrom(filepath); encoding != "" {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("transcoded from %s", encoding))
+	}
+	result.Warnings = append(result.Warnings, parser.Warnings()...)
+	if parsed := parser.ParseWarnings(); len(parsed) > 0 {
+		result.ParseWarnings = parser.ParseWarningCount()
+		result.Warnings = append(result.Warnings, fmt.Sprintf("%d malformed group code / value pairs skipped, first at %s", result.ParseWarnings, parsed[0]))
+		for _, warning := range parsed {
+			debugPrint(fmt.Sprintf("[DEBUG] Parse warning in %s: %s", filepath, warning))
+		}
+	}
+	if result.Proxies = parser.ProxyEntities(); result.Proxies > 0 {
+		debugPrint(fmt.Sprintf("[DEBUG] Skipped the payload of %d proxy entities in %s", result.Proxies, filepath))
+	}
+	if warning := header.versionWarning(); warning != "" {
+		result.Warnings = append(result.Warnings, warning)
+	}
+	if units.Millimeters != 1 {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("drawing units %s (%s) converted to millimeters", units.Name, units.Source))
+	}
+	result.Partial = parser.Truncation() != nil
+	if run.rawTables {
+		result.RawMatRows = matTable.RawRows
+		result.RawCutRows = cutTable.RawRows
+	}
+	for _, warning := range result.Warnings {
+		fmt.Println(msg("bom.file_warning", filepath, warning))
+	}
 
 	// Add Drawing-No. and Pipe Class to each row
 	if len(matRows) > 0 {
@@ -568,18 +835,58 @@ func processDXFFileWithCaching(filepath string, weldFlag bool) (DXFResult, *File
 		for i, row := range matRows {
 			result.MatRows[i] = append(row, drawingNo, pipeClass)
 		}
+
+		// Tags of valve / instrument rows from the drawing
+		if run.tags {
+			tags := assignTags(result.MatHeader, result.MatRows, textEntities, run.tagRadius, patterns.Tag)
+			result.MatHeader = append(result.MatHeader, "TAG")
+			for i := range result.MatRows {
+				result.MatRows[i] = append(result.MatRows[i], tags[i])
+			}
+		}
 	}
 
 	if len(cutRows) > 0 {
-		// Extract pipe descriptions from material table for cut length table
-		pipeDescriptions := extractPipeDescriptions(matRows)
-		
+		// Extract pipe rows from material table for cut length table
+		pipes := extractPipeRows(matRows)
+
 		// Convert to single-row format with pipe descriptions
-		result.CutHeader, result.CutRows = convertCutLengthToSingleRowFormat(cutHeader, cutRows, drawingNo, pipeClass, pipeDescriptions)
+		result.CutHeader, result.CutRows = convertCutLengthToSingleRowFormat(cutHeader, cutRows, drawingNo, pipeClass, pipes, run.pipePolicy)
+	}
+	result.PieceCheck = checkPieceNumbers(result.CutRows)
+	result.MatConfidence = scoreTable("ERECTION MATERIALS", result.MatHeader, result.MatRows)
+	result.CutConfidence = scoreTable("CUT PIPE LENGTH", result.CutHeader, result.CutRows)
+	if weldFlag {
+		cache.SizeCallouts = pieceSizeCallouts(result.CutRows, textEntities)
+		cache.PipeSizes = cutPieceSizes(result.CutRows)
+		if run.weldGraph {
+			cache.GraphNodes = graphNodes(result.MatHeader, result.MatRows, result.CutRows, textEntities, result.MatSource == "")
+		}
+	}
+	if result.PieceCheck != "" && result.PieceCheck != "OK" {
+		debugPrint(fmt.Sprintf("[DEBUG] Piece numbers of %s: %s", filepath, result.PieceCheck))
 	}
 
 	result.DrawingNo = drawingNo
 	result.PipeClass = pipeClass
+	result.Revision = findRevision(filepath, patterns)
+
+	// Map output rows back to their source entities if requested
+	if run.provenance || run.handles {
+		matProvenance := buildProvenance("ERECTION MATERIALS", result.MatHeader, result.MatRows, textEntities, filepath, drawingNo)
+		cutProvenance := buildProvenance("CUT PIPE LENGTH", result.CutHeader, result.CutRows, textEntities, filepath, drawingNo)
+		if run.provenance {
+			result.MatProvenance, result.CutProvenance = matProvenance, cutProvenance
+		}
+		if run.handles {
+			result.MatHeader, result.MatRows = addHandleColumn(result.MatHeader, result.MatRows, matProvenance)
+			result.CutHeader, result.CutRows = addHandleColumn(result.CutHeader, result.CutRows, cutProvenance)
+		}
+	}
+
+	// Cut lengths after the provenance, which finds the cells by their printed text
+	convertCutLengths(result.CutHeader, result.CutRows, units)
+
 	result.ProcessingTime = time.Since(start).Seconds()
 
 	debugPrint(fmt.Sprintf("[DEBUG] Extracted %d material rows and %d cut length rows from %s", len(result.MatRows), len(result.CutRows), filepath))
@@ -588,82 +895,107 @@ func processDXFFileWithCaching(filepath string, weldFlag bool) (DXFResult, *File
 	return result, cache
 }
 
-// Process files sequentially with optional caching for weld detection
-func processFilesSequentialWithCaching(files []string, debug bool, weldFlag bool) ([]DXFResult, map[string]FileCache) {
+// Process files sequentially with optional caching for weld detection. When ctx is done the
+// results of the files completed so far are returned with an error wrapping ctx.Err().
+func processFilesSequentialWithCaching(ctx context.Context, files []string, debug bool, weldFlag bool, run *runConfig) ([]DXFResult, map[string]FileCache, error) {
 	results := make([]DXFResult, 0, len(files))
 	var fileCache map[string]FileCache
-	
+
 	if weldFlag {
 		fileCache = make(map[string]FileCache)
 	}
-	
+
 	for i, filePath := range files {
+		if ctx.Err() != nil {
+			return results, fileCache, fmt.Errorf("processing interrupted after %d of %d files: %w", i, len(files), ctx.Err())
+		}
 		if debug {
 			fmt.Printf("[%d/%d] Processing: %s\n", i+1, len(files), filepath.Base(filePath))
 		} else {
-			fmt.Printf("Processing file %d/%d: %s\n", i+1, len(files), filepath.Base(filePath))
+			fmt.Println(msg("bom.progress_start", i+1, len(files), filepath.Base(filePath)))
+		}
+
+		result, cache := processInputFileIsolated(ctx, filePath, weldFlag, run)
+		if ctx.Err() != nil && result.Error != "" {
+			// Interrupted while parsing: the file is not done
+			return results, fileCache, fmt.Errorf("processing interrupted after %d of %d files: %w", i, len(files), ctx.Err())
 		}
-		
-		result, cache := processDXFFileWithCaching(filePath, weldFlag)
 		results = append(results, result)
-		
+
 		if weldFlag && cache != nil {
 			fileCache[filePath] = *cache
 		}
 	}
-	
-	return results, fileCache
+
+	return results, fileCache, nil
 }
 
-// Process files in parallel with optional caching for weld detection
-func processFilesParallelWithCaching(files []string, workers int, debug bool, weldFlag bool) ([]DXFResult, map[string]FileCache) {
+// Process files in parallel with optional caching for weld detection. When ctx is done the
+// workers skip the remaining files and the results of the completed files are returned with
+// an error wrapping ctx.Err().
+func processFilesParallelWithCaching(ctx context.Context, files []string, workers int, debug bool, weldFlag bool, run *runConfig) ([]DXFResult, map[string]FileCache, error) {
 	jobs := make(chan string, len(files))
 	type resultWithCache struct {
 		result DXFResult
 		cache  *FileCache
 	}
 	results := make(chan resultWithCache, len(files))
-	
-	// Start workers
+
+	// Start workers; after cancellation they drain the jobs without processing them
+	var wg sync.WaitGroup
 	for w := 0; w < workers; w++ {
+		wg.Add(1)
 		go func() {
+			defer wg.Done()
 			for filePath := range jobs {
-				result, cache := processDXFFileWithCaching(filePath, weldFlag)
+				if ctx.Err() != nil {
+					continue
+				}
+				result, cache := processInputFileIsolated(ctx, filePath, weldFlag, run)
+				if ctx.Err() != nil && result.Error != "" {
+					continue // interrupted while parsing
+				}
 				results <- resultWithCache{result: result, cache: cache}
 			}
 		}()
 	}
-	
+
 	// Send jobs
 	for _, filePath := range files {
 		jobs <- filePath
 	}
 	close(jobs)
-	
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
 	// Collect results
 	var allResults []DXFResult
 	var fileCache map[string]FileCache
-	
+
 	if weldFlag {
 		fileCache = make(map[string]FileCache)
 	}
-	
-	for i := 0; i < len(files); i++ {
-		resultWithCache := <-results
+
+	i := 0
+	for resultWithCache := range results {
 		allResults = append(allResults, resultWithCache.result)
-		
+
 		if weldFlag && resultWithCache.cache != nil {
 			fileCache[resultWithCache.result.FilePath] = *resultWithCache.cache
 		}
-		
+
 		if debug {
 			fmt.Printf("[%d/%d] Completed: %s\n", i+1, len(files), filepath.Base(resultWithCache.result.FilePath))
 		} else {
-			fmt.Printf("Completed file %d/%d: %s\n", i+1, len(files), filepath.Base(resultWithCache.result.FilePath))
+			fmt.Println(msg("bom.progress_done", i+1, len(files), filepath.Base(resultWithCache.result.FilePath)))
 		}
+		i++
 	}
-	
-	return allResults, fileCache
-}
-
 
+	if i < len(files) {
+		return allResults, fileCache, fmt.Errorf("processing interrupted after %d of %d files: %w", i, len(files), ctx.Err())
+	}
+	return allResults, fileCache, nil
+}
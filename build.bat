@@ -3,7 +3,7 @@ echo Building DXF Isometric Pipe Drawing Analyzer Tools...
 echo.
 
 echo [1/2] Building Unified BOM and Cut Length Extractor...
-C:\Users\szil\Software\go\bin\go.exe build -o bom_cut_length_extractor.exe main.go cut_length_extractor.go cli.go spatial.go table_extraction.go bom_utils.go bom_extractor.go
+C:\Users\szil\Software\go\bin\go.exe build -o bom_cut_length_extractor.exe ./cmd/dxf_parser
 if %errorlevel% neq 0 (
     echo ERROR: Failed to build bom_cut_length_extractor.exe
     exit /b 1
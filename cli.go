@@ -1,10 +1,12 @@
-package main
+package dxfparser
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"os"
+	"reflect"
 	"runtime"
 	"strconv"
 	"time"
@@ -18,7 +20,7 @@ func runCLI() {
 	}
 
 	command := os.Args[1]
-	
+
 	switch command {
 	case "parse":
 		handleParseCommand()
@@ -28,66 +30,159 @@ func runCLI() {
 		handleBenchmarkCommand()
 	case "bom":
 		bomMain()
+	case "report":
+		handleReportCommand()
+	case "orientation":
+		handleOrientationCommand()
+	case "outline":
+		handleOutlineCommand()
+	case "header":
+		handleHeaderCommand()
+	case "layers":
+		handleLayersCommand()
+	case "replay":
+		handleReplayCommand()
+	case "eval":
+		handleEvalCommand()
+	case "serve":
+		handleServeCommand()
+	case "daemon":
+		handleDaemonCommand()
+	case "export-config":
+		handleExportConfigCommand()
+	case "config":
+		handleConfigCommand()
+	case "version":
+		fmt.Printf("dxf_parser %s\n", getToolVersion())
 	case "help":
 		printUsage()
 	default:
-		fmt.Printf("Unknown command: %s\n\n", command)
+		fmt.Printf("%s\n\n", msg("cli.unknown_command", command))
 		printUsage()
 		os.Exit(1)
 	}
 }
 
 func printUsage() {
-	fmt.Println("DXF Text Parser - High-performance text extraction from DXF files")
-	fmt.Println("\nUsage:")
-	fmt.Println("  dxf_parser parse <file.dxf> [workers]     - Parse DXF file and show results")
-	fmt.Println("  dxf_parser spatial <file.dxf> [command]  - Run spatial analysis")
-	fmt.Println("  dxf_parser benchmark <file.dxf>          - Run performance benchmarks")
-	fmt.Println("  dxf_parser bom -dir <directory> [options] - Extract BOM and cut lengths")
-	fmt.Println("  dxf_parser help                          - Show this help message")
-	fmt.Println("\nSpatial Commands:")
-	fmt.Println("  stats                                    - Show entity statistics")
-	fmt.Println("  near <text> <distance>                  - Find entities near text")
-	fmt.Println("  range <minX> <minY> <maxX> <maxY>       - Find entities in coordinate range")
-	fmt.Println("  quadrant <text>                         - Find entities in top-right quadrant of text")
-	fmt.Println("\nExamples:")
-	fmt.Println("  dxf_parser parse drawing.dxf 8")
+	fmt.Println(msg("cli.title"))
+	fmt.Println("\n" + msg("cli.usage"))
+	fmt.Println("  dxf_parser parse <file.dxf> [-workers N]  - " + msg("cli.cmd.parse"))
+	fmt.Println("  dxf_parser spatial <file.dxf> [command]  - " + msg("cli.cmd.spatial"))
+	fmt.Println("  dxf_parser benchmark <file.dxf>          - " + msg("cli.cmd.benchmark"))
+	fmt.Println("  dxf_parser bom -dir <directory> [options] - " + msg("cli.cmd.bom"))
+	fmt.Println("  dxf_parser orientation <file|dir> [opts] - " + msg("cli.cmd.orientation"))
+	fmt.Println("  dxf_parser outline <file.dxf> [-json]    - " + msg("cli.cmd.outline"))
+	fmt.Println("  dxf_parser header <file.dxf> [-json]     - " + msg("cli.cmd.header"))
+	fmt.Println("  dxf_parser layers <file|dir> [opts]      - " + msg("cli.cmd.layers"))
+	fmt.Println("  dxf_parser replay <summary.csv> <dwg-no> - " + msg("cli.cmd.replay"))
+	fmt.Println("  dxf_parser report trends [options]       - " + msg("cli.cmd.report"))
+	fmt.Println("  dxf_parser report pivot <output-dir>     - " + msg("cli.cmd.pivot"))
+	fmt.Println("  dxf_parser eval <corpus.yaml> [options]  - " + msg("cli.cmd.eval"))
+	fmt.Println("  dxf_parser serve -dir <directory> [opts] - " + msg("cli.cmd.serve"))
+	fmt.Println("  dxf_parser daemon -state <dir> [opts]    - " + msg("cli.cmd.daemon"))
+	fmt.Println("  dxf_parser export-config [-out <dir>]    - " + msg("cli.cmd.export"))
+	fmt.Println("  dxf_parser config validate <file|dir>    - " + msg("cli.cmd.config"))
+	fmt.Println("  dxf_parser version                       - " + msg("cli.cmd.version"))
+	fmt.Println("  dxf_parser help                          - " + msg("cli.cmd.help"))
+	fmt.Println("\n" + msg("cli.spatial_cmds"))
+	fmt.Println("  stats                                    - " + msg("cli.spatial.stats"))
+	fmt.Println("  near <text> <distance>                  - " + msg("cli.spatial.near"))
+	fmt.Println("  range <minX> <minY> <maxX> <maxY>       - " + msg("cli.spatial.range"))
+	fmt.Println("  quadrant <text>                         - " + msg("cli.spatial.quad"))
+	fmt.Println("\n" + msg("cli.examples"))
+	fmt.Println("  dxf_parser parse drawing.dxf -workers 8")
 	fmt.Println("  dxf_parser spatial drawing.dxf stats")
 	fmt.Println("  dxf_parser spatial drawing.dxf near \"PIPE\" 50.0")
 	fmt.Println("  dxf_parser benchmark drawing.dxf")
 }
 
 func handleParseCommand() {
-	if len(os.Args) < 3 {
-		fmt.Println("Error: Missing DXF file argument")
-		fmt.Println("Usage: dxf_parser parse <file.dxf> [workers]")
-		os.Exit(1)
+	fs := newCommandFlagSet("parse", "dxf_parser parse <file.dxf|archive.zip> [-workers N] [-chunk-size 1MB] [-scan-buffer 1MB] [-encoding auto] [-raw-mtext] [-xdata] [-keep-whitespace] [-raw-content] [-include-hidden] [-recover] [-strict] [-block-depth 16] [-exclude-colors 8,9] [-layout model] [-scope entities+blocks] [-indexed] [-section ENTITIES]")
+	workers := fs.Int("workers", runtime.NumCPU(), "Number of parser workers")
+	chunkSize := byteSize(defaultChunkSize)
+	fs.Var(&chunkSize, "chunk-size", "Minimum bytes per concurrent chunk (KB, MB suffixes)")
+	scanBuffer := byteSize(defaultScanBuffer)
+	fs.Var(&scanBuffer, "scan-buffer", "Longest line kept whole (KB, MB suffixes); longer lines are cut with a warning")
+	encoding := fs.String("encoding", "auto", "Code page of text values: auto ($DWGCODEPAGE), ANSI_1252, ANSI_936, utf-8, ...")
+	rawMText := fs.Bool("raw-mtext", false, "Keep MTEXT formatting codes instead of the plain text")
+	xdata := fs.Bool("xdata", false, "Read the extended data (XDATA) of the text and list it by application")
+	keepWhitespace := fs.Bool("keep-whitespace", false, "Also show the text with the leading and trailing blanks of its values where they differ")
+	rawContent := fs.Bool("raw-content", false, "Also show the content as written in the file where it differs")
+	includeHidden := fs.Bool("include-hidden", false, "Also list text flagged invisible (60)")
+	recoverFile := fs.Bool("recover", false, "Parse a file that ends inside an entity up to that entity instead of failing")
+	strict := fs.Bool("strict", false, "Fail on malformed group codes or numbers, unknown sections, unbalanced SECTION/ENDSEC or a missing EOF record")
+	blockDepth := fs.Int("block-depth", defaultBlockDepth, "Deepest chain of nested block references expanded")
+	var excluded colorList
+	fs.Var(&excluded, "exclude-colors", "Drop text in these ACI colors, ByLayer resolved (e.g. 8,9,250-254)")
+	layout := fs.String("layout", LayoutModel, "Text of model space (model), of the paper space layout of this name, or of every space (all)")
+	scopeValue := fs.String("scope", string(ScopeEntitiesBlocks), "DXF sections text is read from: entities, entities+blocks, all")
+	indexed := fs.Bool("indexed", false, "Index the record offsets first and split the concurrent chunks at records")
+	section := fs.String("section", "", "Only parse the records of this section (e.g. ENTITIES), read at their offsets in the record index")
+	args := parseCommandArgs(fs, os.Args[2:])
+	// The worker count used to be a second positional argument; it is still accepted
+	checkArgCount(fs, args, 1, 2, msg("cli.missing_file"))
+
+	filename := args[0]
+	if len(args) > 1 {
+		w, err := strconv.Atoi(args[1])
+		if err != nil {
+			usageError(fs, msg("cli.invalid_workers", args[1]))
+		}
+		*workers = w
 	}
+	if *workers <= 0 {
+		usageError(fs, msg("cli.invalid_workers", strconv.Itoa(*workers)))
+	}
+	if _, err := newEncodingState(*encoding); err != nil {
+		usageError(fs, fmt.Sprintf("Error: %v", err))
+	}
+	if *blockDepth <= 0 {
+		usageError(fs, "Error: -block-depth must be positive")
+	}
+	scope, err := parseExtractionScope(*scopeValue)
+	if err != nil {
+		usageError(fs, fmt.Sprintf("Error: %v", err))
+	}
+	useProjectConfig(filename, nil)
 
-	filename := os.Args[2]
-	workers := runtime.NumCPU()
-
-	if len(os.Args) > 3 {
-		if w, err := strconv.Atoi(os.Args[3]); err == nil && w > 0 {
-			workers = w
-		}
+	opts := ParseOptions{Workers: *workers, ChunkSize: int64(chunkSize), ScanBuffer: int(scanBuffer), Encoding: *encoding, RawMText: *rawMText, XData: *xdata, KeepWhitespace: *keepWhitespace, RawContent: *rawContent, IncludeHidden: *includeHidden, Recover: *recoverFile, Strict: *strict, BlockDepth: *blockDepth, ExcludeColors: excluded, Layout: *layout, Scope: scope}
+	if isZip(filename) {
+		parseArchive(filename, opts)
+		return
 	}
 
-	fmt.Printf("Parsing DXF file: %s\n", filename)
-	fmt.Printf("Using %d workers\n", workers)
+	fmt.Println(msg("cli.parsing", filename))
+	fmt.Println(msg("cli.using_workers", *workers))
+
+	parser := NewDXFParserWithOptions(opts)
 
-	parser := NewDXFParser(workers)
-	
 	start := time.Now()
-	entities, err := parser.ParseFile(filename)
+	var entities []TextEntity
+	if *indexed || *section != "" {
+		entities, err = parseIndexed(parser, filename, *section)
+	} else {
+		entities, err = parser.ParseFile(filename)
+	}
 	duration := time.Since(start)
 
 	if err != nil {
 		log.Fatalf("Error parsing file: %v", err)
 	}
 
-	fmt.Printf("\nParsing completed in: %v\n", duration)
-	fmt.Printf("Found %d text entities\n\n", len(entities))
+	fmt.Println("\n" + msg("cli.parse_done", duration))
+	for _, warning := range parser.Warnings() {
+		fmt.Println(msg("cli.parse_warning", warning))
+	}
+	if count := parser.ParseWarningCount(); count > 0 {
+		fmt.Println(msg("cli.parse_skipped", count))
+		for _, warning := range parser.ParseWarnings() {
+			fmt.Println("  " + warning.String())
+		}
+	}
+	if proxies := parser.ProxyEntities(); proxies > 0 {
+		fmt.Println(msg("cli.parse_proxies", proxies))
+	}
+	fmt.Printf("%s\n\n", msg("cli.found_entities", len(entities)))
 
 	// Display first 10 entities
 	limit := 10
@@ -95,32 +190,97 @@ func handleParseCommand() {
 		limit = len(entities)
 	}
 
-	fmt.Printf("First %d text entities:\n", limit)
+	fmt.Println(msg("cli.first_entities", limit))
 	fmt.Println("----------------------------------------")
 	for i := 0; i < limit; i++ {
 		entity := entities[i]
 		fmt.Printf("%d. %s: \"%s\" at (%.3f, %.3f) height=%.2f layer=%s\n",
 			i+1, entity.EntityType, entity.Content, entity.X, entity.Y, entity.Height, entity.Layer)
+		if entity.Untrimmed != "" && entity.Untrimmed != entity.Content {
+			fmt.Printf("   untrimmed: \"%s\"\n", entity.Untrimmed)
+		}
+		if entity.RawContent != "" && entity.RawContent != entity.Content {
+			fmt.Printf("   raw: \"%s\"\n", entity.RawContent)
+		}
+		for _, app := range entity.XData.Apps() {
+			fmt.Printf("   xdata %s: %v\n", app, entity.XData[app])
+		}
 	}
 
 	if len(entities) > limit {
-		fmt.Printf("... and %d more entities\n", len(entities)-limit)
+		fmt.Println(msg("cli.more_entities", len(entities)-limit))
+	}
+}
+
+// parseArchive parses every DXF entry of a ZIP archive in memory and prints its entity count
+func parseArchive(archive string, opts ParseOptions) {
+	entries, _, err := zipEntries(archive, false)
+	if err != nil {
+		log.Fatalf("Error parsing file: %v", err)
+	}
+	fmt.Println(msg("cli.zip_entries", len(entries), archive))
+	fmt.Println(msg("cli.using_workers", opts.Workers))
+
+	parser := NewDXFParserWithOptions(opts)
+	start := time.Now()
+	total := 0
+	for _, entry := range entries {
+		entryStart := time.Now()
+		entities, err := parser.ParseFile(entry)
+		if err != nil {
+			log.Fatalf("Error parsing file: %v", err)
+		}
+		total += len(entities)
+		_, name, _ := splitZipPath(entry)
+		fmt.Println(msg("cli.zip_entry", name, len(entities), time.Since(entryStart)))
+		ReleaseEntities(entities)
+	}
+
+	fmt.Println("\n" + msg("cli.parse_done", time.Since(start)))
+	fmt.Println(msg("cli.found_entities", total))
+}
+
+// parseIndexed indexes the records of a file and parses it through the index: all of it, or
+// the records of section
+func parseIndexed(parser *DXFParser, filename, section string) ([]TextEntity, error) {
+	start := time.Now()
+	index, err := parser.IndexFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Println(msg("cli.indexed", len(index.Records), time.Since(start)))
+	if section == "" {
+		return parser.ParseIndexed(filename, index)
+	}
+	first, last := index.Section(section)
+	if first == last {
+		return nil, fmt.Errorf("no section %s in %s", section, filename)
 	}
+	return parser.ParseRecords(filename, index, first, last)
 }
 
 func handleSpatialCommand() {
-	if len(os.Args) < 4 {
-		fmt.Println("Error: Missing arguments for spatial command")
-		fmt.Println("Usage: dxf_parser spatial <file.dxf> <command> [args...]")
-		os.Exit(1)
+	fs := newCommandFlagSet("spatial", "dxf_parser spatial <file.dxf> <stats|near|range|quadrant> [args...]")
+	args := parseCommandArgs(fs, os.Args[2:])
+	checkArgCount(fs, args, 2, -1, msg("cli.missing_spatial"))
+
+	filename := args[0]
+	spatialCmd := args[1]
+	cmdArgs := args[2:]
+
+	// Validate the arguments before the file is parsed
+	wantArgs := map[string]int{"stats": 0, "near": 2, "range": 4, "quadrant": 1}
+	want, ok := wantArgs[spatialCmd]
+	if !ok {
+		usageError(fs, msg("cli.unknown_spatial", spatialCmd))
 	}
+	checkArgCount(fs, cmdArgs, want, want, msg("cli.missing_spatial"))
 
-	filename := os.Args[2]
-	spatialCmd := os.Args[3]
+	useProjectConfig(filename, nil)
 
 	// Parse the file
 	parser := NewDXFParser(runtime.NumCPU())
-	entities, err := parser.ParseFile(filename)
+	entities, err := parseFileStored(context.Background(), parser, filename)
 	if err != nil {
 		log.Fatalf("Error parsing file: %v", err)
 	}
@@ -131,52 +291,44 @@ func handleSpatialCommand() {
 	case "stats":
 		handleStatsCommand(analyzer)
 	case "near":
-		handleNearCommand(analyzer)
+		handleNearCommand(analyzer, cmdArgs)
 	case "range":
-		handleRangeCommand(analyzer)
+		handleRangeCommand(analyzer, cmdArgs)
 	case "quadrant":
-		handleQuadrantCommand(analyzer)
-	default:
-		fmt.Printf("Unknown spatial command: %s\n", spatialCmd)
-		os.Exit(1)
+		handleQuadrantCommand(analyzer, cmdArgs)
 	}
 }
 
 func handleStatsCommand(analyzer *SpatialAnalyzer) {
 	stats := analyzer.GetEntityStats()
-	
+
 	fmt.Println("DXF File Statistics:")
 	fmt.Println("===================")
-	
+
 	statsJSON, _ := json.MarshalIndent(stats, "", "  ")
 	fmt.Println(string(statsJSON))
 }
 
-func handleNearCommand(analyzer *SpatialAnalyzer) {
-	if len(os.Args) < 6 {
-		fmt.Println("Usage: dxf_parser spatial <file.dxf> near <text> <distance>")
-		os.Exit(1)
-	}
-
-	searchText := os.Args[4]
-	distance, err := strconv.ParseFloat(os.Args[5], 64)
+func handleNearCommand(analyzer *SpatialAnalyzer, args []string) {
+	searchText := args[0]
+	distance, err := strconv.ParseFloat(args[1], 64)
 	if err != nil {
-		fmt.Printf("Error: Invalid distance value: %s\n", os.Args[5])
-		os.Exit(1)
+		fmt.Println(msg("cli.invalid_dist", args[1]))
+		os.Exit(usageExitCode)
 	}
 
 	fmt.Printf("Finding entities near \"%s\" within distance %.2f:\n\n", searchText, distance)
-	
+
 	nearEntities := analyzer.FindEntitiesNearText(searchText, distance)
-	
+
 	if len(nearEntities) == 0 {
-		fmt.Println("No entities found near the specified text.")
+		fmt.Println(msg("cli.none_near"))
 		return
 	}
 
-	fmt.Printf("Found %d entities:\n", len(nearEntities))
+	fmt.Println(msg("cli.found", len(nearEntities)))
 	fmt.Println("----------------------------------------")
-	
+
 	for i, entityWithDistance := range nearEntities {
 		entity := entityWithDistance.Entity
 		fmt.Printf("%d. \"%s\" at (%.3f, %.3f) - distance: %.3f\n",
@@ -184,60 +336,50 @@ func handleNearCommand(analyzer *SpatialAnalyzer) {
 	}
 }
 
-func handleRangeCommand(analyzer *SpatialAnalyzer) {
-	if len(os.Args) < 8 {
-		fmt.Println("Usage: dxf_parser spatial <file.dxf> range <minX> <minY> <maxX> <maxY>")
-		os.Exit(1)
-	}
-
-	minX, err1 := strconv.ParseFloat(os.Args[4], 64)
-	minY, err2 := strconv.ParseFloat(os.Args[5], 64)
-	maxX, err3 := strconv.ParseFloat(os.Args[6], 64)
-	maxY, err4 := strconv.ParseFloat(os.Args[7], 64)
+func handleRangeCommand(analyzer *SpatialAnalyzer, args []string) {
+	minX, err1 := strconv.ParseFloat(args[0], 64)
+	minY, err2 := strconv.ParseFloat(args[1], 64)
+	maxX, err3 := strconv.ParseFloat(args[2], 64)
+	maxY, err4 := strconv.ParseFloat(args[3], 64)
 
 	if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
-		fmt.Println("Error: Invalid coordinate values")
-		os.Exit(1)
+		fmt.Println(msg("cli.invalid_coords"))
+		os.Exit(usageExitCode)
 	}
 
 	fmt.Printf("Finding entities in range (%.2f, %.2f) to (%.2f, %.2f):\n\n", minX, minY, maxX, maxY)
-	
+
 	entities := analyzer.FindEntitiesInRange(minX, minY, maxX, maxY)
-	
+
 	if len(entities) == 0 {
-		fmt.Println("No entities found in the specified range.")
+		fmt.Println(msg("cli.none_range"))
 		return
 	}
 
-	fmt.Printf("Found %d entities:\n", len(entities))
+	fmt.Println(msg("cli.found", len(entities)))
 	fmt.Println("----------------------------------------")
-	
+
 	for i, entity := range entities {
 		fmt.Printf("%d. \"%s\" at (%.3f, %.3f)\n",
 			i+1, entity.Content, entity.X, entity.Y)
 	}
 }
 
-func handleQuadrantCommand(analyzer *SpatialAnalyzer) {
-	if len(os.Args) < 5 {
-		fmt.Println("Usage: dxf_parser spatial <file.dxf> quadrant <text>")
-		os.Exit(1)
-	}
+func handleQuadrantCommand(analyzer *SpatialAnalyzer, args []string) {
+	searchText := args[0]
 
-	searchText := os.Args[4]
-	
 	fmt.Printf("Finding entities in top-right quadrant relative to \"%s\":\n\n", searchText)
-	
+
 	entities := analyzer.FindEntitiesInTopRightQuadrant(searchText)
-	
+
 	if len(entities) == 0 {
-		fmt.Println("No entities found in the top-right quadrant of the specified text.")
+		fmt.Println(msg("cli.none_quadrant"))
 		return
 	}
 
-	fmt.Printf("Found %d entities:\n", len(entities))
+	fmt.Println(msg("cli.found", len(entities)))
 	fmt.Println("----------------------------------------")
-	
+
 	for i, entity := range entities {
 		fmt.Printf("%d. \"%s\" at (%.3f, %.3f)\n",
 			i+1, entity.Content, entity.X, entity.Y)
@@ -245,22 +387,39 @@ func handleQuadrantCommand(analyzer *SpatialAnalyzer) {
 }
 
 func handleBenchmarkCommand() {
-	if len(os.Args) < 3 {
-		fmt.Println("Error: Missing DXF file argument")
-		fmt.Println("Usage: dxf_parser benchmark <file.dxf>")
-		os.Exit(1)
+	if len(os.Args) > 2 && os.Args[2] == "welds" {
+		handleWeldBenchmark(os.Args[3:])
+		return
+	}
+	if len(os.Args) > 2 && os.Args[2] == "compare" {
+		handleCompareBenchmark(os.Args[3:])
+		return
 	}
 
-	filename := os.Args[2]
-	
-	fmt.Printf("Running benchmarks on: %s\n", filename)
+	fs := newCommandFlagSet("benchmark", "dxf_parser benchmark <file.dxf> [-iterations 3] [-chunk-size 1MB] [-scan-buffer 1MB]\n       dxf_parser benchmark welds [-symbols 400] [-noise 4000] [-iterations 5]\n       dxf_parser benchmark compare <file.dxf|dir>... [-reader name=command]... [-iterations 3] [-json report.json]")
+	iterations := fs.Int("iterations", 3, "Runs per worker count")
+	chunkSize := byteSize(defaultChunkSize)
+	fs.Var(&chunkSize, "chunk-size", "Minimum bytes per concurrent chunk (KB, MB suffixes)")
+	scanBuffer := byteSize(defaultScanBuffer)
+	fs.Var(&scanBuffer, "scan-buffer", "Longest line kept whole (KB, MB suffixes); longer lines are cut with a warning")
+	args := parseCommandArgs(fs, os.Args[2:])
+	checkArgCount(fs, args, 1, 1, msg("cli.missing_file"))
+	if *iterations <= 0 {
+		usageError(fs, "Error: -iterations must be positive")
+	}
+
+	filename := args[0]
+	useProjectConfig(filename, nil)
+
+	fmt.Println(msg("cli.benchmarking", filename))
 	fmt.Println("=====================================")
 
 	// Test different worker counts
 	workerCounts := []int{1, 2, 4, 8, runtime.NumCPU()}
-	
+
 	var baselineTime time.Duration
 	var baselineEntities int
+	var baselineResult []TextEntity
 
 	for i, workers := range workerCounts {
 		if workers > runtime.NumCPU() {
@@ -270,14 +429,13 @@ func handleBenchmarkCommand() {
 		fmt.Printf("\nBenchmark %d: %d workers\n", i+1, workers)
 		fmt.Println("-------------------------")
 
-		parser := NewDXFParser(workers)
-		
+		parser := NewDXFParserWithOptions(ParseOptions{Workers: workers, ChunkSize: int64(chunkSize), ScanBuffer: int(scanBuffer)})
+
 		// Run multiple iterations for average
-		iterations := 3
 		var totalTime time.Duration
 		var entityCount int
 
-		for j := 0; j < iterations; j++ {
+		for j := 0; j < *iterations; j++ {
 			start := time.Now()
 			entities, err := parser.ParseFile(filename)
 			duration := time.Since(start)
@@ -288,11 +446,18 @@ func handleBenchmarkCommand() {
 
 			totalTime += duration
 			entityCount = len(entities)
-			
+
+			// The 1 worker run is the sequential path; chunked runs must return the same entities
+			if i == 0 && j == 0 {
+				baselineResult = entities
+			} else if !reflect.DeepEqual(entities, baselineResult) {
+				log.Fatalf("Error in benchmark: %d workers returned different entities than the sequential parser", workers)
+			}
+
 			fmt.Printf("  Run %d: %v (%d entities)\n", j+1, duration, entityCount)
 		}
 
-		avgTime := totalTime / time.Duration(iterations)
+		avgTime := totalTime / time.Duration(*iterations)
 		fmt.Printf("  Average: %v\n", avgTime)
 
 		if i == 0 {
@@ -304,10 +469,10 @@ func handleBenchmarkCommand() {
 		}
 	}
 
-	fmt.Printf("\nTotal entities found: %d\n", baselineEntities)
-	
+	fmt.Println("\n" + msg("cli.total_entities", baselineEntities))
+
 	// Memory usage estimate
 	entitySize := 120 // Rough estimate of TextEntity struct size in bytes
-	memoryUsage := float64(baselineEntities * entitySize) / (1024 * 1024)
-	fmt.Printf("Estimated memory usage: %.2f MB\n", memoryUsage)
+	memoryUsage := float64(baselineEntities*entitySize) / (1024 * 1024)
+	fmt.Println(msg("cli.memory", memoryUsage))
 }
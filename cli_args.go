@@ -0,0 +1,157 @@
+package dxfparser
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// usageExitCode is returned for malformed invocations; code 2 is a bom run with failed files
+const usageExitCode = exitConfigError
+
+// newCommandFlagSet creates the flag set of a subcommand; usage is the synopsis printed
+// above the flag defaults when the invocation is malformed or -help is given
+func newCommandFlagSet(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s\n", usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseCommandArgs parses args with fs and returns the positional arguments. Unlike
+// fs.Parse, flags may appear before, between or after the positional arguments
+// ("parse -workers=8 file.dxf" and "parse file.dxf --workers 8" are the same).
+// Everything after "--" and negative numbers such as "-12.5" are positional.
+// Unknown flags and flags without a value end the program with the usage.
+func parseCommandArgs(fs *flag.FlagSet, args []string) []string {
+	// Parse errors exit with usageExitCode instead of the flag package's 2
+	fs.Init(fs.Name(), flag.ContinueOnError)
+
+	var flagArgs, positional []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			positional = append(positional, args[i+1:]...)
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			positional = append(positional, arg)
+			continue
+		}
+
+		name := strings.TrimLeft(arg, "-")
+		hasValue := false
+		if eq := strings.Index(name, "="); eq >= 0 {
+			name, hasValue = name[:eq], true
+		}
+		f := fs.Lookup(name)
+		if f == nil {
+			if _, err := strconv.ParseFloat(arg, 64); err == nil {
+				positional = append(positional, arg)
+				continue
+			}
+		}
+
+		flagArgs = append(flagArgs, arg)
+		if f != nil && !hasValue && !isBoolFlag(f) && i+1 < len(args) {
+			i++
+			flagArgs = append(flagArgs, args[i])
+		}
+	}
+
+	if err := fs.Parse(flagArgs); err != nil {
+		if err == flag.ErrHelp {
+			os.Exit(0)
+		}
+		os.Exit(usageExitCode)
+	}
+	return positional
+}
+
+// isBoolFlag reports whether f takes no value, like -debug
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// checkArgCount ends the program with the usage of fs unless there are between min and max
+// positional arguments (max < 0: no upper limit); missing is printed if there are too few
+func checkArgCount(fs *flag.FlagSet, positional []string, min, max int, missing string) {
+	switch {
+	case len(positional) < min:
+		fmt.Fprintln(fs.Output(), missing)
+	case max >= 0 && len(positional) > max:
+		fmt.Fprintln(fs.Output(), msg("cli.unexpected_args", strings.Join(positional[max:], " ")))
+	default:
+		return
+	}
+	fs.Usage()
+	os.Exit(usageExitCode)
+}
+
+// stringList is a flag that can be repeated, collecting every value in order
+type stringList []string
+
+func (l *stringList) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// byteSize is a flag holding a size in bytes, given as a number with an optional KB, MB or GB
+// suffix (powers of 1024), e.g. "512KB" or "4MB"
+type byteSize int64
+
+func (b *byteSize) String() string {
+	if b == nil {
+		return "0"
+	}
+	return strconv.FormatInt(int64(*b), 10)
+}
+
+func (b *byteSize) Set(value string) error {
+	size, err := parseByteSize(value)
+	if err != nil {
+		return err
+	}
+	*b = byteSize(size)
+	return nil
+}
+
+// parseByteSize parses a byteSize value; the size must be positive
+func parseByteSize(value string) (int64, error) {
+	number := strings.ToUpper(strings.TrimSpace(value))
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(number, unit.suffix) {
+			number, multiplier = strings.TrimSpace(strings.TrimSuffix(number, unit.suffix)), unit.size
+			break
+		}
+	}
+	n, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || n <= 0 || n > math.MaxInt64/multiplier {
+		return 0, fmt.Errorf("invalid size %q (e.g. 65536, 512KB, 4MB)", value)
+	}
+	return n * multiplier, nil
+}
+
+// usageError ends the program with message and the usage of fs
+func usageError(fs *flag.FlagSet, message string) {
+	fmt.Fprintln(fs.Output(), message)
+	fs.Usage()
+	os.Exit(usageExitCode)
+}
@@ -0,0 +1,10 @@
+// Command dxf_parser extracts text, BOM tables, cut lengths and weld counts from DXF
+// drawings; see the README for its commands. The code is the package
+// github.com/jeffcall-ch/dxf_parser_go.
+package main
+
+import dxfparser "github.com/jeffcall-ch/dxf_parser_go"
+
+func main() {
+	dxfparser.Main()
+}
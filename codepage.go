@@ -0,0 +1,249 @@
+package dxfparser
+
+import (
+	_ "embed"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// gbkTable maps the GBK (ANSI_936) byte pairs to Unicode: one little-endian uint16 per lead
+// byte 0x81-0xFE and trail byte 0x40-0xFE, 0 for undefined pairs. Generated from the Windows
+// code page 936 definition.
+//
+//go:embed codepages/gbk.bin
+var gbkTable []byte
+
+const (
+	gbkFirstLead  = 0x81
+	gbkFirstTrail = 0x40
+	gbkTrails     = 0xFF - gbkFirstTrail
+)
+
+// codePage decodes text written in a Windows ANSI code page. A nil *codePage is UTF-8.
+type codePage struct {
+	name   string     // canonical $DWGCODEPAGE name, e.g. "ANSI_1252"
+	single *[128]rune // upper half of a single-byte code page
+	gbk    bool       // double-byte ANSI_936
+}
+
+// lookupCodePage returns the code page of a $DWGCODEPAGE value or -encoding name: "ANSI_1252",
+// "cp1252", "windows-1252" and "1252" are the same page; "gbk" is ANSI_936. "utf-8" returns nil.
+func lookupCodePage(name string) (*codePage, error) {
+	normalized := strings.ToLower(strings.TrimSpace(name))
+	switch normalized {
+	case "utf-8", "utf8":
+		return nil, nil
+	case "gbk", "gb2312":
+		normalized = "936"
+	}
+	for _, prefix := range []string{"ansi_", "windows-", "cp"} {
+		normalized = strings.TrimPrefix(normalized, prefix)
+	}
+
+	number, err := strconv.Atoi(normalized)
+	if err == nil {
+		if number == 936 {
+			return &codePage{name: "ANSI_936", gbk: true}, nil
+		}
+		if table, ok := singleByteCodePages[number]; ok {
+			return &codePage{name: fmt.Sprintf("ANSI_%d", number), single: table}, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported code page '%s' (ANSI_874, ANSI_1250 to ANSI_1258, ANSI_936, utf-8)", name)
+}
+
+// decode converts value from the code page to UTF-8
+func (c *codePage) decode(value string) string {
+	if c == nil || isASCII(value) {
+		return value
+	}
+
+	var b strings.Builder
+	b.Grow(len(value) * 2)
+	for i := 0; i < len(value); i++ {
+		ch := value[i]
+		switch {
+		case ch < 0x80:
+			b.WriteByte(ch)
+		case c.single != nil:
+			b.WriteRune(c.single[ch-0x80])
+		case ch == 0x80: // the euro sign is the only single byte above ASCII in GBK
+			b.WriteRune('€')
+		case i+1 < len(value):
+			if r := gbkRune(ch, value[i+1]); r != 0 {
+				b.WriteRune(r)
+				i++
+			} else {
+				b.WriteRune(utf8.RuneError)
+			}
+		default:
+			b.WriteRune(utf8.RuneError)
+		}
+	}
+	return b.String()
+}
+
+// gbkRune returns the character of a GBK byte pair, 0 if the pair is undefined
+func gbkRune(lead, trail byte) rune {
+	if lead < gbkFirstLead || lead == 0xFF || trail < gbkFirstTrail || trail == 0xFF {
+		return 0
+	}
+	offset := (int(lead-gbkFirstLead)*gbkTrails + int(trail-gbkFirstTrail)) * 2
+	return rune(binary.LittleEndian.Uint16(gbkTable[offset:]))
+}
+
+// isASCII reports whether value has no bytes above 0x7F
+func isASCII(value string) bool {
+	for i := 0; i < len(value); i++ {
+		if value[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// mbcsEscape matches the \M+nXXXX escapes of double-byte characters that AutoCAD writes into
+// DXF files before R2007; n = 5 is ANSI_936
+var mbcsEscape = regexp.MustCompile(`\\M\+([1-5])([0-9A-Fa-f]{4})`)
+
+// decodeMBCS replaces the \M+5XXXX escapes of a text value by their characters. Escapes of
+// other code pages are left as they are.
+func decodeMBCS(value string) string {
+	if !strings.Contains(value, `\M+`) {
+		return value
+	}
+	return mbcsEscape.ReplaceAllStringFunc(value, func(match string) string {
+		if match[3] != '5' {
+			return match
+		}
+		code, _ := strconv.ParseUint(match[4:], 16, 16)
+		if r := gbkRune(byte(code>>8), byte(code)); r != 0 {
+			return string(r)
+		}
+		return match
+	})
+}
+
+// utf8Version is the first $ACADVER (AutoCAD 2007) whose DXF files are always UTF-8
+const utf8Version = "AC1021"
+
+// legacyCodePage is the code page of text in files before AutoCAD 2007 without $DWGCODEPAGE,
+// which R12 exporters often leave out
+var legacyCodePage, _ = lookupCodePage("ANSI_1252")
+
+// encodingState follows the code page of a drawing while it is scanned. DXF files before
+// AutoCAD 2007 are written in the $DWGCODEPAGE of the HEADER, ANSI_1252 without one; later
+// ones are UTF-8 whatever $DWGCODEPAGE says. An -encoding override fixes the code page and
+// ignores the HEADER.
+type encodingState struct {
+	fixed    bool      // set by an override, the HEADER is ignored
+	declared *codePage // $DWGCODEPAGE
+	utf8     bool      // $ACADVER is AC1021 or later
+	legacy   bool      // $ACADVER is before AC1021
+	variable string    // HEADER variable of the last code 9
+}
+
+// newEncodingState returns the state for an -encoding value; "" or "auto" follows the HEADER
+func newEncodingState(encoding string) (encodingState, error) {
+	if encoding == "" || strings.EqualFold(encoding, "auto") {
+		return encodingState{}, nil
+	}
+	page, err := lookupCodePage(encoding)
+	if err != nil {
+		return encodingState{}, err
+	}
+	return encodingState{fixed: true, declared: page}, nil
+}
+
+// observe follows the HEADER variables; call it with every group code / value pair outside
+// of entities
+func (s *encodingState) observe(code, value string) {
+	switch code {
+	case "0":
+		s.variable = ""
+	case "9":
+		s.variable = value
+	case "1":
+		if s.variable == "$ACADVER" {
+			s.utf8 = value >= utf8Version
+			s.legacy = !s.utf8
+		}
+	case "3":
+		if s.variable == "$DWGCODEPAGE" && !s.fixed {
+			page, err := lookupCodePage(value)
+			if err != nil {
+				debugPrint(fmt.Sprintf("[DEBUG] $DWGCODEPAGE: %v, text is read as UTF-8", err))
+			}
+			s.declared = page
+		}
+	}
+}
+
+// decode converts a text value to UTF-8. A declared code page is only applied to values that
+// are not valid UTF-8, since some exporters write UTF-8 whatever the HEADER says.
+func (s *encodingState) decode(value string) string {
+	if s.fixed {
+		return decodeMBCS(s.declared.decode(value))
+	}
+	page := s.page()
+	if s.utf8 || page == nil {
+		return value
+	}
+	if !utf8.ValidString(value) {
+		value = page.decode(value)
+	}
+	return decodeMBCS(value)
+}
+
+// page returns the code page of text that is not valid UTF-8, nil to keep it as it is
+func (s *encodingState) page() *codePage {
+	if s.declared == nil && s.legacy {
+		return legacyCodePage
+	}
+	return s.declared
+}
+
+// transcode converts a text value to UTF-8 like decode but keeps its \M+ escapes, for the
+// content as written
+func (s *encodingState) transcode(value string) string {
+	if s.fixed {
+		return s.declared.decode(value)
+	}
+	if s.utf8 || s.page() == nil || utf8.ValidString(value) {
+		return value
+	}
+	return s.page().decode(value)
+}
+
+// detectEncoding reads the HEADER at the start of r and returns the encoding state it declares,
+// starting from initial. Reading stops at the end of the HEADER section.
+func detectEncoding(r io.Reader, maxLine int, initial encodingState) encodingState {
+	state := initial
+	if state.fixed {
+		return state
+	}
+	scanner := newLineScanner(r, maxLine)
+	inHeader := false
+	for scanner.Scan() {
+		code := strings.TrimSpace(scanner.Text())
+		if !scanner.Scan() {
+			break
+		}
+		value := strings.TrimSpace(scanner.Text())
+		switch {
+		case code == "2" && value == "HEADER":
+			inHeader = true
+		case code == "0" && value == "ENDSEC" && inHeader:
+			return state
+		case code == "0" && value != "SECTION" && !inHeader:
+			return state // no HEADER before the first entity
+		}
+		state.observe(code, value)
+	}
+	return state
+}
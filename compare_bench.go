@@ -0,0 +1,323 @@
+package dxfparser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// compareBaseline names this parser in the reports of "benchmark compare"
+const compareBaseline = "dxf_parser"
+
+// compareResult is the outcome of one reader on one file. External readers print one JSON
+// line of it per file.
+type compareResult struct {
+	File     string         `json:"file"`
+	Seconds  float64        `json:"seconds"`
+	Entities map[string]int `json:"entities"` // entity records read per type
+	Error    string         `json:"error,omitempty"`
+}
+
+// ReaderReport sums the results of one reader over the corpus. Coverage is the share of the
+// entity records this parser reads, on the files both read, that the reader reads too, per type
+// capped at the count of this parser.
+type ReaderReport struct {
+	Name        string            `json:"name"`
+	Command     string            `json:"command,omitempty"`
+	FilesOK     int               `json:"files_ok"`
+	Failed      map[string]string `json:"failed,omitempty"` // file: error
+	Bytes       int64             `json:"bytes"`
+	Seconds     float64           `json:"seconds"` // sum of the best time per file
+	MBPerSecond float64           `json:"mb_per_second"`
+	Entities    map[string]int    `json:"entities"`
+	Coverage    float64           `json:"coverage"`
+}
+
+// CompareReport is the report of "benchmark compare"
+type CompareReport struct {
+	Files      int            `json:"files"`
+	Bytes      int64          `json:"bytes"`
+	Iterations int            `json:"iterations"`
+	Readers    []ReaderReport `json:"readers"`
+}
+
+// compareReader reads all files once and returns a result per file, in the order of files
+type compareReader struct {
+	name    string
+	command string
+	read    func(files []string) ([]compareResult, error)
+}
+
+// handleCompareBenchmark implements "benchmark compare": it reads a corpus with this parser and
+// with external readers, keeps the best time per file over the iterations, and reports throughput
+// and the entity records read per type
+func handleCompareBenchmark(args []string) {
+	usage := "dxf_parser benchmark compare <file.dxf|dir>... [-reader name=command]... [-iterations 3] [-json report.json]"
+	fs := newCommandFlagSet("benchmark compare", usage)
+	var readerSpecs stringList
+	fs.Var(&readerSpecs, "reader", "External reader as name=command; the command gets the files as arguments and prints a JSON line per file (repeatable)")
+	iterations := fs.Int("iterations", 3, "Runs per reader; the best time per file counts")
+	jsonOut := fs.String("json", "", "Write the report as JSON to this file")
+	inputs := parseCommandArgs(fs, args)
+	checkArgCount(fs, inputs, 1, -1, "Error: no files or directories to compare")
+	if *iterations <= 0 {
+		usageError(fs, "Error: -iterations must be positive")
+	}
+
+	readers := []compareReader{{name: compareBaseline, read: readWithParser}}
+	for _, spec := range readerSpecs {
+		name, command, ok := strings.Cut(spec, "=")
+		if !ok || strings.TrimSpace(name) == "" || strings.TrimSpace(command) == "" {
+			usageError(fs, fmt.Sprintf("Error: invalid -reader %q, expected name=command", spec))
+		}
+		readers = append(readers, compareReader{name: name, command: command, read: externalReader(command)})
+	}
+
+	files, err := compareCorpus(inputs)
+	if err != nil {
+		log.Fatalf("Error collecting files: %v", err)
+	}
+	if len(files) == 0 {
+		log.Fatalf("Error: no uncompressed DXF files in %s", strings.Join(inputs, ", "))
+	}
+	sizes := make(map[string]int64, len(files))
+	var total int64
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			log.Fatalf("Error reading %s: %v", file, err)
+		}
+		sizes[file] = info.Size()
+		total += info.Size()
+	}
+
+	fmt.Printf("Reader comparison: %d files, %.1f MB, best of %d runs\n", len(files), float64(total)/(1024*1024), *iterations)
+	fmt.Println("=====================================")
+
+	results := make([][]compareResult, len(readers))
+	for i, reader := range readers {
+		for run := 0; run < *iterations; run++ {
+			found, err := reader.read(files)
+			if err != nil {
+				log.Fatalf("Error running reader %s: %v", reader.name, err)
+			}
+			results[i] = bestResults(results[i], found, files)
+		}
+	}
+
+	report := CompareReport{Files: len(files), Bytes: total, Iterations: *iterations}
+	for i, reader := range readers {
+		report.Readers = append(report.Readers, summarizeReader(reader, results[i], results[0], sizes))
+	}
+	printCompareReport(report)
+
+	if *jsonOut != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err == nil {
+			err = writeOutputFile(*jsonOut, append(data, '\n'))
+		}
+		if err != nil {
+			log.Fatalf("Error writing report: %v", err)
+		}
+		fmt.Printf("\nReport written to %s\n", *jsonOut)
+	}
+}
+
+// compareCorpus expands the inputs into the DXF files all readers can open: plain files on disk,
+// not ZIP entries or gzip-compressed drawings, which other readers do not unpack
+func compareCorpus(inputs []string) ([]string, error) {
+	all, _, _, err := collectDXFFiles(inputs, false)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, file := range all {
+		if _, _, ok := splitZipPath(file); !ok && strings.HasSuffix(strings.ToLower(file), ".dxf") {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// readWithParser reads the files with ParseEntities, counting the entity records of the
+// ENTITIES section. VERTEX, SEQEND and ATTRIB records belong to the entity before them and
+// are not counted, like in readers that nest them.
+func readWithParser(files []string) ([]compareResult, error) {
+	results := make([]compareResult, 0, len(files))
+	for _, file := range files {
+		result := compareResult{File: file, Entities: make(map[string]int)}
+		parser := NewDXFParserWithOptions(ParseOptions{Workers: 1})
+		parser.OnEntity("*", func(entity Entity) error {
+			switch {
+			case entity.Section != "ENTITIES":
+			case entity.Type == "VERTEX" || entity.Type == "SEQEND" || entity.Type == "ATTRIB":
+			default:
+				result.Entities[entity.Type]++
+			}
+			return nil
+		})
+
+		start := time.Now()
+		input, err := openInput(file)
+		if err == nil {
+			err = parser.ParseEntities(input)
+			input.Close()
+		}
+		result.Seconds = time.Since(start).Seconds()
+		if err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// externalReader runs command with the files as arguments and reads its JSON lines. A file
+// the command printed no line for failed; so do all files if the command fails.
+func externalReader(command string) func(files []string) ([]compareResult, error) {
+	return func(files []string) ([]compareResult, error) {
+		fields := strings.Fields(command)
+		cmd := exec.Command(fields[0], append(fields[1:], files...)...)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, runErr := cmd.Output()
+
+		byFile := make(map[string]compareResult)
+		scanner := bufio.NewScanner(bytes.NewReader(out))
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			var result compareResult
+			if err := json.Unmarshal(line, &result); err != nil {
+				return nil, fmt.Errorf("invalid output line %q: %w", line, err)
+			}
+			byFile[result.File] = result
+		}
+
+		missing := "no result printed"
+		if runErr != nil {
+			missing = strings.TrimSpace(fmt.Sprintf("%v %s", runErr, stderr.String()))
+		}
+		results := make([]compareResult, 0, len(files))
+		for _, file := range files {
+			result, ok := byFile[file]
+			if !ok {
+				result = compareResult{File: file, Error: missing}
+			}
+			results = append(results, result)
+		}
+		return results, nil
+	}
+}
+
+// bestResults keeps the faster successful result per file of two runs
+func bestResults(best, run []compareResult, files []string) []compareResult {
+	if best == nil {
+		return run
+	}
+	for i := range files {
+		switch {
+		case run[i].Error != "":
+		case best[i].Error != "" || run[i].Seconds < best[i].Seconds:
+			best[i] = run[i]
+		}
+	}
+	return best
+}
+
+// summarizeReader sums the results of a reader and its coverage of the baseline results
+func summarizeReader(reader compareReader, results, baseline []compareResult, sizes map[string]int64) ReaderReport {
+	report := ReaderReport{Name: reader.name, Command: reader.command, Entities: make(map[string]int)}
+	covered, reference := 0, 0
+	for i, result := range results {
+		if result.Error != "" {
+			if report.Failed == nil {
+				report.Failed = make(map[string]string)
+			}
+			report.Failed[result.File] = result.Error
+			continue
+		}
+		report.FilesOK++
+		report.Bytes += sizes[result.File]
+		report.Seconds += result.Seconds
+		for entityType, count := range result.Entities {
+			report.Entities[entityType] += count
+		}
+		if baseline[i].Error != "" {
+			continue
+		}
+		for entityType, count := range baseline[i].Entities {
+			reference += count
+			if read := result.Entities[entityType]; read < count {
+				covered += read
+			} else {
+				covered += count
+			}
+		}
+	}
+	if report.Seconds > 0 {
+		report.MBPerSecond = float64(report.Bytes) / (1024 * 1024) / report.Seconds
+	}
+	if reference > 0 {
+		report.Coverage = float64(covered) / float64(reference)
+	}
+	return report
+}
+
+// printCompareReport prints the reader table and the entity records per type
+func printCompareReport(report CompareReport) {
+	fmt.Printf("\n%-14s %6s %7s %10s %9s %10s %9s\n", "reader", "ok", "failed", "seconds", "MB/s", "entities", "coverage")
+	types := make(map[string]bool)
+	for _, r := range report.Readers {
+		entities := 0
+		for entityType, count := range r.Entities {
+			types[entityType] = true
+			entities += count
+		}
+		fmt.Printf("%-14s %6d %7d %10.4f %9.1f %10d %8.1f%%\n", r.Name, r.FilesOK, len(r.Failed), r.Seconds,
+			r.MBPerSecond, entities, r.Coverage*100)
+	}
+
+	names := make([]string, 0, len(types))
+	for entityType := range types {
+		names = append(names, entityType)
+	}
+	sort.Strings(names)
+	fmt.Printf("\nEntity records per type\n%-14s", "type")
+	for _, r := range report.Readers {
+		fmt.Printf(" %14s", r.Name)
+	}
+	fmt.Println()
+	for _, entityType := range names {
+		fmt.Printf("%-14s", entityType)
+		for _, r := range report.Readers {
+			fmt.Printf(" %14d", r.Entities[entityType])
+		}
+		fmt.Println()
+	}
+
+	for _, r := range report.Readers {
+		if len(r.Failed) == 0 {
+			continue
+		}
+		files := make([]string, 0, len(r.Failed))
+		for file := range r.Failed {
+			files = append(files, file)
+		}
+		sort.Strings(files)
+		fmt.Printf("\nFailed with %s:\n", r.Name)
+		for _, file := range files {
+			fmt.Printf("  %s: %s\n", file, r.Failed[file])
+		}
+	}
+}
@@ -0,0 +1,444 @@
+package dxfparser
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configTables are the table titles table_aliases can give alternatives for
+var configTables = []string{"ERECTION MATERIALS", "CUT PIPE LENGTH"}
+
+// configProblem is an error in a config file at a line and column (0 when not known)
+type configProblem struct {
+	Line, Column int
+	Message      string
+}
+
+// yamlErrorLine matches one error of yaml.v3: "yaml: line 3: ..." or "line 3: ..."
+var yamlErrorLine = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// yamlUnknownField matches the error of a key the config does not have
+var yamlUnknownField = regexp.MustCompile(`^field (\S+) not found in type .*$`)
+
+// handleConfigCommand implements "config validate": it checks project config files the way a
+// bom run would load them and for settings that load but cannot work, without reading drawings
+func handleConfigCommand() {
+	if len(os.Args) < 3 || os.Args[2] != "validate" {
+		fmt.Fprintln(os.Stderr, "Usage: dxf_parser config validate <.dxfparser.yaml|dir>...")
+		os.Exit(usageExitCode)
+	}
+	fs := newCommandFlagSet("config validate", "dxf_parser config validate <.dxfparser.yaml|dir>...")
+	args := parseCommandArgs(fs, os.Args[3:])
+	checkArgCount(fs, args, 1, -1, "Error: no config file to validate")
+
+	failed := false
+	for _, arg := range args {
+		path := arg
+		if info, err := os.Stat(arg); err == nil && info.IsDir() {
+			path = filepath.Join(arg, projectConfigFile)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, msg("config.error", err))
+			failed = true
+			continue
+		}
+		problems := validateProjectConfig(data)
+		for _, p := range problems {
+			switch {
+			case p.Column > 0:
+				fmt.Printf("%s:%d:%d: %s\n", path, p.Line, p.Column, p.Message)
+			case p.Line > 0:
+				fmt.Printf("%s:%d: %s\n", path, p.Line, p.Message)
+			default:
+				fmt.Printf("%s: %s\n", path, p.Message)
+			}
+		}
+		if len(problems) > 0 {
+			fmt.Println(msg("config.invalid", path, len(problems)))
+			failed = true
+		} else {
+			fmt.Println(msg("config.valid", path))
+		}
+	}
+	if failed {
+		os.Exit(exitConfigError)
+	}
+}
+
+// validateProjectConfig checks project config data: YAML syntax, unknown keys and value types,
+// the bom options in defaults, table aliases that match another table, weld lengths and
+// tolerances out of range, repeated length pairs, layer sets never reached, invalid patterns
+// and dictionary names. The problems are sorted by line.
+func validateProjectConfig(data []byte) []configProblem {
+	var problems []configProblem
+	add := func(node *yaml.Node, format string, args ...interface{}) {
+		problems = append(problems, configProblem{Line: node.Line, Column: node.Column, Message: fmt.Sprintf(format, args...)})
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return yamlProblems(err)
+	}
+	if len(root.Content) == 0 {
+		return nil // an empty config keeps all defaults
+	}
+	doc := root.Content[0]
+	if doc.Kind != yaml.MappingNode {
+		add(doc, "expected a mapping of config sections")
+		return problems
+	}
+
+	var config ProjectConfig
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		problems = append(problems, yamlProblems(err)...)
+	}
+
+	validateConfigDefaults(configValue(doc, "defaults"), add)
+	validateConfigAliases(configValue(doc, "table_aliases"), add)
+	validateConfigWeld(configValue(doc, "weld"), add)
+	validateConfigPatterns(configValue(doc, "patterns"), add)
+
+	if node := configValue(doc, "ebom", "dictionaries"); node != nil && node.Kind == yaml.SequenceNode {
+		seen := make(map[string]int)
+		for _, name := range node.Content {
+			switch {
+			case strings.TrimSpace(name.Value) == "":
+				add(name, "ebom.dictionaries: empty dictionary name")
+			case seen[name.Value] > 0:
+				add(name, "ebom.dictionaries: '%s' repeats line %d", name.Value, seen[name.Value])
+			default:
+				seen[name.Value] = name.Line
+			}
+		}
+	}
+
+	if node := configValue(doc, "title_styles"); node != nil && node.Kind == yaml.SequenceNode {
+		seen := make(map[string]int)
+		for _, style := range node.Content {
+			key := strings.ToUpper(strings.TrimSpace(style.Value))
+			switch {
+			case key == "":
+				add(style, "title_styles: empty style name")
+			case seen[key] > 0:
+				add(style, "title_styles: '%s' repeats line %d", style.Value, seen[key])
+			default:
+				seen[key] = style.Line
+			}
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].Line != problems[j].Line {
+			return problems[i].Line < problems[j].Line
+		}
+		return problems[i].Column < problems[j].Column
+	})
+	return problems
+}
+
+// validateConfigDefaults checks the defaults against the bom options: the option must exist and
+// take the value, and values bom checks after parsing must be valid too
+func validateConfigDefaults(node *yaml.Node, add func(*yaml.Node, string, ...interface{})) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return
+	}
+	var flags bomFlags
+	fs := flag.NewFlagSet("bom", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	flags.define(fs)
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if fs.Lookup(key.Value) == nil {
+			add(key, "defaults: unknown bom option '%s'", key.Value)
+			continue
+		}
+		if value.Kind != yaml.ScalarNode {
+			add(value, "defaults: value of '%s' must be a single value", key.Value)
+			continue
+		}
+		err := fs.Set(key.Value, value.Value)
+		if err == nil {
+			switch key.Value {
+			case "pipe-policy":
+				_, err = parsePipePolicy(value.Value)
+			case "rotated-text":
+				_, err = parseRotatedTextPolicy(value.Value)
+			case "scope":
+				_, err = parseExtractionScope(value.Value)
+			case "units":
+				_, err = parseUnits(value.Value)
+			case "encoding":
+				_, err = newEncodingState(value.Value)
+			case "workers":
+				if flags.opts.Workers < 0 {
+					err = errors.New("must not be negative")
+				}
+			case "block-depth":
+				if flags.opts.BlockDepth <= 0 {
+					err = errors.New("must be positive")
+				}
+			case "weld-graph-radius":
+				if flags.opts.WeldGraphRadius <= 0 {
+					err = errors.New("must be positive")
+				}
+			case "fail-on-error-rate":
+				if !(flags.opts.FailOnErrorRate >= 0 && flags.opts.FailOnErrorRate <= 1) {
+					err = errors.New("must be between 0 and 1")
+				}
+			case "dwg-converter":
+				if value.Value != "" {
+					_, err = newDWGConverter(value.Value)
+				}
+			}
+		}
+		if err != nil {
+			add(value, "defaults: invalid value for '%s': %v", key.Value, err)
+		}
+	}
+}
+
+// validateConfigAliases checks that the aliases belong to known tables and that no alias also
+// matches the title or an alias of another table, which would read one table for the other
+func validateConfigAliases(node *yaml.Node, add func(*yaml.Node, string, ...interface{})) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return
+	}
+	type alias struct {
+		table string
+		node  *yaml.Node
+	}
+	var aliases []alias
+	headings := make(map[string][]string) // table: title and aliases, lower case
+	for _, table := range configTables {
+		headings[table] = []string{strings.ToLower(table)}
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		table := strings.ToUpper(strings.TrimSpace(key.Value))
+		if _, ok := headings[table]; !ok {
+			add(key, "table_aliases: unknown table '%s' (%s)", key.Value, strings.Join(configTables, ", "))
+			continue
+		}
+		if value.Kind != yaml.SequenceNode {
+			continue // reported by the schema check
+		}
+		seen := make(map[string]bool)
+		for _, a := range value.Content {
+			text := strings.ToLower(a.Value)
+			switch {
+			case strings.TrimSpace(text) == "":
+				add(a, "table_aliases: empty alias of %s", table)
+			case seen[text]:
+				add(a, "table_aliases: alias '%s' of %s repeats", a.Value, table)
+			default:
+				seen[text] = true
+				aliases = append(aliases, alias{table, a})
+				headings[table] = append(headings[table], text)
+			}
+		}
+	}
+
+	for _, a := range aliases {
+		text := strings.ToLower(a.node.Value)
+		for _, other := range configTables {
+			if other == a.table {
+				continue
+			}
+			for _, heading := range headings[other] {
+				if strings.Contains(heading, text) {
+					add(a.node, "table_aliases: alias '%s' of %s also matches '%s' of %s", a.node.Value, a.table, heading, other)
+					break
+				}
+			}
+		}
+	}
+}
+
+// validateConfigWeld checks the weld lengths and tolerances, length pairs repeated within the
+// length tolerance and layer sets whose pattern an earlier set always matches first
+func validateConfigWeld(node *yaml.Node, add func(*yaml.Node, string, ...interface{})) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return
+	}
+	tolerances := make(map[string]float64)
+	for _, t := range []struct {
+		key      string
+		min, max float64
+		open     bool // min itself is out of range
+	}{
+		{"length_tolerance", 0, math.Inf(1), true},
+		{"center_tolerance", 0, 0.5, true},
+		{"group_center_tolerance", 0, 0.5, true},
+		{"duplicate_distance", 0, math.Inf(1), false},
+		{"label_radius", 0, math.Inf(1), false},
+		{"segment_epsilon", 0, math.Inf(1), false},
+	} {
+		value := configValue(node, t.key)
+		v, ok := configFloat(value)
+		if !ok {
+			continue
+		}
+		tolerances[t.key] = v
+		switch {
+		case t.open && v <= t.min:
+			add(value, "weld.%s: must be positive", t.key)
+		case v < t.min:
+			add(value, "weld.%s: must not be negative", t.key)
+		case v > t.max:
+			add(value, "weld.%s: %v is more than %v, the share of the segment length from its midpoint to an end", t.key, v, t.max)
+		}
+	}
+	center, hasCenter := tolerances["center_tolerance"]
+	if group, ok := tolerances["group_center_tolerance"]; ok && hasCenter && group < center {
+		add(configValue(node, "group_center_tolerance"), "weld.group_center_tolerance: %v is below center_tolerance %v; grouped symbols would match less than loose ones", group, center)
+	}
+	tolerance, ok := tolerances["length_tolerance"]
+	if !ok {
+		tolerance = DefaultWeldConfig().LengthTolerance
+	}
+
+	validateConfigPairs(configValue(node, "length_pairs"), "weld.length_pairs", tolerance, add)
+	sets := configValue(node, "layer_pairs")
+	if sets == nil || sets.Kind != yaml.SequenceNode {
+		return
+	}
+	var patterns []*yaml.Node
+	for i, set := range sets.Content {
+		var pairs LayerLengthPairs
+		if set.Decode(&pairs) != nil {
+			continue // reported by the schema check
+		}
+		if err := pairs.validate(); err != nil {
+			add(set, "weld.layer_pairs: %v", err)
+			continue
+		}
+		validateConfigPairs(configValue(set, "length_pairs"), fmt.Sprintf("weld.layer_pairs[%d].length_pairs", i), tolerance, add)
+		layer := configValue(set, "layer")
+		for _, earlier := range patterns {
+			if ok, _ := path.Match(strings.ToUpper(earlier.Value), strings.ToUpper(layer.Value)); ok {
+				add(layer, "weld.layer_pairs: layer '%s' is always matched by '%s' of line %d first", layer.Value, earlier.Value, earlier.Line)
+				break
+			}
+		}
+		patterns = append(patterns, layer)
+	}
+}
+
+// validateConfigPairs checks a list of length pairs: two positive lengths each, and no pair
+// matching an earlier one within tolerance, in either order
+func validateConfigPairs(node *yaml.Node, name string, tolerance float64, add func(*yaml.Node, string, ...interface{})) {
+	if node == nil || node.Kind != yaml.SequenceNode {
+		return
+	}
+	var pairs [][2]float64
+	var lines []int
+	for _, pairNode := range node.Content {
+		var pair [2]float64
+		if pairNode.Decode(&pair) != nil {
+			continue // reported by the schema check
+		}
+		if pair[0] <= 0 || pair[1] <= 0 {
+			add(pairNode, "%s: lengths must be positive, got [%v, %v]", name, pair[0], pair[1])
+			continue
+		}
+		for i, earlier := range pairs {
+			same := func(a, b [2]float64) bool {
+				return math.Abs(a[0]-b[0]) <= tolerance && math.Abs(a[1]-b[1]) <= tolerance
+			}
+			if same(pair, earlier) || same(pair, [2]float64{earlier[1], earlier[0]}) {
+				add(pairNode, "%s: [%v, %v] repeats [%v, %v] of line %d within the length tolerance", name,
+					pair[0], pair[1], earlier[0], earlier[1], lines[i])
+				break
+			}
+		}
+		pairs = append(pairs, pair)
+		lines = append(lines, pairNode.Line)
+	}
+}
+
+// validateConfigPatterns compiles the patterns; the revision pattern needs the group the
+// revision is taken from
+func validateConfigPatterns(node *yaml.Node, add func(*yaml.Node, string, ...interface{})) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if value.Kind != yaml.ScalarNode || value.Value == "" {
+			continue
+		}
+		re, err := regexp.Compile(value.Value)
+		switch {
+		case err != nil:
+			add(value, "patterns.%s: invalid pattern: %v", key.Value, err)
+		case key.Value == "revision" && re.NumSubexp() == 0:
+			add(value, "patterns.revision: needs a group around the revision, e.g. '_(\\d+(?:\\.\\d+)?)'")
+		}
+	}
+}
+
+// configValue returns the value of the mapping keys below node, nil if one is missing
+func configValue(node *yaml.Node, keys ...string) *yaml.Node {
+	for _, key := range keys {
+		if node == nil || node.Kind != yaml.MappingNode {
+			return nil
+		}
+		var value *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				value = node.Content[i+1]
+				break
+			}
+		}
+		node = value
+	}
+	return node
+}
+
+// configFloat returns the number of a scalar node
+func configFloat(node *yaml.Node) (float64, bool) {
+	var v float64
+	if node == nil || node.Kind != yaml.ScalarNode || node.Decode(&v) != nil {
+		return 0, false
+	}
+	return v, true
+}
+
+// yamlProblems splits a yaml.v3 error into its problems with their lines
+func yamlProblems(err error) []configProblem {
+	lines := []string{err.Error()}
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		lines = typeErr.Errors
+	}
+	var problems []configProblem
+	for _, text := range lines {
+		problem := configProblem{Message: strings.TrimPrefix(text, "yaml: ")}
+		if m := yamlErrorLine.FindStringSubmatch(text); m != nil {
+			problem.Line, _ = strconv.Atoi(m[1])
+			problem.Message = m[2]
+		}
+		if m := yamlUnknownField.FindStringSubmatch(problem.Message); m != nil {
+			problem.Message = fmt.Sprintf("unknown key '%s'", m[1])
+		}
+		problems = append(problems, problem)
+	}
+	return problems
+}
@@ -0,0 +1,80 @@
+package dxfparser
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+// crashReportName is the output listing the panics of a bom run with their stack traces
+const crashReportName = "0009_CRASH_REPORT.txt"
+
+// crashReport is a panic caught while one file was processed
+type crashReport struct {
+	FilePath string
+	Stage    string // "extraction" or "weld detection"
+	Panic    string
+	Stack    string
+	Time     time.Time
+}
+
+// crashReports collects the crash reports of a run; workers add to it concurrently
+type crashReports struct {
+	sync.Mutex
+	reports []crashReport
+}
+
+// recoverCrash is deferred by the work on one file. A panic of that work, e.g. an index out of
+// range on a malformed table row, is recovered and recorded with its stack trace, and fail is
+// called with the error of the file, so the other files of the run are processed as usual.
+func (run *runConfig) recoverCrash(filePath, stage string, fail func(message string)) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	report := crashReport{FilePath: filePath, Stage: stage, Panic: fmt.Sprint(r), Stack: string(debug.Stack()), Time: time.Now()}
+	run.crashes.Lock()
+	run.crashes.reports = append(run.crashes.reports, report)
+	run.crashes.Unlock()
+	debugPrint(fmt.Sprintf("[DEBUG] Panic in %s of %s: %v", stage, filePath, r))
+	fail(fmt.Sprintf("internal error in %s: %v (stack trace in %s)", stage, r, run.outputs.name(crashReportName)))
+}
+
+// processInputFileIsolated is processInputFile with a panic turned into an error of the file,
+// which then fails like a file that cannot be converted
+func processInputFileIsolated(ctx context.Context, path string, weldFlag bool, run *runConfig) (result DXFResult, cache *FileCache) {
+	defer run.recoverCrash(path, "extraction", func(message string) {
+		result, cache = DXFResult{Filename: path, FilePath: path, Error: message}, nil
+		if weldFlag {
+			cache = &FileCache{SegmentError: message}
+		}
+	})
+	return processInputFile(ctx, path, weldFlag, run)
+}
+
+// writeCrashReport writes the crash reports of run to directory, if there are any
+func writeCrashReport(run *runConfig, directory string) error {
+	run.crashes.Lock()
+	reports := append([]crashReport(nil), run.crashes.reports...)
+	run.crashes.Unlock()
+	if len(reports) == 0 {
+		return nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "dxf_parser_go %s, crashed files: %d\n", getToolVersion(), len(reports))
+	for _, report := range reports {
+		fmt.Fprintf(&b, "\n=== %s (%s, %s)\npanic: %s\n\n%s", report.FilePath, report.Stage,
+			report.Time.Format(time.RFC3339), report.Panic, report.Stack)
+	}
+
+	filename := run.outputs.path(directory, crashReportName)
+	if err := run.outputs.writeFile(filename, []byte(b.String())); err != nil {
+		return fmt.Errorf("error writing crash report: %v", err)
+	}
+	fmt.Println(msg("bom.crash_report", filename, len(reports)))
+	return nil
+}
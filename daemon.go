@@ -0,0 +1,482 @@
+package dxfparser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// daemonQueueFile is the job queue database in the -state directory
+const daemonQueueFile = "daemon.db"
+
+// errOutsideRoots is returned for submitted jobs with paths outside the -root directories
+var errOutsideRoots = errors.New("not under a -root directory")
+
+// daemonJobRequest is the body of POST /api/jobs
+type daemonJobRequest struct {
+	Name      string   `json:"name"`
+	Inputs    []string `json:"inputs"`
+	OutputDir string   `json:"output_dir"` // default: the first input directory
+	Priority  int      `json:"priority"`
+	Weld      bool     `json:"weld"`
+}
+
+// handleDaemonCommand implements "daemon -state <dir>": a long-running extraction service with
+// a persistent job queue. Batches are submitted through the job API or found in watched
+// directories, run by priority one file at a time, and resumed after a restart. The job API
+// has no authentication: it listens on localhost unless -addr says otherwise, and submitted
+// jobs only read and write below the -root directories.
+func handleDaemonCommand() {
+	fs := newCommandFlagSet("daemon", "dxf_parser daemon -state <dir> [-addr 127.0.0.1:8080] [-root <dir>]... [-watch <dir>]... [-priority 0] [-interval 5s] [-weld] [-debug]")
+	state := fs.String("state", "", "Directory of the job queue database (required)")
+	addr := fs.String("addr", "127.0.0.1:8080", "Address of the job API")
+	var roots, watch stringList
+	fs.Var(&roots, "root", "Directory the inputs and output directory of submitted jobs must be in (repeatable; default: the -watch directories)")
+	fs.Var(&watch, "watch", "Directory to watch; new or modified DXF files are queued as a batch with outputs in that directory (repeatable)")
+	priority := fs.Int("priority", 0, "Priority of the batches found in watched directories")
+	interval := fs.Duration("interval", 5*time.Second, "How often watched directories are scanned and the queue is polled")
+	weld := fs.Bool("weld", false, "Also count weld symbols in the batches of watched directories")
+	debug := fs.Bool("debug", false, "Enable debug output")
+	checkArgCount(fs, parseCommandArgs(fs, os.Args[2:]), 0, 0, "")
+
+	if *state == "" {
+		usageError(fs, msg("daemon.state_required"))
+	}
+	if *interval <= 0 {
+		usageError(fs, "Error: -interval must be positive")
+	}
+	if len(roots) == 0 {
+		roots = watch
+	}
+	for _, directory := range append(append([]string{}, roots...), watch...) {
+		if info, err := os.Stat(directory); err != nil || !info.IsDir() {
+			fmt.Println(msg("bom.dir_missing", directory))
+			os.Exit(1)
+		}
+	}
+	roots = resolveRoots(roots)
+	if err := os.MkdirAll(*state, 0o755); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	debugMode = *debug
+	useProjectConfig(*state, nil)
+
+	queue, err := openDaemonQueue(filepath.Join(*state, daemonQueueFile))
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer queue.Close()
+
+	templates := make([]DaemonJob, len(watch))
+	for i, directory := range watch {
+		templates[i] = DaemonJob{Name: "watch " + directory, OutputDir: directory, Priority: *priority, Weld: *weld}
+	}
+	fmt.Println(msg("daemon.started", *state, displayAddr(*addr)))
+	server := &http.Server{Addr: *addr, Handler: daemonAPI(queue, roots)}
+	if err := runJobService(queue, server, templates, *interval); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		queue.Close()
+		os.Exit(1)
+	}
+	fmt.Println(msg("daemon.stopped"))
+}
+
+// runJobService runs the job queue of serve and daemon until SIGINT / SIGTERM: a watcher for
+// the output directory of every job of watch, which are templates for the batches found, the
+// job runner, and server. It returns when the runner has stopped after its current file.
+func runJobService(queue *DaemonQueue, server *http.Server, watch []DaemonJob, interval time.Duration) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	for _, template := range watch {
+		go watchDirectory(ctx, queue, template.OutputDir, template, interval)
+	}
+	done := make(chan struct{})
+	go func() {
+		runJobs(ctx, queue, interval)
+		close(done)
+	}()
+	go func() {
+		<-ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+
+	err := server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	}
+	stop()
+	<-done
+	return err
+}
+
+// watchDirectory queues a batch of the DXF files in directory that are new or modified since
+// they were last queued. Files changed within the last interval may still be copied and wait
+// for the next scan.
+func watchDirectory(ctx context.Context, queue *DaemonQueue, directory string, template DaemonJob, interval time.Duration) {
+	for ctx.Err() == nil {
+		files := make(map[string]time.Time)
+		filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() || !isDXF(path) || time.Since(info.ModTime()) < interval {
+				return nil
+			}
+			files[path] = info.ModTime()
+			return nil
+		})
+		if len(files) > 0 {
+			if job, ok, err := queue.SubmitWatched(template, files); err != nil {
+				fmt.Printf("Error: %v\n", err)
+			} else if ok {
+				fmt.Println(msg("daemon.queued", job.ID, job.Name, len(job.Inputs)))
+			}
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(interval):
+		}
+	}
+}
+
+// runJobs runs queued jobs one at a time until ctx is done
+func runJobs(ctx context.Context, queue *DaemonQueue, idle time.Duration) {
+	for ctx.Err() == nil {
+		job, ok, err := queue.Next()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+		}
+		if !ok {
+			select {
+			case <-ctx.Done():
+			case <-time.After(idle):
+			}
+			continue
+		}
+		if err := runDaemonJob(ctx, queue, job, ProcessDrawingContext); err != nil {
+			fmt.Printf("Error: job %d: %v\n", job.ID, err)
+		}
+	}
+}
+
+// drawingProcessor extracts one drawing of a job, like ProcessDrawingContext
+type drawingProcessor func(ctx context.Context, path string, opts DrawingOptions) (*DrawingReport, error)
+
+// runDaemonJob processes the files of job not checkpointed yet with process and writes the BOM
+// outputs once all are. A canceled job stops before the next file, or after its last one,
+// without outputs. When ctx is done the job stops and stays running, to be queued again by the
+// next start.
+func runDaemonJob(ctx context.Context, queue *DaemonQueue, job DaemonJob, process drawingProcessor) error {
+	if job.Files == nil {
+		files, _, _, err := collectDXFFiles(job.Inputs, false)
+		if err == nil && len(files) == 0 {
+			err = errors.New("no DXF files found")
+		}
+		if err != nil {
+			_, finishErr := queue.Finish(job.ID, func(j *DaemonJob) {
+				j.Status = JobFailed
+				j.Error = err.Error()
+			})
+			return finishErr
+		}
+		if job, err = queue.Update(job.ID, func(j *DaemonJob) error {
+			j.Files = make([]DaemonFile, len(files))
+			for i, file := range files {
+				j.Files[i] = DaemonFile{Path: file, Status: JobQueued}
+			}
+			j.Total = len(files)
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	fmt.Println(msg("daemon.running", job.ID, job.Name, job.Processed, job.Total))
+
+	for i, file := range job.Files {
+		if file.Status != JobQueued {
+			continue
+		}
+		if stop, err := stopDaemonJob(ctx, queue, job.ID); stop || err != nil {
+			return err
+		}
+
+		report, processErr := process(ctx, file.Path, DrawingOptions{Welds: job.Weld})
+		if ctx.Err() != nil {
+			return nil
+		}
+		if processErr != nil {
+			file.Status = JobFailed
+			file.Error = processErr.Error()
+			report = nil
+		} else {
+			file.Status = JobDone
+			file.DrawingNo = report.DrawingNo
+			file.PipeClass = report.PipeClass
+			file.Warnings = report.Warnings
+			file.MatRows = len(report.Materials.Rows)
+			file.CutRows = len(report.CutLengths.Rows)
+			file.WeldCount = report.WeldCount()
+			file.Duration = report.Timings.Total
+		}
+		file.FinishedAt = time.Now()
+		if err := queue.Checkpoint(job.ID, i, file, report); err != nil {
+			return err
+		}
+		job.Files[i] = file
+	}
+	// The job may have been canceled or the daemon stopped while the last file was processed
+	if stop, err := stopDaemonJob(ctx, queue, job.ID); stop || err != nil {
+		return err
+	}
+
+	reports, err := queue.Reports(job.ID)
+	if err != nil {
+		return err
+	}
+	outputs, writeErr := writeDaemonOutputs(job, reports)
+	job, err = queue.Finish(job.ID, func(j *DaemonJob) {
+		j.Outputs = outputs
+		if writeErr != nil {
+			j.Status = JobFailed
+			j.Error = writeErr.Error()
+		} else if j.Status != JobCanceled {
+			j.Status = JobDone
+		}
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println(msg("daemon.finished", job.ID, job.Status, job.Processed-job.FailedFiles, job.FailedFiles))
+	return nil
+}
+
+// stopDaemonJob reports whether job id is not to go on: when ctx is done, leaving the job
+// running, or when it was canceled, which finishes it without outputs
+func stopDaemonJob(ctx context.Context, queue *DaemonQueue, id int64) (bool, error) {
+	current, err := queue.Get(id)
+	if err != nil {
+		return true, err
+	}
+	if ctx.Err() != nil {
+		return true, nil
+	}
+	if current.Status != JobCanceled {
+		return false, nil
+	}
+	_, err = queue.Finish(id, func(*DaemonJob) {})
+	fmt.Println(msg("daemon.finished", id, JobCanceled, current.Processed-current.FailedFiles, current.FailedFiles))
+	return true, err
+}
+
+// writeDaemonOutputs writes the BOM CSV outputs of job to its output directory, named like a
+// bom run with the run id "job-<id>", and returns their paths
+func writeDaemonOutputs(job DaemonJob, reports map[int]*DrawingReport) ([]string, error) {
+	var materialRows, cutRows [][]string
+	var matHeader, cutHeader []string
+	var summary []SummaryRow
+	for i, file := range job.Files {
+		row := SummaryRow{FilePath: file.Path, Filename: file.Path, Error: file.Error, MatMissing: true, CutMissing: true}
+		if report := reports[i]; report != nil {
+			if len(report.Materials.Rows) > 0 {
+				if len(matHeader) == 0 {
+					matHeader = report.Materials.Header
+				}
+				materialRows = append(materialRows, report.Materials.Rows...)
+			}
+			if len(report.CutLengths.Rows) > 0 {
+				if len(cutHeader) == 0 {
+					cutHeader = report.CutLengths.Header
+				}
+				cutRows = append(cutRows, report.CutLengths.Rows...)
+			}
+			row.DrawingNo = report.DrawingNo
+			row.PipeClass = report.PipeClass
+			row.MatRows = len(report.Materials.Rows)
+			row.CutRows = len(report.CutLengths.Rows)
+			row.MatMissing = row.MatRows == 0
+			row.CutMissing = row.CutRows == 0
+			row.ProcessingTime = report.Timings.Total
+			row.Warnings = strings.Join(report.Warnings, "; ")
+			row.PieceCheck = report.PieceCheck
+			row.MatConfidence = formatConfidence(report.Materials.Confidence)
+			row.CutConfidence = formatConfidence(report.CutLengths.Confidence)
+		}
+		summary = append(summary, row)
+	}
+
+	run := defaultRunConfig()
+	run.outputs.runID = "job-" + strconv.FormatInt(job.ID, 10)
+	if err := writeOutputFiles(run, job.OutputDir, materialRows, cutRows, summary, matHeader, cutHeader); err != nil {
+		return nil, err
+	}
+	var outputs []string
+	for _, name := range []string{"0001_ERECTION_MATERIALS.csv", "0002_CUT_PIPE_LENGTH.csv", "0003_AGGREGATED_MATERIALS.csv", "0004_SUMMARY.csv"} {
+		path := filepath.Join(job.OutputDir, run.outputs.name(name))
+		if _, err := os.Stat(path); err == nil {
+			outputs = append(outputs, path)
+		}
+	}
+	return outputs, nil
+}
+
+// daemonAPI serves the job API:
+//
+//	GET    /api/jobs       all jobs without their file lists
+//	POST   /api/jobs       submit a job (daemonJobRequest), 201 with the queued job
+//	GET    /api/jobs/<id>  a job with the state of every file
+//	DELETE /api/jobs/<id>  cancel a queued or running job
+//
+// Submitted jobs must have their inputs and output directory below one of roots, which are
+// absolute paths with symlinks resolved; without roots every submission is refused.
+func daemonAPI(queue *DaemonQueue, roots []string) http.Handler {
+	mux := http.NewServeMux()
+	reply := func(w http.ResponseWriter, status int, value interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(value)
+	}
+	fail := func(w http.ResponseWriter, status int, err error) {
+		reply(w, status, map[string]string{"error": err.Error()})
+	}
+
+	mux.HandleFunc("/api/jobs", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			jobs, err := queue.List()
+			if err != nil {
+				fail(w, http.StatusInternalServerError, err)
+				return
+			}
+			reply(w, http.StatusOK, jobs)
+		case http.MethodPost:
+			var request daemonJobRequest
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				fail(w, http.StatusBadRequest, fmt.Errorf("invalid job: %v", err))
+				return
+			}
+			job, err := newDaemonJob(request, roots)
+			if errors.Is(err, errOutsideRoots) {
+				fail(w, http.StatusForbidden, err)
+				return
+			}
+			if err != nil {
+				fail(w, http.StatusBadRequest, err)
+				return
+			}
+			if job, err = queue.Submit(job); err != nil {
+				fail(w, http.StatusInternalServerError, err)
+				return
+			}
+			fmt.Println(msg("daemon.queued", job.ID, job.Name, len(job.Inputs)))
+			reply(w, http.StatusCreated, job)
+		default:
+			fail(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		}
+	})
+
+	mux.HandleFunc("/api/jobs/", func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/api/jobs/"), 10, 64)
+		if err != nil {
+			fail(w, http.StatusNotFound, errJobNotFound)
+			return
+		}
+		var job DaemonJob
+		switch r.Method {
+		case http.MethodGet:
+			job, err = queue.Get(id)
+		case http.MethodDelete:
+			job, err = queue.Update(id, func(j *DaemonJob) error {
+				if j.Status != JobQueued && j.Status != JobRunning {
+					return fmt.Errorf("job %d is %s", id, j.Status)
+				}
+				if j.Status == JobQueued {
+					j.FinishedAt = time.Now()
+				}
+				j.Status = JobCanceled
+				return nil
+			})
+		default:
+			fail(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+			return
+		}
+		switch {
+		case errors.Is(err, errJobNotFound):
+			fail(w, http.StatusNotFound, err)
+		case err != nil:
+			fail(w, http.StatusConflict, err)
+		default:
+			reply(w, http.StatusOK, job)
+		}
+	})
+	return mux
+}
+
+// newDaemonJob checks a submitted job; inputs must exist on the daemon's machine, and they
+// and the output directory must be below one of roots
+func newDaemonJob(request daemonJobRequest, roots []string) (DaemonJob, error) {
+	if len(request.Inputs) == 0 {
+		return DaemonJob{}, errors.New("no inputs")
+	}
+	for _, input := range request.Inputs {
+		if !underRoot(input, roots) {
+			return DaemonJob{}, fmt.Errorf("input '%s': %w", input, errOutsideRoots)
+		}
+		if _, err := os.Stat(input); err != nil {
+			return DaemonJob{}, fmt.Errorf("input '%s' does not exist", input)
+		}
+	}
+	job := DaemonJob{Name: request.Name, Inputs: request.Inputs, OutputDir: request.OutputDir, Priority: request.Priority, Weld: request.Weld}
+	if job.OutputDir == "" {
+		job.OutputDir = defaultOutputDir(job.Inputs)
+	}
+	if !underRoot(job.OutputDir, roots) {
+		return DaemonJob{}, fmt.Errorf("output directory '%s': %w", job.OutputDir, errOutsideRoots)
+	}
+	if info, err := os.Stat(job.OutputDir); err != nil || !info.IsDir() {
+		return DaemonJob{}, fmt.Errorf("output directory '%s' does not exist", job.OutputDir)
+	}
+	return job, nil
+}
+
+// resolveRoots returns directories as absolute paths with symlinks resolved, for underRoot
+func resolveRoots(directories []string) []string {
+	roots := make([]string, 0, len(directories))
+	for _, directory := range directories {
+		roots = append(roots, resolvePath(directory))
+	}
+	return roots
+}
+
+// resolvePath returns path absolute and cleaned, with symlinks resolved if it exists
+func resolvePath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	if absolute, err := filepath.Abs(path); err == nil {
+		path = absolute
+	}
+	return filepath.Clean(path)
+}
+
+// underRoot reports whether path is one of roots or inside one, after resolving ".." and
+// symlinks, so neither leads out of the roots
+func underRoot(path string, roots []string) bool {
+	path = resolvePath(path)
+	for _, root := range roots {
+		rel, err := filepath.Rel(root, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
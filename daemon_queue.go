@@ -0,0 +1,374 @@
+package dxfparser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// States of jobs and their files
+const (
+	JobQueued   = "queued"
+	JobRunning  = "running"
+	JobDone     = "done"
+	JobFailed   = "failed"
+	JobCanceled = "canceled" // through the API
+)
+
+// Buckets of the daemon queue database
+var (
+	daemonJobsBucket    = []byte("jobs")    // job id: DaemonJob
+	daemonReportsBucket = []byte("reports") // job id + file index: DrawingReport of a processed file
+	daemonSeenBucket    = []byte("seen")    // watched file path: modification time when it was queued
+)
+
+// errJobNotFound is returned for job ids the queue does not know
+var errJobNotFound = errors.New("job not found")
+
+// DaemonFile is one drawing of a daemon job
+type DaemonFile struct {
+	Path       string    `json:"path"`
+	Status     string    `json:"status"` // JobQueued, JobDone or JobFailed
+	DrawingNo  string    `json:"drawing_no,omitempty"`
+	PipeClass  string    `json:"pipe_class,omitempty"`
+	MatRows    int       `json:"mat_rows"`
+	CutRows    int       `json:"cut_rows"`
+	WeldCount  int       `json:"weld_count"`
+	Duration   float64   `json:"duration"`
+	FinishedAt time.Time `json:"finished_at,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Warnings   []string  `json:"warnings,omitempty"`
+}
+
+// DaemonJob is a batch of drawings submitted to the daemon or found by a watched directory.
+// The files are listed when the job first starts; each processed file is checkpointed, so a
+// job interrupted by a restart continues with the next file.
+type DaemonJob struct {
+	ID          int64        `json:"id"`
+	Name        string       `json:"name,omitempty"`
+	Inputs      []string     `json:"inputs"` // directories and files, as for bom
+	OutputDir   string       `json:"output_dir"`
+	Priority    int          `json:"priority"` // higher runs first; equal priorities in submission order
+	Weld        bool         `json:"weld"`
+	Status      string       `json:"status"`
+	SubmittedAt time.Time    `json:"submitted_at"`
+	StartedAt   time.Time    `json:"started_at,omitempty"`
+	FinishedAt  time.Time    `json:"finished_at,omitempty"`
+	Total       int          `json:"total"`     // files of the job
+	Processed   int          `json:"processed"` // files done or failed
+	FailedFiles int          `json:"failed_files"`
+	Outputs     []string     `json:"outputs,omitempty"`
+	Error       string       `json:"error,omitempty"`
+	Files       []DaemonFile `json:"files,omitempty"`
+}
+
+// DaemonQueue is the persistent job queue of the daemon, a bbolt database. Every change is
+// a committed transaction, so the queue and the progress of jobs survive a crash or reboot.
+type DaemonQueue struct {
+	db *bolt.DB
+}
+
+// openDaemonQueue opens or creates the queue database at path. Jobs that were running when
+// the previous process stopped are queued again, keeping the files already processed. A
+// database held by another daemon is reported as an error instead of waiting for it.
+func openDaemonQueue(path string) (*DaemonQueue, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("job queue %s is in use by another daemon", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening job queue %s: %v", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{daemonJobsBucket, daemonReportsBucket, daemonSeenBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return forEachJob(tx, func(job *DaemonJob) (bool, error) {
+			if job.Status != JobRunning {
+				return false, nil
+			}
+			job.Status = JobQueued
+			return true, nil
+		})
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error opening job queue %s: %v", path, err)
+	}
+	return &DaemonQueue{db: db}, nil
+}
+
+// Close closes the database
+func (q *DaemonQueue) Close() error {
+	return q.db.Close()
+}
+
+// jobKey is the key of a job, big-endian so keys sort by id
+func jobKey(id int64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(id))
+	return key
+}
+
+// reportKey is the key of the report of file index of job id; the reports of a job share
+// the job key as prefix
+func reportKey(id int64, index int) []byte {
+	return binary.BigEndian.AppendUint32(jobKey(id), uint32(index))
+}
+
+// getJob reads job id in tx
+func getJob(tx *bolt.Tx, id int64) (*DaemonJob, error) {
+	data := tx.Bucket(daemonJobsBucket).Get(jobKey(id))
+	if data == nil {
+		return nil, errJobNotFound
+	}
+	job := &DaemonJob{}
+	if err := json.Unmarshal(data, job); err != nil {
+		return nil, fmt.Errorf("job %d: %v", id, err)
+	}
+	return job, nil
+}
+
+// putJob writes job in tx
+func putJob(tx *bolt.Tx, job *DaemonJob) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(daemonJobsBucket).Put(jobKey(job.ID), data)
+}
+
+// forEachJob calls fn with every job in id order and writes back the jobs fn changed
+func forEachJob(tx *bolt.Tx, fn func(job *DaemonJob) (changed bool, err error)) error {
+	var changed []*DaemonJob
+	err := tx.Bucket(daemonJobsBucket).ForEach(func(_, data []byte) error {
+		job := &DaemonJob{}
+		if err := json.Unmarshal(data, job); err != nil {
+			return err
+		}
+		ok, err := fn(job)
+		if ok {
+			changed = append(changed, job)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	for _, job := range changed {
+		if err := putJob(tx, job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Submit queues job with the next id and returns it as stored
+func (q *DaemonQueue) Submit(job DaemonJob) (DaemonJob, error) {
+	err := q.db.Update(func(tx *bolt.Tx) error {
+		return submitJob(tx, &job)
+	})
+	return job, err
+}
+
+// submitJob assigns job an id and stores it queued
+func submitJob(tx *bolt.Tx, job *DaemonJob) error {
+	id, err := tx.Bucket(daemonJobsBucket).NextSequence()
+	if err != nil {
+		return err
+	}
+	job.ID = int64(id)
+	job.Status = JobQueued
+	job.SubmittedAt = time.Now()
+	job.Files = nil
+	return putJob(tx, job)
+}
+
+// SubmitWatched queues a job for the files found in a watched directory and records their
+// modification times in the same transaction, so every version of a file is queued once
+// even across restarts. files maps paths to modification times; paths queued before with the
+// same time are left out, and no job is queued if none is left. ok reports whether one was.
+func (q *DaemonQueue) SubmitWatched(job DaemonJob, files map[string]time.Time) (DaemonJob, bool, error) {
+	ok := false
+	err := q.db.Update(func(tx *bolt.Tx) error {
+		seen := tx.Bucket(daemonSeenBucket)
+		job.Inputs = nil
+		for path, modTime := range files {
+			stamp := modTime.UTC().Format(time.RFC3339Nano)
+			if string(seen.Get([]byte(path))) == stamp {
+				continue
+			}
+			if err := seen.Put([]byte(path), []byte(stamp)); err != nil {
+				return err
+			}
+			job.Inputs = append(job.Inputs, path)
+		}
+		if len(job.Inputs) == 0 {
+			return nil
+		}
+		sort.Strings(job.Inputs)
+		ok = true
+		return submitJob(tx, &job)
+	})
+	return job, ok, err
+}
+
+// Next marks the queued job with the highest priority (the oldest of equal ones) as running
+// and returns it
+func (q *DaemonQueue) Next() (DaemonJob, bool, error) {
+	var next *DaemonJob
+	err := q.db.Update(func(tx *bolt.Tx) error {
+		err := forEachJob(tx, func(job *DaemonJob) (bool, error) {
+			if job.Status == JobQueued && (next == nil || job.Priority > next.Priority) {
+				next = job
+			}
+			return false, nil
+		})
+		if err != nil || next == nil {
+			return err
+		}
+		next.Status = JobRunning
+		if next.StartedAt.IsZero() {
+			next.StartedAt = time.Now()
+		}
+		return putJob(tx, next)
+	})
+	if err != nil || next == nil {
+		return DaemonJob{}, false, err
+	}
+	return *next, true, nil
+}
+
+// Get returns job id
+func (q *DaemonQueue) Get(id int64) (DaemonJob, error) {
+	var job *DaemonJob
+	err := q.db.View(func(tx *bolt.Tx) error {
+		var err error
+		job, err = getJob(tx, id)
+		return err
+	})
+	if err != nil {
+		return DaemonJob{}, err
+	}
+	return *job, nil
+}
+
+// List returns all jobs in id order without their file lists
+func (q *DaemonQueue) List() ([]DaemonJob, error) {
+	return q.jobs(false)
+}
+
+// jobs returns all jobs in id order, with their file lists if files is set
+func (q *DaemonQueue) jobs(files bool) ([]DaemonJob, error) {
+	jobs := []DaemonJob{}
+	err := q.db.View(func(tx *bolt.Tx) error {
+		return forEachJob(tx, func(job *DaemonJob) (bool, error) {
+			if !files {
+				job.Files = nil
+			}
+			jobs = append(jobs, *job)
+			return false, nil
+		})
+	})
+	return jobs, err
+}
+
+// Update changes job id with fn in one transaction and returns the job as stored
+func (q *DaemonQueue) Update(id int64, fn func(job *DaemonJob) error) (DaemonJob, error) {
+	var job *DaemonJob
+	err := q.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		if job, err = getJob(tx, id); err != nil {
+			return err
+		}
+		if err := fn(job); err != nil {
+			return err
+		}
+		return putJob(tx, job)
+	})
+	if err != nil {
+		return DaemonJob{}, err
+	}
+	return *job, nil
+}
+
+// Checkpoint stores the outcome of file index of job id together with its report (nil for a
+// failed file)
+func (q *DaemonQueue) Checkpoint(id int64, index int, file DaemonFile, report *DrawingReport) error {
+	return q.db.Update(func(tx *bolt.Tx) error {
+		job, err := getJob(tx, id)
+		if err != nil {
+			return err
+		}
+		if index >= len(job.Files) {
+			return fmt.Errorf("job %d has no file %d", id, index)
+		}
+		job.Files[index] = file
+		job.Processed++
+		if file.Status == JobFailed {
+			job.FailedFiles++
+		}
+		if report != nil {
+			data, err := json.Marshal(report)
+			if err != nil {
+				return err
+			}
+			if err := tx.Bucket(daemonReportsBucket).Put(reportKey(id, index), data); err != nil {
+				return err
+			}
+		}
+		return putJob(tx, job)
+	})
+}
+
+// Reports returns the reports of the processed files of job id by file index
+func (q *DaemonQueue) Reports(id int64) (map[int]*DrawingReport, error) {
+	reports := make(map[int]*DrawingReport)
+	prefix := jobKey(id)
+	err := q.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(daemonReportsBucket).Cursor()
+		for key, data := cursor.Seek(prefix); bytes.HasPrefix(key, prefix); key, data = cursor.Next() {
+			report := &DrawingReport{}
+			if err := json.Unmarshal(data, report); err != nil {
+				return err
+			}
+			reports[int(binary.BigEndian.Uint32(key[len(prefix):]))] = report
+		}
+		return nil
+	})
+	return reports, err
+}
+
+// Finish stores the final state of job id and drops the reports of its files, which are
+// in the outputs by then
+func (q *DaemonQueue) Finish(id int64, fn func(job *DaemonJob)) (DaemonJob, error) {
+	var job *DaemonJob
+	prefix := jobKey(id)
+	err := q.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		if job, err = getJob(tx, id); err != nil {
+			return err
+		}
+		fn(job)
+		job.FinishedAt = time.Now()
+		cursor := tx.Bucket(daemonReportsBucket).Cursor()
+		for key, _ := cursor.Seek(prefix); bytes.HasPrefix(key, prefix); key, _ = cursor.Seek(prefix) {
+			if err := cursor.Delete(); err != nil {
+				return err
+			}
+		}
+		return putJob(tx, job)
+	})
+	if err != nil {
+		return DaemonJob{}, err
+	}
+	return *job, nil
+}
@@ -0,0 +1,83 @@
+package dxfparser
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunDaemonJobCancel(t *testing.T) {
+	cases := []struct {
+		name      string
+		cancelAt  int // index of the file during which the job is canceled, -1 for none
+		status    string
+		processed int
+		outputs   bool
+	}{
+		{"not canceled", -1, JobDone, 2, true},
+		{"canceled during the first file", 0, JobCanceled, 1, false},
+		{"canceled during the last file", 1, JobCanceled, 2, false},
+	}
+	for _, c := range cases {
+		inputs, outputDir := t.TempDir(), t.TempDir()
+		for _, name := range []string{"a.dxf", "b.dxf"} {
+			if err := os.WriteFile(filepath.Join(inputs, name), []byte("0\nEOF\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		queue, err := openDaemonQueue(filepath.Join(t.TempDir(), "jobs.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer queue.Close()
+
+		if _, err := queue.Submit(DaemonJob{Inputs: []string{inputs}, OutputDir: outputDir}); err != nil {
+			t.Fatal(err)
+		}
+		job, ok, err := queue.Next()
+		if err != nil || !ok {
+			t.Fatalf("%s: Next() = %v, %v", c.name, ok, err)
+		}
+
+		processed := 0
+		process := func(ctx context.Context, path string, opts DrawingOptions) (*DrawingReport, error) {
+			if processed == c.cancelAt {
+				// As the job API does
+				if _, err := queue.Update(job.ID, func(j *DaemonJob) error {
+					j.Status = JobCanceled
+					return nil
+				}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			processed++
+			return &DrawingReport{Path: path, DrawingNo: "1QFB10BR001"}, nil
+		}
+		if err := runDaemonJob(context.Background(), queue, job, process); err != nil {
+			t.Errorf("%s: runDaemonJob: %v", c.name, err)
+			continue
+		}
+
+		job, err = queue.Get(job.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Status != c.status || job.Processed != c.processed {
+			t.Errorf("%s: job %s with %d files processed, want %s with %d", c.name, job.Status, job.Processed, c.status, c.processed)
+		}
+		if job.FinishedAt.IsZero() {
+			t.Errorf("%s: job not finished", c.name)
+		}
+		written, err := os.ReadDir(outputDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := len(job.Outputs) > 0 || len(written) > 0; got != c.outputs {
+			t.Errorf("%s: outputs %v, files %d in the output directory, want outputs %v", c.name, job.Outputs, len(written), c.outputs)
+		}
+		if reports, err := queue.Reports(job.ID); err != nil || len(reports) != 0 {
+			t.Errorf("%s: %d reports kept after the job finished (%v)", c.name, len(reports), err)
+		}
+	}
+}
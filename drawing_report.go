@@ -0,0 +1,126 @@
+package dxfparser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DrawingOptions selects the optional parts of a DrawingReport
+type DrawingOptions struct {
+	Welds       bool      // detect weld symbols
+	WeldLabels  bool      // attach the nearest text label to every weld symbol (needs Welds)
+	WeldExplain bool      // explain why every weld symbol was accepted (needs Welds)
+	Provenance  bool      // map every table cell to its source text entity
+	Patterns    *Patterns // nil: the patterns of the project config
+}
+
+// DrawingTable is one extracted table with the Drawing-No. / Pipe Class columns of the CSV outputs
+type DrawingTable struct {
+	Header     []string         `json:"header"`
+	Rows       [][]string       `json:"rows"`
+	Provenance []RowProvenance  `json:"provenance,omitempty"`
+	Confidence *TableConfidence `json:"confidence,omitempty"` // nil for a missing table
+	Source     string           `json:"source,omitempty"`     // "ebom:<dictionary>" or "acad_table:<handle>" if not reconstructed from text
+}
+
+// DrawingWelds is the weld detection result of one drawing
+type DrawingWelds struct {
+	Count             int          `json:"count"`
+	DuplicateSegments int          `json:"duplicate_segments"`
+	Symbols           []WeldSymbol `json:"symbols"`
+	BySize            []SizeCount  `json:"by_size,omitempty"` // welds per N.S. of the nearest cut piece
+}
+
+// DrawingTimings are the durations of the processing stages in seconds
+type DrawingTimings struct {
+	Extraction float64 `json:"extraction"` // parsing, tables and metadata
+	Welds      float64 `json:"welds"`
+	Total      float64 `json:"total"`
+}
+
+// DrawingReport is everything extracted from one drawing
+type DrawingReport struct {
+	Path       string         `json:"path"`
+	DrawingNo  string         `json:"drawing_no"`
+	PipeClass  string         `json:"pipe_class"`
+	Revision   string         `json:"revision"`
+	Materials  DrawingTable   `json:"materials"`
+	CutLengths DrawingTable   `json:"cut_lengths"`
+	Welds      *DrawingWelds  `json:"welds,omitempty"` // nil unless DrawingOptions.Welds
+	Warnings   []string       `json:"warnings,omitempty"`
+	PieceCheck string         `json:"piece_check,omitempty"` // piece number continuity of the cut lengths
+	Timings    DrawingTimings `json:"timings"`
+}
+
+// ProcessDrawing extracts metadata, tables and optionally weld symbols of one DXF file.
+// An error is returned if the file cannot be read or parsed; problems that only affect
+// part of the result (truncated tables, failed weld detection) are reported as warnings.
+func ProcessDrawing(path string, opts DrawingOptions) (*DrawingReport, error) {
+	return ProcessDrawingContext(context.Background(), path, opts)
+}
+
+// ProcessDrawingContext is ProcessDrawing stopping with ctx.Err() when ctx is done
+func ProcessDrawingContext(ctx context.Context, path string, opts DrawingOptions) (*DrawingReport, error) {
+	start := time.Now()
+
+	run := defaultRunConfig()
+	run.patterns = opts.Patterns
+	result, cache := processDXFFileWithCaching(ctx, path, opts.Welds || opts.Provenance, run)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if result.Error != "" {
+		return nil, errors.New(result.Error)
+	}
+
+	report := &DrawingReport{
+		Path:       path,
+		DrawingNo:  result.DrawingNo,
+		PipeClass:  result.PipeClass,
+		Revision:   result.Revision,
+		Materials:  DrawingTable{Header: result.MatHeader, Rows: result.MatRows, Provenance: result.MatProvenance, Confidence: result.MatConfidence, Source: result.MatSource},
+		CutLengths: DrawingTable{Header: result.CutHeader, Rows: result.CutRows, Provenance: result.CutProvenance, Confidence: result.CutConfidence, Source: result.CutSource},
+		Warnings:   result.Warnings,
+		PieceCheck: result.PieceCheck,
+	}
+
+	if opts.Provenance && cache != nil {
+		report.Materials.Provenance = buildProvenance("ERECTION MATERIALS", result.MatHeader, result.MatRows, cache.TextEntities, path, result.DrawingNo)
+		report.CutLengths.Provenance = buildProvenance("CUT PIPE LENGTH", result.CutHeader, result.CutRows, cache.TextEntities, path, result.DrawingNo)
+	}
+	report.Timings.Extraction = time.Since(start).Seconds()
+
+	if opts.Welds && cache != nil {
+		weldStart := time.Now()
+		report.Welds = &DrawingWelds{}
+		if cache.SegmentError != "" {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("weld detection failed: %s", cache.SegmentError))
+		} else {
+			var labels []TextEntity
+			if opts.WeldLabels {
+				labels = cache.TextEntities
+			}
+			config := weldConfig
+			config.Explain = opts.WeldExplain
+			detection := ExtractWeldSymbolsDetailed(labels, cache.Segments, config)
+			report.Welds.Count = len(detection.Symbols)
+			report.Welds.DuplicateSegments = detection.DuplicateSegments
+			report.Welds.Symbols = detection.Symbols
+			report.Welds.BySize = attributeWeldSizes(detection.Symbols, cache.SizeCallouts, cache.PipeSizes)
+		}
+		report.Timings.Welds = time.Since(weldStart).Seconds()
+	}
+
+	report.Timings.Total = time.Since(start).Seconds()
+	return report, nil
+}
+
+// WeldCount returns the number of detected weld symbols, 0 if welds were not detected
+func (r *DrawingReport) WeldCount() int {
+	if r.Welds == nil {
+		return 0
+	}
+	return r.Welds.Count
+}
@@ -0,0 +1,180 @@
+package dxfparser
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// DWGConverter turns a DWG file into a DXF file in outDir and returns the DXF path
+type DWGConverter interface {
+	Convert(ctx context.Context, dwgPath, outDir string) (string, error)
+}
+
+// odaConverterTemplate runs the ODA File Converter on the directory of the input, restricted
+// to the input file by its filter argument (output version ACAD2018, no recursion, audit on)
+const odaConverterTemplate = `ODAFileConverter {indir} {outdir} ACAD2018 DXF 0 1 {name}`
+
+// commandConverter runs a command template for every file. Placeholders: {input} (DWG path),
+// {indir} (its directory), {name} (its file name), {outdir} (directory for the DXF) and
+// {output} (the expected DXF path, {outdir}/<name>.dxf).
+type commandConverter struct {
+	args []string
+}
+
+// newDWGConverter creates the converter of a -dwg-converter value: "oda" for the ODA File
+// Converter on the PATH, or a command template. The template is split into arguments like a
+// shell command line (double or single quotes group words) but run without a shell.
+func newDWGConverter(spec string) (DWGConverter, error) {
+	if spec == "oda" {
+		spec = odaConverterTemplate
+	}
+	args, err := splitCommandLine(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DWG converter command: %v", err)
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("invalid DWG converter command: empty")
+	}
+	hasInput := false
+	for _, arg := range args {
+		if strings.Contains(arg, "{input}") || strings.Contains(arg, "{name}") {
+			hasInput = true
+		}
+	}
+	if !hasInput {
+		return nil, fmt.Errorf("invalid DWG converter command '%s': needs {input} or {name}", spec)
+	}
+	return &commandConverter{args: args}, nil
+}
+
+func (c *commandConverter) Convert(ctx context.Context, dwgPath, outDir string) (string, error) {
+	name := filepath.Base(dwgPath)
+	output := filepath.Join(outDir, strings.TrimSuffix(name, filepath.Ext(name))+".dxf")
+	replacer := strings.NewReplacer(
+		"{input}", dwgPath,
+		"{indir}", filepath.Dir(dwgPath),
+		"{name}", name,
+		"{outdir}", outDir,
+		"{output}", output,
+	)
+	args := make([]string, len(c.args))
+	for i, arg := range c.args {
+		args[i] = replacer.Replace(arg)
+	}
+
+	debugPrint(fmt.Sprintf("[DEBUG] Converting DWG: %s", strings.Join(args, " ")))
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if len(out) > 0 {
+			return "", fmt.Errorf("%s: %v: %s", args[0], err, strings.TrimSpace(string(out)))
+		}
+		return "", fmt.Errorf("%s: %v", args[0], err)
+	}
+	if _, err := os.Stat(output); err != nil {
+		return "", fmt.Errorf("%s did not write %s", args[0], output)
+	}
+	return output, nil
+}
+
+// splitCommandLine splits a command template into arguments at unquoted white space
+func splitCommandLine(line string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	var quote rune
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// dwgConversion numbers the directories of the DWG conversions below the run's dwgTempDir
+var dwgConversion atomic.Int64
+
+// isDWG reports whether path has the .dwg extension
+func isDWG(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".dwg")
+}
+
+// processInputFile processes a DXF file, or a DWG file after converting it into its own
+// directory below the run's dwgTempDir. Results of a DWG file name the DWG file, not the temporary DXF.
+func processInputFile(ctx context.Context, path string, weldFlag bool, run *runConfig) (DXFResult, *FileCache) {
+	if !isDWG(path) {
+		return processDXFFileWithCaching(ctx, path, weldFlag, run)
+	}
+
+	failed := func(err error) (DXFResult, *FileCache) {
+		result := DXFResult{Filename: path, FilePath: path, Error: fmt.Sprintf("DWG conversion failed: %v", err)}
+		if weldFlag {
+			return result, &FileCache{SegmentError: result.Error}
+		}
+		return result, nil
+	}
+	if run.dwg == nil {
+		return failed(fmt.Errorf("no converter configured (-dwg-converter)"))
+	}
+
+	outDir := filepath.Join(run.dwgTempDir, fmt.Sprint(dwgConversion.Add(1)))
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return failed(err)
+	}
+	defer os.RemoveAll(outDir)
+
+	// The converter needs a file; DWG entries of ZIP archives are extracted next to its output
+	dwgPath := path
+	if _, entry, ok := splitZipPath(path); ok {
+		data, err := readInput(path)
+		if err != nil {
+			return failed(err)
+		}
+		dwgPath = filepath.Join(outDir, filepath.Base(entry))
+		if err := os.WriteFile(dwgPath, data, 0644); err != nil {
+			return failed(err)
+		}
+	}
+
+	dxfPath, err := run.dwg.Convert(ctx, dwgPath, outDir)
+	if err != nil {
+		return failed(err)
+	}
+
+	result, cache := processDXFFileWithCaching(ctx, dxfPath, weldFlag, run)
+	result.Filename, result.FilePath = path, path
+	for _, rows := range [][]RowProvenance{result.MatProvenance, result.CutProvenance} {
+		for i := range rows {
+			rows[i].FilePath = path
+		}
+	}
+	return result, cache
+}
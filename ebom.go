@@ -0,0 +1,193 @@
+package dxfparser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// tableSourceEBOM prefixes the dictionary name in the table source of tables read from an eBOM
+const tableSourceEBOM = "ebom"
+
+// ebomDictionaries are the named object dictionaries holding structured BOM data (ebom.dictionaries
+// of the project config); empty disables the eBOM lookup
+var ebomDictionaries []string
+
+// Output columns of the eBOM tables, in the order of the reconstructed text tables. Record keys
+// are matched to them like table headers in scoreTable, e.g. "COMPONENT DESCRIPTION" to DESCRIPTION.
+var (
+	ebomMaterialColumns = []string{"PT NO", "DESCRIPTION", "N.S.", "QTY", "WEIGHT", "CATEGORY"}
+	ebomMaterialHeader  = []string{"PT NO", "COMPONENT DESCRIPTION (MM)", "N.S.", "QTY", "WEIGHT", "CATEGORY"}
+	ebomCutColumns      = []string{"PIECE NO", "CUT LENGTH", "N.S.", "REMARKS"}
+	ebomCutHeader       = []string{"PIECE NO", "CUT LENGTH", "N.S. (MM)", "REMARKS"}
+)
+
+// ebomObject is a DICTIONARY or XRECORD of the OBJECTS section
+type ebomObject struct {
+	Type    string
+	Entries []ebomEntry  // DICTIONARY entries in file order
+	Data    []GroupValue // XRECORD data groups
+}
+
+// ebomEntry is a named entry of a DICTIONARY and the handle of the object it owns
+type ebomEntry struct {
+	Name   string
+	Handle string
+}
+
+// EBOMTables is the BOM data found in the dictionaries of a drawing. A table without rows
+// was not found and falls back to the reconstruction from text.
+type EBOMTables struct {
+	Dictionary string // name of the dictionary the tables were read from
+	Materials  TableExtraction
+	CutLengths TableExtraction
+}
+
+// ebomCollector gathers the dictionaries and XRECORDs of the OBJECTS section during a parse
+type ebomCollector struct {
+	objects map[string]*ebomObject
+	root    *ebomObject // the named object dictionary, first in OBJECTS
+}
+
+// newEBOMCollector registers the collector with parser
+func newEBOMCollector(parser *DXFParser) *ebomCollector {
+	c := &ebomCollector{objects: make(map[string]*ebomObject)}
+	collect := func(entity Entity) error {
+		if entity.Section != "OBJECTS" {
+			return nil
+		}
+		object := parseEBOMObject(entity)
+		if c.root == nil && object.Type == "DICTIONARY" {
+			c.root = object
+		}
+		if handle, ok := entity.Value(5); ok {
+			c.objects[strings.ToUpper(handle)] = object
+		}
+		return nil
+	}
+	for _, recordType := range []string{"DICTIONARY", "ACDBDICTIONARYWDFLT", "XRECORD"} {
+		parser.OnEntity(recordType, collect)
+	}
+	return c
+}
+
+// tables returns the BOM tables stored in the first of dictionaries found in the named object
+// dictionary. The dictionary holds one sub-dictionary per table, named after the table title
+// ("ERECTION_MATERIALS", "CUT_PIPE_LENGTH" or a project alias), with one XRECORD per row. A
+// row's data are key / value pairs: a 1 group naming the column, followed by the group holding
+// its value. Returns nil if none of the dictionaries exists.
+func (c *ebomCollector) tables(dictionaries []string) *EBOMTables {
+	if c.root == nil {
+		return nil
+	}
+	for _, name := range dictionaries {
+		for _, entry := range c.root.Entries {
+			if !strings.EqualFold(entry.Name, name) {
+				continue
+			}
+			dictionary := c.objects[entry.Handle]
+			if dictionary == nil || dictionary.Type != "DICTIONARY" {
+				continue
+			}
+			return &EBOMTables{
+				Dictionary: entry.Name,
+				Materials:  ebomTable(dictionary, c.objects, "ERECTION MATERIALS", ebomMaterialColumns, ebomMaterialHeader),
+				CutLengths: ebomTable(dictionary, c.objects, "CUT PIPE LENGTH", ebomCutColumns, ebomCutHeader),
+			}
+		}
+	}
+	return nil
+}
+
+// parseEBOMObject keeps the entries of a DICTIONARY or the data groups of an XRECORD
+func parseEBOMObject(entity Entity) *ebomObject {
+	object := &ebomObject{Type: entity.Type}
+	if entity.Type == "ACDBDICTIONARYWDFLT" {
+		object.Type = "DICTIONARY"
+	}
+
+	if object.Type == "DICTIONARY" {
+		name := ""
+		for _, g := range entity.Groups {
+			switch g.Code {
+			case 3:
+				name = g.Value
+			case 350, 360:
+				if name != "" {
+					object.Entries = append(object.Entries, ebomEntry{Name: name, Handle: strings.ToUpper(g.Value)})
+					name = ""
+				}
+			}
+		}
+		return object
+	}
+
+	// XRECORD data follow the subclass marker and the cloning flag (280)
+	inData := false
+	for i, g := range entity.Groups {
+		switch {
+		case g.Code == 100:
+			inData = true
+		case !inData && g.Code == 5:
+		case inData && g.Code == 280 && i > 0 && entity.Groups[i-1].Code == 100:
+		default:
+			object.Data = append(object.Data, g)
+		}
+	}
+	return object
+}
+
+// ebomTable builds the table of the sub-dictionary named after title; columns are the output
+// columns the record keys are matched to, header their names
+func ebomTable(dictionary *ebomObject, objects map[string]*ebomObject, title string, columns, header []string) TableExtraction {
+	table := TableExtraction{Header: header}
+	for _, entry := range dictionary.Entries {
+		if !matchesTableTitle(strings.ReplaceAll(entry.Name, "_", " "), title) {
+			continue
+		}
+		rows := objects[entry.Handle]
+		if rows == nil || rows.Type != "DICTIONARY" {
+			continue
+		}
+		unknown := make(map[string]bool)
+		for _, rowEntry := range rows.Entries {
+			record := objects[rowEntry.Handle]
+			if record == nil || record.Type != "XRECORD" {
+				continue
+			}
+			row := make([]string, len(columns))
+			data := record.Data
+			for i := 0; i+1 < len(data); i += 2 {
+				key, value := data[i].Value, strings.TrimSpace(decodeText(data[i+1].Value))
+				if column := ebomColumn(key, columns); column >= 0 {
+					row[column] = value
+				} else if !unknown[key] {
+					unknown[key] = true
+					table.Warnings = append(table.Warnings, fmt.Sprintf("eBOM %s: unknown column '%s'", title, key))
+				}
+			}
+			markPieceNumber(title, row)
+			table.Rows = append(table.Rows, row)
+		}
+		break
+	}
+	return table
+}
+
+// markPieceNumber writes a numeric piece number of a CUT PIPE LENGTH row as printed on the
+// drawing, "<1>"
+func markPieceNumber(title string, row []string) {
+	if title == "CUT PIPE LENGTH" && isNumber(row[0]) {
+		row[0] = "<" + row[0] + ">"
+	}
+}
+
+// ebomColumn returns the index of the output column a record key names, or -1
+func ebomColumn(key string, columns []string) int {
+	key = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(key), "_", " "))
+	for i, column := range columns {
+		if strings.Contains(key, column) || strings.ReplaceAll(key, ".", "") == strings.ReplaceAll(column, ".", "") {
+			return i
+		}
+	}
+	return -1
+}
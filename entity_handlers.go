@@ -0,0 +1,313 @@
+package dxfparser
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// GroupValue is one group code / value pair of an entity
+type GroupValue struct {
+	Code  int    `json:"code"`
+	Value string `json:"value"`
+}
+
+// Entity is one DXF record (group code 0 to the next code 0) as raw group values, without
+// application groups and reactor handles; only text values (1, 3, 1000) are decoded to UTF-8.
+// Section is the section it was found in.
+type Entity struct {
+	Type    string
+	Section string
+	Groups  []GroupValue
+}
+
+// Value returns the first value of a group code
+func (e Entity) Value(code int) (string, bool) {
+	for _, g := range e.Groups {
+		if g.Code == code {
+			return g.Value, true
+		}
+	}
+	return "", false
+}
+
+// Float returns the first value of a group code as a number
+func (e Entity) Float(code int) (float64, bool) {
+	value, ok := e.Value(code)
+	if !ok {
+		return 0, false
+	}
+	return parseGroupFloat(strconv.Itoa(code), value)
+}
+
+// Layer returns the layer (group 8) of the entity
+func (e Entity) Layer() string {
+	layer, _ := e.Value(8)
+	return layer
+}
+
+// Polyline is a POLYLINE with its VERTEX records or an LWPOLYLINE
+type Polyline struct {
+	Type   string // "POLYLINE" or "LWPOLYLINE"
+	Layer  string
+	Closed bool // flag 70 bit 1
+	Points [][2]float64
+}
+
+// Insert is a block reference
+type Insert struct {
+	Block          string
+	Layer          string
+	X, Y           float64
+	ScaleX, ScaleY float64
+	Rotation       float64 // degrees
+	XData          XData   // extended data, e.g. of a Plant 3D component
+}
+
+// entityHooks are the handlers registered on a DXFParser
+type entityHooks struct {
+	byType    map[string][]func(Entity) error
+	text      []func(TextEntity) error
+	polylines []func(Polyline) error
+	inserts   []func(Insert) error
+}
+
+// OnEntity registers fn for every record of an entity type, e.g. "CIRCLE"; "*" matches all types
+func (p *DXFParser) OnEntity(entityType string, fn func(Entity) error) {
+	if p.hooks.byType == nil {
+		p.hooks.byType = make(map[string][]func(Entity) error)
+	}
+	p.hooks.byType[entityType] = append(p.hooks.byType[entityType], fn)
+}
+
+// OnText registers fn for every TEXT, MTEXT, ATTRIB, ATTDEF, DIMENSION, MULTILEADER,
+// ARCALIGNEDTEXT and TOLERANCE entity with content, decoded like ParseFile does
+func (p *DXFParser) OnText(fn func(TextEntity) error) {
+	p.hooks.text = append(p.hooks.text, fn)
+}
+
+// OnPolyline registers fn for every POLYLINE (after its SEQEND) and LWPOLYLINE
+func (p *DXFParser) OnPolyline(fn func(Polyline) error) {
+	p.hooks.polylines = append(p.hooks.polylines, fn)
+}
+
+// OnInsert registers fn for every INSERT (block reference)
+func (p *DXFParser) OnInsert(fn func(Insert) error) {
+	p.hooks.inserts = append(p.hooks.inserts, fn)
+}
+
+// ParseEntities reads r in one pass and calls the registered handlers for every entity in file
+// order. Parsing stops at the first handler error, which is returned unchanged.
+func (p *DXFParser) ParseEntities(r io.Reader) error {
+	encoding, err := newEncodingState(p.encoding)
+	if err != nil {
+		return err
+	}
+	var polyline *Polyline // open POLYLINE collecting VERTEX records
+
+	dispatch := func(entity Entity) error {
+		for _, key := range []string{entity.Type, "*"} {
+			for _, fn := range p.hooks.byType[key] {
+				if err := fn(entity); err != nil {
+					return err
+				}
+			}
+		}
+
+		switch entity.Type {
+		case "TEXT", "MTEXT", "ATTRIB", "ATTDEF", "DIMENSION", "MULTILEADER", "ARCALIGNEDTEXT", "TOLERANCE":
+			if len(p.hooks.text) == 0 {
+				return nil
+			}
+			text := TextEntity{EntityType: entity.Type, Color: colorByLayer}
+			embedded := false // in the embedded MTEXT of a multiline attribute, repeating its text
+			for _, g := range entity.Groups {
+				embedded = embedded || g.Code == 101
+				if embedded && g.Code < 1000 {
+					continue
+				}
+				text.setGroup(strconv.Itoa(g.Code), g.Value)
+			}
+			if !p.xdata {
+				text.XData = nil
+			}
+			if !text.finish() {
+				return nil
+			}
+			return p.plainText(func(text TextEntity) error {
+				for _, fn := range p.hooks.text {
+					if err := fn(text); err != nil {
+						return err
+					}
+				}
+				return nil
+			})(text)
+		case "POLYLINE":
+			polyline = &Polyline{Type: entity.Type, Layer: entity.Layer(), Closed: polylineClosed(entity)}
+		case "VERTEX":
+			if polyline != nil {
+				x, _ := entity.Float(10)
+				y, _ := entity.Float(20)
+				polyline.Points = append(polyline.Points, [2]float64{x, y})
+			}
+		case "SEQEND":
+			if polyline != nil {
+				done := *polyline
+				polyline = nil
+				return p.emitPolyline(done)
+			}
+		case "LWPOLYLINE":
+			lw := Polyline{Type: entity.Type, Layer: entity.Layer(), Closed: polylineClosed(entity)}
+			var x float64
+			for _, g := range entity.Groups {
+				switch g.Code {
+				case 10:
+					x, _ = parseGroupFloat("10", g.Value)
+				case 20:
+					y, _ := parseGroupFloat("20", g.Value)
+					lw.Points = append(lw.Points, [2]float64{x, y})
+				}
+			}
+			return p.emitPolyline(lw)
+		case "INSERT":
+			if len(p.hooks.inserts) == 0 {
+				return nil
+			}
+			insert := Insert{Layer: entity.Layer(), ScaleX: 1, ScaleY: 1}
+			insert.Block, _ = entity.Value(2)
+			insert.X, _ = entity.Float(10)
+			insert.Y, _ = entity.Float(20)
+			if v, ok := entity.Float(41); ok {
+				insert.ScaleX = v
+			}
+			if v, ok := entity.Float(42); ok {
+				insert.ScaleY = v
+			}
+			insert.Rotation, _ = entity.Float(50)
+			insert.XData = entity.XData()
+			for _, fn := range p.hooks.inserts {
+				if err := fn(insert); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	truncated, err := scanEntities(r, p.scanBuffer, encoding, dispatch)
+	p.setScanWarnings(truncated)
+	return err
+}
+
+// emitPolyline calls the polyline handlers
+func (p *DXFParser) emitPolyline(polyline Polyline) error {
+	for _, fn := range p.hooks.polylines {
+		if err := fn(polyline); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// polylineClosed reports whether the closed bit of the polyline flags (70) is set
+func polylineClosed(entity Entity) bool {
+	flags, ok := entity.Value(70)
+	if !ok {
+		return false
+	}
+	n, err := strconv.Atoi(flags)
+	return err == nil && n&1 == 1
+}
+
+// scanEntities reads DXF group code / value pairs from r and calls emit for every record.
+// SECTION, ENDSEC and EOF markers are not records; a SECTION's name sets Entity.Section.
+// maxLine is the longest line kept, in bytes; the number of longer lines, which are cut, is
+// returned. Text values (1, 3, 1000) are decoded with encoding, which follows the HEADER.
+// Proxy records are emitted without the payload from their first binary chunk, see proxySkipper.
+func scanEntities(r io.Reader, maxLine int, encoding encodingState, emit func(Entity) error) (int, error) {
+	scanner := newLineScanner(r, maxLine)
+
+	var current *Entity
+	section := ""
+	inSectionHeader := false
+	var groups appGroupFilter
+	var proxies proxySkipper
+	lineNo := 0
+
+	flush := func() error {
+		if current == nil {
+			return nil
+		}
+		entity := *current
+		current = nil
+		return emit(entity)
+	}
+
+	for scanner.Scan() {
+		lineNo++
+		codeLine := strings.TrimSpace(scanner.Text())
+		var value string
+		if proxies.active {
+			if proxies.skip(codeLine) {
+				continue
+			}
+			// The record after the proxy: the line is the value of a code 0
+			codeLine, value = "0", codeLine
+		} else if proxies.payload(codeLine) {
+			continue
+		} else {
+			if !scanner.Scan() {
+				break
+			}
+			lineNo++
+			value = strings.TrimSpace(scanner.Text())
+			value = keepBlankText(codeLine, value, scanner.Text() != "")
+		}
+
+		code, err := strconv.Atoi(codeLine)
+		if err != nil {
+			return scanner.Truncated(), fmt.Errorf("invalid group code %q at line %d", codeLine, lineNo-1)
+		}
+		if groups.skip(codeLine, value) {
+			continue
+		}
+		if section == "HEADER" {
+			encoding.observe(codeLine, value)
+		}
+
+		if code == 0 {
+			if err := flush(); err != nil {
+				return scanner.Truncated(), err
+			}
+			switch value {
+			case "SECTION":
+				inSectionHeader = true
+			case "ENDSEC":
+				section = ""
+			case "EOF":
+			default:
+				current = &Entity{Type: value, Section: section}
+				proxies.enter(value)
+			}
+			continue
+		}
+		if inSectionHeader {
+			if code == 2 {
+				section = value
+			}
+			inSectionHeader = false
+			continue
+		}
+		if current != nil {
+			if code == 1 || code == 3 || code == 1000 {
+				value = encoding.decode(value)
+			}
+			current.Groups = append(current.Groups, GroupValue{Code: code, Value: value})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return scanner.Truncated(), err
+	}
+	return scanner.Truncated(), flush()
+}
@@ -0,0 +1,230 @@
+package dxfparser
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// EntityIndex is the byte offset of every record (group code 0) of a DXF file, found by one
+// scan of its lines. With it a parse splits the file into concurrent chunks at records whose
+// section is known, and ParseRecords reads a part of the file, e.g. one section, without
+// scanning the content before it again.
+type EntityIndex struct {
+	Size    int64          // bytes of the content indexed
+	Records []RecordOffset // in file order
+}
+
+// RecordOffset is the position of a record in an EntityIndex
+type RecordOffset struct {
+	Offset  int64  // byte offset of the group code 0 line of the record
+	Line    int    // 1-based number of that line
+	Type    string // record name, e.g. TEXT or SECTION
+	Section string // section the record is in, upper case; "" outside sections
+}
+
+// Section returns the records of a section, e.g. ENTITIES, as the range first to last of
+// ParseRecords; first == last if the file has no such section
+func (x *EntityIndex) Section(name string) (first, last int) {
+	name = strings.ToUpper(name)
+	for first < len(x.Records) && x.Records[first].Section != name {
+		first++
+	}
+	last = first
+	for last < len(x.Records) && x.Records[last].Section == name {
+		last++
+	}
+	return first, last
+}
+
+// end returns the offset after the records before last
+func (x *EntityIndex) end(last int) int64 {
+	if last < len(x.Records) {
+		return x.Records[last].Offset
+	}
+	return x.Size
+}
+
+// chunks splits the first size bytes of the indexed content into one chunk per worker, at
+// least minSize bytes each, at the records next to even splits
+func (x *EntityIndex) chunks(workers int, minSize, size int64) []Chunk {
+	count := workers
+	if count > int(size/minSize) {
+		count = int(size/minSize) + 1
+	}
+	records := x.Records[:sort.Search(len(x.Records), func(i int) bool { return x.Records[i].Offset >= size })]
+
+	var chunks []Chunk
+	start, section := int64(0), ""
+	for i := 1; i < count; i++ {
+		target := int64(i) * (size / int64(count))
+		next := sort.Search(len(records), func(j int) bool { return records[j].Offset >= target })
+		if next == len(records) {
+			break
+		}
+		if record := records[next]; record.Offset > start {
+			chunks = append(chunks, Chunk{start, record.Offset, section})
+			start, section = record.Offset, record.Section
+		}
+	}
+	return append(chunks, Chunk{start, size, section})
+}
+
+// indexRecords scans DXF content for its records, reading pairs like scanTextEntities so
+// record names in values and the payload of proxies are not taken for records. maxLine is
+// the longest line kept, in bytes.
+func indexRecords(r io.Reader, maxLine int) (*EntityIndex, error) {
+	index := &EntityIndex{}
+	scanner := newLineScanner(r, maxLine)
+	names := make(stringTable)
+	var sections sectionTracker
+	var proxies proxySkipper
+	code, expectingValue := "", false
+	var codeOffset, previous int64 // offsets of the group code line and of the line before
+	codeLine := 0
+	for scanner.Scan() {
+		offset := scanner.Offset()
+		trimmed := bytes.TrimSpace(scanner.Bytes())
+		if proxies.active {
+			if proxies.skip(string(trimmed)) {
+				previous = offset
+				continue
+			}
+			// The record after the proxy, whose code 0 was the line before
+			code, expectingValue = "0", true
+			codeOffset, codeLine = previous, scanner.Line()-1
+		}
+		previous = offset
+
+		if !expectingValue {
+			code = names.get(trimmed)
+			if proxies.payload(code) {
+				continue
+			}
+			codeOffset, codeLine = offset, scanner.Line()
+			expectingValue = true
+			continue
+		}
+		expectingValue = false
+		value := ""
+		if code == "0" || code == "2" {
+			value = names.get(trimmed)
+		}
+		if code == "0" {
+			index.Records = append(index.Records, RecordOffset{Offset: codeOffset, Line: codeLine, Type: value, Section: sections.name})
+			proxies.enter(value)
+		}
+		sections.pair(code, value)
+	}
+	return index, scanner.Err()
+}
+
+// IndexFile returns the record index of a DXF file for ParseIndexed and ParseRecords. The
+// offsets are those of the content as parsed, decompressed for gzip files and archive entries.
+func (p *DXFParser) IndexFile(filename string) (*EntityIndex, error) {
+	r, size, closeInput, err := openParseInput(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer closeInput()
+	index, err := indexRecords(io.NewSectionReader(r, 0, size), p.scanBuffer)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+	index.Size = size
+	return index, nil
+}
+
+// openIndexed opens a file for a parse with its index, which must be of the same content
+func openIndexed(filename string, index *EntityIndex) (io.ReaderAt, func() error, error) {
+	r, size, closeInput, err := openParseInput(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	if size != index.Size {
+		closeInput()
+		return nil, nil, fmt.Errorf("index of %d bytes does not match the %d bytes of %s; index it again", index.Size, size, filename)
+	}
+	return r, closeInput, nil
+}
+
+// ParseIndexed is ParseFile with the concurrent chunks split at the records of index, which
+// know the section they start in, instead of at code 0 lines searched near even splits
+func (p *DXFParser) ParseIndexed(filename string, index *EntityIndex) ([]TextEntity, error) {
+	r, closeInput, err := openIndexed(filename, index)
+	if err != nil {
+		return nil, err
+	}
+	defer closeInput()
+	return p.parseIndexedReaderAt(context.Background(), r, index.Size, index)
+}
+
+// ParseRecords parses the records first to last (exclusive) of index, read from their offsets
+// in the file. The layer table and the block definitions are read as well if they come before
+// the records, for the colors and the text of the INSERTs among them. The content is not
+// checked for truncation or, with ParseOptions.Strict, validated as a whole; warnings have the
+// line numbers of the file.
+func (p *DXFParser) ParseRecords(filename string, index *EntityIndex, first, last int) ([]TextEntity, error) {
+	if first < 0 || last > len(index.Records) || first > last {
+		return nil, fmt.Errorf("records %d to %d outside the %d records of the index", first, last, len(index.Records))
+	}
+	r, closeInput, err := openIndexed(filename, index)
+	if err != nil {
+		return nil, err
+	}
+	defer closeInput()
+	encoding, err := newEncodingState(p.encoding)
+	if err != nil {
+		return nil, err
+	}
+	encoding = detectEncoding(io.NewSectionReader(r, 0, index.Size), p.scanBuffer, encoding)
+
+	entities := newEntities(0)
+	emit := false // the entities of the tables and blocks read for the range are dropped
+	expander := newBlockExpander(p.entityFilter(func(entity TextEntity) error {
+		if emit {
+			entities = append(entities, entity)
+		}
+		return nil
+	}), p.blockDepth)
+
+	var parts [][2]int
+	for _, name := range []string{"TABLES", "BLOCKS"} {
+		if from, to := index.Section(name); from < to && to <= first {
+			parts = append(parts, [2]int{from, to})
+		}
+	}
+	if first < last {
+		parts = append(parts, [2]int{first, last})
+	}
+	truncated := 0
+	var warnings parseWarnings
+	for i, part := range parts {
+		record := index.Records[part[0]]
+		sections := sectionTracker{name: record.Section}
+		var partWarnings parseWarnings
+		chunk, cut, err := p.parseChunk(context.Background(), r, record.Offset, index.end(part[1]), encoding, &sections, &partWarnings)
+		truncated += cut
+		if err != nil {
+			return nil, err
+		}
+		emit = i == len(parts)-1 && first < last
+		if emit {
+			warnings = parseWarnings{lines: record.Line - 1}
+			warnings.merge(partWarnings)
+		}
+		for _, e := range chunk {
+			if p.inScope(e) {
+				expander.add(e)
+			}
+		}
+		ReleaseEntities(chunk)
+	}
+	p.setScanWarnings(truncated)
+	p.setParseWarnings(warnings)
+	p.addWarnings(expander.Warnings())
+	return entities, nil
+}
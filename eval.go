@@ -0,0 +1,287 @@
+package dxfparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EvalCorpus is a labeled set of drawings with the expected extraction results.
+// Fields left out of an entry are not evaluated for that drawing.
+type EvalCorpus struct {
+	Drawings []EvalDrawing `yaml:"drawings"`
+}
+
+// EvalDrawing holds the expected values of one drawing of the corpus
+type EvalDrawing struct {
+	Path         string  `yaml:"path"` // relative to the corpus file
+	DrawingNo    *string `yaml:"drawing_no"`
+	PipeClass    *string `yaml:"pipe_class"`
+	MaterialRows *int    `yaml:"material_rows"`
+	CutRows      *int    `yaml:"cut_rows"`
+	Welds        *int    `yaml:"welds"`
+}
+
+// EvalFieldMetrics are the accuracy figures of one field over the whole corpus.
+// For text fields precision is correct / non-empty extractions; for count fields
+// precision and recall compare the overlapping count with the extracted and expected totals.
+type EvalFieldMetrics struct {
+	Field     string  `json:"field"`
+	Labeled   int     `json:"labeled"`
+	Correct   int     `json:"correct"`
+	Accuracy  float64 `json:"accuracy"`
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+
+	extracted      int // text fields: non-empty extractions
+	expectedTotal  int // count fields: sum of expected counts
+	extractedTotal int // count fields: sum of extracted counts
+	overlapTotal   int // count fields: sum of min(expected, extracted)
+}
+
+// EvalMismatch records one field of one drawing that did not match the label
+type EvalMismatch struct {
+	Path     string `json:"path"`
+	Field    string `json:"field"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// EvalReport is the result of an evaluation run, also used as baseline for the next run
+type EvalReport struct {
+	ToolVersion string             `json:"tool_version"`
+	Drawings    int                `json:"drawings"`
+	Fields      []EvalFieldMetrics `json:"fields"`
+	Mismatches  []EvalMismatch     `json:"mismatches"`
+}
+
+// evalFields is the report order of the evaluated fields
+var evalFields = []string{"drawing_no", "pipe_class", "material_rows", "cut_rows", "welds"}
+
+// loadEvalCorpus reads a corpus file
+func loadEvalCorpus(path string) (*EvalCorpus, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading corpus: %v", err)
+	}
+	var corpus EvalCorpus
+	if err := yaml.Unmarshal(data, &corpus); err != nil {
+		return nil, fmt.Errorf("error parsing corpus %s: %v", path, err)
+	}
+	if len(corpus.Drawings) == 0 {
+		return nil, fmt.Errorf("corpus %s has no drawings", path)
+	}
+	return &corpus, nil
+}
+
+// runEval extracts every drawing of the corpus and compares the results with the labels
+func runEval(corpus *EvalCorpus, baseDir string) EvalReport {
+	metrics := make(map[string]*EvalFieldMetrics)
+	for _, field := range evalFields {
+		metrics[field] = &EvalFieldMetrics{Field: field}
+	}
+
+	report := EvalReport{ToolVersion: getToolVersion(), Drawings: len(corpus.Drawings)}
+
+	mismatch := func(path, field, expected, actual string) {
+		report.Mismatches = append(report.Mismatches, EvalMismatch{Path: path, Field: field, Expected: expected, Actual: actual})
+	}
+	checkText := func(path, field string, expected *string, actual string) {
+		if expected == nil {
+			return
+		}
+		m := metrics[field]
+		m.Labeled++
+		if actual != "" {
+			m.extracted++
+		}
+		if strings.EqualFold(strings.TrimSpace(*expected), strings.TrimSpace(actual)) {
+			m.Correct++
+		} else {
+			mismatch(path, field, *expected, actual)
+		}
+	}
+	checkCount := func(path, field string, expected *int, actual int) {
+		if expected == nil {
+			return
+		}
+		m := metrics[field]
+		m.Labeled++
+		m.expectedTotal += *expected
+		m.extractedTotal += actual
+		if actual < *expected {
+			m.overlapTotal += actual
+		} else {
+			m.overlapTotal += *expected
+		}
+		if actual == *expected {
+			m.Correct++
+		} else {
+			mismatch(path, field, fmt.Sprintf("%d", *expected), fmt.Sprintf("%d", actual))
+		}
+	}
+
+	for i, drawing := range corpus.Drawings {
+		path := drawing.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		fmt.Println(msg("bom.progress_start", i+1, len(corpus.Drawings), drawing.Path))
+
+		report, err := ProcessDrawing(path, DrawingOptions{Welds: drawing.Welds != nil})
+		if err != nil {
+			// A failed drawing is scored with empty results
+			fmt.Println(msg("bom.file_warning", drawing.Path, err))
+			report = &DrawingReport{Path: path}
+		}
+
+		checkText(drawing.Path, "drawing_no", drawing.DrawingNo, report.DrawingNo)
+		checkText(drawing.Path, "pipe_class", drawing.PipeClass, report.PipeClass)
+		checkCount(drawing.Path, "material_rows", drawing.MaterialRows, len(report.Materials.Rows))
+		checkCount(drawing.Path, "cut_rows", drawing.CutRows, len(report.CutLengths.Rows))
+		checkCount(drawing.Path, "welds", drawing.Welds, report.WeldCount())
+	}
+
+	for _, field := range evalFields {
+		m := metrics[field]
+		if m.Labeled == 0 {
+			continue
+		}
+		m.Accuracy = float64(m.Correct) / float64(m.Labeled)
+		if m.expectedTotal > 0 || m.extractedTotal > 0 {
+			m.Precision = ratioOrOne(m.overlapTotal, m.extractedTotal)
+			m.Recall = ratioOrOne(m.overlapTotal, m.expectedTotal)
+		} else {
+			m.Precision = ratioOrOne(m.Correct, m.extracted)
+			m.Recall = m.Accuracy
+		}
+		report.Fields = append(report.Fields, *m)
+	}
+
+	sort.SliceStable(report.Mismatches, func(i, j int) bool {
+		return report.Mismatches[i].Path < report.Mismatches[j].Path
+	})
+
+	return report
+}
+
+// ratioOrOne returns a/b, or 1 when there is nothing to compare
+func ratioOrOne(a, b int) float64 {
+	if b == 0 {
+		return 1
+	}
+	return float64(a) / float64(b)
+}
+
+// compareEvalReports returns the fields whose accuracy, precision or recall dropped against the baseline
+func compareEvalReports(baseline, current EvalReport) []string {
+	previous := make(map[string]EvalFieldMetrics)
+	for _, m := range baseline.Fields {
+		previous[m.Field] = m
+	}
+
+	const epsilon = 1e-9
+	var regressions []string
+	for _, m := range current.Fields {
+		prev, ok := previous[m.Field]
+		if !ok {
+			continue
+		}
+		check := func(name string, before, after float64) {
+			if after < before-epsilon {
+				regressions = append(regressions, fmt.Sprintf("%s %s dropped %.1f%% -> %.1f%%", m.Field, name, before*100, after*100))
+			}
+		}
+		check("accuracy", prev.Accuracy, m.Accuracy)
+		check("precision", prev.Precision, m.Precision)
+		check("recall", prev.Recall, m.Recall)
+	}
+	return regressions
+}
+
+// printEvalReport prints the per-field metrics and the mismatching drawings
+func printEvalReport(report EvalReport) {
+	fmt.Printf("\nEvaluation of %d drawings (%s)\n", report.Drawings, report.ToolVersion)
+	fmt.Println(strings.Repeat("=", 72))
+	fmt.Printf("%-15s %8s %8s %10s %10s %10s\n", "FIELD", "LABELED", "CORRECT", "ACCURACY", "PRECISION", "RECALL")
+	fmt.Println(strings.Repeat("-", 72))
+	for _, m := range report.Fields {
+		fmt.Printf("%-15s %8d %8d %9.1f%% %9.1f%% %9.1f%%\n",
+			m.Field, m.Labeled, m.Correct, m.Accuracy*100, m.Precision*100, m.Recall*100)
+	}
+	fmt.Println(strings.Repeat("=", 72))
+
+	if len(report.Mismatches) > 0 {
+		fmt.Printf("\nMismatches (%d):\n", len(report.Mismatches))
+		for _, mm := range report.Mismatches {
+			fmt.Printf("  %s: %s expected '%s', got '%s'\n", mm.Path, mm.Field, mm.Expected, mm.Actual)
+		}
+	}
+}
+
+// handleEvalCommand implements "eval <corpus.yaml> [-o report.json] [-baseline report.json]".
+// The exit code is 1 if any metric dropped against the baseline, so it can gate releases.
+func handleEvalCommand() {
+	fs := newCommandFlagSet("eval", "dxf_parser eval <corpus.yaml> [-o report.json] [-baseline previous.json] [-debug]")
+	output := fs.String("o", "", "Write the evaluation report as JSON to this file")
+	baselinePath := fs.String("baseline", "", "Fail if any metric is worse than in this earlier report")
+	debug := fs.Bool("debug", false, "Enable debug output of the extraction")
+	args := parseCommandArgs(fs, os.Args[2:])
+	checkArgCount(fs, args, 1, 1, "Error: Missing corpus file argument")
+
+	corpusPath := args[0]
+
+	debugMode = *debug
+
+	corpus, err := loadEvalCorpus(corpusPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	baseDir := filepath.Dir(corpusPath)
+	useProjectConfig(baseDir, nil)
+
+	report := runEval(corpus, baseDir)
+	printEvalReport(report)
+
+	if *output != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err == nil {
+			err = writeOutputFile(*output, data)
+		}
+		if err != nil {
+			fmt.Printf("Error writing report: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Wrote evaluation report to: %s\n", *output)
+	}
+
+	if *baselinePath != "" {
+		data, err := os.ReadFile(*baselinePath)
+		if err != nil {
+			fmt.Printf("Error reading baseline: %v\n", err)
+			os.Exit(1)
+		}
+		var baseline EvalReport
+		if err := json.Unmarshal(data, &baseline); err != nil {
+			fmt.Printf("Error parsing baseline %s: %v\n", *baselinePath, err)
+			os.Exit(1)
+		}
+
+		regressions := compareEvalReports(baseline, report)
+		if len(regressions) > 0 {
+			fmt.Printf("\nREGRESSIONS against %s (%s):\n", *baselinePath, baseline.ToolVersion)
+			for _, regression := range regressions {
+				fmt.Printf("  ! %s\n", regression)
+			}
+			os.Exit(1)
+		}
+		fmt.Printf("\nNo regressions against %s (%s)\n", *baselinePath, baseline.ToolVersion)
+	}
+}
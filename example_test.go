@@ -0,0 +1,124 @@
+package dxfparser_test
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+
+	dxfparser "github.com/jeffcall-ch/dxf_parser_go"
+)
+
+const exampleDrawing = "dxf_test_input_files/regression/TB020-TEST-1QFB10BR001_1.0_app-groups.dxf"
+
+func ExampleDXFParser_ParseFile() {
+	parser := dxfparser.NewDXFParser(4)
+	for _, path := range []string{exampleDrawing} {
+		entities, err := parser.ParseFile(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(path, len(entities) > 0)
+		dxfparser.ReleaseEntities(entities)
+	}
+	// Output: dxf_test_input_files/regression/TB020-TEST-1QFB10BR001_1.0_app-groups.dxf true
+}
+
+func ExampleDXFParser_ParseBytes() {
+	data, err := os.ReadFile(exampleDrawing)
+	if err != nil {
+		log.Fatal(err)
+	}
+	parser := dxfparser.NewDXFParser(4)
+	fromBytes, err := parser.ParseBytes(data)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fromReader, err := parser.ParseReader(bytes.NewReader(data))
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(len(fromBytes) == len(fromReader))
+	// Output: true
+}
+
+func ExampleDXFParser_ParseStream() {
+	parser := dxfparser.NewDXFParser(1)
+	texts := 0
+	err := parser.ParseStream(exampleDrawing, func(entity dxfparser.TextEntity) error {
+		texts++
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(texts > 0)
+	// Output: true
+}
+
+func ExampleDXFParser_OnText() {
+	file, err := os.Open(exampleDrawing)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	parser := dxfparser.NewDXFParser(1)
+	layers := make(map[string]int)
+	parser.OnText(func(text dxfparser.TextEntity) error {
+		layers[text.Layer]++
+		return nil
+	})
+	if err := parser.ParseEntities(file); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(len(layers) > 0)
+	// Output: true
+}
+
+func ExampleExtractWeldSymbols() {
+	data, err := os.ReadFile(exampleDrawing)
+	if err != nil {
+		log.Fatal(err)
+	}
+	entities, err := dxfparser.NewDXFParser(4).ParseBytes(data)
+	if err != nil {
+		log.Fatal(err)
+	}
+	segments, err := dxfparser.ParseSegments(data)
+	if err != nil {
+		log.Fatal(err)
+	}
+	symbols := dxfparser.ExtractWeldSymbols(entities, segments, dxfparser.DefaultWeldConfig())
+	fmt.Println(len(symbols))
+	// Output: 1
+}
+
+func ExampleProcessDrawing() {
+	report, err := dxfparser.ProcessDrawing(exampleDrawing, dxfparser.DrawingOptions{Welds: true})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(report.DrawingNo, report.PipeClass, report.WeldCount())
+	// Output: 1QFB10BR001 ABCD 1
+}
+
+func ExampleAggregate() {
+	report, err := dxfparser.ProcessDrawing(exampleDrawing, dxfparser.DrawingOptions{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	results := []dxfparser.DXFResult{{
+		FilePath:  report.Path,
+		DrawingNo: report.DrawingNo,
+		MatHeader: report.Materials.Header,
+		MatRows:   report.Materials.Rows,
+	}}
+	items, err := dxfparser.Aggregate(results, dxfparser.AggregateOptions{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, item := range items {
+		fmt.Println(item.Category, item.Description, item.NS, item.TotalQty)
+	}
+}
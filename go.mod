@@ -1,3 +1,11 @@
-module dxf_parser_go
+module github.com/jeffcall-ch/dxf_parser_go
 
 go 1.21
+
+require github.com/lib/pq v1.10.9
+
+require gopkg.in/yaml.v3 v3.0.1
+
+require go.etcd.io/bbolt v1.3.10
+
+require golang.org/x/sys v0.4.0 // indirect
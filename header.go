@@ -0,0 +1,289 @@
+package dxfparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DXFHeader holds the common HEADER variables of a drawing, for unit conversion, sanity checks
+// of coordinates against the drawing extents and version-specific handling
+type DXFHeader struct {
+	Version     string     `json:"version,omitempty"`   // $ACADVER, e.g. "AC1015"
+	Release     string     `json:"release,omitempty"`   // AutoCAD release of Version, e.g. "2000"
+	CodePage    string     `json:"code_page,omitempty"` // $DWGCODEPAGE, e.g. "ANSI_1252"
+	InsUnits    int        `json:"insunits"`            // $INSUNITS: 0 unitless (also without the variable), 1 inches, 4 millimeters, 6 meters, ...
+	Units       string     `json:"units"`               // name of InsUnits, e.g. "millimeters"
+	Measurement int        `json:"measurement"`         // $MEASUREMENT: 0 imperial, 1 metric, -1 not in the HEADER
+	ExtMin      [3]float64 `json:"extmin"`              // $EXTMIN x, y, z
+	ExtMax      [3]float64 `json:"extmax"`              // $EXTMAX x, y, z
+	HasExtents  bool       `json:"has_extents"`         // both extents are set and span a drawn area
+	Variables   int        `json:"variables"`           // number of $ variables in the HEADER
+}
+
+// r12Version is the $ACADVER of AutoCAD R11/R12, the oldest DXF version read as written: only
+// POLYLINE with VERTEX records for polylines (LWPOLYLINE follows in R14), often no
+// $DWGCODEPAGE (see legacyCodePage)
+const r12Version = "AC1009"
+
+// acadReleases are the AutoCAD releases of the $ACADVER values
+var acadReleases = map[string]string{
+	"AC1006": "R10",
+	"AC1009": "R11/R12",
+	"AC1012": "R13",
+	"AC1014": "R14",
+	"AC1015": "2000",
+	"AC1018": "2004",
+	"AC1021": "2007",
+	"AC1024": "2010",
+	"AC1027": "2013",
+	"AC1032": "2018",
+}
+
+// insUnits are the names and sizes in millimeters of the $INSUNITS values; 0 is unitless
+var insUnits = map[int]struct {
+	name        string
+	millimeters float64
+}{
+	0:  {"unitless", 0},
+	1:  {"inches", 25.4},
+	2:  {"feet", 304.8},
+	3:  {"miles", 1609344},
+	4:  {"millimeters", 1},
+	5:  {"centimeters", 10},
+	6:  {"meters", 1000},
+	7:  {"kilometers", 1e6},
+	8:  {"microinches", 25.4e-6},
+	9:  {"mils", 0.0254},
+	10: {"yards", 914.4},
+	11: {"angstroms", 1e-7},
+	12: {"nanometers", 1e-6},
+	13: {"microns", 1e-3},
+	14: {"decimeters", 100},
+	15: {"decameters", 1e4},
+	16: {"hectometers", 1e5},
+	17: {"gigameters", 1e12},
+	18: {"astronomical units", 1.495978707e14},
+	19: {"light years", 9.4607304725808e18},
+	20: {"parsecs", 3.0856775814913673e19},
+	21: {"US survey feet", 1200.0 / 3937 * 1000},
+}
+
+// emptyExtent is the magnitude AutoCAD writes into $EXTMIN and $EXTMAX of an empty drawing
+const emptyExtent = 1e20
+
+// Millimeters returns the size of one drawing unit in millimeters; ok is false for unitless
+// drawings and unknown $INSUNITS values
+func (h *DXFHeader) Millimeters() (size float64, ok bool) {
+	units, known := insUnits[h.InsUnits]
+	return units.millimeters, known && units.millimeters > 0
+}
+
+// VersionName returns the $ACADVER of the drawing with its release, e.g. "AC1015 (2000)": the
+// version alone if it is unknown, "" without one or without a header
+func (h *DXFHeader) VersionName() string {
+	switch {
+	case h == nil || h.Version == "":
+		return ""
+	case h.Release == "":
+		return h.Version
+	}
+	return fmt.Sprintf("%s (%s)", h.Version, h.Release)
+}
+
+// versionWarning returns a warning for a $ACADVER the parser does not know or older than R12,
+// the first version it is written for; "" for the others and drawings without one
+func (h *DXFHeader) versionWarning() string {
+	switch {
+	case h == nil || h.Version == "":
+		return ""
+	case h.Release == "":
+		return fmt.Sprintf("unknown DXF version %s", h.Version)
+	case h.Version < r12Version:
+		return fmt.Sprintf("DXF version %s is older than R12 and read like R12", h.VersionName())
+	}
+	return ""
+}
+
+// Metric reports whether the drawing uses metric measurement: $MEASUREMENT 1, or without the
+// variable a metric $INSUNITS
+func (h *DXFHeader) Metric() bool {
+	if h.Measurement >= 0 {
+		return h.Measurement == 1
+	}
+	switch h.InsUnits {
+	case 4, 5, 6, 7, 11, 12, 13, 14, 15, 16, 17:
+		return true
+	}
+	return false
+}
+
+// Contains reports whether the point x, y lies within the drawing extents enlarged by margin
+// on every side; true when the header has no extents to check against
+func (h *DXFHeader) Contains(x, y, margin float64) bool {
+	if !h.HasExtents {
+		return true
+	}
+	return x >= h.ExtMin[0]-margin && x <= h.ExtMax[0]+margin && y >= h.ExtMin[1]-margin && y <= h.ExtMax[1]+margin
+}
+
+// ParseHeader reads the HEADER variables of a DXF file. Reading stops at the end of the HEADER
+// section; a file without one gives the defaults (Measurement -1, no extents).
+func (p *DXFParser) ParseHeader(filename string) (*DXFHeader, error) {
+	file, err := openInput(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+	return readHeader(file, p.scanBuffer)
+}
+
+// readHeader reads the HEADER at the start of r; maxLine is the longest line kept, in bytes
+func readHeader(r io.Reader, maxLine int) (*DXFHeader, error) {
+	header := &DXFHeader{Measurement: -1}
+	var extMin, extMax [3]bool // coordinates of $EXTMIN and $EXTMAX read
+	scanner := newLineScanner(r, maxLine)
+	inHeader := false
+	variable := ""
+read:
+	for scanner.Scan() {
+		code := strings.TrimSpace(scanner.Text())
+		if !scanner.Scan() {
+			break
+		}
+		value := strings.TrimSpace(scanner.Text())
+		switch {
+		case code == "2" && value == "HEADER":
+			inHeader = true
+			continue
+		case code == "0" && value == "ENDSEC" && inHeader:
+			break read
+		case code == "0" && value != "SECTION" && !inHeader:
+			break read // no HEADER before the first record
+		case code == "9":
+			variable = strings.ToUpper(value)
+			header.Variables++
+			continue
+		}
+		if !inHeader {
+			continue
+		}
+
+		switch variable {
+		case "$ACADVER":
+			if code == "1" {
+				header.Version = strings.ToUpper(value)
+			}
+		case "$DWGCODEPAGE":
+			if code == "3" {
+				header.CodePage = value
+			}
+		case "$INSUNITS":
+			if n, err := strconv.Atoi(value); err == nil && code == "70" {
+				header.InsUnits = n
+			}
+		case "$MEASUREMENT":
+			if n, err := strconv.Atoi(value); err == nil && code == "70" {
+				header.Measurement = n
+			}
+		case "$EXTMIN":
+			setPointGroup(&header.ExtMin, &extMin, code, value)
+		case "$EXTMAX":
+			setPointGroup(&header.ExtMax, &extMax, code, value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+
+	header.Release = acadReleases[header.Version]
+	header.Units = insUnits[header.InsUnits].name
+	if header.Units == "" {
+		header.Units = fmt.Sprintf("unknown (%d)", header.InsUnits)
+	}
+	header.HasExtents = extMin[0] && extMin[1] && extMax[0] && extMax[1] && spansArea(header.ExtMin, header.ExtMax)
+	return header, nil
+}
+
+// setPointGroup sets the coordinate of point a 10/20/30 group holds and marks it read
+func setPointGroup(point *[3]float64, read *[3]bool, code, value string) {
+	var i int
+	switch code {
+	case "10":
+		i = 0
+	case "20":
+		i = 1
+	case "30":
+		i = 2
+	default:
+		return
+	}
+	if f, ok := parseGroupFloat(code, value); ok {
+		point[i], read[i] = f, true
+	}
+}
+
+// spansArea reports whether min and max are finite extents with min not beyond max in X and
+// Y; AutoCAD writes 1e20 / -1e20 for a drawing without entities
+func spansArea(min, max [3]float64) bool {
+	for i := 0; i < 2; i++ {
+		if math.IsNaN(min[i]) || math.IsNaN(max[i]) || math.Abs(min[i]) >= emptyExtent || math.Abs(max[i]) >= emptyExtent {
+			return false
+		}
+		if min[i] > max[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// handleHeaderCommand implements "header <file.dxf> [-json]"
+func handleHeaderCommand() {
+	fs := newCommandFlagSet("header", "dxf_parser header <file.dxf> [-json]")
+	asJSON := fs.Bool("json", false, "Print the header variables as JSON")
+	args := parseCommandArgs(fs, os.Args[2:])
+	checkArgCount(fs, args, 1, 1, msg("cli.missing_file"))
+
+	header, err := NewDXFParser(1).ParseHeader(args[0])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(header); err != nil {
+			fmt.Printf("Error writing output: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	version := "not set"
+	if header.Version != "" {
+		release := header.Release
+		if release == "" {
+			release = "unknown"
+		}
+		version = fmt.Sprintf("%s (AutoCAD %s)", header.Version, release)
+	}
+	measurement := map[int]string{-1: "not set", 0: "imperial", 1: "metric"}[header.Measurement]
+	if measurement == "" {
+		measurement = fmt.Sprintf("unknown (%d)", header.Measurement)
+	}
+	fmt.Printf("Version:     %s\n", version)
+	fmt.Printf("Code page:   %s\n", header.CodePage)
+	fmt.Printf("Units:       %s ($INSUNITS %d)\n", header.Units, header.InsUnits)
+	fmt.Printf("Measurement: %s\n", measurement)
+	if header.HasExtents {
+		fmt.Printf("Extents:     (%.3f, %.3f) - (%.3f, %.3f)\n", header.ExtMin[0], header.ExtMin[1], header.ExtMax[0], header.ExtMax[1])
+	} else {
+		fmt.Println("Extents:     none")
+	}
+	fmt.Printf("Variables:   %d\n", header.Variables)
+}
@@ -0,0 +1,266 @@
+package dxfparser
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// gzipMagic starts every gzip stream; compressed drawings (.dxf.gz) are recognized by it, not
+// by their name
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// Byte order marks some exporters put in front of a DXF file
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// isDXF reports whether path names a DXF file, plain (.dxf) or gzip-compressed (.dxf.gz)
+func isDXF(path string) bool {
+	lower := strings.ToLower(path)
+	return strings.HasSuffix(lower, ".dxf") || strings.HasSuffix(lower, ".dxf.gz")
+}
+
+// inputReader is the content of an input with the closers of its layers
+type inputReader struct {
+	io.Reader
+	closers []io.Closer // innermost first
+}
+
+func (r *inputReader) Close() error {
+	var err error
+	for _, closer := range r.closers {
+		if closeErr := closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// openDecompressed opens a drawing file or ZIP entry, decompressing gzip content
+func openDecompressed(p string) (*bufio.Reader, *inputReader, error) {
+	var input io.ReadCloser
+	var err error
+	if archive, entry, ok := splitZipPath(p); ok {
+		input, err = openZipEntry(p, archive, entry)
+	} else {
+		input, err = os.Open(p)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reader := &inputReader{closers: []io.Closer{input}}
+	buffered := bufio.NewReader(input)
+	if magic, _ := buffered.Peek(len(gzipMagic)); bytes.Equal(magic, gzipMagic) {
+		decompressed, err := gzip.NewReader(buffered)
+		if err != nil {
+			input.Close()
+			return nil, nil, err
+		}
+		reader.closers = append([]io.Closer{decompressed}, reader.closers...)
+		buffered = bufio.NewReader(decompressed)
+	}
+	return buffered, reader, nil
+}
+
+// byteOrderMark returns the encoding named by the byte order mark at the start of content
+// ("UTF-8", "UTF-16LE", "UTF-16BE") and its length, or "" without one
+func byteOrderMark(content []byte) (string, int) {
+	switch {
+	case bytes.HasPrefix(content, bomUTF8):
+		return "UTF-8", len(bomUTF8)
+	case bytes.HasPrefix(content, bomUTF16LE):
+		return "UTF-16LE", len(bomUTF16LE)
+	case bytes.HasPrefix(content, bomUTF16BE):
+		return "UTF-16BE", len(bomUTF16BE)
+	}
+	return "", 0
+}
+
+// openInput opens a drawing file or ZIP entry for reading. gzip-compressed content is
+// decompressed, a UTF-8 byte order mark is skipped and UTF-16 content is transcoded to UTF-8.
+func openInput(p string) (io.ReadCloser, error) {
+	buffered, reader, err := openDecompressed(p)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix, _ := buffered.Peek(len(bomUTF8))
+	encoding, size := byteOrderMark(prefix)
+	buffered.Discard(size)
+	switch encoding {
+	case "UTF-16LE":
+		reader.Reader = &utf16Reader{src: buffered, order: binary.LittleEndian}
+	case "UTF-16BE":
+		reader.Reader = &utf16Reader{src: buffered, order: binary.BigEndian}
+	default:
+		reader.Reader = buffered
+	}
+	return reader, nil
+}
+
+// transcodedFrom returns the encoding a drawing is transcoded from when it is read ("UTF-16LE"
+// or "UTF-16BE"), or "" for UTF-8 content
+func transcodedFrom(p string) string {
+	buffered, reader, err := openDecompressed(p)
+	if err != nil {
+		return ""
+	}
+	defer reader.Close()
+
+	prefix, _ := buffered.Peek(len(bomUTF8))
+	if encoding, _ := byteOrderMark(prefix); encoding != "UTF-8" {
+		return encoding
+	}
+	return ""
+}
+
+// readInput reads a drawing file or ZIP entry into memory, decompressed and as UTF-8
+func readInput(p string) ([]byte, error) {
+	if _, _, ok := splitZipPath(p); !ok {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		if encoding, _ := byteOrderMark(data); encoding == "" && !bytes.HasPrefix(data, gzipMagic) {
+			return data, nil
+		}
+	}
+	input, err := openInput(p)
+	if err != nil {
+		return nil, err
+	}
+	defer input.Close()
+	return io.ReadAll(input)
+}
+
+// isPlainFile reports whether p is an uncompressed UTF-8 file on disk without byte order mark,
+// which the parser reads in place. Other inputs are decoded into memory first.
+func isPlainFile(p string) bool {
+	if _, _, ok := splitZipPath(p); ok {
+		return false
+	}
+	file, err := os.Open(p)
+	if err != nil {
+		return true // reported by the caller opening it
+	}
+	defer file.Close()
+	return isPlainContent(file)
+}
+
+// isPlainContent reports whether the content of file, read from its start, is neither
+// compressed nor starts with a byte order mark
+func isPlainContent(file *os.File) bool {
+	prefix := make([]byte, len(bomUTF8))
+	n, _ := io.ReadFull(file, prefix)
+	prefix = prefix[:n]
+	encoding, _ := byteOrderMark(prefix)
+	return encoding == "" && !bytes.HasPrefix(prefix, gzipMagic)
+}
+
+// inputContent is the content of an input in memory: mapped from a plain file (see
+// isPlainFile), or read onto the heap for the other inputs and where mapping fails
+type inputContent struct {
+	Data   []byte
+	mapped bool
+}
+
+// Close releases the mapping of the content; Data must not be used after it. Strings taken
+// from the content while it was open are copies and stay valid.
+func (c *inputContent) Close() error {
+	if !c.mapped {
+		return nil
+	}
+	data := c.Data
+	c.Data, c.mapped = nil, false
+	return unmapFile(data)
+}
+
+// loadInput returns the content of an input like readInput. A plain file is mapped into memory
+// instead of being read, so a large drawing is not copied onto the heap and its text and
+// polylines are scanned from the same pages; where mapping is not supported or fails, the file
+// is read. The file must not be truncated while it is mapped.
+func loadInput(p string) (*inputContent, error) {
+	data, err := mapPlainFile(p)
+	if err != nil {
+		debugPrint(fmt.Sprintf("[DEBUG] Reading %s instead of mapping it: %v", p, err))
+	}
+	if data != nil {
+		return &inputContent{Data: data, mapped: true}, nil
+	}
+	if data, err = readInput(p); err != nil {
+		return nil, err
+	}
+	return &inputContent{Data: data}, nil
+}
+
+// mapPlainFile maps a plain file into memory; nil for other inputs, empty files and files that
+// cannot be opened, which readInput reports
+func mapPlainFile(p string) ([]byte, error) {
+	if _, _, ok := splitZipPath(p); ok {
+		return nil, nil
+	}
+	file, err := os.Open(p)
+	if err != nil {
+		return nil, nil
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil || !info.Mode().IsRegular() || info.Size() == 0 || !isPlainContent(file) {
+		return nil, nil
+	}
+	if int64(int(info.Size())) != info.Size() {
+		return nil, fmt.Errorf("%d bytes are too many to map", info.Size())
+	}
+	return mapFile(file, info.Size())
+}
+
+// utf16Reader transcodes UTF-16 content to UTF-8
+type utf16Reader struct {
+	src   io.Reader
+	order binary.ByteOrder
+	in    [4096]byte
+	have  int    // bytes in in
+	out   []byte // transcoded bytes not yet read
+	err   error
+}
+
+func (u *utf16Reader) Read(b []byte) (int, error) {
+	for len(u.out) == 0 && u.err == nil {
+		n, err := u.src.Read(u.in[u.have:])
+		u.have += n
+		if err != nil {
+			u.err = err
+		}
+
+		units := make([]uint16, u.have/2)
+		for i := range units {
+			units[i] = u.order.Uint16(u.in[2*i:])
+		}
+		// A high surrogate at the end waits for its pair, unless the content ends there
+		if u.err == nil && len(units) > 0 && units[len(units)-1] >= 0xD800 && units[len(units)-1] < 0xDC00 {
+			units = units[:len(units)-1]
+		}
+		for _, r := range utf16.Decode(units) {
+			u.out = utf8.AppendRune(u.out, r)
+		}
+		u.have = copy(u.in[:], u.in[2*len(units):u.have])
+	}
+
+	n := copy(b, u.out)
+	u.out = u.out[n:]
+	if n == 0 {
+		return 0, u.err
+	}
+	return n, nil
+}
@@ -0,0 +1,101 @@
+// Package geometry holds the plane geometry shared by the weld detection, the drawing
+// orientation statistics and the spatial text queries: distances, midpoints, directions and
+// segment intersections. Angles are in degrees. Coordinates come straight from drawings, so
+// every function is defined for huge and non-finite input: a NaN never passes a tolerance
+// check, and an intersection is only reported with a finite point.
+package geometry
+
+import "math"
+
+// parallelLimit is the cross product magnitude below which two segments count as parallel
+const parallelLimit = 1e-10
+
+// Distance returns the distance between two points without overflowing for huge coordinates.
+// NaN input gives +Inf, so the result never passes a tolerance check.
+func Distance(x1, y1, x2, y2 float64) float64 {
+	d := math.Hypot(x2-x1, y2-y1)
+	if math.IsNaN(d) {
+		return math.Inf(1)
+	}
+	return d
+}
+
+// Midpoint returns the point halfway between two points
+func Midpoint(x1, y1, x2, y2 float64) (float64, float64) {
+	return (x1 + x2) / 2, (y1 + y2) / 2
+}
+
+// Angle returns the direction of the vector dx, dy counterclockwise from the X axis, in the
+// range 0-360; 0 for the zero vector
+func Angle(dx, dy float64) float64 {
+	angle := math.Atan2(dy, dx) * 180 / math.Pi
+	if angle < 0 {
+		angle += 360
+	}
+	if angle >= 360 {
+		angle = 0
+	}
+	return angle
+}
+
+// Direction returns the direction of the segment from x1, y1 to x2, y2 folded to the range
+// 0-180, so both directions along a line give the same angle
+func Direction(x1, y1, x2, y2 float64) float64 {
+	angle := math.Mod(Angle(x2-x1, y2-y1), 180)
+	if angle >= 180-1e-9 {
+		angle = 0
+	}
+	return angle
+}
+
+// DirectionNear reports whether the folded direction angle is within tolerance of target,
+// treating 0 and 180 as equal
+func DirectionNear(angle, target, tolerance float64) bool {
+	diff := math.Abs(angle - target)
+	if diff > 90 {
+		diff = 180 - diff
+	}
+	return diff <= tolerance
+}
+
+// crossing returns the parameters t along the segment x1, y1 - x2, y2 and u along x3, y3 -
+// x4, y4 of the intersection of their lines. ok is false for parallel or degenerate segments
+// and non-finite input.
+func crossing(x1, y1, x2, y2, x3, y3, x4, y4 float64) (t, u float64, ok bool) {
+	denom := (x1-x2)*(y3-y4) - (y1-y2)*(x3-x4)
+	if !(math.Abs(denom) >= parallelLimit) || math.IsInf(denom, 0) {
+		return 0, 0, false
+	}
+	t = ((x1-x3)*(y3-y4) - (y1-y3)*(x3-x4)) / denom
+	u = -((x1-x2)*(y1-y3) - (y1-y2)*(x1-x3)) / denom
+	return t, u, true
+}
+
+// Crosses reports whether the segments x1, y1 - x2, y2 and x3, y3 - x4, y4 intersect,
+// end points included. Parallel segments never cross, even when they overlap.
+func Crosses(x1, y1, x2, y2, x3, y3, x4, y4 float64) bool {
+	t, u, ok := crossing(x1, y1, x2, y2, x3, y3, x4, y4)
+	return ok && onBoth(t, u)
+}
+
+// onBoth reports whether the line parameters t and u lie on both segments; NaN fails every
+// comparison
+func onBoth(t, u float64) bool {
+	return t >= 0 && t <= 1 && u >= 0 && u <= 1
+}
+
+// Intersect returns the intersection point of the segments x1, y1 - x2, y2 and x3, y3 - x4,
+// y4. ok is false if they do not cross (see Crosses) or the point is not finite. Swapping the
+// segments can change the decision at the segment ends by rounding, so callers that check a
+// pair with Crosses first must pass the segments in the same order.
+func Intersect(x1, y1, x2, y2, x3, y3, x4, y4 float64) (x, y float64, ok bool) {
+	t, u, ok := crossing(x1, y1, x2, y2, x3, y3, x4, y4)
+	if !ok || !onBoth(t, u) {
+		return 0, 0, false
+	}
+	x, y = x1+t*(x2-x1), y1+t*(y2-y1)
+	if math.IsNaN(x) || math.IsInf(x, 0) || math.IsNaN(y) || math.IsInf(y, 0) {
+		return 0, 0, false
+	}
+	return x, y, true
+}
@@ -0,0 +1,277 @@
+package geometry
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// trials is the number of random cases of each property test
+const trials = 10000
+
+// near reports whether a and b differ by at most tolerance, relative to their size above 1
+func near(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance*math.Max(1, math.Max(math.Abs(a), math.Abs(b)))
+}
+
+// randomPoint returns a point with coordinates in -scale..scale
+func randomPoint(r *rand.Rand, scale float64) (float64, float64) {
+	return (r.Float64()*2 - 1) * scale, (r.Float64()*2 - 1) * scale
+}
+
+func TestDistance(t *testing.T) {
+	inf := math.Inf(1)
+	cases := []struct {
+		x1, y1, x2, y2, want float64
+	}{
+		{0, 0, 3, 4, 5},
+		{1, 1, 1, 1, 0},
+		{-1, -1, 2, 3, 5},
+		{0, 0, 1e308, 1e308, math.Sqrt2 * 1e308}, // no overflow in the squares
+		{math.NaN(), 0, 1, 1, inf},
+		{0, 0, inf, 0, inf},
+	}
+	for _, c := range cases {
+		if got := Distance(c.x1, c.y1, c.x2, c.y2); !near(got, c.want, 1e-12) && got != c.want {
+			t.Errorf("Distance(%v, %v, %v, %v) = %v, want %v", c.x1, c.y1, c.x2, c.y2, got, c.want)
+		}
+	}
+}
+
+func TestDistanceProperties(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < trials; i++ {
+		ax, ay := randomPoint(r, 1e4)
+		bx, by := randomPoint(r, 1e4)
+		cx, cy := randomPoint(r, 1e4)
+		ab, ba := Distance(ax, ay, bx, by), Distance(bx, by, ax, ay)
+		if ab != ba {
+			t.Fatalf("Distance not symmetric: %v != %v", ab, ba)
+		}
+		if ab < 0 {
+			t.Fatalf("negative distance %v", ab)
+		}
+		if ac, cb := Distance(ax, ay, cx, cy), Distance(cx, cy, bx, by); ab > ac+cb+1e-9 {
+			t.Fatalf("triangle inequality violated: %v > %v + %v", ab, ac, cb)
+		}
+		// Translation invariance
+		dx, dy := randomPoint(r, 1e3)
+		if moved := Distance(ax+dx, ay+dy, bx+dx, by+dy); !near(moved, ab, 1e-9) {
+			t.Fatalf("distance changed by translation: %v != %v", moved, ab)
+		}
+	}
+}
+
+func TestMidpoint(t *testing.T) {
+	if x, y := Midpoint(0, 0, 4, -2); x != 2 || y != -1 {
+		t.Errorf("Midpoint(0, 0, 4, -2) = %v, %v, want 2, -1", x, y)
+	}
+	r := rand.New(rand.NewSource(2))
+	for i := 0; i < trials; i++ {
+		ax, ay := randomPoint(r, 1e4)
+		bx, by := randomPoint(r, 1e4)
+		mx, my := Midpoint(ax, ay, bx, by)
+		toA, toB, whole := Distance(mx, my, ax, ay), Distance(mx, my, bx, by), Distance(ax, ay, bx, by)
+		if !near(toA, toB, 1e-9) || !near(toA+toB, whole, 1e-9) {
+			t.Fatalf("midpoint of (%v, %v)-(%v, %v) at %v/%v of %v", ax, ay, bx, by, toA, toB, whole)
+		}
+	}
+}
+
+func TestAngle(t *testing.T) {
+	cases := []struct {
+		dx, dy, want float64
+	}{
+		{1, 0, 0},
+		{0, 1, 90},
+		{-1, 0, 180},
+		{0, -1, 270},
+		{1, 1, 45},
+		{-1, -1, 225},
+		{1, -1e-18, 0}, // rounds to 360, which is 0
+		{0, 0, 0},
+	}
+	for _, c := range cases {
+		if got := Angle(c.dx, c.dy); !near(got, c.want, 1e-12) {
+			t.Errorf("Angle(%v, %v) = %v, want %v", c.dx, c.dy, got, c.want)
+		}
+	}
+	r := rand.New(rand.NewSource(3))
+	for i := 0; i < trials; i++ {
+		want := r.Float64() * 360
+		length := r.Float64()*1e3 + 1e-3
+		sin, cos := math.Sincos(want * math.Pi / 180)
+		got := Angle(cos*length, sin*length)
+		if got < 0 || got >= 360 {
+			t.Fatalf("Angle out of range: %v", got)
+		}
+		if diff := math.Abs(got - want); diff > 1e-9 && 360-diff > 1e-9 {
+			t.Fatalf("Angle of %v° vector = %v", want, got)
+		}
+	}
+}
+
+func TestDirection(t *testing.T) {
+	cases := []struct {
+		x1, y1, x2, y2, want float64
+	}{
+		{0, 0, 1, 0, 0},
+		{1, 0, 0, 0, 0},
+		{0, 0, 0, 1, 90},
+		{0, 1, 0, 0, 90},
+		{0, 0, 1, 1, 45},
+		{1, 1, 0, 0, 45},
+		{0, 0, -1, 1, 135},
+	}
+	for _, c := range cases {
+		if got := Direction(c.x1, c.y1, c.x2, c.y2); !near(got, c.want, 1e-12) {
+			t.Errorf("Direction(%v, %v, %v, %v) = %v, want %v", c.x1, c.y1, c.x2, c.y2, got, c.want)
+		}
+	}
+	r := rand.New(rand.NewSource(4))
+	for i := 0; i < trials; i++ {
+		ax, ay := randomPoint(r, 1e3)
+		bx, by := randomPoint(r, 1e3)
+		forward, backward := Direction(ax, ay, bx, by), Direction(bx, by, ax, ay)
+		if forward < 0 || forward >= 180 {
+			t.Fatalf("Direction out of range: %v", forward)
+		}
+		if !DirectionNear(forward, backward, 1e-9) {
+			t.Fatalf("reversed segment has direction %v, not %v", backward, forward)
+		}
+	}
+}
+
+func TestDirectionNear(t *testing.T) {
+	cases := []struct {
+		angle, target, tolerance float64
+		want                     bool
+	}{
+		{1, 0, 2, true},
+		{179.5, 0, 1, true},
+		{0.5, 180, 1, true},
+		{30, 30, 0, true},
+		{33, 30, 2, false},
+		{90, 0, 45, false},
+		{math.NaN(), 0, 180, false},
+	}
+	for _, c := range cases {
+		if got := DirectionNear(c.angle, c.target, c.tolerance); got != c.want {
+			t.Errorf("DirectionNear(%v, %v, %v) = %v, want %v", c.angle, c.target, c.tolerance, got, c.want)
+		}
+	}
+}
+
+func TestIntersect(t *testing.T) {
+	nan, inf := math.NaN(), math.Inf(1)
+	cases := []struct {
+		name           string
+		x1, y1, x2, y2 float64
+		x3, y3, x4, y4 float64
+		ok             bool
+		x, y           float64
+	}{
+		{"cross", -1, 0, 1, 0, 0, -1, 0, 1, true, 0, 0},
+		{"diagonal cross", 0, 0, 4, 4, 0, 4, 4, 0, true, 2, 2},
+		{"touching end", 0, 0, 2, 0, 1, 0, 1, 3, true, 1, 0},
+		{"shared end point", 0, 0, 1, 1, 1, 1, 2, 0, true, 1, 1},
+		{"lines cross beyond a segment", 0, 0, 1, 0, 2, -1, 2, 1, false, 0, 0},
+		{"parallel", 0, 0, 1, 0, 0, 1, 1, 1, false, 0, 0},
+		{"collinear overlap", 0, 0, 2, 0, 1, 0, 3, 0, false, 0, 0},
+		{"degenerate", 0, 0, 0, 0, -1, 0, 1, 0, false, 0, 0},
+		{"nan", nan, 0, 1, 0, 0, -1, 0, 1, false, 0, 0},
+		{"infinite", -inf, 0, inf, 0, 0, -1, 0, 1, false, 0, 0},
+		{"huge", -1e300, 0, 1e300, 0, 0, -1e300, 0, 1e300, false, 0, 0}, // cross product overflows
+	}
+	for _, c := range cases {
+		x, y, ok := Intersect(c.x1, c.y1, c.x2, c.y2, c.x3, c.y3, c.x4, c.y4)
+		if ok != c.ok || (ok && (!near(x, c.x, 1e-12) || !near(y, c.y, 1e-12))) {
+			t.Errorf("%s: Intersect = %v, %v, %v, want %v, %v, %v", c.name, x, y, ok, c.x, c.y, c.ok)
+		}
+		if crosses := Crosses(c.x1, c.y1, c.x2, c.y2, c.x3, c.y3, c.x4, c.y4); crosses != c.ok {
+			t.Errorf("%s: Crosses = %v, want %v", c.name, crosses, c.ok)
+		}
+	}
+}
+
+// TestIntersectKnownPoint draws two segments of random directions through a random point,
+// which must be found as their intersection in either operand order
+func TestIntersectKnownPoint(t *testing.T) {
+	r := rand.New(rand.NewSource(5))
+	for i := 0; i < trials; i++ {
+		px, py := randomPoint(r, 1e4)
+		a1 := r.Float64() * math.Pi
+		a2 := a1 + (0.01+r.Float64()*0.98)*math.Pi // not parallel to the first
+		segment := func(angle float64) (float64, float64, float64, float64) {
+			sin, cos := math.Sincos(angle)
+			before, after := 0.01+r.Float64()*100, 0.01+r.Float64()*100
+			return px - cos*before, py - sin*before, px + cos*after, py + sin*after
+		}
+		x1, y1, x2, y2 := segment(a1)
+		x3, y3, x4, y4 := segment(a2)
+
+		x, y, ok := Intersect(x1, y1, x2, y2, x3, y3, x4, y4)
+		if !ok || !near(x, px, 1e-6) || !near(y, py, 1e-6) {
+			t.Fatalf("segments through (%v, %v): Intersect = %v, %v, %v", px, py, x, y, ok)
+		}
+		if !Crosses(x1, y1, x2, y2, x3, y3, x4, y4) || !Crosses(x3, y3, x4, y4, x1, y1, x2, y2) {
+			t.Fatalf("segments through (%v, %v) do not cross", px, py)
+		}
+		sx, sy, ok := Intersect(x3, y3, x4, y4, x1, y1, x2, y2)
+		if !ok || !near(sx, x, 1e-6) || !near(sy, y, 1e-6) {
+			t.Fatalf("swapped segments: Intersect = %v, %v, %v, want %v, %v", sx, sy, ok, x, y)
+		}
+	}
+}
+
+// TestIntersectSeparated checks segments on either side of a gap never cross
+func TestIntersectSeparated(t *testing.T) {
+	r := rand.New(rand.NewSource(6))
+	for i := 0; i < trials; i++ {
+		// The first segment left of x = 0, the second right of x = gap
+		gap := r.Float64()*10 + 1e-6
+		x1, x2 := -r.Float64()*100, -r.Float64()*100
+		x3, x4 := gap+r.Float64()*100, gap+r.Float64()*100
+		_, y1 := randomPoint(r, 100)
+		_, y2 := randomPoint(r, 100)
+		_, y3 := randomPoint(r, 100)
+		_, y4 := randomPoint(r, 100)
+		if Crosses(x1, y1, x2, y2, x3, y3, x4, y4) {
+			t.Fatalf("separated segments cross: (%v, %v)-(%v, %v) and (%v, %v)-(%v, %v)", x1, y1, x2, y2, x3, y3, x4, y4)
+		}
+		if _, _, ok := Intersect(x1, y1, x2, y2, x3, y3, x4, y4); ok {
+			t.Fatalf("separated segments intersect")
+		}
+	}
+}
+
+// TestIntersectOnBothSegments checks that every intersection of random segments lies on both
+// of them and that Intersect and Crosses agree
+func TestIntersectOnBothSegments(t *testing.T) {
+	r := rand.New(rand.NewSource(7))
+	found := 0
+	for i := 0; i < trials; i++ {
+		x1, y1 := randomPoint(r, 100)
+		x2, y2 := randomPoint(r, 100)
+		x3, y3 := randomPoint(r, 100)
+		x4, y4 := randomPoint(r, 100)
+		x, y, ok := Intersect(x1, y1, x2, y2, x3, y3, x4, y4)
+		if crosses := Crosses(x1, y1, x2, y2, x3, y3, x4, y4); crosses != ok {
+			t.Fatalf("Crosses = %v, Intersect ok = %v", crosses, ok)
+		}
+		if !ok {
+			continue
+		}
+		found++
+		for _, s := range [][4]float64{{x1, y1, x2, y2}, {x3, y3, x4, y4}} {
+			// On the segment: the detour over the point is no longer than the segment
+			detour := Distance(s[0], s[1], x, y) + Distance(x, y, s[2], s[3])
+			if length := Distance(s[0], s[1], s[2], s[3]); !near(detour, length, 1e-9) {
+				t.Fatalf("intersection (%v, %v) off segment %v: %v > %v", x, y, s, detour, length)
+			}
+		}
+	}
+	if found == 0 {
+		t.Fatal("no random segments crossed")
+	}
+}
@@ -0,0 +1,110 @@
+package dxfparser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// layerColor is the color of a LAYER table record
+type layerColor struct {
+	Color     int // ACI color (62); negative for a layer that is switched off
+	TrueColor int // 24-bit RGB (420), 0 if not set
+}
+
+// layerColors holds the colors of the LAYER table by upper case layer name, as AutoCAD
+// matches layer names without regard to case
+type layerColors map[string]layerColor
+
+// isLayerRecord reports whether a record of the given type is a LAYER table record, which the
+// text scanner passes on like a block record so the block expansion can resolve ByLayer colors
+func isLayerRecord(recordType string) bool {
+	return recordType == "LAYER"
+}
+
+// add records the color of a LAYER record; its name (2) is in the block record
+func (l layerColors) add(record TextEntity) {
+	if record.Color == colorByLayer {
+		return // no color of its own, nothing to resolve with
+	}
+	l[strings.ToUpper(record.block.Name)] = layerColor{Color: record.Color, TrueColor: record.TrueColor}
+}
+
+// resolve returns e with a ByLayer color replaced by the color of its layer. Text of a layer
+// that is switched off gets the color without the sign, so switching a layer off does not
+// hide its text from the extraction; text of layers missing from the table stays ByLayer.
+func (l layerColors) resolve(e TextEntity) TextEntity {
+	if e.Color != colorByLayer {
+		return e
+	}
+	layer, ok := l[strings.ToUpper(e.Layer)]
+	if !ok {
+		return e
+	}
+	e.Color = layer.Color
+	if e.Color < 0 {
+		e.Color = -e.Color
+	}
+	if e.TrueColor == 0 {
+		e.TrueColor = layer.TrueColor
+	}
+	return e
+}
+
+// parseColorList parses a list of ACI color numbers and ranges, e.g. "8,9,250-254"; 0 is
+// ByBlock and 256 ByLayer text on a layer missing from the LAYER table
+func parseColorList(value string) ([]int, error) {
+	var colors []int
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		low, high, isRange := strings.Cut(part, "-")
+		from, err := strconv.Atoi(strings.TrimSpace(low))
+		to := from
+		if err == nil && isRange {
+			to, err = strconv.Atoi(strings.TrimSpace(high))
+		}
+		if err != nil || from < 0 || to > colorByLayer || from > to {
+			return nil, fmt.Errorf("invalid color '%s' (ACI numbers 0-256 or ranges, e.g. 8,9,250-254)", part)
+		}
+		for color := from; color <= to; color++ {
+			colors = append(colors, color)
+		}
+	}
+	return colors, nil
+}
+
+// colorList is a flag holding ACI color numbers, given as numbers and ranges, e.g. "8,9,250-254"
+type colorList []int
+
+func (c *colorList) String() string {
+	if c == nil {
+		return ""
+	}
+	parts := make([]string, len(*c))
+	for i, color := range *c {
+		parts[i] = strconv.Itoa(color)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (c *colorList) Set(value string) error {
+	colors, err := parseColorList(value)
+	if err != nil {
+		return err
+	}
+	*c = colors
+	return nil
+}
+
+// colorExcluded reports whether the resolved color of e is one of colors
+func colorExcluded(e TextEntity, colors []int) bool {
+	for _, color := range colors {
+		if e.Color == color {
+			return true
+		}
+	}
+	return false
+}
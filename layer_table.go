@@ -0,0 +1,293 @@
+package dxfparser
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Layer is a layer of a drawing: a record of the LAYER table, or a layer only used by entities
+type Layer struct {
+	Name      string `json:"name"`
+	Defined   bool   `json:"defined"`              // has a LAYER table record
+	Color     int    `json:"color"`                // ACI color (62) without the sign that switches the layer off
+	TrueColor int    `json:"true_color,omitempty"` // 24-bit RGB (420), 0 if not set
+	Linetype  string `json:"linetype,omitempty"`   // 6
+	On        bool   `json:"on"`                   // switched on: the color is not negative
+	Frozen    bool   `json:"frozen"`               // flag 1 of 70
+	Locked    bool   `json:"locked"`               // flag 4 of 70
+	Plot      bool   `json:"plot"`                 // 290; layers are plotted unless it is 0
+	Entities  int    `json:"entities"`             // records on the layer in ENTITIES and BLOCKS
+}
+
+// ParseLayers reads the LAYER table of a DXF file and counts the records on every layer in the
+// ENTITIES and BLOCKS sections. Layers used by entities but missing from the table are
+// included with Defined false. Layers are sorted by name.
+func (p *DXFParser) ParseLayers(filename string) ([]Layer, error) {
+	file, err := openInput(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+	return readLayers(file, p.scanBuffer)
+}
+
+// readLayers reads the layers from DXF group code / value pairs; maxLine is the longest line
+// kept, in bytes. Layer names are matched without regard to case, as AutoCAD does.
+func readLayers(r io.Reader, maxLine int) ([]Layer, error) {
+	layers := make(map[string]*Layer)
+	layer := func(name string) *Layer {
+		key := strings.ToUpper(name)
+		if layers[key] == nil {
+			layers[key] = &Layer{Name: name, Color: 7, On: true, Plot: true}
+		}
+		return layers[key]
+	}
+
+	// A LAYER record is read into table and takes over its layer at the next record
+	var table *Layer
+	finish := func() {
+		if table == nil || table.Name == "" {
+			return
+		}
+		l := layer(table.Name)
+		entities := l.Entities
+		*l = *table
+		l.Defined, l.Entities = true, entities
+		if l.Color < 0 {
+			l.Color, l.On = -l.Color, false
+		}
+	}
+
+	var sections sectionTracker
+	var groups appGroupFilter
+	var proxies proxySkipper
+	record := "" // type of the current record (group code 0)
+	scanner := newLineScanner(r, maxLine)
+	for scanner.Scan() {
+		code := strings.TrimSpace(scanner.Text())
+		var value string
+		if proxies.active {
+			if proxies.skip(code) {
+				continue
+			}
+			// The record after the proxy: the line is the value of a code 0
+			code, value = "0", code
+		} else if proxies.payload(code) {
+			continue
+		} else {
+			if !scanner.Scan() {
+				break
+			}
+			value = strings.TrimSpace(scanner.Text())
+		}
+		sections.pair(code, value)
+		if groups.skip(code, value) {
+			continue
+		}
+
+		if code == "0" {
+			finish()
+			record, table = value, nil
+			proxies.enter(record)
+			if record == "LAYER" && sections.name == "TABLES" {
+				table = &Layer{Color: 7, On: true, Plot: true}
+			}
+			continue
+		}
+
+		switch {
+		case table != nil:
+			switch code {
+			case "2":
+				table.Name = value
+			case "62":
+				if n, err := strconv.Atoi(value); err == nil {
+					table.Color = n
+				}
+			case "420":
+				table.TrueColor, _ = strconv.Atoi(value)
+			case "6":
+				table.Linetype = value
+			case "70":
+				flags, _ := strconv.Atoi(value)
+				table.Frozen, table.Locked = flags&1 != 0, flags&4 != 0
+			case "290":
+				table.Plot = value != "0"
+			}
+		case code == "8" && record != "BLOCK" && record != "ENDBLK" && (sections.name == "ENTITIES" || sections.name == "BLOCKS"):
+			layer(value).Entities++
+		}
+	}
+	finish()
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+
+	result := make([]Layer, 0, len(layers))
+	for _, l := range layers {
+		result = append(result, *l)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// layerState describes whether a layer is on, frozen and locked
+func (l Layer) layerState() string {
+	state := "on"
+	if !l.On {
+		state = "off"
+	}
+	if l.Frozen {
+		state += ",frozen"
+	}
+	if l.Locked {
+		state += ",locked"
+	}
+	return state
+}
+
+// LayerReport is the layer list of one file of the layers command
+type LayerReport struct {
+	FilePath string  `json:"file_path"`
+	Layers   []Layer `json:"layers"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// handleLayersCommand implements "layers <file.dxf|directory> [options]"
+func handleLayersCommand() {
+	fs := newCommandFlagSet("layers", "dxf_parser layers <file.dxf|directory> [-format text|json|csv] [-o output]")
+	format := fs.String("format", "text", "Output format (text, json, csv)")
+	output := fs.String("o", "", "Write output to this file instead of stdout")
+	args := parseCommandArgs(fs, os.Args[2:])
+	checkArgCount(fs, args, 1, 1, "Error: Missing DXF file or directory argument")
+	if *format != "text" && *format != "json" && *format != "csv" {
+		usageError(fs, fmt.Sprintf("Error: Unknown format: %s (use text, json or csv)", *format))
+	}
+
+	target := args[0]
+	var files []string
+	if info, err := os.Stat(target); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	} else if info.IsDir() {
+		filepath.Walk(target, func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() && isDXF(path) {
+				files = append(files, path)
+			}
+			return nil
+		})
+	} else {
+		files = []string{target}
+	}
+
+	parser := NewDXFParser(1)
+	reports := make([]LayerReport, 0, len(files))
+	for _, path := range files {
+		report := LayerReport{FilePath: path}
+		layers, err := parser.ParseLayers(path)
+		if err != nil {
+			report.Error = err.Error()
+		}
+		report.Layers = layers
+		reports = append(reports, report)
+	}
+
+	out := os.Stdout
+	var file *outputFile
+	if *output != "" {
+		var err error
+		file, err = createOutput(*output)
+		if err != nil {
+			fmt.Printf("Error creating output file: %v\n", err)
+			os.Exit(1)
+		}
+		out = file.File
+	}
+
+	var err error
+	switch *format {
+	case "text":
+		err = writeLayersText(out, reports)
+	case "json":
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(reports)
+	case "csv":
+		err = writeLayersCSV(out, reports)
+	}
+	if err == nil && file != nil {
+		err = file.Commit()
+	}
+	if err != nil {
+		if file != nil {
+			file.Close()
+		}
+		fmt.Printf("Error writing output: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// writeLayersText writes one aligned table per file
+func writeLayersText(out io.Writer, reports []LayerReport) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for i, report := range reports {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s (%d layers)\n", report.FilePath, len(report.Layers))
+		if report.Error != "" {
+			fmt.Fprintf(w, "Error: %s\n", report.Error)
+			continue
+		}
+		fmt.Fprintln(w, "NAME\tCOLOR\tLINETYPE\tSTATE\tPLOT\tENTITIES")
+		for _, l := range report.Layers {
+			name := l.Name
+			if !l.Defined {
+				name += " [not in LAYER table]"
+			}
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%t\t%d\n", name, l.Color, l.Linetype, l.layerState(), l.Plot, l.Entities)
+		}
+	}
+	return w.Flush()
+}
+
+// writeLayersCSV writes one row per layer and file
+func writeLayersCSV(out io.Writer, reports []LayerReport) error {
+	writer := csv.NewWriter(out)
+	header := []string{"FilePath", "Layer", "Defined", "Color", "TrueColor", "Linetype", "On", "Frozen", "Locked", "Plot", "Entities", "Error"}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, report := range reports {
+		if report.Error != "" {
+			if err := writer.Write([]string{report.FilePath, "", "", "", "", "", "", "", "", "", "", report.Error}); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, l := range report.Layers {
+			trueColor := ""
+			if l.TrueColor != 0 {
+				trueColor = fmt.Sprintf("#%06X", l.TrueColor)
+			}
+			record := []string{
+				report.FilePath, l.Name, strconv.FormatBool(l.Defined), strconv.Itoa(l.Color), trueColor, l.Linetype,
+				strconv.FormatBool(l.On), strconv.FormatBool(l.Frozen), strconv.FormatBool(l.Locked),
+				strconv.FormatBool(l.Plot), strconv.Itoa(l.Entities), "",
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,80 @@
+package dxfparser
+
+import "strings"
+
+// Layouts a parse can be restricted to with ParseOptions.Layout and -layout; any other value
+// names a paper space layout
+const (
+	LayoutModel = "model" // model space only (default)
+	LayoutAll   = "all"   // model space and every layout, as earlier versions
+)
+
+// inLayout reports whether an entity with paper space flag paper (67) and layout name (410) is
+// drawn in layout: in model space for "" and LayoutModel, anywhere for LayoutAll, otherwise on
+// the paper space layout of that name, compared without regard to case
+func inLayout(paper bool, name, layout string) bool {
+	switch {
+	case strings.EqualFold(layout, LayoutAll):
+		return true
+	case layout == "" || strings.EqualFold(layout, LayoutModel):
+		return !paper && (name == "" || strings.EqualFold(name, "Model"))
+	}
+	return strings.EqualFold(name, layout)
+}
+
+// InLayout reports whether the text is drawn in layout, see ParseOptions.Layout
+func (e TextEntity) InLayout(layout string) bool {
+	return inLayout(e.PaperSpace, e.Layout, layout)
+}
+
+// keeps reports whether the parser returns e: text in its layout, not in an excluded color and
+// not invisible unless the parser includes hidden text
+func (p *DXFParser) keeps(e TextEntity) bool {
+	return e.InLayout(p.layout) && !colorExcluded(e, p.excludeColors) && (p.includeHidden || !e.Invisible)
+}
+
+// keepsEntity reports whether a raw entity is drawn in the layout of the parser, by its paper
+// space flag (67) and layout name (410)
+func (p *DXFParser) keepsEntity(entity Entity) bool {
+	paper, _ := entity.Value(67)
+	name, _ := entity.Value(410)
+	return inLayout(strings.TrimSpace(paper) == "1", name, p.layout)
+}
+
+// entityFilter wraps emit to drop the text the parser does not keep, and the XDATA, untrimmed
+// and raw content unless it keeps those
+func (p *DXFParser) entityFilter(emit func(TextEntity) error) func(TextEntity) error {
+	return func(e TextEntity) error {
+		if !p.keeps(e) {
+			return nil
+		}
+		p.dropOptional(&e)
+		return emit(e)
+	}
+}
+
+// filterEntities returns the entities the parser keeps, without XDATA, untrimmed and raw
+// content unless it keeps those
+func (p *DXFParser) filterEntities(entities []TextEntity) []TextEntity {
+	kept := entities[:0]
+	for _, e := range entities {
+		if p.keeps(e) {
+			p.dropOptional(&e)
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
+// dropOptional clears the fields of an entity that are only kept on request
+func (p *DXFParser) dropOptional(e *TextEntity) {
+	if !p.xdata {
+		e.XData = nil
+	}
+	if !p.whitespace {
+		e.Untrimmed = ""
+	}
+	if !p.rawContent {
+		e.RawContent = ""
+	}
+}
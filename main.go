@@ -1,353 +1,1364 @@
-package main
+package dxfparser
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"math"
 	"os"
-	"regexp"
 	"runtime"
+	"runtime/debug"
 	"strconv"
 	"strings"
 	"sync"
+	"unicode/utf8"
+
+	"github.com/jeffcall-ch/dxf_parser_go/internal/geometry"
 )
 
-// TextEntity represents a text entity extracted from a DXF file
+// toolVersion identifies the build in run history records.
+// Release builds set it with:
+// go build -ldflags "-X github.com/jeffcall-ch/dxf_parser_go.toolVersion=v1.2.0" ./cmd/dxf_parser
+var toolVersion = "dev"
+
+// getToolVersion returns the release version of this binary. Builds installed with
+// "go install github.com/jeffcall-ch/dxf_parser_go/cmd/dxf_parser@v1.2.0" report the module version.
+func getToolVersion() string {
+	if toolVersion != "dev" {
+		return toolVersion
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return toolVersion
+}
+
+// TextEntity represents a text entity extracted from a DXF file: TEXT, MTEXT, the ATTRIB
+// values of block references and ATTDEF defaults of block definitions, the text of a DIMENSION,
+// the MTEXT content of a MULTILEADER, the text along the arc of an ARCALIGNEDTEXT, or the
+// feature control frame of a TOLERANCE
 type TextEntity struct {
-	Content    string  `json:"content"`
-	X          float64 `json:"x"`
-	Y          float64 `json:"y"`
-	Height     float64 `json:"height,omitempty"`
-	EntityType string  `json:"entity_type"`
-	Layer      string  `json:"layer,omitempty"`
-}
-
-// decodeUnicode decodes Unicode escape sequences like \U+00B0 to actual Unicode characters
-func decodeUnicode(text string) string {
-	// Regex to match \U+xxxx patterns
-	re := regexp.MustCompile(`\\U\+([0-9A-Fa-f]{4})`)
-	
-	result := re.ReplaceAllStringFunc(text, func(match string) string {
-		// Extract the hex code (remove \U+)
-		hexCode := match[3:]
-		
-		// Parse the hex code to integer
-		if codePoint, err := strconv.ParseInt(hexCode, 16, 32); err == nil {
-			// Convert to Unicode character
-			return string(rune(codePoint))
-		}
-		
-		// If parsing fails, return original
-		return match
-	})
-	
-	return result
+	Content     string    `json:"content"`
+	X           float64   `json:"x"`
+	Y           float64   `json:"y"`
+	Z           float64   `json:"z,omitempty"` // elevation (30) in drawings exported from 3D models
+	Height      float64   `json:"height,omitempty"`
+	Rotation    float64   `json:"rotation,omitempty"`     // degrees counterclockwise, 0-360 (50; 53 of a DIMENSION)
+	Style       string    `json:"style,omitempty"`        // text style name (7), "" for the default style STANDARD
+	WidthFactor float64   `json:"width_factor,omitempty"` // relative X scale (41) of TEXT, ATTRIB and ATTDEF, 0 if not set (1)
+	EntityType  string    `json:"entity_type"`
+	Tag         string    `json:"tag,omitempty"`         // attribute tag (2) of ATTRIB and ATTDEF
+	Measurement float64   `json:"measurement,omitempty"` // actual measurement (42) of a DIMENSION
+	Arrow       []float64 `json:"arrow,omitempty"`       // arrowhead [x, y] of a MULTILEADER, the point its text refers to
+	Layer       string    `json:"layer,omitempty"`
+	Script      string    `json:"script,omitempty"`      // "latin", "cyrillic" or "mixed" (set when language detection is enabled)
+	Color       int       `json:"color"`                 // ACI color (62): 0 ByBlock, 256 ByLayer (default)
+	TrueColor   int       `json:"true_color,omitempty"`  // 24-bit RGB (420), 0 if not set
+	Invisible   bool      `json:"invisible,omitempty"`   // invisibility flag (60) set
+	Handle      string    `json:"handle,omitempty"`      // entity handle (5); block text has the handle of the INSERT placing it
+	PaperSpace  bool      `json:"paper_space,omitempty"` // drawn in paper space (67)
+	Layout      string    `json:"layout,omitempty"`      // layout name (410), "Model" or "" in model space
+	XData       XData     `json:"xdata,omitempty"`       // extended data by application, with ParseOptions.XData
+	Untrimmed   string    `json:"untrimmed,omitempty"`   // content with the leading and trailing blanks of its values, with ParseOptions.KeepWhitespace
+	RawContent  string    `json:"raw_content,omitempty"` // content values as written, escapes and formatting codes included, with ParseOptions.RawContent
+
+	leader     *leaderState // MULTILEADER groups being read
+	arc        *arcState    // arc of an ARCALIGNEDTEXT
+	block      *blockRecord // set on the BLOCK, ENDBLK and INSERT records passed to the block expansion
+	section    string       // section the record was read in, see sectionTracker
+	directionX float64      // X of the X axis direction (11) of an MTEXT until its Y follows
+	justify    [2]int       // horizontal (72) and vertical (73; 74 of an attribute) justification of single-line text
+	align      []float64    // alignment point [x, y] (11/21) of single-line text, if given
+	xdataApp   string       // application of the XDATA groups being read (1001)
+}
+
+// colorByLayer is the ACI color of entities without a color of their own
+const colorByLayer = 256
+
+// InStyle reports whether the text is in one of styles, compared without regard to case; text
+// without a style name is in the default style STANDARD
+func (e TextEntity) InStyle(styles []string) bool {
+	style := e.Style
+	if style == "" {
+		style = "STANDARD"
+	}
+	for _, s := range styles {
+		if strings.EqualFold(strings.TrimSpace(s), style) {
+			return true
+		}
+	}
+	return false
+}
+
+// Hidden reports whether the text plots nowhere: it is flagged invisible or has a negative
+// color number, which some exporters write for text of switched-off template layers
+func (e TextEntity) Hidden() bool {
+	return e.Invisible || e.Color < 0
+}
+
+// visibleEntities returns the entities that are not Hidden
+func visibleEntities(entities []TextEntity) []TextEntity {
+	visible := newEntities(len(entities))
+	for _, entity := range entities {
+		if !entity.Hidden() {
+			visible = append(visible, entity)
+		}
+	}
+	return visible
+}
+
+// parseDXFFloat parses a numeric group value (codes 10/20/40 etc.).
+// Besides plain and scientific notation ("1.2345678901234E+06") it accepts Fortran-style
+// exponents ("1.5D+03") and a comma decimal separator ("1,5") written by some exporters.
+// NaN, Inf and out-of-range values are rejected so they never reach geometry code.
+func parseDXFFloat(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		normalized := strings.NewReplacer("D", "E", "d", "e").Replace(value)
+		if !strings.Contains(normalized, ".") {
+			normalized = strings.Replace(normalized, ",", ".", 1)
+		}
+		f, err = strconv.ParseFloat(normalized, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid numeric value %q", value)
+		}
+	}
+
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("numeric value %q out of range", value)
+	}
+	return f, nil
+}
+
+// parseGroupFloat parses a numeric group value, logging failures in debug mode
+// instead of silently leaving the field at zero
+func parseGroupFloat(groupCode, value string) (float64, bool) {
+	f, err := parseDXFFloat(value)
+	if err != nil {
+		debugPrint(fmt.Sprintf("[DEBUG] Group code %s: %v", groupCode, err))
+		return 0, false
+	}
+	return f, true
+}
+
+// appGroupFilter skips the 102 application groups of an entity ("{ACAD_REACTORS" ... "}",
+// "{ACAD_XDICTIONARY" or groups of third-party applications) and the 330/360 reactor and
+// owner handles. Codes inside an application group belong to the application: a 1 or 10/20
+// there is not the text or position of the entity. A new entity (code 0) ends an unclosed group.
+// The 360 values of a DICTIONARY outside application groups are its entries and are kept.
+type appGroupFilter struct {
+	open       bool
+	dictionary bool // the record is a DICTIONARY
+}
+
+// skip reports whether the code / value pair is part of an application group or a handle chain
+func (f *appGroupFilter) skip(code, value string) bool {
+	switch code {
+	case "0":
+		f.open = false
+		f.dictionary = value == "DICTIONARY" || value == "ACDBDICTIONARYWDFLT"
+		return false
+	case "102":
+		f.open = strings.HasPrefix(value, "{")
+		return true
+	case "330":
+		return true
+	case "360":
+		return f.open || !f.dictionary
+	}
+	return f.open
 }
 
 // DXFParser handles parsing of DXF files
 type DXFParser struct {
-	workers    int
-	chunkSize  int64
-	textBuffer []TextEntity
-	mutex      sync.RWMutex
+	workers       int
+	chunkSize     int64
+	scanBuffer    int
+	encoding      string
+	rawMText      bool
+	recover       bool
+	strict        bool
+	blockDepth    int
+	excludeColors []int
+	xdata         bool
+	whitespace    bool
+	rawContent    bool
+	includeHidden bool
+	layout        string
+	scope         ExtractionScope
+	textBuffer    []TextEntity
+	warnings      []string         // of the last parse
+	parseWarnings parseWarnings    // malformed pairs of the last parse
+	truncation    *TruncationError // of the last parse of a truncated file
+	mutex         sync.RWMutex
+	hooks         entityHooks // handlers registered for ParseEntities
+}
+
+// Parser defaults of NewDXFParser
+const (
+	defaultChunkSize  = 1024 * 1024 // 1MB chunks
+	defaultScanBuffer = 1024 * 1024 // longest line, 1MB
+)
+
+// ParseOptions tunes a DXFParser. Zero values use the defaults.
+type ParseOptions struct {
+	Workers    int   // concurrent chunk parsers (default: number of CPUs)
+	ChunkSize  int64 // minimum bytes per chunk; smaller content is parsed sequentially (default 1MB)
+	ScanBuffer int   // longest line kept whole, in bytes; longer lines are cut (default 1MB)
+
+	// Encoding of text values: "" or "auto" follows $DWGCODEPAGE, a code page name
+	// ("ANSI_1252", "cp1251", "gbk", "utf-8") overrides it
+	Encoding string
+
+	// RawMText keeps the inline formatting codes of MTEXT content (\P, {\fArial|b1;...}, \H2.5x;)
+	// instead of returning the plain text
+	RawMText bool
+
+	// Recover parses a file that ends inside a record up to that record instead of failing
+	// with a *TruncationError; the partial result is flagged by Truncation
+	Recover bool
+
+	// Strict fails a file with a structural problem with a *ParseError instead of parsing what
+	// can be read: malformed pairs, unknown sections, unbalanced SECTION / ENDSEC, or content
+	// ending without EOF, also with Recover. The file is checked in a pass before the parse.
+	Strict bool
+
+	// BlockDepth is the deepest chain of nested block references expanded (default 16); deeper
+	// references and blocks inserting themselves are not expanded and get a warning
+	BlockDepth int
+
+	// ExcludeColors drops text whose color, with ByLayer resolved through the LAYER table, is
+	// one of these ACI numbers, e.g. the grey of reference text
+	ExcludeColors []int
+
+	// IncludeHidden also returns the text flagged invisible (60, or the invisible flag of an
+	// attribute), e.g. an alternate language block switched off; it is skipped by default
+	IncludeHidden bool
+
+	// XData keeps the extended data (XDATA, groups 1000 and up) of the text in TextEntity.XData,
+	// keyed by application name
+	XData bool
+
+	// KeepWhitespace also returns the text of TEXT, MTEXT, ATTRIB and ATTDEF with the leading
+	// and trailing blanks of its values in TextEntity.Untrimmed, e.g. for tables aligned with
+	// spaces or fixed-width remark fields; Content stays trimmed
+	KeepWhitespace bool
+
+	// RawContent also returns the content values of the text as written in
+	// TextEntity.RawContent: untrimmed, with the escapes (\U+, %%d), MTEXT formatting codes and
+	// the <> of a DIMENSION override that Content decodes, converted to UTF-8 from the code page
+	// only. Tools writing or diffing DXF can round-trip the text with it.
+	RawContent bool
+
+	// Layout restricts the text to model space ("" or LayoutModel), to the paper space layout of
+	// this name, or keeps every space (LayoutAll). Paper space layouts often repeat model space
+	// text through viewports or as a copy, which would count table rows twice.
+	Layout string
+
+	// Scope selects the sections text is read from (default ScopeEntitiesBlocks): text-like
+	// records out of context, e.g. in OBJECTS, are skipped unless it is ScopeAll
+	Scope ExtractionScope
 }
 
 // NewDXFParser creates a new parser with specified number of workers
 func NewDXFParser(workers int) *DXFParser {
-	if workers <= 0 {
-		workers = runtime.NumCPU()
+	return NewDXFParserWithOptions(ParseOptions{Workers: workers})
+}
+
+// NewDXFParserWithOptions creates a parser with explicit chunk and scanner buffer sizes.
+// Large chunks suit big files on network shares, small ones local files that fit in a few chunks.
+func NewDXFParserWithOptions(opts ParseOptions) *DXFParser {
+	if opts.Workers <= 0 {
+		opts.Workers = runtime.NumCPU()
+	}
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = defaultChunkSize
+	}
+	if opts.ScanBuffer <= 0 {
+		opts.ScanBuffer = defaultScanBuffer
 	}
 	return &DXFParser{
-		workers:   workers,
-		chunkSize: 1024 * 1024, // 1MB chunks
+		workers:       opts.Workers,
+		chunkSize:     opts.ChunkSize,
+		scanBuffer:    opts.ScanBuffer,
+		encoding:      opts.Encoding,
+		rawMText:      opts.RawMText,
+		recover:       opts.Recover,
+		strict:        opts.Strict,
+		blockDepth:    blockDepthLimit(opts.BlockDepth),
+		excludeColors: opts.ExcludeColors,
+		xdata:         opts.XData,
+		whitespace:    opts.KeepWhitespace,
+		rawContent:    opts.RawContent,
+		includeHidden: opts.IncludeHidden,
+		layout:        opts.Layout,
+		scope:         opts.Scope,
 	}
 }
 
 // ParseFile parses a DXF file and extracts all text entities
 func (p *DXFParser) ParseFile(filename string) ([]TextEntity, error) {
+	return p.ParseFileContext(context.Background(), filename)
+}
+
+// ParseFileContext is ParseFile stopping when ctx is done. A cancelled parse returns the
+// entities of the part of the file read so far, in file order, and an error wrapping ctx.Err().
+func (p *DXFParser) ParseFileContext(ctx context.Context, filename string) ([]TextEntity, error) {
+	r, size, closeInput, err := openParseInput(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer closeInput()
+	return p.parseReaderAt(ctx, r, size)
+}
+
+// openParseInput opens the content of a file as the parse methods read it, with the function
+// closing it
+func openParseInput(filename string) (io.ReaderAt, int64, func() error, error) {
+	// Entries of ZIP archives ("package.zip!/drawing.dxf") and gzip files are read into memory
+	if !isPlainFile(filename) {
+		data, err := readInput(filename)
+		if err != nil {
+			return nil, 0, nil, fmt.Errorf("failed to open file: %w", err)
+		}
+		return bytes.NewReader(data), int64(len(data)), func() error { return nil }, nil
+	}
+
 	file, err := os.Open(filename)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %w", err)
+		return nil, 0, nil, fmt.Errorf("failed to open file: %w", err)
 	}
-	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+	return file, info.Size(), file.Close, nil
+}
+
+// ParseBytes parses DXF content held in memory, e.g. an uploaded file. Large content is
+// parsed in concurrent chunks like ParseFile.
+func (p *DXFParser) ParseBytes(data []byte) ([]TextEntity, error) {
+	return p.parseReaderAt(context.Background(), bytes.NewReader(data), int64(len(data)))
+}
+
+// ParseReader parses DXF content from r, e.g. an HTTP request body. r is read sequentially
+// to the end; use ParseBytes or ParseFile for concurrent parsing of large content. A strict
+// parser reads the content into memory to check it first.
+func (p *DXFParser) ParseReader(r io.Reader) ([]TextEntity, error) {
+	if p.strict {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("error reading file: %w", err)
+		}
+		return p.ParseBytes(data)
+	}
+	return p.parseSequential(context.Background(), r)
+}
+
+// Warnings returns the problems of the last parse that did not stop it, e.g. lines cut at the
+// scanner buffer size
+func (p *DXFParser) Warnings() []string {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.warnings
+}
+
+// Truncation returns where the file of the last ParseFile or ParseBytes ends inside a record,
+// nil for a complete file. With ParseOptions.Recover the entities before Offset were returned.
+func (p *DXFParser) Truncation() *TruncationError {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.truncation
+}
+
+// setTruncation records a truncated file and the partial result parsed from it
+func (p *DXFParser) setTruncation(truncation *TruncationError) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.truncation = truncation
+	if p.recover {
+		p.warnings = append(p.warnings, fmt.Sprintf("%v, partial result", truncation))
+	}
+}
+
+// restoreResult sets the warnings, parse warnings and truncation of a parse loaded from the
+// artifact store
+func (p *DXFParser) restoreResult(warnings []string, truncation *TruncationError, parsed []ParseWarning, parsedCount, proxies int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.warnings = warnings
+	p.truncation = truncation
+	p.parseWarnings = parseWarnings{list: parsed, count: parsedCount, proxies: proxies}
+}
+
+// setScanWarnings records the warnings of a parse that cut truncated lines
+func (p *DXFParser) setScanWarnings(truncated int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.warnings = nil
+	p.truncation = nil
+	p.parseWarnings = parseWarnings{}
+	if truncated > 0 {
+		p.warnings = append(p.warnings, fmt.Sprintf("%d lines longer than %d bytes cut (raise -scan-buffer)", truncated, p.scanBuffer))
+	}
+}
 
+// addWarnings records more warnings of the last parse
+func (p *DXFParser) addWarnings(warnings []string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.warnings = append(p.warnings, warnings...)
+}
+
+// parseReaderAt parses size bytes of r, concurrently if they span several chunks. Content
+// ending inside a record fails with a *TruncationError, or is parsed up to that record when
+// recovering.
+func (p *DXFParser) parseReaderAt(ctx context.Context, r io.ReaderAt, size int64) ([]TextEntity, error) {
+	return p.parseIndexedReaderAt(ctx, r, size, nil)
+}
+
+// parseIndexedReaderAt is parseReaderAt splitting concurrent chunks at the records of index,
+// if it is not nil
+func (p *DXFParser) parseIndexedReaderAt(ctx context.Context, r io.ReaderAt, size int64, index *EntityIndex) ([]TextEntity, error) {
 	p.textBuffer = make([]TextEntity, 0)
-	
-	// For now, always use sequential parsing to ensure correctness
-	// TODO: Fix concurrent parsing chunking logic for better performance
-	return p.parseSequential(file)
+
+	if p.strict {
+		if err := validateDXF(contextReader{ctx, io.NewSectionReader(r, 0, size)}, p.scanBuffer); err != nil {
+			p.setScanWarnings(0)
+			return nil, err
+		}
+	}
+
+	truncation, err := findTruncation(r, size)
+	if err != nil {
+		return nil, err
+	}
+	if truncation != nil {
+		if !p.recover {
+			p.setScanWarnings(0)
+			p.setTruncation(truncation)
+			return nil, truncation
+		}
+		size = truncation.Offset
+	}
+
+	// Content of a few chunks is not worth the goroutines
+	var entities []TextEntity
+	if p.workers > 1 && size >= 2*p.chunkSize {
+		entities, err = p.parseConcurrent(ctx, r, size, index)
+	} else {
+		entities, err = p.parseSequential(ctx, io.NewSectionReader(r, 0, size))
+	}
+	if truncation != nil {
+		p.setTruncation(truncation)
+	}
+	return entities, err
+}
+
+// parseSequential processes the content sequentially for smaller files
+func (p *DXFParser) parseSequential(ctx context.Context, r io.Reader) ([]TextEntity, error) {
+	encoding, err := newEncodingState(p.encoding)
+	if err != nil {
+		return nil, err
+	}
+	entities := newEntities(0)
+	expander := newBlockExpander(p.entityFilter(func(entity TextEntity) error {
+		entities = append(entities, entity)
+		return nil
+	}), p.blockDepth)
+	var warnings parseWarnings
+	truncated, err := scanTextEntities(contextReader{ctx, r}, p.scanBuffer, encoding, &sectionTracker{}, &warnings, p.plainText(p.scopeFilter(expander.add)))
+	p.setScanWarnings(truncated)
+	p.setParseWarnings(warnings)
+	p.addWarnings(expander.Warnings())
+	if err != nil {
+		if ctx.Err() != nil {
+			return entities, fmt.Errorf("parsing interrupted: %w", err)
+		}
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+	return entities, nil
+}
+
+// contextReader fails reads with ctx.Err() once ctx is done, which stops a scan at the next
+// buffer refill
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c contextReader) Read(b []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(b)
+}
+
+// ParseStream parses a DXF file and calls fn for every text entity in file order, without
+// keeping the entities in memory. Parsing stops at the first error returned by fn, which
+// ParseStream returns unchanged. Streams are always read sequentially; a strict parser reads
+// the file twice, to check it before the first entity is sent.
+func (p *DXFParser) ParseStream(filename string, fn func(TextEntity) error) error {
+	if p.strict {
+		if err := p.validateFile(filename); err != nil {
+			p.setScanWarnings(0)
+			return err
+		}
+	}
+	file, err := openInput(filename)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	encoding, err := newEncodingState(p.encoding)
+	if err != nil {
+		return err
+	}
+	var fnErr error
+	expander := newBlockExpander(p.entityFilter(func(entity TextEntity) error {
+		fnErr = fn(entity)
+		return fnErr
+	}), p.blockDepth)
+	var warnings parseWarnings
+	truncated, err := scanTextEntities(file, p.scanBuffer, encoding, &sectionTracker{}, &warnings, p.plainText(p.scopeFilter(expander.add)))
+	p.setScanWarnings(truncated)
+	p.setParseWarnings(warnings)
+	p.addWarnings(expander.Warnings())
+	if fnErr != nil {
+		return fnErr
+	}
+	if err != nil {
+		return fmt.Errorf("error reading file: %w", err)
+	}
+	return nil
+}
+
+// ParseChan parses a DXF file in a goroutine and sends the text entities in file order on the
+// returned channel, which is closed at the end of the file. The error channel receives at most
+// one error and is closed after the entity channel. The consumer must drain the entity channel.
+func (p *DXFParser) ParseChan(filename string, buffer int) (<-chan TextEntity, <-chan error) {
+	entities := make(chan TextEntity, buffer)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(entities)
+		if err := p.ParseStream(filename, func(entity TextEntity) error {
+			entities <- entity
+			return nil
+		}); err != nil {
+			errs <- err
+		}
+	}()
+	return entities, errs
+}
+
+// isTextRecord reports whether a record of the given type is read as a TextEntity
+func isTextRecord(recordType string) bool {
+	switch recordType {
+	case "TEXT", "MTEXT", "ATTRIB", "ATTDEF", "DIMENSION", "MULTILEADER", "ARCALIGNEDTEXT", "TOLERANCE":
+		return true
+	}
+	return false
+}
+
+// finish completes the entity after its last group and reports whether it has text to emit.
+// A DIMENSION shows its measurement where the text override (1) is empty or has "<>"; an
+// override of a single space suppresses the text.
+func (e *TextEntity) finish() bool {
+	if e.block != nil {
+		return true
+	}
+	e.finishLeader()
+	e.finishArc()
+	if e.justified() {
+		e.X, e.Y = e.align[0], e.align[1]
+	}
+	if e.EntityType == "DIMENSION" {
+		measurement := ""
+		if e.Measurement != 0 {
+			measurement = strconv.FormatFloat(math.Round(e.Measurement*1e4)/1e4, 'f', -1, 64)
+		}
+		switch {
+		case e.Content == "":
+			e.Content = measurement
+		case strings.TrimSpace(e.Content) == "":
+			e.Content = ""
+		default:
+			e.Content = strings.ReplaceAll(e.Content, "<>", measurement)
+		}
+	}
+	return strings.TrimSpace(e.Content) != ""
+}
+
+// justified reports whether single-line text is placed by its alignment point (11/21) rather
+// than its insertion point (10/20): any justification except left on the baseline, and except
+// aligned and fit, which run the text from the insertion point to the alignment point. Some
+// exporters write the insertion point as computed for the default font, or not at all.
+func (e TextEntity) justified() bool {
+	if len(e.align) != 2 {
+		return false
+	}
+	horizontal, vertical := e.justify[0], e.justify[1]
+	return (horizontal != 0 || vertical != 0) && horizontal != 3 && horizontal != 5
+}
+
+// keepBlankText returns the trimmed value of a group, but a blank text value (1), one with a
+// line that is not empty, as a single space: a DIMENSION text override of blanks suppresses the
+// text instead of showing the measurement
+func keepBlankText(code, trimmed string, blank bool) string {
+	if code == "1" && trimmed == "" && blank {
+		return " "
+	}
+	return trimmed
+}
+
+// addUntrimmed adds a text value (1, 3) of TEXT, MTEXT, ATTRIB or ATTDEF as read, untrimmed, to
+// Untrimmed; the trimmed value goes to Content through setGroup
+func (e *TextEntity) addUntrimmed(code, raw string) {
+	switch e.EntityType {
+	case "TEXT", "MTEXT", "ATTRIB", "ATTDEF":
+		if e.isContentCode(code) {
+			e.Untrimmed += decodeText(raw)
+		}
+	}
+}
+
+// isContentCode reports whether the values of a group code make up the content of the entity:
+// 1 and 3 of TEXT, MTEXT and ATTRIB, 1 of ATTDEF, DIMENSION, ARCALIGNEDTEXT and TOLERANCE, and
+// 304 in the context data of a MULTILEADER
+func (e *TextEntity) isContentCode(code string) bool {
+	switch e.EntityType {
+	case "TEXT", "MTEXT", "ATTRIB":
+		return code == "1" || code == "3"
+	case "ATTDEF", "DIMENSION", "ARCALIGNEDTEXT", "TOLERANCE":
+		return code == "1"
+	case "MULTILEADER":
+		return code == "304" && e.leader != nil && e.leader.section == "context"
+	}
+	return false
+}
+
+// isAttribute reports whether the entity is an ATTRIB or ATTDEF
+func (e TextEntity) isAttribute() bool {
+	return e.EntityType == "ATTRIB" || e.EntityType == "ATTDEF"
+}
+
+// setGroup applies one group code / value pair of a TEXT, MTEXT, ATTRIB, ATTDEF, DIMENSION,
+// MULTILEADER, ARCALIGNEDTEXT or TOLERANCE entity
+func (e *TextEntity) setGroup(code, value string) {
+	if e.setXDataGroup(code, value) {
+		return
+	}
+	if e.block != nil && e.setBlockGroup(code, value) {
+		return
+	}
+	if e.EntityType == "MULTILEADER" && e.setLeaderGroup(code, value) {
+		return
+	}
+	if e.EntityType == "ARCALIGNEDTEXT" && e.setArcGroup(code, value) {
+		return
+	}
+	dimension := e.EntityType == "DIMENSION"
+	singleLine := e.EntityType == "TEXT" || e.isAttribute()
+	directed := e.EntityType == "MTEXT" || e.EntityType == "TOLERANCE" // rotated by an X axis direction
+	switch code {
+	case "1", "3": // Text content; 3 is the prompt of an ATTDEF and the style of a DIMENSION or TOLERANCE
+		if code == "3" && (e.EntityType == "ATTDEF" || dimension || e.EntityType == "TOLERANCE") {
+			return
+		}
+		e.Content += decodeText(value)
+	case "11": // Text midpoint of a DIMENSION, which follows its definition point (10); X axis direction of an MTEXT
+		// or TOLERANCE; alignment point of justified single-line text
+		if x, ok := parseGroupFloat(code, value); ok && dimension {
+			e.X = x
+		} else if ok && directed {
+			e.directionX = x
+		} else if ok && singleLine {
+			e.align = []float64{x, e.Y}
+		}
+	case "21":
+		if y, ok := parseGroupFloat(code, value); ok && dimension {
+			e.Y = y
+		} else if ok && directed {
+			e.Rotation = geometry.Angle(e.directionX, y)
+		} else if ok && singleLine && len(e.align) == 2 {
+			e.align[1] = y
+		}
+	case "72": // Horizontal justification: 0 left, 1 center, 2 right, 3 aligned, 4 middle, 5 fit
+		if j, err := strconv.Atoi(value); err == nil && singleLine {
+			e.justify[0] = j
+		}
+	case "73", "74": // Vertical justification: 0 baseline, 1 bottom, 2 middle, 3 top; 74 of an attribute (73 is its field length)
+		if j, err := strconv.Atoi(value); err == nil && ((code == "73" && e.EntityType == "TEXT") || (code == "74" && e.isAttribute())) {
+			e.justify[1] = j
+		}
+	case "50": // Rotation in degrees, also for MTEXT; the dimension line angle of a DIMENSION
+		if r, ok := parseGroupFloat(code, value); ok && !dimension {
+			e.Rotation = normalizeRotation(r)
+		}
+	case "53": // Text rotation of a DIMENSION
+		if r, ok := parseGroupFloat(code, value); ok && dimension {
+			e.Rotation = normalizeRotation(r)
+		}
+	case "42": // Actual measurement of a DIMENSION
+		if m, ok := parseGroupFloat(code, value); ok && dimension {
+			e.Measurement = m
+		}
+	case "7": // Text style name
+		if !dimension {
+			e.Style = value
+		}
+	case "41": // Width factor; the reference rectangle width of an MTEXT
+		if w, ok := parseGroupFloat(code, value); ok && singleLine {
+			e.WidthFactor = w
+		}
+	case "2": // Attribute tag
+		if e.isAttribute() {
+			e.Tag = value
+		}
+	case "70": // Attribute flags: 1 = invisible
+		if flags, err := strconv.Atoi(value); err == nil && e.isAttribute() && flags&1 != 0 {
+			e.Invisible = true
+		}
+	case "8": // Layer
+		e.Layer = value
+	case "5": // Handle
+		e.Handle = strings.ToUpper(value)
+	case "67": // Space: 1 = paper space
+		e.PaperSpace = strings.TrimSpace(value) == "1"
+	case "410": // Layout name
+		e.Layout = value
+	case "10": // X coordinate
+		if x, ok := parseGroupFloat(code, value); ok {
+			e.X = x
+		}
+	case "20": // Y coordinate
+		if y, ok := parseGroupFloat(code, value); ok {
+			e.Y = y
+		}
+	case "30": // Z coordinate
+		if z, ok := parseGroupFloat(code, value); ok {
+			e.Z = z
+		}
+	case "31": // Text midpoint Z of a DIMENSION
+		if z, ok := parseGroupFloat(code, value); ok && dimension {
+			e.Z = z
+		}
+	case "40": // Text height; the leader length of a DIMENSION
+		if h, ok := parseGroupFloat(code, value); ok && !dimension {
+			e.Height = h
+		}
+	case "60": // Visibility: 1 = invisible
+		e.Invisible = value == "1"
+	case "62": // ACI color
+		if c, err := strconv.Atoi(value); err == nil {
+			e.Color = c
+		}
+	case "420": // True color
+		if c, err := strconv.Atoi(value); err == nil {
+			e.TrueColor = c
+		}
+	}
+}
+
+// lineScanner reads the lines of a DXF file like bufio.Scanner, but a line longer than maxLine
+// bytes does not stop the scan: its first maxLine bytes are kept and the rest is skipped.
+// MTEXT notes of several 100KB in one group 1 value lose their end instead of the drawing.
+// Lines are found in its read buffer and returned from there without a copy.
+type lineScanner struct {
+	reader     io.Reader
+	maxLine    int
+	buf        []byte // read buffer; the bytes not scanned yet are buf[start:end]
+	start, end int
+	base       int64  // input offset of buf[0]
+	offset     int64  // input offset of the last line
+	line       []byte // last line, in buf or in long
+	long       []byte // a line that does not fit into buf, up to maxLine bytes
+	err        error  // read error, reported once buf is scanned
+	truncated  int    // lines cut at maxLine
+	lines      int    // lines read
+}
+
+// lineBufferSize is the read buffer of a lineScanner
+const lineBufferSize = 64 * 1024
+
+// plainText wraps emit to strip the formatting codes of MTEXT content, which DIMENSION text
+// overrides and MULTILEADER content use as well, unless the parser keeps them; entities left without content, e.g. only
+// a font change, are not emitted. TOLERANCE frames get their GDT symbols and cell separators, see toleranceText.
+func (p *DXFParser) plainText(emit func(TextEntity) error) func(TextEntity) error {
+	if p.rawMText {
+		return emit
+	}
+	return func(entity TextEntity) error {
+		if entity.EntityType == "TOLERANCE" {
+			entity.Content = toleranceText(entity.Content)
+			if entity.Content == "" {
+				return nil
+			}
+		}
+		if entity.EntityType == "MTEXT" || entity.EntityType == "DIMENSION" || entity.EntityType == "MULTILEADER" {
+			entity.Content = stripMTextFormat(entity.Content)
+			entity.Untrimmed = stripMTextFormat(entity.Untrimmed)
+			if strings.TrimSpace(entity.Content) == "" {
+				return nil
+			}
+		}
+		return emit(entity)
+	}
+}
+
+// newLineScanner returns a line scanner of r keeping lines of up to maxLine bytes
+func newLineScanner(r io.Reader, maxLine int) *lineScanner {
+	return &lineScanner{reader: r, maxLine: maxLine, buf: make([]byte, lineBufferSize)}
+}
+
+// Scan advances to the next line, which is then available through Text and Bytes. It
+// returns false at the end of the input or on a read error.
+func (s *lineScanner) Scan() bool {
+	s.long = s.long[:0]
+	s.offset = s.base + int64(s.start)
+	long, cut := false, false
+	// keep adds a part of a long line, up to maxLine bytes
+	keep := func(part []byte) {
+		if room := s.maxLine - len(s.long); len(part) > room {
+			part, cut = part[:room], true
+		}
+		s.long = append(s.long, part...)
+	}
+	for {
+		window := s.buf[s.start:s.end]
+		if i := indexNewline(window); i >= 0 {
+			s.start += i + 1
+			if !long && i+1 <= s.maxLine {
+				// A whole line in the read buffer, the common case, is not copied
+				s.line = window[:i+1]
+			} else {
+				keep(window[:i+1])
+				s.line = s.long
+			}
+			break
+		}
+		if s.err != nil {
+			// The last line has no line ending
+			s.start = s.end
+			if len(window) == 0 && !long {
+				return false
+			}
+			keep(window)
+			s.line = s.long
+			break
+		}
+		if s.start == 0 && s.end == len(s.buf) {
+			// The line does not fit into the buffer
+			keep(window)
+			long = true
+			s.base += int64(s.end)
+			s.end = 0
+		} else {
+			s.base += int64(s.start)
+			s.end = copy(s.buf, window)
+		}
+		s.start = 0
+		n, err := s.reader.Read(s.buf[s.end:])
+		s.end += n
+		s.err = err
+	}
+	if cut {
+		s.truncated++
+		// Do not end the kept part inside a UTF-8 sequence
+		for i := 1; i <= utf8.UTFMax && i <= len(s.line); i++ {
+			if tail := s.line[len(s.line)-i:]; utf8.RuneStart(tail[0]) {
+				if !utf8.FullRune(tail) {
+					s.line = s.line[:len(s.line)-i]
+				}
+				break
+			}
+		}
+	}
+	if n := len(s.line); n > 0 && s.line[n-1] == '\n' {
+		s.line = s.line[:n-1]
+	}
+	if n := len(s.line); n > 0 && s.line[n-1] == '\r' {
+		s.line = s.line[:n-1]
+	}
+	s.lines++
+	return true
+}
+
+// Bytes returns the last line read, without its line ending. The bytes may point into the
+// read buffer and are only valid until the next Scan.
+func (s *lineScanner) Bytes() []byte {
+	return s.line
 }
 
-// parseSequential processes the file sequentially for smaller files
-func (p *DXFParser) parseSequential(file *os.File) ([]TextEntity, error) {
-	scanner := bufio.NewScanner(file)
-	entities := make([]TextEntity, 0)
-	
+// indexNewline returns the index of the first '\n' in b, -1 if there is none. DXF lines are
+// mostly a few bytes long, shorter than the setup of bytes.IndexByte pays off for.
+func indexNewline(b []byte) int {
+	for i := 0; i < len(b) && i < 16; i++ {
+		if b[i] == '\n' {
+			return i
+		}
+	}
+	if len(b) <= 16 {
+		return -1
+	}
+	if i := bytes.IndexByte(b[16:], '\n'); i >= 0 {
+		return i + 16
+	}
+	return -1
+}
+
+// Line returns the 1-based number of the last line read
+func (s *lineScanner) Line() int {
+	return s.lines
+}
+
+// Offset returns the byte offset of the last line read from the start of the input
+func (s *lineScanner) Offset() int64 {
+	return s.offset
+}
+
+// Text returns the last line read, without its line ending
+func (s *lineScanner) Text() string {
+	return string(s.line)
+}
+
+// stringTable returns one string for all lines with the same bytes, for the group codes and
+// record names that repeat throughout a file. Looking a line up does not allocate.
+type stringTable map[string]string
+
+// maxTableStrings bounds a table, against content of other lines taken for group codes
+const maxTableStrings = 4096
+
+// groupCodeNames are the strings of the group codes 0 to 1071, which a table returns without
+// a lookup
+var groupCodeNames = func() []string {
+	names := make([]string, 1072)
+	for code := range names {
+		names[code] = strconv.Itoa(code)
+	}
+	return names
+}()
+
+// get returns the string of line
+func (t stringTable) get(line []byte) string {
+	if n := len(line); n > 0 && n <= 4 && (line[0] != '0' || n == 1) {
+		code := 0
+		for _, c := range line {
+			if c < '0' || c > '9' {
+				code = -1
+				break
+			}
+			code = code*10 + int(c-'0')
+		}
+		if code >= 0 && code < len(groupCodeNames) {
+			return groupCodeNames[code]
+		}
+	}
+	if s, ok := t[string(line)]; ok {
+		return s
+	}
+	s := string(line)
+	if len(t) < maxTableStrings {
+		t[s] = s
+	}
+	return s
+}
+
+// Err returns the first read error other than io.EOF
+func (s *lineScanner) Err() error {
+	if s.err == io.EOF {
+		return nil
+	}
+	return s.err
+}
+
+// Truncated returns the number of lines cut at the maximum line length so far
+func (s *lineScanner) Truncated() int {
+	return s.truncated
+}
+
+// scanTextEntities reads DXF group code / value pairs from r and calls emit for every TEXT,
+// MTEXT, ATTRIB, ATTDEF, DIMENSION, MULTILEADER, ARCALIGNEDTEXT and TOLERANCE entity with content. It is the state machine shared by all text parsing paths; the
+// state is reset at every code 0, so any part of a file starting at a code 0 can be scanned.
+// Records are only recognized by the value of a code 0, and every record is tagged with the
+// section sections follows it in, which the scan leaves at the section the content ends in.
+// maxLine is the longest line kept, in bytes; the number of longer lines, which are cut, is
+// returned. Text values are decoded with encoding, which follows the HEADER of the content.
+// Group codes that are no number, non-numeric values of the floating point groups of text
+// records and a group code without value at the end are added to warnings, and the proxy
+// entities, whose payload is skipped, are counted there.
+func scanTextEntities(r io.Reader, maxLine int, encoding encodingState, sections *sectionTracker, warnings *parseWarnings, emit func(TextEntity) error) (int, error) {
+	scanner := newLineScanner(r, maxLine)
+
 	currentEntity := &TextEntity{}
 	inTextEntity := false
 	expectingValue := false
 	lastGroupCode := ""
+	pairCode := "" // group code of every pair, for the HEADER variables
+	var groups appGroupFilter
+	embedded := false // in the embedded MTEXT (101) of a multiline attribute, which repeats its text
+	ended := false    // after the EOF record, where blank lines may follow
+	var proxies proxySkipper
 
+	// The lines are read as bytes; group codes and record names come from a table and other
+	// values only become strings where they are used: in text records, for the section and
+	// HEADER state, and the raw line for untrimmed and raw content when it has blanks
+	names := make(stringTable)
 	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		
+		raw := scanner.Bytes()
+		trimmed := bytes.TrimSpace(raw)
+		if proxies.active {
+			if proxies.skip(string(trimmed)) {
+				continue
+			}
+			// The record after the proxy
+			pairCode, lastGroupCode, expectingValue = "0", "", true
+		}
+
 		if !expectingValue {
+			code := names.get(trimmed)
+			if proxies.payload(code) {
+				continue
+			}
+			pairCode = code
 			// This is a group code
-			if line == "0" {
+			if !isGroupCode(code) && !ended {
+				warnings.add(scanner.Line(), code, "group code is not a number")
+			}
+			if code == "0" {
 				// Start of new entity
-				if inTextEntity && currentEntity.Content != "" {
-					entities = append(entities, *currentEntity)
+				if inTextEntity && currentEntity.finish() {
+					if err := emit(*currentEntity); err != nil {
+						return scanner.Truncated(), err
+					}
 				}
-				currentEntity = &TextEntity{}
+				*currentEntity = TextEntity{}
 				inTextEntity = false
+				embedded = false
+				groups = appGroupFilter{}
 			} else if inTextEntity {
-				lastGroupCode = line
+				lastGroupCode = code
 			}
 			expectingValue = true
 		} else {
 			// This is a value
-			if lastGroupCode == "" && (line == "TEXT" || line == "MTEXT") {
+			var line string
+			switch {
+			case pairCode == "0", inTextEntity && (pairCode == "7" || pairCode == "8" || pairCode == "100"):
+				line = names.get(trimmed) // record, style, layer and class names repeat
+			case inTextEntity, pairCode == "1", pairCode == "2", pairCode == "3", pairCode == "9":
+				line = string(trimmed)
+			}
+			value := line // trimmed, before decoding
+			sections.pair(pairCode, line)
+			ended = ended || (pairCode == "0" && line == "EOF")
+			line = keepBlankText(lastGroupCode, line, len(raw) > 0)
+			if pairCode == "0" {
+				proxies.enter(line)
+			}
+			if pairCode == "0" && (isTextRecord(line) || isBlockRecord(line) || isLayerRecord(line)) {
 				inTextEntity = true
-				currentEntity.EntityType = line
-			} else if inTextEntity {
-				switch lastGroupCode {
-				case "1", "3": // Text content
-					decodedLine := decodeUnicode(line)
-					if currentEntity.Content == "" {
-						currentEntity.Content = decodedLine
-					} else {
-						currentEntity.Content += decodedLine
-					}
-				case "8": // Layer
-					currentEntity.Layer = line
-				case "10": // X coordinate
-					if x, err := strconv.ParseFloat(line, 64); err == nil {
-						currentEntity.X = x
-					}
-				case "20": // Y coordinate
-					if y, err := strconv.ParseFloat(line, 64); err == nil {
-						currentEntity.Y = y
-					}
-				case "40": // Text height
-					if h, err := strconv.ParseFloat(line, 64); err == nil {
-						currentEntity.Height = h
-					}
+				if isBlockRecord(line) || isLayerRecord(line) {
+					*currentEntity = newBlockRecord(line)
+				} else {
+					currentEntity.EntityType = line
+					currentEntity.Color = colorByLayer
+				}
+				currentEntity.section = sections.name
+			} else if inTextEntity && isXDataCode(lastGroupCode) {
+				// XDATA follows the embedded MTEXT of a multiline attribute
+				warnings.checkFloat(scanner.Line()-1, lastGroupCode, line)
+				if lastGroupCode == "1000" {
+					line = encoding.decode(line)
 				}
+				currentEntity.setXDataGroup(lastGroupCode, line)
+			} else if lastGroupCode == "101" {
+				embedded = true
+			} else if inTextEntity && !embedded && !groups.skip(lastGroupCode, line) {
+				warnings.checkFloat(scanner.Line()-1, lastGroupCode, line)
+				untrimmed := value
+				if len(trimmed) != len(raw) {
+					untrimmed = string(raw)
+				}
+				if lastGroupCode == "1" || lastGroupCode == "3" {
+					line = encoding.decode(line)
+					currentEntity.addUntrimmed(lastGroupCode, encoding.decode(untrimmed))
+				}
+				if currentEntity.isContentCode(lastGroupCode) {
+					currentEntity.RawContent += encoding.transcode(untrimmed)
+				}
+				currentEntity.setGroup(lastGroupCode, line)
+			} else if !inTextEntity {
+				encoding.observe(pairCode, line)
 			}
 			expectingValue = false
 			lastGroupCode = ""
 		}
 	}
 
-	// Add the last entity if it's valid
-	if inTextEntity && currentEntity.Content != "" {
-		entities = append(entities, *currentEntity)
+	if expectingValue && !ended && scanner.Err() == nil {
+		warnings.add(scanner.Line(), pairCode, "group code without value at the end")
 	}
+	warnings.lines = scanner.Line()
+	warnings.proxies = proxies.entities
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading file: %w", err)
+	// Add the last entity if it's valid
+	if inTextEntity && currentEntity.finish() {
+		if err := emit(*currentEntity); err != nil {
+			return scanner.Truncated(), err
+		}
 	}
 
-	return entities, nil
+	return scanner.Truncated(), scanner.Err()
 }
 
-// parseConcurrent processes large files using multiple goroutines
-func (p *DXFParser) parseConcurrent(file *os.File, fileSize int64) ([]TextEntity, error) {
+// parseConcurrent processes large files using multiple goroutines. Chunks start at a code 0
+// line, where the parser state is reset, so parsing them separately and concatenating the
+// results in chunk order gives the entities of parseSequential in file order. A chunk found by
+// calculateChunks does not know the section it starts in; mergeChunks resolves it from the
+// chunks before. With an index the chunks start at its records and know their section.
+// A cancelled parse returns the entities of the chunks up to the first interrupted one.
+func (p *DXFParser) parseConcurrent(ctx context.Context, file io.ReaderAt, fileSize int64, index *EntityIndex) ([]TextEntity, error) {
 	// Calculate chunk boundaries ensuring we don't split entities
-	chunks := p.calculateChunks(file, fileSize)
-	
-	// Channel to collect results
-	resultChan := make(chan []TextEntity, len(chunks))
-	errorChan := make(chan error, len(chunks))
-	
+	var chunks []Chunk
+	var err error
+	if index != nil {
+		chunks = index.chunks(p.workers, p.chunkSize, fileSize)
+	} else if chunks, err = p.calculateChunks(file, fileSize); err != nil {
+		return nil, err
+	}
+
+	// Only the first chunk holds the HEADER; the others start with its code page
+	encoding, err := newEncodingState(p.encoding)
+	if err != nil {
+		return nil, err
+	}
+	encoding = detectEncoding(io.NewSectionReader(file, 0, fileSize), p.scanBuffer, encoding)
+
+	// One result slot per chunk keeps the file order
+	results := make([][]TextEntity, len(chunks))
+	sections := make([]sectionTracker, len(chunks))
+	truncated := make([]int, len(chunks))
+	chunkWarnings := make([]parseWarnings, len(chunks))
+	errs := make([]error, len(chunks))
+
 	// WaitGroup to synchronize goroutines
 	var wg sync.WaitGroup
-	
-	// Process chunks concurrently
-	for _, chunk := range chunks {
+
+	// Process chunks concurrently; parseChunk only uses ReadAt, which is safe for concurrent use
+	for i, chunk := range chunks {
 		wg.Add(1)
-		go func(start, end int64) {
+		go func(i int, chunk Chunk) {
 			defer wg.Done()
-			
-			entities, err := p.parseChunk(file, start, end)
-			if err != nil {
-				errorChan <- err
-				return
-			}
-			resultChan <- entities
-		}(chunk.start, chunk.end)
+			sections[i].name = chunk.section
+			results[i], truncated[i], errs[i] = p.parseChunk(ctx, file, chunk.start, chunk.end, encoding, &sections[i], &chunkWarnings[i])
+		}(i, chunk)
 	}
-	
-	// Close channels when all goroutines complete
-	go func() {
-		wg.Wait()
-		close(resultChan)
-		close(errorChan)
-	}()
-	
-	// Check for errors
-	select {
-	case err := <-errorChan:
-		return nil, err
-	default:
+	wg.Wait()
+
+	truncatedLines := 0
+	for _, n := range truncated {
+		truncatedLines += n
 	}
-	
-	// Collect results
-	var allEntities []TextEntity
-	for entities := range resultChan {
-		allEntities = append(allEntities, entities...)
+	p.setScanWarnings(truncatedLines)
+
+	// Warnings are numbered by the lines of their chunk; merging counts the lines before
+	var parsed parseWarnings
+	for _, w := range chunkWarnings {
+		parsed.merge(w)
+	}
+	p.setParseWarnings(parsed)
+
+	total := 0
+	for i := range chunks {
+		if errs[i] != nil {
+			if ctx.Err() == nil {
+				return nil, errs[i]
+			}
+			// Keep the file order: nothing after the interrupted chunk
+			partial := p.mergeChunks(results[:i+1], sections)
+			releaseChunks(results)
+			merged := partial
+			partial, warnings := expandBlocks(partial, p.blockDepth)
+			ReleaseEntities(merged)
+			p.addWarnings(warnings)
+			return p.filterEntities(partial), fmt.Errorf("parsing interrupted: %w", ctx.Err())
+		}
+		total += len(results[i])
 	}
-	
+
+	// Merge in file order; the blocks defined in one chunk are inserted in others
+	// The chunk and merged slices are copied from and go back to the pool
+	merged := p.mergeChunks(results, sections)
+	releaseChunks(results)
+	allEntities, warnings := expandBlocks(merged, p.blockDepth)
+	ReleaseEntities(merged)
+	p.addWarnings(warnings)
+	allEntities = p.filterEntities(allEntities)
+
+	debugPrint(fmt.Sprintf("[DEBUG] Parsed %d chunks with %d workers: %d text entities", len(chunks), p.workers, total))
 	return allEntities, nil
 }
 
+// releaseChunks releases the entities of the chunks of a parse once they are merged
+func releaseChunks(results [][]TextEntity) {
+	for _, entities := range results {
+		ReleaseEntities(entities)
+	}
+}
+
 // Chunk represents a portion of the file to process
 type Chunk struct {
 	start, end int64
+	section    string // section at start, sectionUnknown if the chunk does not know it
 }
 
-// calculateChunks divides the file into chunks that don't split entities
-func (p *DXFParser) calculateChunks(file *os.File, fileSize int64) []Chunk {
+// calculateChunks divides the file into one chunk per worker (at least chunkSize bytes each);
+// every chunk but the first starts at a code 0 line so no entity is split
+func (p *DXFParser) calculateChunks(file io.ReaderAt, fileSize int64) ([]Chunk, error) {
 	numChunks := p.workers
 	if numChunks > int(fileSize/p.chunkSize) {
 		numChunks = int(fileSize/p.chunkSize) + 1
 	}
-	
+
 	if numChunks <= 1 {
-		return []Chunk{{0, fileSize}}
+		return []Chunk{{0, fileSize, ""}}, nil
 	}
-	
+
 	chunks := make([]Chunk, 0, numChunks)
 	chunkSize := fileSize / int64(numChunks)
-	
-	for i := 0; i < numChunks; i++ {
-		start := int64(i) * chunkSize
-		end := start + chunkSize
-		
-		if i == numChunks-1 {
-			end = fileSize
-		} else {
-			// Adjust end to not split entities
-			end = p.findSafeChunkEnd(file, end)
+
+	start := int64(0)
+	for i := 1; i < numChunks && start < fileSize; i++ {
+		target := int64(i) * chunkSize
+		if target <= start {
+			continue
 		}
-		
-		if start < end {
-			chunks = append(chunks, Chunk{start, end})
+		end, err := p.findSafeChunkEnd(file, target, fileSize)
+		if err != nil {
+			return nil, err
 		}
+		if end > start {
+			chunks = append(chunks, Chunk{start, end, chunkSection(start)})
+			start = end
+		}
+	}
+	if start < fileSize {
+		chunks = append(chunks, Chunk{start, fileSize, chunkSection(start)})
 	}
-	
-	return chunks
+
+	return chunks, nil
 }
 
-// findSafeChunkEnd finds a safe place to end a chunk (after an entity boundary)
-func (p *DXFParser) findSafeChunkEnd(file *os.File, position int64) int64 {
-	// Seek to the position
-	file.Seek(position, 0)
-	scanner := bufio.NewScanner(file)
-	
-	// Look for the next "0" group code that starts a new entity
-	for scanner.Scan() {
-		position += int64(len(scanner.Bytes()) + 1) // +1 for newline
-		line := strings.TrimSpace(scanner.Text())
-		
-		if line == "0" {
-			// Read the next line to see if it's an entity start
-			if scanner.Scan() {
-				nextLine := strings.TrimSpace(scanner.Text())
-				position += int64(len(scanner.Bytes()) + 1)
-				
-				if nextLine == "SECTION" || nextLine == "ENDSEC" || nextLine == "EOF" {
-					return position
-				}
-			}
-			return position
-		}
+// chunkSection is the section of a chunk found by calculateChunks: none at the start of the
+// file, unknown at a record further on
+func chunkSection(start int64) string {
+	if start == 0 {
+		return ""
 	}
-	
-	return position
+	return sectionUnknown
 }
 
-// parseChunk processes a specific chunk of the file
-func (p *DXFParser) parseChunk(file *os.File, start, end int64) ([]TextEntity, error) {
-	// Create a section reader for this chunk
-	section := io.NewSectionReader(file, start, end-start)
-	scanner := bufio.NewScanner(section)
-	
-	entities := make([]TextEntity, 0)
-	currentEntity := &TextEntity{}
-	inTextEntity := false
-	expectingValue := false
-	lastGroupCode := ""
-	
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		
-		if !expectingValue {
-			// This is a group code
-			if line == "0" {
-				// Start of new entity
-				if inTextEntity && currentEntity.Content != "" {
-					entities = append(entities, *currentEntity)
-				}
-				currentEntity = &TextEntity{}
-				inTextEntity = false
-			} else if inTextEntity {
-				lastGroupCode = line
+// findSafeChunkEnd returns the offset of the first code 0 line at or after position, or
+// fileSize if there is none. A code 0 line is a "0" line followed by a record name
+// (SECTION, TEXT, ENDSEC, ...); a "0" value is always followed by a numeric group code,
+// so it is never mistaken for one, whatever the code / value alignment at position.
+func (p *DXFParser) findSafeChunkEnd(file io.ReaderAt, position, fileSize int64) (int64, error) {
+	reader := bufio.NewReader(io.NewSectionReader(file, position, fileSize-position))
+
+	// Skip the rest of the line position falls into, unless it starts a line
+	offset := position
+	if position > 0 {
+		var previous [1]byte
+		if _, err := file.ReadAt(previous[:], position-1); err != nil {
+			return 0, fmt.Errorf("error reading chunk boundary: %w", err)
+		}
+		if previous[0] != '\n' {
+			skipped, err := reader.ReadString('\n')
+			offset += int64(len(skipped))
+			if err == io.EOF {
+				return fileSize, nil
+			} else if err != nil {
+				return 0, fmt.Errorf("error reading chunk boundary: %w", err)
 			}
-			expectingValue = true
-		} else {
-			// This is a value
-			if lastGroupCode == "" && (line == "TEXT" || line == "MTEXT") {
-				inTextEntity = true
-				currentEntity.EntityType = line
-			} else if inTextEntity {
-				switch lastGroupCode {
-				case "1", "3": // Text content
-					decodedLine := decodeUnicode(line)
-					if currentEntity.Content == "" {
-						currentEntity.Content = decodedLine
-					} else {
-						currentEntity.Content += decodedLine
-					}
-				case "8": // Layer
-					currentEntity.Layer = line
-				case "10": // X coordinate
-					if x, err := strconv.ParseFloat(line, 64); err == nil {
-						currentEntity.X = x
-					}
-				case "20": // Y coordinate
-					if y, err := strconv.ParseFloat(line, 64); err == nil {
-						currentEntity.Y = y
-					}
-				case "40": // Text height
-					if h, err := strconv.ParseFloat(line, 64); err == nil {
-						currentEntity.Height = h
-					}
-				}
+		}
+	}
+
+	prevLine, prevStart := "", int64(-1)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			trimmed := strings.TrimSpace(line)
+			if prevLine == "0" && isRecordName(trimmed) {
+				return prevStart, nil
 			}
-			expectingValue = false
-			lastGroupCode = ""
+			prevLine, prevStart = trimmed, offset
+			offset += int64(len(line))
+		}
+		if err == io.EOF {
+			return fileSize, nil
+		} else if err != nil {
+			return 0, fmt.Errorf("error reading chunk boundary: %w", err)
 		}
 	}
-	
-	// Add the last entity if it's valid
-	if inTextEntity && currentEntity.Content != "" {
-		entities = append(entities, *currentEntity)
+}
+
+// isRecordName reports whether value can follow a code 0: an upper case record name such as
+// TEXT, ACAD_TABLE or 3DFACE. Group codes are all digits, so they never qualify.
+func isRecordName(value string) bool {
+	hasLetter := false
+	for _, r := range value {
+		switch {
+		case r >= 'A' && r <= 'Z', r == '_':
+			hasLetter = true
+		case r >= '0' && r <= '9':
+		default:
+			return false
+		}
 	}
-	
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading chunk: %w", err)
+	return hasLetter
+}
+
+// parseChunk processes a specific chunk of the file and returns its entities and the number
+// of lines cut at the scanner buffer size; sections starts at the section of the chunk start
+func (p *DXFParser) parseChunk(ctx context.Context, file io.ReaderAt, start, end int64, encoding encodingState, sections *sectionTracker, warnings *parseWarnings) ([]TextEntity, int, error) {
+	// Create a section reader for this chunk
+	section := io.NewSectionReader(file, start, end-start)
+
+	entities := newEntities(0)
+	truncated, err := scanTextEntities(contextReader{ctx, section}, p.scanBuffer, encoding, sections, warnings, p.plainText(func(entity TextEntity) error {
+		entities = append(entities, entity)
+		return nil
+	}))
+	if err != nil {
+		if ctx.Err() != nil {
+			return entities, truncated, err
+		}
+		return nil, truncated, fmt.Errorf("error reading chunk: %w", err)
 	}
-	
-	return entities, nil
+
+	return entities, truncated, nil
 }
 
-func main() {
+// Main runs the dxf_parser command line with os.Args; cmd/dxf_parser is the binary
+func Main() {
 	if len(os.Args) > 1 && os.Args[1] == "bom" {
 		// Remove "bom" from args and run BOM extractor
 		os.Args = append([]string{os.Args[0]}, os.Args[2:]...)
 		bomMain()
 	} else {
+		// The artifact store of the other commands; bom also takes -store
+		if err := useArtifactStore(os.Getenv(artifactStoreEnv)); err != nil {
+			fmt.Fprintln(os.Stderr, msg("config.error", err))
+			os.Exit(exitConfigError)
+		}
 		// Run the original DXF parser CLI
 		runCLI()
 	}
@@ -0,0 +1,101 @@
+package dxfparser
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseDXFFloat(t *testing.T) {
+	cases := []struct {
+		value string
+		want  float64
+		ok    bool
+	}{
+		// Plain and scientific notation
+		{"1.5", 1.5, true},
+		{"-0.25", -0.25, true},
+		{"0", 0, true},
+		{"1.2345678901234E+06", 1234567.8901234, true},
+		{"2.5e-3", 0.0025, true},
+
+		// Fortran-style D exponents
+		{"1.5D+03", 1500, true},
+		{"2.5d-2", 0.025, true},
+		{"-4D2", -400, true},
+
+		// Comma decimal separator, also with a D exponent
+		{"1,5", 1.5, true},
+		{"-0,75", -0.75, true},
+		{"1,5D+02", 150, true},
+		{"1.000,5", 0, false}, // a comma after a decimal point is no separator
+
+		// Leading and trailing white space of the group value line
+		{"  42  ", 42, true},
+		{"\t-3.25\r", -3.25, true},
+		{" 1,5 ", 1.5, true},
+
+		// NaN and Inf never reach geometry code
+		{"NaN", 0, false},
+		{"nan", 0, false},
+		{"Inf", 0, false},
+		{"+Inf", 0, false},
+		{"-Infinity", 0, false},
+
+		// Out of range
+		{"1e309", 0, false},
+		{"-1E400", 0, false},
+		{"1D400", 0, false},
+		{"1e-400", 0, true}, // underflow rounds to zero
+
+		// Not numbers
+		{"", 0, false},
+		{"   ", 0, false},
+		{"abc", 0, false},
+		{"1.5E", 0, false},
+		{"1.5 2.5", 0, false},
+	}
+	for _, c := range cases {
+		got, err := parseDXFFloat(c.value)
+		if ok := err == nil; ok != c.ok {
+			t.Errorf("parseDXFFloat(%q) error = %v, want ok %v", c.value, err, c.ok)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("parseDXFFloat(%q) = %v, want %v", c.value, got, c.want)
+		}
+	}
+}
+
+func TestParseTextRotation(t *testing.T) {
+	cases := []struct {
+		entity  string
+		value   string
+		want    float64
+		rotated bool
+	}{
+		{"TEXT", "90", 90, true},
+		{"MTEXT", "90", 90, true},
+		{"MTEXT", "-90", 270, true},
+		{"MTEXT", "450", 90, true},
+		{"MTEXT", "0.5", 0.5, false},
+		{"MTEXT", "0", 0, false},
+	}
+	for _, c := range cases {
+		content := "0\nSECTION\n2\nENTITIES\n0\n" + c.entity + "\n8\n0\n10\n1\n20\n2\n40\n2.5\n1\nNOTE\n50\n" + c.value +
+			"\n0\nENDSEC\n0\nEOF\n"
+		entities, err := NewDXFParser(1).ParseBytes([]byte(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entities) != 1 {
+			t.Errorf("%s 50=%s: %d entities, want 1", c.entity, c.value, len(entities))
+			continue
+		}
+		if got := entities[0].Rotation; math.Abs(got-c.want) > 1e-9 {
+			t.Errorf("%s 50=%s: Rotation = %v, want %v", c.entity, c.value, got, c.want)
+		}
+		if got := entities[0].Rotated(); got != c.rotated {
+			t.Errorf("%s 50=%s: Rotated() = %v, want %v", c.entity, c.value, got, c.rotated)
+		}
+	}
+}
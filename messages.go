@@ -0,0 +1,297 @@
+package dxfparser
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// defaultLanguage is used when no catalog matches the environment and for missing translations
+const defaultLanguage = "en"
+
+// messageCatalog holds the user-facing CLI strings per language, keyed by message id.
+// Debug logs and CSV headers are intentionally not translated: they stay English so
+// logs can be shared with support and output files stay compatible with downstream tools.
+var messageCatalog = map[string]map[string]string{
+	"en": {
+		// General CLI
+		"cli.title":           "DXF Text Parser - High-performance text extraction from DXF files",
+		"cli.usage":           "Usage:",
+		"cli.spatial_cmds":    "Spatial Commands:",
+		"cli.examples":        "Examples:",
+		"cli.cmd.parse":       "Parse DXF file and show results",
+		"cli.cmd.spatial":     "Run spatial analysis",
+		"cli.cmd.benchmark":   "Run performance benchmarks",
+		"cli.cmd.bom":         "Extract BOM and cut lengths",
+		"cli.cmd.orientation": "Segment angle/length histograms (JSON/CSV)",
+		"cli.cmd.outline":     "Show sections, entity counts, blocks and layers as a tree",
+		"cli.cmd.header":      "Show version, units, measurement and extents from the HEADER",
+		"cli.cmd.layers":      "List the layers with color, on/off/frozen state and entity counts",
+		"cli.cmd.replay":      "Re-run one drawing with trace and overlay",
+		"cli.cmd.report":      "Compare totals across recorded runs",
+		"cli.cmd.pivot":       "Pivot materials and welds of a run by category, class and area",
+		"cli.cmd.eval":        "Measure extraction accuracy on a labeled corpus",
+		"cli.cmd.serve":       "Watch a directory and show job status in a web UI",
+		"cli.cmd.daemon":      "Run the extraction service with a persistent job queue and job API",
+		"cli.cmd.export":      "Write the built-in default config (.dxfparser.yaml) for customization",
+		"cli.cmd.config":      "Check project config files for errors before a batch run",
+		"cli.cmd.version":     "Show the tool version",
+		"cli.cmd.help":        "Show this help message",
+		"cli.spatial.stats":   "Show entity statistics",
+		"cli.spatial.near":    "Find entities near text",
+		"cli.spatial.range":   "Find entities in coordinate range",
+		"cli.spatial.quad":    "Find entities in top-right quadrant of text",
+		"cli.unknown_command": "Unknown command: %s",
+		"cli.unknown_spatial": "Unknown spatial command: %s",
+		"cli.missing_file":    "Error: Missing DXF file argument",
+		"cli.missing_spatial": "Error: Missing arguments for spatial command",
+		"cli.invalid_dist":    "Error: Invalid distance value: %s",
+		"cli.invalid_coords":  "Error: Invalid coordinate values",
+		"cli.invalid_workers": "Error: Invalid worker count: %s (must be a positive integer)",
+		"cli.unexpected_args": "Error: Unexpected arguments: %s",
+		"cli.parsing":         "Parsing DXF file: %s",
+		"cli.using_workers":   "Using %d workers",
+		"cli.parse_done":      "Parsing completed in: %v",
+		"cli.indexed":         "Indexed %d records in %v",
+		"cli.parse_warning":   "Warning: %s",
+		"cli.parse_skipped":   "Skipped %d malformed group code / value pairs:",
+		"cli.parse_proxies":   "%d proxy entities (graphics not read)",
+		"cli.found_entities":  "Found %d text entities",
+		"cli.first_entities":  "First %d text entities:",
+		"cli.more_entities":   "... and %d more entities",
+		"cli.zip_entry":       "%s: %d text entities in %v",
+		"cli.zip_entries":     "Parsing %d DXF files in archive: %s",
+		"cli.found":           "Found %d entities:",
+		"cli.none_near":       "No entities found near the specified text.",
+		"cli.none_range":      "No entities found in the specified range.",
+		"cli.none_quadrant":   "No entities found in the top-right quadrant of the specified text.",
+		"cli.benchmarking":    "Running benchmarks on: %s",
+		"cli.total_entities":  "Total entities found: %d",
+		"cli.memory":          "Estimated memory usage: %.2f MB",
+
+		// BOM extraction
+		"bom.title":             "DXF Isometric BOM Extractor",
+		"bom.usage":             "Usage: %s -dir <directory> [-dir <directory> ...] [-file <file.dxf> ...] [options]",
+		"bom.options":           "Options:",
+		"bom.dir_required":      "Error: At least one -dir or -file is required",
+		"serve.dir_required":    "Error: Directory is required",
+		"bom.dir_missing":       "Error: Input '%s' does not exist",
+		"bom.scan_error":        "Error scanning directory: %v",
+		"bom.no_files":          "No DXF files found.",
+		"bom.processing_par":    "Processing %d DXF files using %d parallel workers",
+		"bom.processing_seq":    "Processing %d DXF files sequentially",
+		"bom.with_weld_cache":   " (with weld caching)",
+		"bom.progress_start":    "Processing file %d/%d: %s",
+		"bom.progress_done":     "Completed file %d/%d: %s",
+		"bom.file_warning":      "Warning: %s: %s",
+		"bom.write_error":       "Error writing output files: %v",
+		"bom.provenance_error":  "Error writing provenance files: %v",
+		"bom.raw_tables_error":  "Error writing raw tables: %v",
+		"bom.dwg_skipped":       "Skipping %d DWG files (convert them with -dwg-converter)",
+		"bom.dwg_error":         "Error: DWG conversion: %v",
+		"bom.interrupted":       "Run stopped: %v; writing the results of the completed files",
+		"bom.failed_files":      "%d of %d files failed (%.1f%%, -fail-on-error-rate %.1f%%): exit code %d",
+		"bom.run_exists":        "Error: results of this run id exist already: %v (use another -run-id or -overwrite)",
+		"bom.weld_processing":   "Processing weld detection for %d cached files...",
+		"bom.weld_write_error":  "Error writing weld output files: %v",
+		"bom.weld_done":         "Weld processing completed in %.3f seconds",
+		"bom.weld_duplicates":   "Dropped %d duplicate segments (drawn twice) before weld matching",
+		"bom.weld_details_json": "Error: -weld-details requires -json-out",
+		"bom.crash_report":      "Wrote crash report to: %s (%d crashed files, see Error in the summary)",
+		"bom.pg_error":          "Error writing PostgreSQL output: %v",
+		"bom.pg_written":        "Wrote %d drawings to PostgreSQL",
+		"bom.pg_skipped":        "Wrote %d drawings to PostgreSQL (%d skipped without drawing number)",
+		"bom.history_error":     "Warning: could not record run history: %v",
+		"bom.history_recorded":  "Recorded run for project '%s' in run history",
+		"bom.perf_stats_error":  "Warning: could not append performance counters: %v",
+		"bom.perf_stats_done":   "Appended performance counters to: %s",
+		"bom.wrote_materials":   "Wrote ERECTION MATERIALS data to: %s (%d rows)",
+		"bom.wrote_cut":         "Wrote CUT PIPE LENGTH data to: %s (%d rows)",
+		"bom.wrote_aggregated":  "Wrote AGGREGATED MATERIALS data to: %s (%d rows)",
+		"bom.wrote_summary":     "Wrote processing summary to: %s (%d files)",
+		"config.using":          "Using project defaults from: %s",
+		"config.error":          "Error: %v",
+		"config.exists":         "Error: %s already exists (use -force to replace it)",
+		"config.valid":          "%s: valid",
+		"config.invalid":        "%s: %d problem(s)",
+		"daemon.state_required": "Error: -state is required",
+		"daemon.started":        "Job queue in %s, job API on http://%s/api/jobs",
+		"daemon.stopped":        "Daemon stopped; running jobs continue at the next start",
+		"daemon.queued":         "Queued job %d (%s): %d inputs",
+		"daemon.running":        "Running job %d (%s): %d of %d files done",
+		"daemon.finished":       "Job %d %s: %d files done, %d failed",
+		"summary.complete":      "PROCESSING COMPLETE",
+		"summary.directory":     "Directory: %s",
+		"summary.total_files":   "Total Files: %d",
+		"summary.successful":    "Successful: %d",
+		"summary.failed":        "Failed: %d",
+		"summary.workers":       "Workers: %d",
+		"summary.material_rows": "Total Material Rows: %d",
+		"summary.cut_rows":      "Total Cut Pipe Rows: %d",
+		"summary.wall_time":     "Wall Clock Time: %.3f seconds",
+		"summary.proc_time":     "Total Processing Time: %.3f seconds",
+		"summary.efficiency":    "Parallel Efficiency: %.1f%%",
+		"summary.avg_time":      "Average Time per File: %.3f seconds",
+	},
+	"de": {
+		// General CLI
+		"cli.title":           "DXF Text Parser - Schnelle Textextraktion aus DXF-Dateien",
+		"cli.usage":           "Verwendung:",
+		"cli.spatial_cmds":    "Räumliche Befehle:",
+		"cli.examples":        "Beispiele:",
+		"cli.cmd.parse":       "DXF-Datei einlesen und Ergebnisse anzeigen",
+		"cli.cmd.spatial":     "Räumliche Analyse ausführen",
+		"cli.cmd.benchmark":   "Leistungsmessung ausführen",
+		"cli.cmd.bom":         "Stückliste und Schnittlängen extrahieren",
+		"cli.cmd.orientation": "Histogramme der Segmentwinkel/-längen (JSON/CSV)",
+		"cli.cmd.outline":     "Abschnitte, Objektanzahlen, Blöcke und Layer als Baum anzeigen",
+		"cli.cmd.header":      "Version, Einheiten, Maßsystem und Ausdehnung aus dem HEADER anzeigen",
+		"cli.cmd.layers":      "Layer mit Farbe, Ein/Aus/Gefroren-Zustand und Objektanzahl auflisten",
+		"cli.cmd.replay":      "Eine Zeichnung mit Ablaufprotokoll und Overlay neu verarbeiten",
+		"cli.cmd.report":      "Summen der aufgezeichneten Läufe vergleichen",
+		"cli.cmd.pivot":       "Material und Schweißnähte eines Laufs nach Kategorie, Klasse und Bereich auswerten",
+		"cli.cmd.eval":        "Extraktionsgenauigkeit an einem Referenzkorpus messen",
+		"cli.cmd.serve":       "Verzeichnis überwachen und Auftragsstatus im Browser anzeigen",
+		"cli.cmd.daemon":      "Extraktionsdienst mit dauerhafter Auftragswarteschlange und Job-API starten",
+		"cli.cmd.export":      "Eingebaute Standardkonfiguration (.dxfparser.yaml) zum Anpassen ausgeben",
+		"cli.cmd.config":      "Projektkonfigurationen vor einem Batchlauf auf Fehler prüfen",
+		"cli.cmd.version":     "Programmversion anzeigen",
+		"cli.cmd.help":        "Diese Hilfe anzeigen",
+		"cli.spatial.stats":   "Statistik der Elemente anzeigen",
+		"cli.spatial.near":    "Elemente in der Nähe eines Textes suchen",
+		"cli.spatial.range":   "Elemente in einem Koordinatenbereich suchen",
+		"cli.spatial.quad":    "Elemente im oberen rechten Quadranten eines Textes suchen",
+		"cli.unknown_command": "Unbekannter Befehl: %s",
+		"cli.unknown_spatial": "Unbekannter räumlicher Befehl: %s",
+		"cli.missing_file":    "Fehler: DXF-Datei fehlt",
+		"cli.missing_spatial": "Fehler: Argumente für den Befehl spatial fehlen",
+		"cli.invalid_dist":    "Fehler: Ungültiger Abstand: %s",
+		"cli.invalid_coords":  "Fehler: Ungültige Koordinaten",
+		"cli.invalid_workers": "Fehler: Ungültige Worker-Anzahl: %s (muss eine positive ganze Zahl sein)",
+		"cli.unexpected_args": "Fehler: Unerwartete Argumente: %s",
+		"cli.parsing":         "Lese DXF-Datei: %s",
+		"cli.using_workers":   "Verwende %d Worker",
+		"cli.parse_done":      "Einlesen abgeschlossen in: %v",
+		"cli.indexed":         "%d Datensätze indiziert in %v",
+		"cli.parse_warning":   "Warnung: %s",
+		"cli.parse_skipped":   "%d fehlerhafte Gruppencode-/Wert-Paare übersprungen:",
+		"cli.parse_proxies":   "%d Proxy-Objekte (Grafik nicht gelesen)",
+		"cli.found_entities":  "%d Textelemente gefunden",
+		"cli.first_entities":  "Erste %d Textelemente:",
+		"cli.more_entities":   "... und %d weitere Elemente",
+		"cli.zip_entry":       "%s: %d Textelemente in %v",
+		"cli.zip_entries":     "Lese %d DXF-Dateien im Archiv: %s",
+		"cli.found":           "%d Elemente gefunden:",
+		"cli.none_near":       "Keine Elemente in der Nähe des angegebenen Textes gefunden.",
+		"cli.none_range":      "Keine Elemente im angegebenen Bereich gefunden.",
+		"cli.none_quadrant":   "Keine Elemente im oberen rechten Quadranten des angegebenen Textes gefunden.",
+		"cli.benchmarking":    "Leistungsmessung für: %s",
+		"cli.total_entities":  "Gefundene Elemente insgesamt: %d",
+		"cli.memory":          "Geschätzter Speicherverbrauch: %.2f MB",
+
+		// BOM extraction
+		"bom.title":             "DXF Isometrie-Stücklistenextraktion",
+		"bom.usage":             "Verwendung: %s -dir <Verzeichnis> [-dir <Verzeichnis> ...] [-file <Datei.dxf> ...] [Optionen]",
+		"bom.options":           "Optionen:",
+		"bom.dir_required":      "Fehler: Mindestens ein -dir oder -file muss angegeben werden",
+		"serve.dir_required":    "Fehler: Verzeichnis muss angegeben werden",
+		"bom.dir_missing":       "Fehler: Eingabe '%s' existiert nicht",
+		"bom.scan_error":        "Fehler beim Durchsuchen des Verzeichnisses: %v",
+		"bom.no_files":          "Keine DXF-Dateien gefunden.",
+		"bom.processing_par":    "Verarbeite %d DXF-Dateien mit %d parallelen Workern",
+		"bom.processing_seq":    "Verarbeite %d DXF-Dateien nacheinander",
+		"bom.with_weld_cache":   " (mit Zwischenspeicher für Schweißnähte)",
+		"bom.progress_start":    "Verarbeite Datei %d/%d: %s",
+		"bom.progress_done":     "Datei %d/%d abgeschlossen: %s",
+		"bom.file_warning":      "Warnung: %s: %s",
+		"bom.write_error":       "Fehler beim Schreiben der Ausgabedateien: %v",
+		"bom.provenance_error":  "Fehler beim Schreiben der Herkunftsdateien: %v",
+		"bom.raw_tables_error":  "Fehler beim Schreiben der Rohtabellen: %v",
+		"bom.dwg_skipped":       "%d DWG-Dateien werden übersprungen (Umwandlung mit -dwg-converter)",
+		"bom.dwg_error":         "Fehler: DWG-Umwandlung: %v",
+		"bom.interrupted":       "Lauf abgebrochen: %v; die Ergebnisse der fertigen Dateien werden geschrieben",
+		"bom.failed_files":      "%d von %d Dateien fehlgeschlagen (%.1f%%, -fail-on-error-rate %.1f%%): Exit-Code %d",
+		"bom.run_exists":        "Fehler: Ergebnisse dieser Lauf-ID sind bereits vorhanden: %v (andere -run-id oder -overwrite angeben)",
+		"bom.weld_processing":   "Schweißnahterkennung für %d zwischengespeicherte Dateien...",
+		"bom.weld_write_error":  "Fehler beim Schreiben der Schweißnaht-Ausgabedateien: %v",
+		"bom.weld_done":         "Schweißnahterkennung abgeschlossen in %.3f Sekunden",
+		"bom.weld_duplicates":   "%d doppelt gezeichnete Segmente vor der Schweißnahterkennung entfernt",
+		"bom.weld_details_json": "Fehler: -weld-details erfordert -json-out",
+		"bom.crash_report":      "Absturzbericht geschrieben nach: %s (%d abgestürzte Dateien, siehe Error in der Zusammenfassung)",
+		"bom.pg_error":          "Fehler beim Schreiben nach PostgreSQL: %v",
+		"bom.pg_written":        "%d Zeichnungen nach PostgreSQL geschrieben",
+		"bom.pg_skipped":        "%d Zeichnungen nach PostgreSQL geschrieben (%d ohne Zeichnungsnummer übersprungen)",
+		"bom.history_error":     "Warnung: Lauf konnte nicht aufgezeichnet werden: %v",
+		"bom.history_recorded":  "Lauf für Projekt '%s' aufgezeichnet",
+		"bom.perf_stats_error":  "Warnung: Leistungszähler konnten nicht angehängt werden: %v",
+		"bom.perf_stats_done":   "Leistungszähler angehängt an: %s",
+		"bom.wrote_materials":   "ERECTION MATERIALS geschrieben nach: %s (%d Zeilen)",
+		"bom.wrote_cut":         "CUT PIPE LENGTH geschrieben nach: %s (%d Zeilen)",
+		"bom.wrote_aggregated":  "Aggregierte Materialien geschrieben nach: %s (%d Zeilen)",
+		"bom.wrote_summary":     "Verarbeitungsübersicht geschrieben nach: %s (%d Dateien)",
+		"config.using":          "Verwende Projektvorgaben aus: %s",
+		"config.error":          "Fehler: %v",
+		"config.exists":         "Fehler: %s ist bereits vorhanden (-force zum Ersetzen)",
+		"config.valid":          "%s: gültig",
+		"config.invalid":        "%s: %d Fehler",
+		"daemon.state_required": "Fehler: -state muss angegeben werden",
+		"daemon.started":        "Auftragswarteschlange in %s, Job-API unter http://%s/api/jobs",
+		"daemon.stopped":        "Dienst beendet; laufende Aufträge werden beim nächsten Start fortgesetzt",
+		"daemon.queued":         "Auftrag %d (%s) eingereiht: %d Eingaben",
+		"daemon.running":        "Auftrag %d (%s) läuft: %d von %d Dateien erledigt",
+		"daemon.finished":       "Auftrag %d %s: %d Dateien erledigt, %d fehlgeschlagen",
+		"summary.complete":      "VERARBEITUNG ABGESCHLOSSEN",
+		"summary.directory":     "Verzeichnis: %s",
+		"summary.total_files":   "Dateien gesamt: %d",
+		"summary.successful":    "Erfolgreich: %d",
+		"summary.failed":        "Fehlgeschlagen: %d",
+		"summary.workers":       "Worker: %d",
+		"summary.material_rows": "Materialzeilen gesamt: %d",
+		"summary.cut_rows":      "Rohrschnittzeilen gesamt: %d",
+		"summary.wall_time":     "Laufzeit: %.3f Sekunden",
+		"summary.proc_time":     "Verarbeitungszeit gesamt: %.3f Sekunden",
+		"summary.efficiency":    "Parallele Effizienz: %.1f%%",
+		"summary.avg_time":      "Durchschnittliche Zeit pro Datei: %.3f Sekunden",
+	},
+}
+
+// currentLanguage is the catalog used for msg, chosen once from the environment
+var currentLanguage = detectLanguage()
+
+// detectLanguage picks the message language from DXF_PARSER_LANG, then the usual
+// locale variables (e.g. LANG=de_CH.UTF-8 -> "de"), falling back to English
+func detectLanguage() string {
+	for _, name := range []string{"DXF_PARSER_LANG", "LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := strings.ToLower(strings.TrimSpace(os.Getenv(name)))
+		if value == "" {
+			continue
+		}
+		if idx := strings.IndexAny(value, "_.-@"); idx > 0 {
+			value = value[:idx]
+		}
+		if _, ok := messageCatalog[value]; ok {
+			return value
+		}
+		// The first variable that is set decides, like the C library does for LC_*
+		if value != "c" && value != "posix" {
+			return defaultLanguage
+		}
+	}
+	return defaultLanguage
+}
+
+// msg returns the translated message for key, formatted with args.
+// Missing translations fall back to English, unknown keys to the key itself.
+func msg(key string, args ...interface{}) string {
+	format, ok := messageCatalog[currentLanguage][key]
+	if !ok {
+		format, ok = messageCatalog[defaultLanguage][key]
+		if !ok {
+			format = key
+		}
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
@@ -0,0 +1,112 @@
+package dxfparser
+
+import "math"
+
+// leaderState follows the CONTEXT_DATA{, LEADER{ and LEADER_LINE{ blocks of a MULTILEADER,
+// which reuse the group codes of points for different things
+type leaderState struct {
+	section      string // "", "context", "leader" or "line"
+	landing      bool   // landing point of the first leader read
+	landX, landY float64
+	landZ        float64
+	landZRead    bool
+	text         bool // text location read
+	textX, textY float64
+	textZ        float64
+	arrowX       float64 // x of the first leader line vertex until its y follows
+	arrow        bool
+}
+
+// setLeaderGroup applies one group of a MULTILEADER and reports whether it used it: the MTEXT
+// content (304), text location (12/22/32), height (41) and rotation (42) of the context data, the
+// landing point of the first leader (10/20/30 in LEADER{) and the first vertex of its first leader
+// line (10/20 in LEADER_LINE{), the arrowhead. Other groups inside the blocks are consumed;
+// groups of the entity itself other than layer, visibility and color, e.g. the block scale in
+// 10/20, are dropped too.
+func (e *TextEntity) setLeaderGroup(code, value string) bool {
+	if e.leader == nil {
+		e.leader = &leaderState{}
+	}
+	l := e.leader
+	switch {
+	case code == "300" && value == "CONTEXT_DATA{":
+		l.section = "context"
+		return true
+	case code == "302" && value == "LEADER{":
+		l.section = "leader"
+		return true
+	case code == "304" && value == "LEADER_LINE{":
+		l.section = "line"
+		return true
+	case code == "305":
+		l.section = "leader"
+		return true
+	case code == "303":
+		l.section = "context"
+		return true
+	case code == "301":
+		l.section = ""
+		return true
+	}
+
+	switch l.section {
+	case "":
+		switch code {
+		case "8", "60", "62", "420":
+			return false
+		}
+	case "context":
+		switch code {
+		case "304": // Default text contents
+			e.Content += decodeText(value)
+		case "12":
+			l.textX, _ = parseGroupFloat(code, value)
+		case "22":
+			l.textY, _ = parseGroupFloat(code, value)
+			l.text = true
+		case "32":
+			l.textZ, _ = parseGroupFloat(code, value)
+		case "41":
+			e.Height, _ = parseGroupFloat(code, value)
+		case "42": // Text rotation in radians
+			if r, ok := parseGroupFloat(code, value); ok {
+				e.Rotation = normalizeRotation(r * 180 / math.Pi)
+			}
+		}
+	case "leader":
+		switch {
+		case code == "10" && !l.landing:
+			l.landX, _ = parseGroupFloat(code, value)
+		case code == "20" && !l.landing:
+			l.landY, _ = parseGroupFloat(code, value)
+			l.landing = true
+		case code == "30" && l.landing && !l.landZRead:
+			l.landZ, _ = parseGroupFloat(code, value)
+			l.landZRead = true
+		}
+	case "line":
+		switch {
+		case code == "10" && !l.arrow:
+			l.arrowX, _ = parseGroupFloat(code, value)
+		case code == "20" && !l.arrow:
+			y, _ := parseGroupFloat(code, value)
+			e.Arrow = []float64{l.arrowX, y}
+			l.arrow = true
+		}
+	}
+	return true
+}
+
+// finishLeader places a MULTILEADER at the landing point of its first leader, where the text
+// attaches, or at the text location of a leader without leader lines
+func (e *TextEntity) finishLeader() {
+	if e.leader == nil {
+		return
+	}
+	switch {
+	case e.leader.landing:
+		e.X, e.Y, e.Z = e.leader.landX, e.leader.landY, e.leader.landZ
+	case e.leader.text:
+		e.X, e.Y, e.Z = e.leader.textX, e.leader.textY, e.leader.textZ
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package dxfparser
+
+import (
+	"errors"
+	"os"
+)
+
+// mapFile is not available on this platform; loadInput reads the file instead
+func mapFile(file *os.File, size int64) ([]byte, error) {
+	return nil, errors.New("memory mapped files are not supported on this platform")
+}
+
+// unmapFile releases a mapping of mapFile
+func unmapFile(data []byte) error {
+	return nil
+}
@@ -0,0 +1,18 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package dxfparser
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapFile maps the first size bytes of file into memory, read-only
+func mapFile(file *os.File, size int64) ([]byte, error) {
+	return syscall.Mmap(int(file.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+// unmapFile releases a mapping of mapFile
+func unmapFile(data []byte) error {
+	return syscall.Munmap(data)
+}
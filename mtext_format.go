@@ -0,0 +1,74 @@
+package dxfparser
+
+import (
+	"strings"
+)
+
+// stripMTextFormat returns the plain text of MTEXT content. Paragraph and column breaks (\P, \N,
+// \X) become newlines, stacked fractions (\S1/2;) "1/2", and escaped characters (\\, \{, \})
+// their literal. Font, height, width, color, alignment and similar codes and the braces that
+// scope them are dropped. Unknown codes are kept as they are.
+func stripMTextFormat(text string) string {
+	if !strings.ContainsAny(text, `\{}`) {
+		return text
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch c {
+		case '{', '}':
+			continue
+		case '\\':
+		default:
+			b.WriteByte(c)
+			continue
+		}
+
+		if i+1 >= len(text) {
+			b.WriteByte(c)
+			break
+		}
+		code := text[i+1]
+		switch code {
+		case 'P', 'N', 'X':
+			b.WriteByte('\n')
+			i++
+		case '~':
+			b.WriteByte(' ')
+			i++
+		case '\\', '{', '}':
+			b.WriteByte(code)
+			i++
+		case 'L', 'l', 'O', 'o', 'K', 'k':
+			// Underline, overline and strike-through on / off
+			i++
+		case 'A', 'C', 'c', 'F', 'f', 'H', 'p', 'Q', 'T', 'W':
+			// Alignment, color, font, height, paragraph, oblique, tracking and width run to ';'
+			end := strings.IndexByte(text[i+2:], ';')
+			if end < 0 {
+				i = len(text)
+			} else {
+				i += 2 + end
+			}
+		case 'S':
+			// Stacked text: \Snumerator/denominator; with '/', '#' or '^' between the parts
+			end := strings.IndexByte(text[i+2:], ';')
+			if end < 0 {
+				b.WriteString(text[i:])
+				i = len(text)
+				break
+			}
+			stack := text[i+2 : i+2+end]
+			// 1\S1/2; is the mixed number 1 1/2
+			if out := b.String(); out != "" && out[len(out)-1] >= '0' && out[len(out)-1] <= '9' {
+				b.WriteByte(' ')
+			}
+			b.WriteString(strings.NewReplacer("#", "/", "^", "/").Replace(stack))
+			i += 2 + end
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,261 @@
+package dxfparser
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/jeffcall-ch/dxf_parser_go/internal/geometry"
+)
+
+// Classification thresholds for isometric vs orthographic drawings
+const (
+	orientationAngleTolerance = 2.0  // degrees around 0/30/90/150 counted as "on axis"
+	orientationMinShare       = 0.25 // minimum share of total segment length to classify
+)
+
+// OrientationStats summarizes polyline segment angles and lengths of one drawing
+type OrientationStats struct {
+	FilePath        string    `json:"file_path"`
+	SegmentCount    int       `json:"segment_count"`
+	TotalLength     float64   `json:"total_length"`
+	BinWidth        float64   `json:"bin_width_deg"`
+	AngleHistogram  []float64 `json:"angle_histogram"`  // segment length per angle bin, angles folded to [0, 180)
+	AngleCounts     []int     `json:"angle_counts"`     // segment count per angle bin
+	LengthBins      []float64 `json:"length_bins"`      // upper bounds of the length histogram bins
+	LengthHistogram []int     `json:"length_histogram"` // segment count per length bin
+	Share0          float64   `json:"share_0"`          // share of length at 0 deg (horizontal)
+	Share30         float64   `json:"share_30"`
+	Share90         float64   `json:"share_90"`
+	Share150        float64   `json:"share_150"`
+	Classification  string    `json:"classification"` // "isometric", "orthographic" or "unknown"
+	Error           string    `json:"error,omitempty"`
+}
+
+// defaultLengthBins are the upper bounds used for the segment length histogram (last bin is open)
+var defaultLengthBins = []float64{1, 5, 10, 50, 100, 500, 1000}
+
+// segmentAngle returns the segment direction folded to [0, 180) degrees
+func segmentAngle(seg PolylineSegment) float64 {
+	return geometry.Direction(seg.X1, seg.Y1, seg.X2, seg.Y2)
+}
+
+// computeOrientationStats builds the angle and length distributions of the segments
+func computeOrientationStats(segments []PolylineSegment, binWidth float64) OrientationStats {
+	if binWidth <= 0 || binWidth > 180 {
+		binWidth = 5
+	}
+	numBins := int(math.Ceil(180 / binWidth))
+
+	stats := OrientationStats{
+		BinWidth:        binWidth,
+		AngleHistogram:  make([]float64, numBins),
+		AngleCounts:     make([]int, numBins),
+		LengthBins:      defaultLengthBins,
+		LengthHistogram: make([]int, len(defaultLengthBins)+1),
+	}
+
+	var length0, length30, length90, length150 float64
+	for _, seg := range segments {
+		if seg.Length <= 0 {
+			continue
+		}
+		angle := segmentAngle(seg)
+		bin := int(angle / binWidth)
+		if bin >= numBins {
+			bin = numBins - 1
+		}
+		stats.AngleHistogram[bin] += seg.Length
+		stats.AngleCounts[bin]++
+		stats.SegmentCount++
+		stats.TotalLength += seg.Length
+
+		lengthBin := sort.SearchFloat64s(defaultLengthBins, seg.Length)
+		stats.LengthHistogram[lengthBin]++
+
+		switch {
+		case geometry.DirectionNear(angle, 0, orientationAngleTolerance):
+			length0 += seg.Length
+		case geometry.DirectionNear(angle, 30, orientationAngleTolerance):
+			length30 += seg.Length
+		case geometry.DirectionNear(angle, 90, orientationAngleTolerance):
+			length90 += seg.Length
+		case geometry.DirectionNear(angle, 150, orientationAngleTolerance):
+			length150 += seg.Length
+		}
+	}
+
+	if stats.TotalLength > 0 {
+		stats.Share0 = length0 / stats.TotalLength
+		stats.Share30 = length30 / stats.TotalLength
+		stats.Share90 = length90 / stats.TotalLength
+		stats.Share150 = length150 / stats.TotalLength
+	}
+	stats.Classification = classifyOrientation(stats)
+
+	return stats
+}
+
+// classifyOrientation decides between isometric (30/90/150 dominance) and orthographic (0/90 dominance).
+// 90 degrees is common to both projections, so only the 30/150 and 0 shares are compared.
+func classifyOrientation(stats OrientationStats) string {
+	isoShare := stats.Share30 + stats.Share150
+	orthoShare := stats.Share0
+
+	if isoShare >= orientationMinShare && isoShare > orthoShare {
+		return "isometric"
+	}
+	if orthoShare >= orientationMinShare && orthoShare > isoShare {
+		return "orthographic"
+	}
+	return "unknown"
+}
+
+// analyzeFileOrientation parses all polyline segments of a file and computes its statistics
+func analyzeFileOrientation(path string, binWidth float64) OrientationStats {
+	var segments []PolylineSegment
+	input, err := loadInput(path)
+	if err == nil {
+		segments, err = parsePolylineSegments(input.Data, nil, defaultRunConfig())
+		input.Close()
+	}
+
+	stats := computeOrientationStats(segments, binWidth)
+	releaseSegments(segments)
+	stats.FilePath = path
+	if err != nil {
+		stats.Error = err.Error()
+	}
+	return stats
+}
+
+// handleOrientationCommand implements "orientation <file.dxf|directory> [options]"
+func handleOrientationCommand() {
+	fs := newCommandFlagSet("orientation", "dxf_parser orientation <file.dxf|directory> [-format json|csv] [-bin 5] [-o output]")
+	format := fs.String("format", "json", "Output format (json, csv)")
+	binWidth := fs.Float64("bin", 5, "Angle histogram bin width in degrees")
+	output := fs.String("o", "", "Write output to this file instead of stdout")
+	args := parseCommandArgs(fs, os.Args[2:])
+	checkArgCount(fs, args, 1, 1, "Error: Missing DXF file or directory argument")
+	if *format != "json" && *format != "csv" {
+		usageError(fs, fmt.Sprintf("Error: Unknown format: %s (use json or csv)", *format))
+	}
+	if *binWidth <= 0 {
+		usageError(fs, "Error: -bin must be positive")
+	}
+
+	target := args[0]
+
+	useProjectConfig(target, nil)
+
+	var files []string
+	if info, err := os.Stat(target); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	} else if info.IsDir() {
+		filepath.Walk(target, func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() && isDXF(path) {
+				files = append(files, path)
+			}
+			return nil
+		})
+	} else {
+		files = []string{target}
+	}
+
+	var results []OrientationStats
+	for _, path := range files {
+		results = append(results, analyzeFileOrientation(path, *binWidth))
+	}
+
+	if *format != "json" && *format != "csv" {
+		fmt.Printf("Unknown format: %s\n", *format)
+		os.Exit(1)
+	}
+
+	out := os.Stdout
+	var file *outputFile
+	if *output != "" {
+		var err error
+		file, err = createOutput(*output)
+		if err != nil {
+			fmt.Printf("Error creating output file: %v\n", err)
+			os.Exit(1)
+		}
+		out = file.File
+	}
+
+	var err error
+	switch *format {
+	case "json":
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(results)
+	case "csv":
+		err = writeOrientationCSV(out, results)
+	}
+	if err == nil && file != nil {
+		err = file.Commit()
+	}
+	if err != nil {
+		if file != nil {
+			file.Close()
+		}
+		fmt.Printf("Error writing output: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// writeOrientationCSV writes one row per file with the angle histogram flattened into columns
+func writeOrientationCSV(out *os.File, results []OrientationStats) error {
+	writer := csv.NewWriter(out)
+
+	header := []string{"FilePath", "SegmentCount", "TotalLength", "Share0", "Share30", "Share90", "Share150", "Classification", "Error"}
+	if len(results) > 0 {
+		stats := results[0]
+		for i := range stats.AngleHistogram {
+			header = append(header, fmt.Sprintf("Angle_%g", float64(i)*stats.BinWidth))
+		}
+		for i := range stats.LengthHistogram {
+			if i < len(stats.LengthBins) {
+				header = append(header, fmt.Sprintf("Length_le_%g", stats.LengthBins[i]))
+			} else {
+				header = append(header, fmt.Sprintf("Length_gt_%g", stats.LengthBins[len(stats.LengthBins)-1]))
+			}
+		}
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, stats := range results {
+		record := []string{
+			stats.FilePath,
+			strconv.Itoa(stats.SegmentCount),
+			fmt.Sprintf("%.3f", stats.TotalLength),
+			fmt.Sprintf("%.4f", stats.Share0),
+			fmt.Sprintf("%.4f", stats.Share30),
+			fmt.Sprintf("%.4f", stats.Share90),
+			fmt.Sprintf("%.4f", stats.Share150),
+			stats.Classification,
+			stats.Error,
+		}
+		for _, length := range stats.AngleHistogram {
+			record = append(record, fmt.Sprintf("%.3f", length))
+		}
+		for _, count := range stats.LengthHistogram {
+			record = append(record, strconv.Itoa(count))
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
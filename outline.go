@@ -0,0 +1,277 @@
+package dxfparser
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// DXFOutline is the structure of a DXF file: its sections, blocks and layers
+type DXFOutline struct {
+	FilePath string           `json:"file_path"`
+	Sections []OutlineSection `json:"sections"`
+	Blocks   []OutlineBlock   `json:"blocks"`
+	Layers   []OutlineLayer   `json:"layers"`
+}
+
+// OutlineSection is one SECTION with the number of records per type; HEADER counts
+// its $ variables, TABLES its table records (LAYER, LTYPE, STYLE, ...)
+type OutlineSection struct {
+	Name   string         `json:"name"`
+	Counts map[string]int `json:"counts"`
+}
+
+// OutlineBlock is one block definition with the number of entities per type
+type OutlineBlock struct {
+	Name   string         `json:"name"`
+	Counts map[string]int `json:"counts"`
+}
+
+// OutlineLayer is a layer defined in the LAYER table or used by an entity
+type OutlineLayer struct {
+	Name     string `json:"name"`
+	Defined  bool   `json:"defined"`  // has a LAYER table record
+	Entities int    `json:"entities"` // entities on the layer in ENTITIES and BLOCKS
+}
+
+// outlineDXF reads the structure of a DXF file without interpreting the entities
+func outlineDXF(path string) (*DXFOutline, error) {
+	file, err := openInput(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	outline, err := readOutline(file)
+	if err != nil {
+		return nil, err
+	}
+	outline.FilePath = path
+	return outline, nil
+}
+
+// readOutline builds the outline from DXF group code / value pairs
+func readOutline(r io.Reader) (*DXFOutline, error) {
+	outline := &DXFOutline{}
+	layers := make(map[string]*OutlineLayer)
+	layer := func(name string) *OutlineLayer {
+		if layers[name] == nil {
+			layers[name] = &OutlineLayer{Name: name}
+		}
+		return layers[name]
+	}
+
+	var section *OutlineSection
+	var block *OutlineBlock
+	record := "" // type of the current record (group code 0)
+	expectSectionName := false
+	namedRecord := false // group code 2 of the current record was read
+	var groups appGroupFilter
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		code := strings.TrimSpace(scanner.Text())
+		if !scanner.Scan() {
+			break
+		}
+		value := strings.TrimSpace(scanner.Text())
+		if groups.skip(code, value) {
+			continue
+		}
+
+		if code == "0" {
+			record, namedRecord = value, false
+			switch {
+			case value == "SECTION":
+				expectSectionName = true
+				continue
+			case value == "ENDSEC":
+				section, block = nil, nil
+				continue
+			case value == "EOF":
+				continue
+			case section == nil:
+				continue
+			}
+
+			switch {
+			case section.Name == "BLOCKS" && value == "BLOCK":
+				outline.Blocks = append(outline.Blocks, OutlineBlock{Counts: make(map[string]int)})
+				block = &outline.Blocks[len(outline.Blocks)-1]
+			case section.Name == "BLOCKS" && value == "ENDBLK":
+				block = nil
+			case block != nil:
+				block.Counts[value]++
+			case section.Name == "TABLES" && (value == "TABLE" || value == "ENDTAB"):
+				// table boundaries, only the records are counted
+			case section.Name != "HEADER" && section.Name != "BLOCKS":
+				section.Counts[value]++
+			}
+			continue
+		}
+
+		if expectSectionName && code == "2" {
+			outline.Sections = append(outline.Sections, OutlineSection{Name: value, Counts: make(map[string]int)})
+			section = &outline.Sections[len(outline.Sections)-1]
+			expectSectionName = false
+			continue
+		}
+		if section == nil {
+			continue
+		}
+
+		switch section.Name {
+		case "HEADER":
+			if code == "9" {
+				section.Counts["variables"]++
+			}
+		case "TABLES":
+			if record == "LAYER" && code == "2" && !namedRecord {
+				layer(value).Defined = true
+				namedRecord = true
+			}
+		case "BLOCKS":
+			if record == "BLOCK" && code == "2" && block != nil && !namedRecord {
+				block.Name = value
+				namedRecord = true
+			} else if code == "8" && block != nil && record != "BLOCK" {
+				layer(value).Entities++
+			}
+		case "ENTITIES":
+			if code == "8" {
+				layer(value).Entities++
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+
+	// Sections are kept in file order, blocks and layers are sorted by name
+	for _, section := range outline.Sections {
+		if section.Name == "BLOCKS" {
+			section.Counts["blocks"] = len(outline.Blocks)
+		}
+	}
+	sort.Slice(outline.Blocks, func(i, j int) bool { return outline.Blocks[i].Name < outline.Blocks[j].Name })
+	for _, l := range layers {
+		outline.Layers = append(outline.Layers, *l)
+	}
+	sort.Slice(outline.Layers, func(i, j int) bool { return outline.Layers[i].Name < outline.Layers[j].Name })
+	return outline, nil
+}
+
+// treeNode is one line of the outline tree view
+type treeNode struct {
+	label    string
+	children []treeNode
+}
+
+// countNodes returns one node per record type, most frequent first
+func countNodes(counts map[string]int) []treeNode {
+	types := make([]string, 0, len(counts))
+	for t := range counts {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if counts[types[i]] != counts[types[j]] {
+			return counts[types[i]] > counts[types[j]]
+		}
+		return types[i] < types[j]
+	})
+	nodes := make([]treeNode, len(types))
+	for i, t := range types {
+		nodes[i] = treeNode{label: fmt.Sprintf("%s: %d", t, counts[t])}
+	}
+	return nodes
+}
+
+// countTotal returns the sum of counts
+func countTotal(counts map[string]int) int {
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	return total
+}
+
+// tree returns the outline as a tree rooted at the file name
+func (o *DXFOutline) tree() treeNode {
+	root := treeNode{label: o.FilePath}
+	for _, section := range o.Sections {
+		node := treeNode{label: section.Name}
+		switch section.Name {
+		case "HEADER":
+			node.label = fmt.Sprintf("HEADER (%d variables)", section.Counts["variables"])
+		case "BLOCKS":
+			node.label = fmt.Sprintf("BLOCKS (%d blocks)", len(o.Blocks))
+			for _, block := range o.Blocks {
+				node.children = append(node.children, treeNode{
+					label:    fmt.Sprintf("%s (%d entities)", block.Name, countTotal(block.Counts)),
+					children: countNodes(block.Counts),
+				})
+			}
+		case "TABLES":
+			node.children = countNodes(section.Counts)
+		default:
+			node.label = fmt.Sprintf("%s (%d records)", section.Name, countTotal(section.Counts))
+			node.children = countNodes(section.Counts)
+		}
+		root.children = append(root.children, node)
+	}
+
+	layers := treeNode{label: fmt.Sprintf("Layers (%d)", len(o.Layers))}
+	for _, l := range o.Layers {
+		label := fmt.Sprintf("%s (%d entities)", l.Name, l.Entities)
+		if !l.Defined {
+			label += " [not in LAYER table]"
+		}
+		layers.children = append(layers.children, treeNode{label: label})
+	}
+	root.children = append(root.children, layers)
+	return root
+}
+
+// writeTree prints node and its children with box drawing connectors
+func writeTree(w io.Writer, node treeNode, prefix string) {
+	for i, child := range node.children {
+		connector, indent := "├── ", "│   "
+		if i == len(node.children)-1 {
+			connector, indent = "└── ", "    "
+		}
+		fmt.Fprintf(w, "%s%s%s\n", prefix, connector, child.label)
+		writeTree(w, child, prefix+indent)
+	}
+}
+
+// handleOutlineCommand implements "outline <file.dxf> [-json]"
+func handleOutlineCommand() {
+	fs := newCommandFlagSet("outline", "dxf_parser outline <file.dxf> [-json]")
+	asJSON := fs.Bool("json", false, "Print the outline as JSON instead of a tree")
+	args := parseCommandArgs(fs, os.Args[2:])
+	checkArgCount(fs, args, 1, 1, "Error: Missing DXF file argument")
+
+	outline, err := outlineDXF(args[0])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(outline); err != nil {
+			fmt.Printf("Error writing output: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	root := outline.tree()
+	fmt.Println(root.label)
+	writeTree(os.Stdout, root, "")
+}
@@ -0,0 +1,107 @@
+package dxfparser
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ParseWarning is a malformed group code / value pair that the parser skipped
+type ParseWarning struct {
+	Line   int    `json:"line"`   // 1-based line of the group code in the content
+	Code   string `json:"code"`   // group code as read
+	Reason string `json:"reason"` // e.g. `invalid numeric value "1,2,3"`
+}
+
+func (w ParseWarning) String() string {
+	return fmt.Sprintf("line %d, group code %s: %s", w.Line, w.Code, w.Reason)
+}
+
+// maxParseWarnings is the number of parse warnings kept per parse; a file out of step after a
+// missing line would otherwise report every pair that follows
+const maxParseWarnings = 100
+
+// parseWarnings collects the parse warnings of a scan and counts the proxy entities it skipped
+type parseWarnings struct {
+	list    []ParseWarning // the first maxParseWarnings
+	count   int            // all of them
+	lines   int            // lines scanned, which precede the lines of the next chunk
+	proxies int            // proxy entities skipped
+}
+
+// add records a warning at line
+func (w *parseWarnings) add(line int, code, reason string) {
+	w.count++
+	if len(w.list) < maxParseWarnings {
+		w.list = append(w.list, ParseWarning{Line: line, Code: code, Reason: reason})
+	}
+}
+
+// merge adds the warnings of the scan of the next chunk
+func (w *parseWarnings) merge(next parseWarnings) {
+	for _, warning := range next.list {
+		if len(w.list) < maxParseWarnings {
+			warning.Line += w.lines
+			w.list = append(w.list, warning)
+		}
+	}
+	w.count += next.count
+	w.lines += next.lines
+	w.proxies += next.proxies
+}
+
+// isGroupCode reports whether line is a group code: an integer, of up to four digits in DXF files
+func isGroupCode(line string) bool {
+	if line == "" {
+		return false
+	}
+	if line[0] == '-' {
+		line = line[1:] // negative codes (-1 to -5) only occur in memory, but are numbers
+	}
+	for i := 0; i < len(line); i++ {
+		if line[i] < '0' || line[i] > '9' {
+			return false
+		}
+	}
+	return line != ""
+}
+
+// isFloatCode reports whether values of the group code are floating point numbers
+func isFloatCode(code string) bool {
+	n, err := strconv.Atoi(code)
+	if err != nil {
+		return false
+	}
+	return (n >= 10 && n <= 59) || (n >= 110 && n <= 149) || (n >= 210 && n <= 239) || (n >= 1010 && n <= 1059)
+}
+
+// checkFloat records a warning for a value of a floating point group code that is no number
+func (w *parseWarnings) checkFloat(line int, code, value string) {
+	if !isFloatCode(code) {
+		return
+	}
+	if _, err := parseDXFFloat(value); err != nil {
+		w.add(line, code, err.Error())
+	}
+}
+
+// ParseWarnings returns the malformed pairs skipped by the last parse, at most 100, in file order
+func (p *DXFParser) ParseWarnings() []ParseWarning {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.parseWarnings.list
+}
+
+// ParseWarningCount returns the number of malformed pairs skipped by the last parse, including
+// those beyond the ones ParseWarnings returns
+func (p *DXFParser) ParseWarningCount() int {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.parseWarnings.count
+}
+
+// setParseWarnings records the parse warnings of the last parse
+func (p *DXFParser) setParseWarnings(warnings parseWarnings) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.parseWarnings = warnings
+}
@@ -0,0 +1,120 @@
+package dxfparser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// Patterns are the regular expressions that recognize drawing metadata. Compiled expressions
+// are safe for concurrent use, so one Patterns value is shared by all workers of a run.
+// A Patterns value is never modified after it is created; Override returns a new one.
+type Patterns struct {
+	DrawingNo *regexp.Regexp // KKS drawing number
+	PipeClass *regexp.Regexp
+	Revision  *regexp.Regexp // matched against the file name, the first group is the revision
+	Tag       *regexp.Regexp // valve / instrument tags for -tags
+}
+
+// PatternOverrides are pattern sources replacing single patterns; empty fields keep the base pattern.
+// The defaults are in defaults/dxfparser.yaml.
+type PatternOverrides struct {
+	DrawingNo string `yaml:"drawing_no"` // KKS drawing number
+	PipeClass string `yaml:"pipe_class"`
+	Revision  string `yaml:"revision"` // file name pattern, first group is the revision
+	Tag       string `yaml:"tag"`      // KKS valve (AA) and measuring point (Cx) codes by default
+}
+
+// DefaultPatterns returns the built-in patterns of the embedded default config
+func DefaultPatterns() *Patterns {
+	p := builtinConfig.Patterns
+	return &Patterns{
+		DrawingNo: regexp.MustCompile(p.DrawingNo),
+		PipeClass: regexp.MustCompile(p.PipeClass),
+		Revision:  regexp.MustCompile(p.Revision),
+		Tag:       regexp.MustCompile(p.Tag),
+	}
+}
+
+// Override returns a copy of p with the non-empty overrides compiled in
+func (p *Patterns) Override(overrides PatternOverrides) (*Patterns, error) {
+	result := *p
+	for _, o := range []struct {
+		name   string
+		source string
+		target **regexp.Regexp
+	}{
+		{"drawing_no", overrides.DrawingNo, &result.DrawingNo},
+		{"pipe_class", overrides.PipeClass, &result.PipeClass},
+		{"revision", overrides.Revision, &result.Revision},
+		{"tag", overrides.Tag, &result.Tag},
+	} {
+		if o.source == "" {
+			continue
+		}
+		re, err := regexp.Compile(o.source)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s pattern: %v", o.name, err)
+		}
+		*o.target = re
+	}
+	return &result, nil
+}
+
+// Set parses a "name=regex" override as given to the -pattern flag
+func (o *PatternOverrides) Set(value string) error {
+	name, source, ok := strings.Cut(value, "=")
+	if !ok || source == "" {
+		return fmt.Errorf("expected name=regex, got '%s'", value)
+	}
+	switch strings.TrimSpace(name) {
+	case "drawing_no":
+		o.DrawingNo = source
+	case "pipe_class":
+		o.PipeClass = source
+	case "revision":
+		o.Revision = source
+	case "tag":
+		o.Tag = source
+	default:
+		return fmt.Errorf("unknown pattern '%s' (drawing_no, pipe_class, revision, tag)", name)
+	}
+	return nil
+}
+
+func (o *PatternOverrides) String() string {
+	if o == nil {
+		return ""
+	}
+	var parts []string
+	for _, p := range [][2]string{{"drawing_no", o.DrawingNo}, {"pipe_class", o.PipeClass}, {"revision", o.Revision}, {"tag", o.Tag}} {
+		if p[1] != "" {
+			parts = append(parts, p[0]+"="+p[1])
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// builtinPatterns and projectPatterns back activePatterns; projectPatterns is set by
+// applyProjectConfig and read by concurrent workers, hence the atomic pointer
+var (
+	builtinPatterns = DefaultPatterns()
+	projectPatterns atomic.Pointer[Patterns]
+)
+
+// activePatterns returns the patterns of the project config, or the built-in ones
+func activePatterns() *Patterns {
+	if p := projectPatterns.Load(); p != nil {
+		return p
+	}
+	return builtinPatterns
+}
+
+// orActivePatterns returns p, or the active patterns if p is nil
+func orActivePatterns(p *Patterns) *Patterns {
+	if p != nil {
+		return p
+	}
+	return activePatterns()
+}
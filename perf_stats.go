@@ -0,0 +1,143 @@
+package dxfparser
+
+import (
+	"encoding/json"
+	"os"
+	"runtime"
+	"time"
+)
+
+// FileTiming is where the processing time of a drawing went, in seconds. The rest of
+// DXFResult.ProcessingTime (metadata, tags, piece checks, provenance) is counted as other.
+type FileTiming struct {
+	Bytes    int64   // size of the input, 0 if unknown
+	Read     float64 // reading the content for the weld segments and the store key
+	Segments float64 // weld candidate segments
+	Parse    float64 // text entities
+	Tables   float64 // structured tables and the reconstruction from text
+}
+
+// lapTimer returns a function returning the seconds since its previous call, or since
+// lapTimer was called
+func lapTimer() func() float64 {
+	last := time.Now()
+	return func() float64 {
+		now := time.Now()
+		seconds := now.Sub(last).Seconds()
+		last = now
+		return seconds
+	}
+}
+
+// perfSizeBuckets are the upper bounds of the file size buckets of the performance counters
+var perfSizeBuckets = []struct {
+	name  string
+	limit int64
+}{
+	{"<256KB", 256 << 10},
+	{"256KB-1MB", 1 << 20},
+	{"1-4MB", 4 << 20},
+	{"4-16MB", 16 << 20},
+	{"16-64MB", 64 << 20},
+	{">=64MB", 1<<63 - 1},
+}
+
+// perfSizeBucket returns the bucket name of a file of size bytes
+func perfSizeBucket(size int64) string {
+	if size <= 0 {
+		return "unknown"
+	}
+	for _, bucket := range perfSizeBuckets {
+		if size < bucket.limit {
+			return bucket.name
+		}
+	}
+	return perfSizeBuckets[len(perfSizeBuckets)-1].name
+}
+
+// PerfBucket sums the files of one size bucket. Stage times are in seconds.
+type PerfBucket struct {
+	Files    int     `json:"files"`
+	Failed   int     `json:"failed"`
+	Bytes    int64   `json:"bytes"`
+	Read     float64 `json:"read"`
+	Segments float64 `json:"segments"`
+	Parse    float64 `json:"parse"`
+	Tables   float64 `json:"tables"`
+	Other    float64 `json:"other"`
+}
+
+// PerfRecord is one line of the -perf-stats file: the performance counters of a bom run. It
+// carries no paths, drawing numbers or contents, so the files of several sites can be pooled.
+type PerfRecord struct {
+	ToolVersion string                 `json:"tool_version"`
+	Date        string                 `json:"date"` // day of the run, no time of day
+	OS          string                 `json:"os"`
+	Arch        string                 `json:"arch"`
+	CPUs        int                    `json:"cpus"`
+	Workers     int                    `json:"workers"`
+	Files       int                    `json:"files"`
+	Failed      int                    `json:"failed"`
+	Weld        bool                   `json:"weld"`
+	Store       bool                   `json:"store"`
+	Stages      map[string]float64     `json:"stages"` // collect, extract, write, welds, total
+	Sizes       map[string]*PerfBucket `json:"sizes"`  // per size bucket
+}
+
+// newPerfRecord sums the file timings of a run into size buckets
+func newPerfRecord(start time.Time, workers int, weld bool, results []DXFResult, stages map[string]float64) PerfRecord {
+	record := PerfRecord{
+		ToolVersion: getToolVersion(),
+		Date:        start.Format("2006-01-02"),
+		OS:          runtime.GOOS,
+		Arch:        runtime.GOARCH,
+		CPUs:        runtime.NumCPU(),
+		Workers:     workers,
+		Files:       len(results),
+		Weld:        weld,
+		Store:       artifactStore != nil,
+		Stages:      stages,
+		Sizes:       make(map[string]*PerfBucket),
+	}
+	for _, result := range results {
+		timing := result.Timing
+		name := perfSizeBucket(timing.Bytes)
+		bucket := record.Sizes[name]
+		if bucket == nil {
+			bucket = &PerfBucket{}
+			record.Sizes[name] = bucket
+		}
+		bucket.Files++
+		if result.Error != "" {
+			record.Failed++
+			bucket.Failed++
+		}
+		bucket.Bytes += timing.Bytes
+		bucket.Read += timing.Read
+		bucket.Segments += timing.Segments
+		bucket.Parse += timing.Parse
+		bucket.Tables += timing.Tables
+		if other := result.ProcessingTime - timing.Read - timing.Segments - timing.Parse - timing.Tables; other > 0 {
+			bucket.Other += other
+		}
+	}
+	return record
+}
+
+// appendPerfRecord appends record as a JSON line to filename, creating it if needed. Runs
+// writing to the same file add whole lines, so the file is never replaced like the outputs.
+func appendPerfRecord(filename string, record PerfRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
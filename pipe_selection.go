@@ -0,0 +1,93 @@
+package dxfparser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PipePolicy decides which pipe a drawing's cut lengths and welds are attributed to when its
+// ERECTION MATERIALS list several PIPE rows
+type PipePolicy string
+
+const (
+	PipePolicyAll         PipePolicy = "all"          // every pipe, unweighted (earlier versions)
+	PipePolicyFirst       PipePolicy = "first"        // the first PIPE row of the table
+	PipePolicyMaxQty      PipePolicy = "max-qty"      // the PIPE row with the largest quantity
+	PipePolicyAllWeighted PipePolicy = "all-weighted" // every pipe with its share of the pipe quantity
+)
+
+// parsePipePolicy validates a -pipe-policy value
+func parsePipePolicy(value string) (PipePolicy, error) {
+	switch policy := PipePolicy(value); policy {
+	case PipePolicyAll, PipePolicyFirst, PipePolicyMaxQty, PipePolicyAllWeighted:
+		return policy, nil
+	}
+	return "", fmt.Errorf("invalid pipe policy '%s' (all, first, max-qty, all-weighted)", value)
+}
+
+// PipeRow is a PIPE row of a drawing's ERECTION MATERIALS
+type PipeRow struct {
+	Description string  `json:"description"`
+	NS          string  `json:"ns"`
+	Qty         float64 `json:"qty"`
+	Share       float64 `json:"share"` // share of the selected pipe quantity, 0 for PipePolicyAll
+}
+
+// selectPipes applies policy to the PIPE rows of one drawing, in table order. first and
+// max-qty return one row with share 1; all-weighted returns every row with its share of the
+// total quantity (equal shares if no quantity can be read), largest first.
+func selectPipes(rows []PipeRow, policy PipePolicy) []PipeRow {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	switch policy {
+	case PipePolicyFirst:
+		selected := rows[0]
+		selected.Share = 1
+		return []PipeRow{selected}
+	case PipePolicyMaxQty:
+		best := 0
+		for i, row := range rows {
+			if row.Qty > rows[best].Qty {
+				best = i
+			}
+		}
+		selected := rows[best]
+		selected.Share = 1
+		return []PipeRow{selected}
+	case PipePolicyAllWeighted:
+		total := 0.0
+		for _, row := range rows {
+			total += row.Qty
+		}
+		selected := make([]PipeRow, len(rows))
+		for i, row := range rows {
+			if total > 0 {
+				row.Share = row.Qty / total
+			} else {
+				row.Share = 1 / float64(len(rows))
+			}
+			selected[i] = row
+		}
+		sort.SliceStable(selected, func(i, j int) bool {
+			return selected[i].Share > selected[j].Share
+		})
+		return selected
+	}
+	return rows
+}
+
+// describePipes joins the descriptions of selected pipes with sep, weighted pipes with their
+// share, e.g. "PIPE 60.3x2.9 (75%) | PIPE 33.7x2.6 (25%)"
+func describePipes(selected []PipeRow, policy PipePolicy, sep string) string {
+	descriptions := make([]string, len(selected))
+	for i, pipe := range selected {
+		descriptions[i] = pipe.Description
+		if policy == PipePolicyAllWeighted {
+			descriptions[i] += fmt.Sprintf(" (%.0f%%)", pipe.Share*100)
+		}
+	}
+	return strings.Join(descriptions, sep)
+}
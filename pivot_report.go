@@ -0,0 +1,348 @@
+package dxfparser
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// pivotNone is the row or column of records without a value for the pivoted field
+const pivotNone = "(none)"
+
+// pivotTable sums values by a row and a column key
+type pivotTable struct {
+	cells map[string]map[string]float64
+	rows  map[string]bool
+	cols  map[string]bool
+}
+
+func newPivotTable() *pivotTable {
+	return &pivotTable{cells: make(map[string]map[string]float64), rows: make(map[string]bool), cols: make(map[string]bool)}
+}
+
+// add adds value to the cell of row and col; empty keys count as pivotNone
+func (p *pivotTable) add(row, col string, value float64) {
+	if row = strings.TrimSpace(row); row == "" {
+		row = pivotNone
+	}
+	if col = strings.TrimSpace(col); col == "" {
+		col = pivotNone
+	}
+	if p.cells[row] == nil {
+		p.cells[row] = make(map[string]float64)
+	}
+	p.cells[row][col] += value
+	p.rows[row] = true
+	p.cols[col] = true
+}
+
+// render returns the header and rows of the table with a Total column, rows ordered by less
+// and columns by name. A TOTAL row is added if totalRow is set; quantities of different
+// categories are in different units, so they are not added up.
+func (p *pivotTable) render(corner string, less func(a, b string) bool, totalRow bool) ([]string, [][]string) {
+	rows := sortedPivotKeys(p.rows, less)
+	cols := sortedPivotKeys(p.cols, func(a, b string) bool { return a < b })
+
+	header := append(append([]string{corner}, cols...), "Total")
+	colTotals := make([]float64, len(cols)+1)
+	var out [][]string
+	for _, row := range rows {
+		line := []string{row}
+		total := 0.0
+		for i, col := range cols {
+			value, ok := p.cells[row][col]
+			total += value
+			colTotals[i] += value
+			if !ok {
+				line = append(line, "")
+				continue
+			}
+			line = append(line, formatPivotValue(value))
+		}
+		colTotals[len(cols)] += total
+		out = append(out, append(line, formatPivotValue(total)))
+	}
+	if totalRow {
+		line := []string{"TOTAL"}
+		for _, value := range colTotals {
+			line = append(line, formatPivotValue(value))
+		}
+		out = append(out, line)
+	}
+	return header, out
+}
+
+// sortedPivotKeys returns the keys of set ordered by less, pivotNone last
+func sortedPivotKeys(set map[string]bool, less func(a, b string) bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if (keys[i] == pivotNone) != (keys[j] == pivotNone) {
+			return keys[j] == pivotNone
+		}
+		return less(keys[i], keys[j])
+	})
+	return keys
+}
+
+// formatPivotValue formats a sum without the noise of adding up decimal quantities
+func formatPivotValue(value float64) string {
+	return strconv.FormatFloat(math.Round(value*1e6)/1e6, 'f', -1, 64)
+}
+
+// leadingSize matches the nominal size a weld size starts with, e.g. 25 of "25 x 15"
+var leadingSize = regexp.MustCompile(`^\s*(\d+(?:\.\d+)?)`)
+
+// lessSize orders weld sizes by their leading number, then by name
+func lessSize(a, b string) bool {
+	ma, mb := leadingSize.FindStringSubmatch(a), leadingSize.FindStringSubmatch(b)
+	if ma != nil && mb != nil {
+		na, _ := strconv.ParseFloat(ma[1], 64)
+		nb, _ := strconv.ParseFloat(mb[1], 64)
+		if na != nb {
+			return na < nb
+		}
+	} else if (ma == nil) != (mb == nil) {
+		return ma != nil
+	}
+	return a < b
+}
+
+// readOutputCSV reads a CSV output of a bom run and returns its rows as maps by column name
+func readOutputCSV(filename string) ([]map[string]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%s is empty", filename)
+	}
+
+	header := records[0]
+	rows := make([]map[string]string, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]string, len(header))
+		for i, col := range header {
+			if i < len(record) {
+				row[strings.TrimSpace(col)] = record[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// findRunPrefix returns the output name prefix of the run in directory: "<run-id>_" for runID,
+// "" for runIDNone and, without a run id, the prefix of the newest summary
+func findRunPrefix(directory, runID string) (string, error) {
+	switch runID {
+	case runIDNone:
+		return "", nil
+	case "":
+	default:
+		return runID + "_", nil
+	}
+
+	summaries, err := filepath.Glob(filepath.Join(directory, "*0004_SUMMARY.csv"))
+	if err != nil {
+		return "", err
+	}
+	newest := ""
+	var newestTime int64
+	for _, summary := range summaries {
+		info, err := os.Stat(summary)
+		if err != nil {
+			continue
+		}
+		if newest == "" || info.ModTime().UnixNano() > newestTime {
+			newest, newestTime = summary, info.ModTime().UnixNano()
+		}
+	}
+	if newest == "" {
+		return "", fmt.Errorf("no run summary (*0004_SUMMARY.csv) in %s", directory)
+	}
+	return strings.TrimSuffix(filepath.Base(newest), "0004_SUMMARY.csv"), nil
+}
+
+// materialsPivot sums the erection materials by category and pipe class. value is qty, weight
+// or rows (the number of material rows).
+func materialsPivot(rows []map[string]string, value string) *pivotTable {
+	pivot := newPivotTable()
+	for _, row := range rows {
+		category := row["CATEGORY"]
+		if strings.Contains(category, "TOTAL") {
+			continue
+		}
+		amount := 1.0
+		switch value {
+		case "qty":
+			amount = parseQuantity(row["QTY"])
+		case "weight":
+			amount = parseQuantity(row["WEIGHT"])
+		}
+		pivot.add(category, row["Pipe Class"], amount)
+	}
+	return pivot
+}
+
+// weldArea returns the area of a drawing: the first group (or the whole match) of area in the
+// drawing number, or without a pattern the directory the drawing is in
+func weldArea(area *regexp.Regexp, filePath, drawingNo string) string {
+	if area == nil {
+		return filepath.Base(filepath.Dir(filepath.FromSlash(strings.ReplaceAll(filePath, "\\", "/"))))
+	}
+	match := area.FindStringSubmatch(drawingNo)
+	switch {
+	case match == nil:
+		return ""
+	case len(match) > 1:
+		return match[1]
+	}
+	return match[0]
+}
+
+// weldsPivot sums the welds by size and area, or by size and pipe class if classes (pipe
+// class by file path) is set
+func weldsPivot(rows []map[string]string, area *regexp.Regexp, classes map[string]string) *pivotTable {
+	pivot := newPivotTable()
+	for _, row := range rows {
+		count, err := strconv.Atoi(strings.TrimSpace(row["WeldCount"]))
+		if err != nil {
+			continue
+		}
+		column := weldArea(area, row["FilePath"], row["DrawingNo"])
+		if classes != nil {
+			column = classes[row["FilePath"]]
+		}
+		pivot.add(row["Size"], column, float64(count))
+	}
+	return pivot
+}
+
+// handlePivotCommand implements "report pivot": it reads the CSV outputs of a bom run and writes
+// materials by category and pipe class and welds by size and area as CSV or one XLSX workbook
+func handlePivotCommand(args []string) {
+	fs := newCommandFlagSet("report pivot", "dxf_parser report pivot <output-dir> [-run-id id] [-format csv|xlsx] [-value qty|weight|rows] [-area regex] [-weld-columns area|class]")
+	runID := fs.String("run-id", "", "Run to pivot, or 'none' for the fixed output names (default: newest run in the directory)")
+	format := fs.String("format", "csv", "Output format (csv, xlsx)")
+	value := fs.String("value", "qty", "Material value to sum (qty, weight, rows)")
+	areaPattern := fs.String("area", "", "Regular expression for the area in the drawing number, first group if any (default: directory of the drawing)")
+	weldColumns := fs.String("weld-columns", "area", "Columns of the weld pivot (area, class)")
+	args = parseCommandArgs(fs, args)
+	checkArgCount(fs, args, 1, 1, "Error: Missing output directory argument")
+
+	switch *format {
+	case "csv", "xlsx":
+	default:
+		usageError(fs, fmt.Sprintf("Error: invalid format '%s' (csv, xlsx)", *format))
+	}
+	switch *value {
+	case "qty", "weight", "rows":
+	default:
+		usageError(fs, fmt.Sprintf("Error: invalid value '%s' (qty, weight, rows)", *value))
+	}
+	switch *weldColumns {
+	case "area", "class":
+	default:
+		usageError(fs, fmt.Sprintf("Error: invalid weld columns '%s' (area, class)", *weldColumns))
+	}
+	var area *regexp.Regexp
+	if *areaPattern != "" {
+		var err error
+		if area, err = regexp.Compile(*areaPattern); err != nil {
+			usageError(fs, fmt.Sprintf("Error: invalid area pattern: %v", err))
+		}
+	}
+
+	directory := args[0]
+	prefix, err := findRunPrefix(directory, *runID)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var sheets []xlsxSheet
+	materialsFile := filepath.Join(directory, prefix+"0001_ERECTION_MATERIALS.csv")
+	if rows, err := readOutputCSV(materialsFile); err == nil {
+		header, table := materialsPivot(rows, *value).render("CATEGORY", func(a, b string) bool { return a < b }, *value != "qty")
+		sheets = append(sheets, xlsxSheet{Name: "Materials", Header: header, Rows: table, Numbers: true})
+	} else if !os.IsNotExist(err) {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	} else {
+		fmt.Printf("No materials in this run (%s), skipping the materials pivot\n", materialsFile)
+	}
+
+	weldsFile := filepath.Join(directory, prefix+"0006_WELDS_BY_SIZE.csv")
+	if rows, err := readOutputCSV(weldsFile); err == nil {
+		var classes map[string]string
+		if *weldColumns == "class" {
+			if classes, err = weldPipeClasses(filepath.Join(directory, prefix+"0005_WELD_COUNTS.csv")); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		header, table := weldsPivot(rows, area, classes).render("Size", lessSize, true)
+		sheets = append(sheets, xlsxSheet{Name: "Welds", Header: header, Rows: table, Numbers: true})
+	} else if !os.IsNotExist(err) {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	} else {
+		fmt.Printf("No weld counts in this run (%s), skipping the weld pivot; run bom with -weld\n", weldsFile)
+	}
+
+	if len(sheets) == 0 {
+		fmt.Printf("Error: nothing to pivot in %s\n", directory)
+		os.Exit(1)
+	}
+
+	if *format == "xlsx" {
+		filename := filepath.Join(directory, prefix+"0007_PIVOTS.xlsx")
+		if err := writeXLSXSheets(nil, filename, sheets); err != nil {
+			fmt.Printf("Error writing pivots: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Wrote PIVOTS to: %s (%d sheets)\n", filename, len(sheets))
+		return
+	}
+	for _, sheet := range sheets {
+		filename := filepath.Join(directory, prefix+"0007_PIVOT_MATERIALS.csv")
+		if sheet.Name == "Welds" {
+			filename = filepath.Join(directory, prefix+"0008_PIVOT_WELDS.csv")
+		}
+		if err := writeCSVFile(nil, filename, sheet.Header, sheet.Rows); err != nil {
+			fmt.Printf("Error writing pivots: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Wrote %s PIVOT to: %s (%d rows)\n", strings.ToUpper(sheet.Name), filename, len(sheet.Rows))
+	}
+}
+
+// weldPipeClasses returns the pipe class of every drawing of a weld counts file by file path
+func weldPipeClasses(filename string) (map[string]string, error) {
+	rows, err := readOutputCSV(filename)
+	if err != nil {
+		return nil, err
+	}
+	classes := make(map[string]string, len(rows))
+	for _, row := range rows {
+		classes[row["FilePath"]] = row["PipeClass"]
+	}
+	return classes, nil
+}
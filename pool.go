@@ -0,0 +1,71 @@
+package dxfparser
+
+import "sync"
+
+// Parses build their entities and polyline segments in slices taken from pools. A batch run
+// parses one drawing after the other on every worker, and without the pools each parse grew
+// its slices from nothing again, which kept the garbage collector busy with the arrays of the
+// drawings before.
+//
+// Ownership: a slice returned by a parse (ParseFile, ParseBytes, ParseReader, ...) belongs to
+// the caller, who may keep it as long as it likes; the pool only gets it back through
+// ReleaseEntities. From then on neither the slice nor sub-slices of it or pointers to its
+// entities may be used, by the caller or code it passed them to: the next parse writes other
+// entities into the array. Values copied out before, the strings, Arrow and XData of an entity
+// included, stay valid. A slice must be released once, and only when it is not shared.
+
+// maxPooledEntities and maxPooledSegments bound the slices kept in the pools, so one huge
+// drawing does not hold its memory for the rest of a run
+const (
+	maxPooledEntities = 1 << 16
+	maxPooledSegments = 1 << 18
+)
+
+var (
+	entityPool  sync.Pool // *[]TextEntity
+	segmentPool sync.Pool // *[]PolylineSegment
+)
+
+// newEntities returns an empty slice with room for size entities, from the pool if it has one
+func newEntities(size int) []TextEntity {
+	if pooled, ok := entityPool.Get().(*[]TextEntity); ok {
+		if cap(*pooled) >= size {
+			return (*pooled)[:0]
+		}
+		entityPool.Put(pooled)
+	}
+	return make([]TextEntity, 0, size)
+}
+
+// ReleaseEntities hands the entities of a parse back for reuse by later parses. The slice and
+// its entities must not be used afterwards; see the ownership rules above. Releasing nil is a
+// no-op.
+func ReleaseEntities(entities []TextEntity) {
+	if cap(entities) == 0 || cap(entities) > maxPooledEntities {
+		return
+	}
+	// Let the strings and maps of the entities go
+	entities = entities[:cap(entities)]
+	clear(entities)
+	entities = entities[:0]
+	entityPool.Put(&entities)
+}
+
+// newSegments returns an empty slice of polyline segments, from the pool if it has one
+func newSegments() []PolylineSegment {
+	if pooled, ok := segmentPool.Get().(*[]PolylineSegment); ok {
+		return (*pooled)[:0]
+	}
+	return nil
+}
+
+// releaseSegments hands polyline segments back for reuse, under the rules of ReleaseEntities
+func releaseSegments(segments []PolylineSegment) {
+	if cap(segments) == 0 || cap(segments) > maxPooledSegments {
+		return
+	}
+	segments = segments[:cap(segments)]
+	clear(segments)
+	segments = segments[:0]
+	segmentPool.Put(&segments)
+}
@@ -0,0 +1,221 @@
+package dxfparser
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq" // registers the "postgres" database/sql driver
+)
+
+// findRevision extracts the drawing revision from the file name with the revision pattern,
+// by default the revision that follows the KKS code in isometric file names
+// e.g. TB020-INOV-2QFB94BR130_1.0_Pipe-Isometric-Drawing.dxf -> "1.0"
+func findRevision(path string, patterns *Patterns) string {
+	match := patterns.Revision.FindStringSubmatch(filepath.Base(path))
+	if len(match) < 2 {
+		return ""
+	}
+	return match[1]
+}
+
+// postgresMigrations are applied in order; the index+1 is the schema version.
+// Never edit an existing entry, always append a new one.
+var postgresMigrations = []string{
+	`CREATE TABLE IF NOT EXISTS dxf_drawings (
+		drawing_no TEXT NOT NULL,
+		revision TEXT NOT NULL,
+		pipe_class TEXT NOT NULL,
+		file_path TEXT NOT NULL,
+		weld_count INTEGER,
+		pipe_ns TEXT,
+		error TEXT NOT NULL,
+		processed_at TIMESTAMPTZ NOT NULL,
+		PRIMARY KEY (drawing_no, revision)
+	)`,
+	`CREATE TABLE IF NOT EXISTS dxf_materials (
+		drawing_no TEXT NOT NULL,
+		revision TEXT NOT NULL,
+		row_no INTEGER NOT NULL,
+		pt_no TEXT NOT NULL,
+		description TEXT NOT NULL,
+		ns TEXT NOT NULL,
+		qty TEXT NOT NULL,
+		weight TEXT NOT NULL,
+		category TEXT NOT NULL,
+		PRIMARY KEY (drawing_no, revision, row_no),
+		FOREIGN KEY (drawing_no, revision) REFERENCES dxf_drawings (drawing_no, revision) ON DELETE CASCADE
+	)`,
+	`CREATE TABLE IF NOT EXISTS dxf_cut_lengths (
+		drawing_no TEXT NOT NULL,
+		revision TEXT NOT NULL,
+		row_no INTEGER NOT NULL,
+		piece_no TEXT NOT NULL,
+		cut_length TEXT NOT NULL,
+		ns TEXT NOT NULL,
+		remarks TEXT NOT NULL,
+		pipe_description TEXT NOT NULL,
+		PRIMARY KEY (drawing_no, revision, row_no),
+		FOREIGN KEY (drawing_no, revision) REFERENCES dxf_drawings (drawing_no, revision) ON DELETE CASCADE
+	)`,
+}
+
+// migratePostgres brings the database schema up to the latest version
+func migratePostgres(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS dxf_schema_migrations (
+		version INTEGER PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	var current int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM dxf_schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for i := current; i < len(postgresMigrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(postgresMigrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d failed: %w", i+1, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO dxf_schema_migrations (version, applied_at) VALUES ($1, $2)`, i+1, time.Now().UTC()); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %d: %w", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		debugPrint(fmt.Sprintf("[DEBUG] Applied PostgreSQL migration %d", i+1))
+	}
+
+	return nil
+}
+
+// writePostgresResults upserts the extraction results by drawing number + revision.
+// Rows of a drawing that is re-processed replace the previously stored rows.
+func writePostgresResults(connStr string, results []DXFResult, weldResults []WeldResult) error {
+	db, err := sql.Open("postgres", connStr)
+	if err != nil {
+		return fmt.Errorf("failed to open PostgreSQL connection: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.Ping(); err != nil {
+		return fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+
+	if err := migratePostgres(db); err != nil {
+		return err
+	}
+
+	weldsByFile := make(map[string]WeldResult)
+	for _, weldResult := range weldResults {
+		weldsByFile[weldResult.FilePath] = weldResult
+	}
+
+	written := 0
+	skipped := 0
+	for _, result := range results {
+		if result.DrawingNo == "" {
+			debugPrint(fmt.Sprintf("[DEBUG] Skipping PostgreSQL upsert for %s: no drawing number", result.FilePath))
+			skipped++
+			continue
+		}
+
+		var weld *WeldResult
+		if weldResult, ok := weldsByFile[result.FilePath]; ok {
+			weld = &weldResult
+		}
+
+		if err := upsertDrawing(db, result, weld); err != nil {
+			return fmt.Errorf("failed to write %s: %w", result.DrawingNo, err)
+		}
+		written++
+	}
+
+	if skipped > 0 {
+		fmt.Println(msg("bom.pg_skipped", written, skipped))
+	} else {
+		fmt.Println(msg("bom.pg_written", written))
+	}
+	return nil
+}
+
+// upsertDrawing stores one drawing and replaces its material and cut length rows in a transaction
+func upsertDrawing(db *sql.DB, result DXFResult, weld *WeldResult) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var weldCount sql.NullInt64
+	var pipeNS sql.NullString
+	if weld != nil && weld.Error == "" {
+		weldCount = sql.NullInt64{Int64: int64(weld.WeldCount), Valid: true}
+		pipeNS = sql.NullString{String: weld.PipeNS, Valid: true}
+	}
+
+	_, err = tx.Exec(`INSERT INTO dxf_drawings (drawing_no, revision, pipe_class, file_path, weld_count, pipe_ns, error, processed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (drawing_no, revision) DO UPDATE SET
+			pipe_class = EXCLUDED.pipe_class,
+			file_path = EXCLUDED.file_path,
+			weld_count = EXCLUDED.weld_count,
+			pipe_ns = EXCLUDED.pipe_ns,
+			error = EXCLUDED.error,
+			processed_at = EXCLUDED.processed_at`,
+		result.DrawingNo, result.Revision, result.PipeClass, result.FilePath, weldCount, pipeNS, result.Error, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM dxf_materials WHERE drawing_no = $1 AND revision = $2`, result.DrawingNo, result.Revision); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM dxf_cut_lengths WHERE drawing_no = $1 AND revision = $2`, result.DrawingNo, result.Revision); err != nil {
+		return err
+	}
+
+	// Material rows: PT NO | DESCRIPTION | N.S. | QTY | WEIGHT | CATEGORY | ...
+	for i, row := range result.MatRows {
+		cells := padRow(row, 6)
+		if _, err := tx.Exec(`INSERT INTO dxf_materials (drawing_no, revision, row_no, pt_no, description, ns, qty, weight, category)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+			result.DrawingNo, result.Revision, i+1, cells[0], cells[1], cells[2], cells[3], cells[4], cells[5]); err != nil {
+			return err
+		}
+	}
+
+	// Cut length rows: PIECE NO | CUT LENGTH | N.S. (MM) | REMARKS | PIPE DESCRIPTION | ...
+	for i, row := range result.CutRows {
+		cells := padRow(row, 5)
+		if _, err := tx.Exec(`INSERT INTO dxf_cut_lengths (drawing_no, revision, row_no, piece_no, cut_length, ns, remarks, pipe_description)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			result.DrawingNo, result.Revision, i+1, cells[0], cells[1], cells[2], cells[3], cells[4]); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// padRow returns a copy of row with at least n cells, trimmed of surrounding whitespace
+func padRow(row []string, n int) []string {
+	size := len(row)
+	if size < n {
+		size = n
+	}
+	padded := make([]string, size)
+	for i, cell := range row {
+		padded[i] = strings.TrimSpace(cell)
+	}
+	return padded
+}
@@ -0,0 +1,270 @@
+package dxfparser
+
+import (
+	_ "embed"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// projectConfigFile is looked up in the drawings directory by every command
+const projectConfigFile = ".dxfparser.yaml"
+
+// defaultConfigYAML is the complete built-in configuration in project config format. Built-in
+// weld settings and patterns are read from it, so export-config shows exactly what a run uses.
+//
+//go:embed defaults/dxfparser.yaml
+var defaultConfigYAML []byte
+
+// builtinConfig is defaultConfigYAML decoded; a broken embedded config is a build error
+var builtinConfig = mustParseBuiltinConfig()
+
+func mustParseBuiltinConfig() *ProjectConfig {
+	config, err := parseProjectConfig(defaultConfigYAML, "embedded default config")
+	if err != nil {
+		panic(err)
+	}
+	w := config.Weld
+	if len(w.LengthPairs) == 0 || w.LengthTolerance == nil || w.CenterTolerance == nil || w.GroupTolerance == nil ||
+		w.DuplicateDistance == nil || w.LabelRadius == nil || w.SegmentEpsilon == nil {
+		panic("embedded default config: incomplete weld section")
+	}
+	p := config.Patterns
+	if p.DrawingNo == "" || p.PipeClass == "" || p.Revision == "" || p.Tag == "" {
+		panic("embedded default config: incomplete patterns section")
+	}
+	return config
+}
+
+// ProjectConfig holds site-specific defaults that travel with the drawings
+type ProjectConfig struct {
+	// Defaults for bom command flags not given on the command line, e.g. workers: 8
+	Defaults map[string]string `yaml:"defaults"`
+
+	// Alternative table titles per canonical title, e.g. "ERECTION MATERIALS": ["MONTAGEMATERIAL"]
+	TableAliases map[string][]string `yaml:"table_aliases"`
+
+	Weld struct {
+		LengthPairs       [][2]float64       `yaml:"length_pairs"`
+		LayerPairs        []LayerLengthPairs `yaml:"layer_pairs"`
+		LengthTolerance   *float64           `yaml:"length_tolerance"`
+		CenterTolerance   *float64           `yaml:"center_tolerance"`
+		GroupTolerance    *float64           `yaml:"group_center_tolerance"`
+		DuplicateDistance *float64           `yaml:"duplicate_distance"`
+		LabelRadius       *float64           `yaml:"label_radius"`
+		SegmentEpsilon    *float64           `yaml:"segment_epsilon"`
+	} `yaml:"weld"`
+
+	Patterns PatternOverrides `yaml:"patterns"`
+
+	// Text styles of the title block, e.g. ["TITLE"]; drawing number candidates in these styles
+	// are preferred over matches in annotation text
+	TitleStyles []string `yaml:"title_styles"`
+
+	// Named object dictionaries with structured BOM data, read before the text tables
+	EBOM struct {
+		Dictionaries []string `yaml:"dictionaries"`
+	} `yaml:"ebom"`
+
+	path string
+}
+
+// Active project settings; replaced by applyProjectConfig
+var (
+	weldConfig        = DefaultWeldConfig()
+	tableTitleAliases = map[string][]string{}
+	titleStyles       []string
+)
+
+// findProjectConfig returns the project config path for a file or directory, or "" if there is none
+func findProjectConfig(target string) string {
+	dir := target
+	if info, err := os.Stat(target); err == nil && !info.IsDir() {
+		dir = filepath.Dir(target)
+	}
+	path := filepath.Join(dir, projectConfigFile)
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// loadProjectConfig reads and validates a project config file
+func loadProjectConfig(path string) (*ProjectConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", path, err)
+	}
+	return parseProjectConfig(data, path)
+}
+
+// parseProjectConfig decodes and validates project config data; path names it in errors
+func parseProjectConfig(data []byte, path string) (*ProjectConfig, error) {
+	var config ProjectConfig
+	decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("error parsing %s: %v", path, err)
+	}
+	config.path = path
+
+	for _, o := range []struct{ name, source string }{
+		{"drawing_no", config.Patterns.DrawingNo},
+		{"pipe_class", config.Patterns.PipeClass},
+		{"revision", config.Patterns.Revision},
+		{"tag", config.Patterns.Tag},
+	} {
+		if _, err := regexp.Compile(o.source); err != nil {
+			return nil, fmt.Errorf("error in %s: invalid %s pattern: %v", path, o.name, err)
+		}
+	}
+	for _, set := range config.Weld.LayerPairs {
+		if err := set.validate(); err != nil {
+			return nil, fmt.Errorf("error in %s: %v", path, err)
+		}
+	}
+
+	return &config, nil
+}
+
+// applyProjectConfig installs the table aliases, weld configuration and patterns of config
+func applyProjectConfig(config *ProjectConfig) {
+	aliases := make(map[string][]string)
+	for title, alternatives := range config.TableAliases {
+		aliases[strings.ToUpper(strings.TrimSpace(title))] = alternatives
+	}
+	tableTitleAliases = aliases
+
+	weld := DefaultWeldConfig()
+	if len(config.Weld.LengthPairs) > 0 {
+		weld.LengthPairs = config.Weld.LengthPairs
+	}
+	if config.Weld.LayerPairs != nil {
+		weld.LayerPairs = config.Weld.LayerPairs
+	}
+	if config.Weld.LengthTolerance != nil {
+		weld.LengthTolerance = *config.Weld.LengthTolerance
+	}
+	if config.Weld.CenterTolerance != nil {
+		weld.CenterTolerance = *config.Weld.CenterTolerance
+	}
+	if config.Weld.GroupTolerance != nil {
+		weld.GroupTolerance = *config.Weld.GroupTolerance
+	}
+	if config.Weld.DuplicateDistance != nil {
+		weld.DuplicateDistance = *config.Weld.DuplicateDistance
+	}
+	if config.Weld.LabelRadius != nil {
+		weld.LabelRadius = *config.Weld.LabelRadius
+	}
+	if config.Weld.SegmentEpsilon != nil {
+		weld.SegmentEpsilon = *config.Weld.SegmentEpsilon
+	}
+	weldConfig = weld
+	ebomDictionaries = config.EBOM.Dictionaries
+	titleStyles = config.TitleStyles
+
+	// Patterns were validated when loading
+	patterns, _ := DefaultPatterns().Override(config.Patterns)
+	projectPatterns.Store(patterns)
+
+	debugPrint(fmt.Sprintf("[DEBUG] Project config %s: %d table aliases, weld pairs %v, %d layer sets", config.path, len(aliases), weld.LengthPairs, len(weld.LayerPairs)))
+}
+
+// applyFlagDefaults sets flags of fs that were not given on the command line from config.Defaults
+func applyFlagDefaults(fs *flag.FlagSet, config *ProjectConfig) error {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	names := make([]string, 0, len(config.Defaults))
+	for name := range config.Defaults {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if explicit[name] {
+			continue
+		}
+		if fs.Lookup(name) == nil {
+			return fmt.Errorf("error in %s: unknown option '%s' in defaults", config.path, name)
+		}
+		if err := fs.Set(name, config.Defaults[name]); err != nil {
+			return fmt.Errorf("error in %s: invalid value for '%s': %v", config.path, name, err)
+		}
+	}
+	return nil
+}
+
+// useProjectConfig loads and applies the project config next to target (file or directory).
+// Commands without flag defaults pass a nil FlagSet. Errors in the config file are fatal.
+func useProjectConfig(target string, fs *flag.FlagSet) {
+	path := findProjectConfig(target)
+	if path == "" {
+		return
+	}
+
+	config, err := loadProjectConfig(path)
+	if err == nil && fs != nil {
+		err = applyFlagDefaults(fs, config)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, msg("config.error", err))
+		os.Exit(exitConfigError)
+	}
+
+	applyProjectConfig(config)
+	fmt.Fprintln(os.Stderr, msg("config.using", path))
+}
+
+// matchesTableTitle checks if text contains the table title or one of its project aliases
+func matchesTableTitle(text, tableTitle string) bool {
+	text = strings.ToLower(text)
+	if strings.Contains(text, strings.ToLower(tableTitle)) {
+		return true
+	}
+	for _, alias := range tableTitleAliases[strings.ToUpper(tableTitle)] {
+		if alias != "" && strings.Contains(text, strings.ToLower(alias)) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleExportConfigCommand implements "export-config": it writes the embedded default config,
+// to standard output or as a starting point for a project's .dxfparser.yaml
+func handleExportConfigCommand() {
+	fs := newCommandFlagSet("export-config", "dxf_parser export-config [-out <directory|file>] [-force]")
+	out := fs.String("out", "", "Write to this file, or to .dxfparser.yaml in this directory (default: standard output)")
+	force := fs.Bool("force", false, "Replace an existing file")
+	checkArgCount(fs, parseCommandArgs(fs, os.Args[2:]), 0, 0, "")
+
+	if *out == "" {
+		os.Stdout.Write(defaultConfigYAML)
+		return
+	}
+
+	path := *out
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, projectConfigFile)
+	}
+	if _, err := os.Stat(path); err == nil && !*force {
+		fmt.Fprintln(os.Stderr, msg("config.exists", path))
+		os.Exit(1)
+	}
+	if err := writeOutputFile(path, defaultConfigYAML); err != nil {
+		fmt.Fprintln(os.Stderr, msg("config.error", err))
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote default config to: %s\n", path)
+}
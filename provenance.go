@@ -0,0 +1,200 @@
+package dxfparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// CellSource points an output cell back to the text entity it was taken from
+type CellSource struct {
+	Column     string  `json:"column"`
+	Value      string  `json:"value"`
+	Found      bool    `json:"found"`
+	X          float64 `json:"x,omitempty"`
+	Y          float64 `json:"y,omitempty"`
+	Z          float64 `json:"z,omitempty"`
+	Layer      string  `json:"layer,omitempty"`
+	EntityType string  `json:"entity_type,omitempty"`
+	Handle     string  `json:"handle,omitempty"`  // DXF handle of the entity, or of the INSERT of block text
+	Content    string  `json:"content,omitempty"` // raw entity text when it differs from the cell value
+}
+
+// RowProvenance maps one output CSV row to the source entities of its cells
+type RowProvenance struct {
+	Table     string       `json:"table"`
+	Row       int          `json:"row"` // 1-based data row number in the output CSV
+	FilePath  string       `json:"file_path"`
+	DrawingNo string       `json:"drawing_no"`
+	Cells     []CellSource `json:"cells"`
+}
+
+// provenanceIndex looks up text entities by their trimmed content
+type provenanceIndex struct {
+	entities  []TextEntity
+	byContent map[string][]int
+}
+
+func newProvenanceIndex(entities []TextEntity) *provenanceIndex {
+	idx := &provenanceIndex{
+		entities:  entities,
+		byContent: make(map[string][]int),
+	}
+	for i, entity := range entities {
+		key := strings.TrimSpace(entity.Content)
+		idx.byContent[key] = append(idx.byContent[key], i)
+	}
+	return idx
+}
+
+// candidates returns entities whose content produced value: exact matches, values with a
+// stripped "M" suffix (QTY) and, for merged multi-line descriptions, the first line
+func (idx *provenanceIndex) candidates(value string) []int {
+	if matches := idx.byContent[value]; len(matches) > 0 {
+		return matches
+	}
+	if matches := idx.byContent[value+"M"]; len(matches) > 0 {
+		return matches
+	}
+	if len(value) < 3 {
+		return nil
+	}
+	var matches []int
+	for i, entity := range idx.entities {
+		content := strings.TrimSpace(entity.Content)
+		if len(content) >= 3 && strings.HasPrefix(value, content) {
+			matches = append(matches, i)
+		}
+	}
+	return matches
+}
+
+// closest picks the candidate nearest to the anchor row (by Y, then X)
+func (idx *provenanceIndex) closest(matches []int, anchorY float64, hasAnchor bool) int {
+	best := matches[0]
+	if !hasAnchor {
+		return best
+	}
+	bestDist := math.Abs(idx.entities[best].Y - anchorY)
+	for _, i := range matches[1:] {
+		if d := math.Abs(idx.entities[i].Y - anchorY); d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best
+}
+
+// buildProvenance maps each row's cells to source entities. The first located cell of a
+// row (usually PT NO / PIECE NO) anchors the row so repeated values resolve to the right line.
+func buildProvenance(table string, header []string, rows [][]string, entities []TextEntity, filePath, drawingNo string) []RowProvenance {
+	idx := newProvenanceIndex(entities)
+	provenance := make([]RowProvenance, 0, len(rows))
+
+	for r, row := range rows {
+		rowProv := RowProvenance{
+			Table:     table,
+			Row:       r + 1,
+			FilePath:  filePath,
+			DrawingNo: drawingNo,
+			Cells:     make([]CellSource, len(row)),
+		}
+
+		var anchorY float64
+		hasAnchor := false
+		for c, value := range row {
+			column := ""
+			if c < len(header) {
+				column = header[c]
+			}
+			cell := CellSource{Column: column, Value: value}
+
+			value = strings.TrimSpace(value)
+			if value != "" {
+				if matches := idx.candidates(value); len(matches) > 0 {
+					entity := idx.entities[idx.closest(matches, anchorY, hasAnchor)]
+					cell.Found = true
+					cell.X = entity.X
+					cell.Y = entity.Y
+					cell.Z = entity.Z
+					cell.Layer = entity.Layer
+					cell.EntityType = entity.EntityType
+					cell.Handle = entity.Handle
+					if strings.TrimSpace(entity.Content) != value {
+						cell.Content = entity.Content
+					}
+					if !hasAnchor {
+						anchorY = entity.Y
+						hasAnchor = true
+					}
+				}
+			}
+			rowProv.Cells[c] = cell
+		}
+
+		provenance = append(provenance, rowProv)
+	}
+
+	return provenance
+}
+
+// addHandleColumn returns header and rows with a HANDLES column: the distinct handles of the
+// source entities of each row's cells, in column order and separated by spaces
+func addHandleColumn(header []string, rows [][]string, provenance []RowProvenance) ([]string, [][]string) {
+	if len(rows) == 0 {
+		return header, rows
+	}
+	for i := range rows {
+		var handles []string
+		seen := make(map[string]bool)
+		for _, cell := range provenance[i].Cells {
+			if cell.Handle != "" && !seen[cell.Handle] {
+				seen[cell.Handle] = true
+				handles = append(handles, cell.Handle)
+			}
+		}
+		rows[i] = append(rows[i], strings.Join(handles, " "))
+	}
+	return append(header, "HANDLES"), rows
+}
+
+// writeProvenanceJSON writes the side-car provenance files next to the CSV outputs.
+// Row numbers are renumbered to match the combined CSV files.
+func writeProvenanceJSON(out *runOutputs, directory string, results []DXFResult) error {
+	var matProv, cutProv []RowProvenance
+	for _, result := range results {
+		for _, row := range result.MatProvenance {
+			row.Row = len(matProv) + 1
+			matProv = append(matProv, row)
+		}
+		for _, row := range result.CutProvenance {
+			row.Row = len(cutProv) + 1
+			cutProv = append(cutProv, row)
+		}
+	}
+
+	outputs := []struct {
+		name string
+		rows []RowProvenance
+	}{
+		{"0001_ERECTION_MATERIALS.provenance.json", matProv},
+		{"0002_CUT_PIPE_LENGTH.provenance.json", cutProv},
+	}
+
+	for _, output := range outputs {
+		if len(output.rows) == 0 {
+			continue
+		}
+		filename := out.path(directory, output.name)
+		data, err := json.MarshalIndent(output.rows, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := out.writeFile(filename, data); err != nil {
+			return fmt.Errorf("error writing %s: %v", output.name, err)
+		}
+		fmt.Printf("Wrote provenance to: %s (%d rows)\n", filename, len(output.rows))
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package dxfparser
+
+// proxySkipper skips the payload of proxy entities (ACAD_PROXY_ENTITY) and objects
+// (ACAD_PROXY_OBJECT) in scans of a file. Their graphics and entity data are binary chunks
+// (310) that some exporters write with raw line breaks, which would shift the code / value
+// alternation for the rest of the file. The common groups before the first chunk (handle,
+// layer, ...) are read as pairs; from there the record is read as lines up to a "0" line
+// followed by a record name, the rule findSafeChunkEnd splits chunks by.
+type proxySkipper struct {
+	record   bool // in a proxy record, before its payload
+	active   bool // in the payload
+	zero     bool // the previous line of the payload was "0"
+	entities int  // proxy entities seen
+}
+
+// enter takes the type of every record
+func (s *proxySkipper) enter(record string) {
+	s.record = record == "ACAD_PROXY_ENTITY" || record == "ACAD_PROXY_OBJECT"
+	if record == "ACAD_PROXY_ENTITY" {
+		s.entities++
+	}
+}
+
+// payload takes every group code and reports whether it starts the payload of a proxy, which
+// is then skipped line by line, its value first
+func (s *proxySkipper) payload(code string) bool {
+	if !s.record || code != "310" {
+		return false
+	}
+	s.record, s.active, s.zero = false, true, false
+	return true
+}
+
+// skip takes the next line of a proxy payload and reports whether it is part of it. It is
+// false for the name of the record after the proxy; the caller reads that line as the value
+// of a code 0.
+func (s *proxySkipper) skip(line string) bool {
+	if s.zero && isRecordName(line) {
+		s.active = false
+		return false
+	}
+	s.zero = line == "0"
+	return true
+}
+
+// ProxyEntities returns the number of proxy entities of the last parse, whose payload was
+// skipped; their graphics need the application that created them
+func (p *DXFParser) ProxyEntities() int {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.parseWarnings.proxies
+}
@@ -0,0 +1,69 @@
+package dxfparser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// writeRawTables writes one CSV per drawing and table with the rows as reconstructed from
+// the text positions, before header merging, category moves, corrections and filtering
+func writeRawTables(out *runOutputs, directory string, results []DXFResult) error {
+	rawDir := out.path(directory, "raw_tables")
+	if err := os.MkdirAll(rawDir, 0755); err != nil {
+		return fmt.Errorf("error creating raw tables directory: %v", err)
+	}
+
+	written := 0
+	for _, result := range results {
+		base := strings.TrimSuffix(filepath.Base(result.FilePath), filepath.Ext(result.FilePath))
+
+		tables := []struct {
+			name string
+			rows []RawTableRow
+		}{
+			{"ERECTION_MATERIALS", result.RawMatRows},
+			{"CUT_PIPE_LENGTH", result.RawCutRows},
+		}
+
+		for _, table := range tables {
+			if len(table.rows) == 0 {
+				continue
+			}
+
+			// Raw rows have varying widths; pad to the widest row
+			width := 0
+			for _, row := range table.rows {
+				if len(row.Cells) > width {
+					width = len(row.Cells)
+				}
+			}
+
+			header := []string{"PAGE", "ROW", "Y"}
+			for i := 0; i < width; i++ {
+				header = append(header, fmt.Sprintf("COL%d", i+1))
+			}
+
+			rows := make([][]string, len(table.rows))
+			for i, row := range table.rows {
+				record := []string{strconv.Itoa(row.Page), strconv.Itoa(i + 1), strconv.FormatFloat(row.Y, 'f', 1, 64)}
+				record = append(record, row.Cells...)
+				for len(record) < len(header) {
+					record = append(record, "")
+				}
+				rows[i] = record
+			}
+
+			filename := filepath.Join(rawDir, base+"_"+table.name+".csv")
+			if err := writeCSV(out, filename, header, rows); err != nil {
+				return fmt.Errorf("error writing raw table %s: %v", filename, err)
+			}
+			written++
+		}
+	}
+
+	fmt.Printf("Wrote %d raw table dumps to: %s\n", written, rawDir)
+	return nil
+}
@@ -0,0 +1,206 @@
+package dxfparser
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// handleReplayCommand implements "replay <summary.csv> <drawing-no>": it looks up the source
+// file of one drawing in a batch summary and re-runs it alone with full debug trace and overlay
+func handleReplayCommand() {
+	fs := newCommandFlagSet("replay", "dxf_parser replay <0004_SUMMARY.csv> <drawing-no> [-o overlay.dxf] [-quiet]")
+	overlayPath := fs.String("o", "", "Overlay DXF output file (default: <drawing-no>_overlay.dxf next to the summary)")
+	quiet := fs.Bool("quiet", false, "Disable the debug trace, only print the extracted tables")
+	args := parseCommandArgs(fs, os.Args[2:])
+	checkArgCount(fs, args, 2, 2, "Error: Missing arguments for replay command")
+
+	summaryPath := args[0]
+	drawingNo := args[1]
+
+	sourcePath, err := findSourceInSummary(summaryPath, drawingNo)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	useProjectConfig(sourcePath, nil)
+
+	fmt.Printf("Replaying drawing %s from: %s\n", drawingNo, sourcePath)
+	fmt.Println(strings.Repeat("=", 60))
+
+	debugMode = !*quiet
+
+	report, err := ProcessDrawing(sourcePath, DrawingOptions{Welds: true, WeldLabels: true, WeldExplain: true, Provenance: true})
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	for i, weld := range report.Welds.Symbols {
+		debugPrint(fmt.Sprintf("[DEBUG] Weld W%d: %s", i+1, weld.Explanation))
+	}
+	for _, warning := range report.Warnings {
+		fmt.Printf("Warning: %s\n", warning)
+	}
+
+	printReplayTable("ERECTION MATERIALS", report.Materials.Header, report.Materials.Rows)
+	printReplayTable("CUT PIPE LENGTH", report.CutLengths.Header, report.CutLengths.Rows)
+	fmt.Printf("\nDrawing No: %s  Pipe Class: %s  Revision: %s  Welds: %d\n",
+		report.DrawingNo, report.PipeClass, report.Revision, report.WeldCount())
+
+	if *overlayPath == "" {
+		*overlayPath = filepath.Join(filepath.Dir(summaryPath), drawingNo+"_overlay.dxf")
+	}
+	if err := writeOverlayDXF(*overlayPath, report); err != nil {
+		fmt.Printf("Error writing overlay: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote overlay to: %s\n", *overlayPath)
+}
+
+// findSourceInSummary returns the file path recorded for drawingNo in a 0004_SUMMARY.csv.
+// Paths written on Windows are converted and tried relative to the summary location too.
+func findSourceInSummary(summaryPath, drawingNo string) (string, error) {
+	file, err := os.Open(summaryPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open summary: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	records, err := reader.ReadAll()
+	if err != nil {
+		return "", fmt.Errorf("failed to read summary: %w", err)
+	}
+	if len(records) == 0 {
+		return "", fmt.Errorf("summary %s is empty", summaryPath)
+	}
+
+	pathIdx, drawingIdx := -1, -1
+	for i, col := range records[0] {
+		switch strings.TrimSpace(col) {
+		case "FilePath":
+			pathIdx = i
+		case "DrawingNo":
+			drawingIdx = i
+		}
+	}
+	if pathIdx == -1 || drawingIdx == -1 {
+		return "", fmt.Errorf("summary %s has no FilePath/DrawingNo columns", summaryPath)
+	}
+
+	for _, record := range records[1:] {
+		if len(record) <= pathIdx || len(record) <= drawingIdx {
+			continue
+		}
+		if !strings.EqualFold(strings.TrimSpace(record[drawingIdx]), drawingNo) {
+			continue
+		}
+
+		recorded := filepath.FromSlash(strings.ReplaceAll(record[pathIdx], "\\", "/"))
+		candidates := []string{
+			recorded,
+			filepath.Join(filepath.Dir(summaryPath), recorded),
+			filepath.Join(filepath.Dir(summaryPath), filepath.Base(recorded)),
+		}
+		for _, candidate := range candidates {
+			if _, err := os.Stat(candidate); err == nil {
+				return candidate, nil
+			}
+		}
+		return "", fmt.Errorf("drawing %s found in summary but source file %s does not exist", drawingNo, record[pathIdx])
+	}
+
+	return "", fmt.Errorf("drawing %s not found in %s", drawingNo, summaryPath)
+}
+
+// printReplayTable prints an extracted table with row numbers
+func printReplayTable(title string, header []string, rows [][]string) {
+	fmt.Printf("\n%s (%d rows)\n", title, len(rows))
+	fmt.Println(strings.Repeat("-", 60))
+	if len(header) > 0 {
+		fmt.Printf("     %s\n", strings.Join(header, " | "))
+	}
+	for i, row := range rows {
+		fmt.Printf("%3d. %s\n", i+1, strings.Join(row, " | "))
+	}
+}
+
+// writeOverlayDXF writes a minimal DXF with markers for every located table cell and weld
+// symbol. It can be attached to the source drawing as an XREF / overlay to review the extraction.
+func writeOverlayDXF(path string, report *DrawingReport) error {
+	file, err := createOutput(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	group := func(code int, value string) {
+		fmt.Fprintf(w, "%d\n%s\n", code, value)
+	}
+	marker := func(layer string, color int, x, y, radius float64, label string, height float64) {
+		group(0, "CIRCLE")
+		group(8, layer)
+		group(62, fmt.Sprintf("%d", color))
+		group(10, fmt.Sprintf("%f", x))
+		group(20, fmt.Sprintf("%f", y))
+		group(30, "0.0")
+		group(40, fmt.Sprintf("%f", radius))
+		if label != "" {
+			group(0, "TEXT")
+			group(8, layer)
+			group(62, fmt.Sprintf("%d", color))
+			group(10, fmt.Sprintf("%f", x+radius))
+			group(20, fmt.Sprintf("%f", y+radius))
+			group(30, "0.0")
+			group(40, fmt.Sprintf("%f", height))
+			group(1, label)
+		}
+	}
+
+	group(0, "SECTION")
+	group(2, "ENTITIES")
+
+	// Materials in green, cut lengths in cyan, weld symbols in red (ACI colors)
+	for _, row := range report.Materials.Provenance {
+		for c, cell := range row.Cells {
+			if cell.Found {
+				label := ""
+				if c == 0 {
+					label = fmt.Sprintf("M%d", row.Row)
+				}
+				marker("DXFPARSER_MATERIALS", 3, cell.X, cell.Y, 1.0, label, 1.5)
+			}
+		}
+	}
+	for _, row := range report.CutLengths.Provenance {
+		for c, cell := range row.Cells {
+			if cell.Found && c < 4 {
+				label := ""
+				if c == 0 {
+					label = fmt.Sprintf("C%d", row.Row)
+				}
+				marker("DXFPARSER_CUT_LENGTH", 4, cell.X, cell.Y, 1.0, label, 1.5)
+			}
+		}
+	}
+	var welds []WeldSymbol
+	if report.Welds != nil {
+		welds = report.Welds.Symbols
+	}
+	for i, weld := range welds {
+		marker("DXFPARSER_WELDS", 1, weld.CenterX, weld.CenterY, 5.0, fmt.Sprintf("W%d", i+1), 2.5)
+	}
+
+	group(0, "ENDSEC")
+	group(0, "EOF")
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return file.Commit()
+}
@@ -0,0 +1,73 @@
+package dxfparser
+
+// runConfig holds the settings of one extraction: a bom run, a daemon job or a ProcessDrawing
+// call. It is built once from the options and passed down to the file processing and the
+// output writers, so extractions in the same process never see each other's settings.
+type runConfig struct {
+	parse           ParseOptions      // text parse of every file, one worker per file
+	patterns        *Patterns         // nil: the patterns of the project config
+	units           string            // unit of all drawings; "" or UnitsAuto detects it per drawing
+	pipePolicy      PipePolicy        // pipe of drawings with several PIPE rows
+	rotatedText     RotatedTextPolicy // rotated text in the table extraction
+	translit        bool              // transliterate descriptions for the aggregation keys
+	provenance      bool              // map every output row back to its source text entities
+	handles         bool              // HANDLES column of the entity handles of each row's cells
+	rawTables       bool              // keep the row/column reconstruction of every table
+	tags            bool              // TAG column of valve / instrument rows
+	tagRadius       float64
+	weldGraph       bool // piece-to-weld graph of every drawing
+	weldGraphRadius float64
+	dwg             DWGConverter // nil leaves DWG files out
+	dwgTempDir      string       // converted DXF files, removed at the end of the run
+	outputs         runOutputs
+	crashes         crashReports
+}
+
+// defaultRunConfig returns the settings of an extraction without bom options
+func defaultRunConfig() *runConfig {
+	return &runConfig{
+		parse:       ParseOptions{Workers: 1},
+		pipePolicy:  PipePolicyAll,
+		rotatedText: RotatedTextKeep,
+	}
+}
+
+// newRunConfig returns the settings of a bom run with opts; the DWG converter is set up by
+// the run, which also removes its temporary directory
+func newRunConfig(opts BOMOptions) *runConfig {
+	run := &runConfig{
+		parse: ParseOptions{
+			Workers:       1,
+			ScanBuffer:    opts.ScanBuffer,
+			Encoding:      opts.Encoding,
+			RawMText:      opts.RawMText,
+			Recover:       opts.Recover,
+			Strict:        opts.Strict,
+			IncludeHidden: opts.IncludeHidden,
+			BlockDepth:    opts.BlockDepth,
+			ExcludeColors: opts.ExcludeColors,
+			Layout:        opts.Layout,
+			Scope:         opts.Scope,
+		},
+		patterns:        opts.Patterns,
+		units:           opts.Units,
+		pipePolicy:      opts.PipePolicy,
+		rotatedText:     opts.RotatedText,
+		translit:        opts.Translit,
+		provenance:      opts.Provenance,
+		handles:         opts.Handles,
+		rawTables:       opts.RawTables,
+		tags:            opts.Tags,
+		tagRadius:       opts.TagRadius,
+		weldGraph:       opts.WeldGraph != "",
+		weldGraphRadius: opts.WeldGraphRadius,
+		outputs:         runOutputs{runID: opts.RunID, fsync: opts.Fsync},
+	}
+	if run.pipePolicy == "" {
+		run.pipePolicy = PipePolicyAll
+	}
+	if run.rotatedText == "" {
+		run.rotatedText = RotatedTextKeep
+	}
+	return run
+}
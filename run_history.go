@@ -0,0 +1,269 @@
+package dxfparser
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// RunRecord stores the totals of a single BOM extraction run for trend analysis
+type RunRecord struct {
+	ID           int64
+	Project      string
+	StartedAt    time.Time
+	ToolVersion  string
+	Directory    string
+	TotalFiles   int
+	FailedFiles  int
+	MaterialRows int
+	CutRows      int
+	WeldEnabled  bool
+	WeldCount    int
+	WallTime     float64
+}
+
+// FailureRate returns the share of files that failed in this run (0..1)
+func (r RunRecord) FailureRate() float64 {
+	if r.TotalFiles == 0 {
+		return 0
+	}
+	return float64(r.FailedFiles) / float64(r.TotalFiles)
+}
+
+// openHistoryDB opens the run history database and makes sure the schema exists.
+// The driver must be linked into the binary; "postgres" is.
+func openHistoryDB(driver, dsn string) (*sql.DB, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s database (available drivers: %s): %w",
+			driver, strings.Join(sql.Drivers(), ", "), err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to %s database: %w", driver, err)
+	}
+	if err := ensureHistorySchema(db, driver); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// ensureHistorySchema creates the dxf_runs table if it does not exist yet
+func ensureHistorySchema(db *sql.DB, driver string) error {
+	idColumn := "id INTEGER PRIMARY KEY AUTOINCREMENT"
+	if driver == "postgres" || driver == "pgx" {
+		idColumn = "id BIGSERIAL PRIMARY KEY"
+	}
+
+	// Timestamps are stored as RFC3339 UTC text so they sort correctly on every backend
+	query := `CREATE TABLE IF NOT EXISTS dxf_runs (
+		` + idColumn + `,
+		project TEXT NOT NULL,
+		started_at TEXT NOT NULL,
+		tool_version TEXT NOT NULL,
+		directory TEXT NOT NULL,
+		total_files INTEGER NOT NULL,
+		failed_files INTEGER NOT NULL,
+		material_rows INTEGER NOT NULL,
+		cut_rows INTEGER NOT NULL,
+		weld_enabled BOOLEAN NOT NULL,
+		weld_count INTEGER NOT NULL,
+		wall_time DOUBLE PRECISION NOT NULL
+	)`
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create dxf_runs table: %w", err)
+	}
+	return nil
+}
+
+// sqlPlaceholders returns n bind parameters in the style expected by the driver
+func sqlPlaceholders(driver string, n int) string {
+	params := make([]string, n)
+	for i := range params {
+		if driver == "postgres" || driver == "pgx" {
+			params[i] = fmt.Sprintf("$%d", i+1)
+		} else {
+			params[i] = "?"
+		}
+	}
+	return strings.Join(params, ", ")
+}
+
+// recordRun stores a finished run in the history database
+func recordRun(driver, dsn string, record RunRecord) error {
+	db, err := openHistoryDB(driver, dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	query := `INSERT INTO dxf_runs (project, started_at, tool_version, directory, total_files, failed_files,
+		material_rows, cut_rows, weld_enabled, weld_count, wall_time) VALUES (` + sqlPlaceholders(driver, 11) + `)`
+
+	_, err = db.Exec(query,
+		record.Project,
+		record.StartedAt.UTC().Format(time.RFC3339),
+		record.ToolVersion,
+		record.Directory,
+		record.TotalFiles,
+		record.FailedFiles,
+		record.MaterialRows,
+		record.CutRows,
+		record.WeldEnabled,
+		record.WeldCount,
+		record.WallTime,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record run: %w", err)
+	}
+	return nil
+}
+
+// loadRunHistory returns all runs of a project ordered from oldest to newest
+func loadRunHistory(driver, dsn, project string) ([]RunRecord, error) {
+	db, err := openHistoryDB(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	query := `SELECT id, project, started_at, tool_version, directory, total_files, failed_files,
+		material_rows, cut_rows, weld_enabled, weld_count, wall_time
+		FROM dxf_runs WHERE project = ` + sqlPlaceholders(driver, 1) + ` ORDER BY started_at, id`
+
+	rows, err := db.Query(query, project)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query run history: %w", err)
+	}
+	defer rows.Close()
+
+	var records []RunRecord
+	for rows.Next() {
+		var record RunRecord
+		var startedAt string
+		if err := rows.Scan(&record.ID, &record.Project, &startedAt, &record.ToolVersion, &record.Directory,
+			&record.TotalFiles, &record.FailedFiles, &record.MaterialRows, &record.CutRows,
+			&record.WeldEnabled, &record.WeldCount, &record.WallTime); err != nil {
+			return nil, fmt.Errorf("failed to read run history: %w", err)
+		}
+		record.StartedAt, _ = time.Parse(time.RFC3339, startedAt)
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
+
+// handleReportCommand dispatches the "report" subcommands
+func handleReportCommand() {
+	if len(os.Args) < 3 {
+		fmt.Println("Error: Missing report type")
+		fmt.Println("Usage: dxf_parser report trends -db-driver <driver> -db <dsn> -project <name>")
+		fmt.Println("       dxf_parser report pivot <output-dir> [-format csv|xlsx]")
+		os.Exit(1)
+	}
+
+	switch os.Args[2] {
+	case "trends":
+		handleTrendsCommand(os.Args[3:])
+	case "pivot":
+		handlePivotCommand(os.Args[3:])
+	default:
+		fmt.Printf("Unknown report type: %s\n", os.Args[2])
+		os.Exit(usageExitCode)
+	}
+}
+
+// handleTrendsCommand compares the totals of consecutive runs of the same project
+func handleTrendsCommand(args []string) {
+	fs := newCommandFlagSet("report trends", "dxf_parser report trends -db-driver <driver> -db <dsn> -project <name> [-threshold 5]")
+	driver := fs.String("db-driver", "", "Database driver of the run history (postgres, or another driver linked into the binary)")
+	dsn := fs.String("db", "", "Database connection string / file of the run history")
+	project := fs.String("project", "", "Project name the runs were recorded under")
+	threshold := fs.Float64("threshold", 5.0, "Flag drops larger than this percentage as possible regressions")
+	checkArgCount(fs, parseCommandArgs(fs, args), 0, 0, "")
+
+	if *driver == "" || *dsn == "" || *project == "" {
+		usageError(fs, "Error: -db-driver, -db and -project are required")
+	}
+
+	records, err := loadRunHistory(*driver, *dsn, *project)
+	if err != nil {
+		fmt.Printf("Error loading run history: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(records) == 0 {
+		fmt.Printf("No runs recorded for project '%s'\n", *project)
+		return
+	}
+
+	printTrendReport(records, *threshold)
+}
+
+// printTrendReport prints one line per run with the change against the previous run
+func printTrendReport(records []RunRecord, threshold float64) {
+	fmt.Printf("Run history for project '%s' (%d runs)\n", records[0].Project, len(records))
+	fmt.Println(strings.Repeat("=", 100))
+	fmt.Printf("%-20s %-10s %7s %8s %10s %9s %9s\n",
+		"STARTED", "VERSION", "FILES", "FAILED", "MATERIALS", "CUTS", "WELDS")
+	fmt.Println(strings.Repeat("-", 100))
+
+	for i, record := range records {
+		welds := "-"
+		if record.WeldEnabled {
+			welds = fmt.Sprintf("%d", record.WeldCount)
+		}
+		fmt.Printf("%-20s %-10s %7d %7.1f%% %10d %9d %9s\n",
+			record.StartedAt.Local().Format("2006-01-02 15:04:05"),
+			record.ToolVersion,
+			record.TotalFiles,
+			record.FailureRate()*100,
+			record.MaterialRows,
+			record.CutRows,
+			welds,
+		)
+
+		if i == 0 {
+			continue
+		}
+		for _, warning := range compareRuns(records[i-1], record, threshold) {
+			fmt.Printf("    ! %s\n", warning)
+		}
+	}
+	fmt.Println(strings.Repeat("=", 100))
+}
+
+// compareRuns returns warnings for totals that dropped by more than threshold percent
+// or failure rates that increased between two runs
+func compareRuns(prev, curr RunRecord, threshold float64) []string {
+	var warnings []string
+
+	checkDrop := func(name string, before, after int) {
+		if before <= 0 {
+			return
+		}
+		change := float64(after-before) / float64(before) * 100
+		if change < -threshold {
+			warnings = append(warnings, fmt.Sprintf("%s dropped %.1f%% (%d -> %d)", name, -change, before, after))
+		}
+	}
+
+	checkDrop("Material rows", prev.MaterialRows, curr.MaterialRows)
+	checkDrop("Cut rows", prev.CutRows, curr.CutRows)
+	if prev.WeldEnabled && curr.WeldEnabled {
+		checkDrop("Weld count", prev.WeldCount, curr.WeldCount)
+	}
+
+	if curr.FailureRate() > prev.FailureRate() {
+		warnings = append(warnings, fmt.Sprintf("Failure rate increased (%.1f%% -> %.1f%%)",
+			prev.FailureRate()*100, curr.FailureRate()*100))
+	}
+	if prev.ToolVersion != curr.ToolVersion && len(warnings) > 0 {
+		warnings = append(warnings, fmt.Sprintf("Tool version changed (%s -> %s)", prev.ToolVersion, curr.ToolVersion))
+	}
+
+	return warnings
+}
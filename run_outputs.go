@@ -0,0 +1,138 @@
+package dxfparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// runIDNone selects the fixed output names of earlier versions (0001_ERECTION_MATERIALS.csv, ...)
+const runIDNone = "none"
+
+// runIDPattern restricts run ids to characters that are safe in file names on all platforms
+var runIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// runOutputs names the outputs of one run and collects the files it wrote for the manifest.
+// With a run id every output name gets the prefix "<run-id>_", so runs into the same directory
+// never share files. Writers of outputs outside a run get a nil runOutputs.
+type runOutputs struct {
+	runID string // "" for the fixed output names
+	fsync bool   // flush every output to disk before renaming it into place
+	sync.Mutex
+	paths []string
+}
+
+// RunManifest describes one bom run and everything it wrote; it is written last as
+// <run-id>_0000_MANIFEST.json, so a run without a manifest did not finish
+type RunManifest struct {
+	RunID       string    `json:"run_id"` // "" for the fixed output names
+	ToolVersion string    `json:"tool_version"`
+	StartedAt   time.Time `json:"started_at"`
+	FinishedAt  time.Time `json:"finished_at"`
+	Inputs      []string  `json:"inputs"`
+	Files       int       `json:"files"`
+	FailedFiles int       `json:"failed_files"`
+	Interrupted string    `json:"interrupted,omitempty"` // why a cancelled run stopped early
+	Outputs     []string  `json:"outputs"`               // relative to the output directory where possible
+}
+
+// newRunID returns the default run id, the local start time of the run
+func newRunID(start time.Time) string {
+	return start.Format("20060102-150405")
+}
+
+// resolveRunID turns the -run-id option into the output name prefix id ("" for fixed names)
+func resolveRunID(option string, start time.Time) (string, error) {
+	switch option {
+	case "":
+		return newRunID(start), nil
+	case runIDNone:
+		return "", nil
+	}
+	if !runIDPattern.MatchString(option) {
+		return "", fmt.Errorf("invalid run id '%s' (letters, digits, '.', '_' and '-' only)", option)
+	}
+	return option, nil
+}
+
+// name returns the file name of an output of the run
+func (o *runOutputs) name(name string) string {
+	if o.runID == "" {
+		return name
+	}
+	return o.runID + "_" + name
+}
+
+// path returns the path of an output of the run in directory and records it for the manifest
+func (o *runOutputs) path(directory, name string) string {
+	path := filepath.Join(directory, o.name(name))
+	o.record(path)
+	return path
+}
+
+// record adds a written file or directory to the manifest of the run
+func (o *runOutputs) record(path string) {
+	o.Lock()
+	defer o.Unlock()
+	for _, existing := range o.paths {
+		if existing == path {
+			return
+		}
+	}
+	o.paths = append(o.paths, path)
+}
+
+// create is createOutput for an output of the run, flushed to disk by Commit with -fsync
+func (o *runOutputs) create(path string) (*outputFile, error) {
+	file, err := createOutput(path)
+	if err != nil {
+		return nil, err
+	}
+	file.sync = o != nil && o.fsync
+	return file, nil
+}
+
+// writeFile is writeOutputFile for an output of the run
+func (o *runOutputs) writeFile(path string, data []byte) error {
+	return writeOutput(path, data, o != nil && o.fsync)
+}
+
+// checkCollision returns an error if directory already holds the results of a run with the
+// same run id; every run writes a summary, so its presence marks a previous run
+func (o *runOutputs) checkCollision(directory string) error {
+	summary := filepath.Join(directory, o.name("0004_SUMMARY.csv"))
+	if _, err := os.Stat(summary); err == nil {
+		return fmt.Errorf("%s already exists", summary)
+	}
+	return nil
+}
+
+// writeManifest writes the manifest of the run to directory
+func (o *runOutputs) writeManifest(directory string, manifest RunManifest) error {
+	filename := filepath.Join(directory, o.name("0000_MANIFEST.json"))
+
+	o.Lock()
+	for _, path := range o.paths {
+		if rel, err := filepath.Rel(directory, path); err == nil && filepath.IsLocal(rel) {
+			path = rel
+		}
+		manifest.Outputs = append(manifest.Outputs, filepath.ToSlash(path))
+	}
+	o.Unlock()
+	sort.Strings(manifest.Outputs)
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := o.writeFile(filename, data); err != nil {
+		return fmt.Errorf("error writing manifest: %v", err)
+	}
+	fmt.Printf("Wrote run manifest to: %s (%d outputs)\n", filename, len(manifest.Outputs))
+	return nil
+}
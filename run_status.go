@@ -0,0 +1,96 @@
+package dxfparser
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Exit codes of the bom command, so CI pipelines can gate on the extraction result
+const (
+	exitSuccess        = 0 // every file processed, or the failures are within -fail-on-error-rate
+	exitAborted        = 1 // interrupted, timed out or the outputs could not be written
+	exitPartialFailure = 2 // some files failed
+	exitAllFailed      = 3 // every file failed
+	exitConfigError    = 4 // malformed invocation, missing input or invalid project config
+)
+
+// Values of RunStatus.Status
+const (
+	statusSuccess        = "success"
+	statusPartialFailure = "partial_failure"
+	statusFailed         = "failed"
+	statusInterrupted    = "interrupted"
+)
+
+// RunStatus is the machine-readable outcome of a bom run, written to -status-json
+type RunStatus struct {
+	Status          string        `json:"status"`
+	ExitCode        int           `json:"exit_code"`
+	RunID           string        `json:"run_id"`
+	Files           int           `json:"files"`
+	ProcessedFiles  int           `json:"processed_files"` // fewer than files after an interruption
+	FailedFiles     int           `json:"failed_files"`
+	PartialFiles    int           `json:"partial_files"` // extracted from truncated files with -recover
+	ErrorRate       float64       `json:"error_rate"`    // failed / processed files
+	FailOnErrorRate float64       `json:"fail_on_error_rate"`
+	Interrupted     string        `json:"interrupted,omitempty"`
+	Failures        []FileFailure `json:"failures"`
+}
+
+// FileFailure is a file whose extraction failed
+type FileFailure struct {
+	File  string `json:"file"`
+	Error string `json:"error"`
+}
+
+// newRunStatus evaluates the results of a run. Failures up to failOnErrorRate (a share of the
+// processed files) still exit with success; a run in which every file failed never does.
+func newRunStatus(runID string, files int, results []DXFResult, failOnErrorRate float64, interrupted error) RunStatus {
+	status := RunStatus{
+		RunID:           runID,
+		Files:           files,
+		ProcessedFiles:  len(results),
+		FailOnErrorRate: failOnErrorRate,
+		Failures:        []FileFailure{},
+	}
+	for _, result := range results {
+		if result.Error != "" {
+			status.Failures = append(status.Failures, FileFailure{File: result.FilePath, Error: result.Error})
+		}
+		if result.Partial {
+			status.PartialFiles++
+		}
+	}
+	status.FailedFiles = len(status.Failures)
+	if status.ProcessedFiles > 0 {
+		status.ErrorRate = float64(status.FailedFiles) / float64(status.ProcessedFiles)
+	}
+
+	switch {
+	case interrupted != nil:
+		status.Status, status.ExitCode = statusInterrupted, exitAborted
+		status.Interrupted = interrupted.Error()
+	case status.FailedFiles == 0:
+		status.Status, status.ExitCode = statusSuccess, exitSuccess
+	case status.FailedFiles == status.ProcessedFiles:
+		status.Status, status.ExitCode = statusFailed, exitAllFailed
+	case status.ErrorRate <= failOnErrorRate:
+		status.Status, status.ExitCode = statusSuccess, exitSuccess
+	default:
+		status.Status, status.ExitCode = statusPartialFailure, exitPartialFailure
+	}
+	return status
+}
+
+// writeRunStatus writes the status of this run as JSON to path
+func writeRunStatus(out *runOutputs, path string, status RunStatus) error {
+	data, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := out.writeFile(path, data); err != nil {
+		return fmt.Errorf("error writing run status: %v", err)
+	}
+	fmt.Printf("Wrote run status to: %s\n", path)
+	return nil
+}
@@ -0,0 +1,109 @@
+package dxfparser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ExtractionScope selects the sections of a DXF file records are read from. Records are only
+// taken where they belong: LAYER in TABLES, BLOCK and ENDBLK in BLOCKS, text, INSERTs and
+// polylines in ENTITIES and BLOCKS. Text-like records elsewhere, e.g. in OBJECTS, are out of
+// context and skipped, except with ScopeAll.
+type ExtractionScope string
+
+const (
+	ScopeEntities       ExtractionScope = "entities"        // the ENTITIES section only; INSERTs add no block content
+	ScopeEntitiesBlocks ExtractionScope = "entities+blocks" // entities and the block content at their INSERTs (default)
+	ScopeAll            ExtractionScope = "all"             // records wherever they are found, as earlier versions
+)
+
+// parseExtractionScope validates a -scope value
+func parseExtractionScope(value string) (ExtractionScope, error) {
+	switch scope := ExtractionScope(strings.ToLower(value)); scope {
+	case ScopeEntities, ScopeEntitiesBlocks, ScopeAll:
+		return scope, nil
+	}
+	return "", fmt.Errorf("invalid extraction scope '%s' (entities, entities+blocks, all)", value)
+}
+
+// allows reports whether a record of recordType read in section is extracted under the scope.
+// Records outside any section, in fragments and files without SECTION markers, are always read.
+func (s ExtractionScope) allows(recordType, section string) bool {
+	switch {
+	case s == ScopeAll || section == "":
+		return true
+	case isLayerRecord(recordType):
+		return section == "TABLES"
+	case section == "ENTITIES":
+		return true
+	case section == "BLOCKS":
+		return s != ScopeEntities
+	}
+	return false
+}
+
+// sectionUnknown is the section of the records of a chunk before its first SECTION marker; it
+// is resolved to the section the previous chunk ends in
+const sectionUnknown = "?"
+
+// sectionTracker follows the section of a group code / value scan: "0 SECTION" followed by
+// "2 <name>" enters a section, "0 ENDSEC" leaves it
+type sectionTracker struct {
+	name    string // HEADER, CLASSES, TABLES, BLOCKS, ENTITIES, OBJECTS; "" outside sections
+	opening bool   // a SECTION record was read and its name (2) comes next
+}
+
+// pair takes the next code / value pair of the scan
+func (s *sectionTracker) pair(code, value string) {
+	switch {
+	case code == "0":
+		s.opening = value == "SECTION"
+		if value == "ENDSEC" {
+			s.name = ""
+		}
+	case code == "2" && s.opening:
+		s.name, s.opening = strings.ToUpper(value), false
+	}
+}
+
+// inScope reports whether e was read in a section of the parser's scope
+func (p *DXFParser) inScope(e TextEntity) bool {
+	return p.scope.allows(e.EntityType, e.section)
+}
+
+// scopeFilter wraps emit to drop the records read outside the parser's scope
+func (p *DXFParser) scopeFilter(emit func(TextEntity) error) func(TextEntity) error {
+	return func(e TextEntity) error {
+		if !p.inScope(e) {
+			return nil
+		}
+		return emit(e)
+	}
+}
+
+// mergeChunks concatenates the records of the chunks of a concurrent parse in file order and
+// drops those outside the parser's scope. Records read before the first SECTION marker of a
+// chunk get the section the chunks before it end in; sections holds the tracker of every chunk
+// after its scan.
+func (p *DXFParser) mergeChunks(results [][]TextEntity, sections []sectionTracker) []TextEntity {
+	total := 0
+	for _, entities := range results {
+		total += len(entities)
+	}
+	merged := newEntities(total)
+	current := ""
+	for i, entities := range results {
+		for _, e := range entities {
+			if e.section == sectionUnknown {
+				e.section = current
+			}
+			if p.inScope(e) {
+				merged = append(merged, e)
+			}
+		}
+		if sections[i].name != sectionUnknown {
+			current = sections[i].name
+		}
+	}
+	return merged
+}
@@ -0,0 +1,195 @@
+package dxfparser
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+//go:embed ui
+var uiFiles embed.FS
+
+// jobStoreFile is the job queue database of serve, kept in the watched directory next to the
+// CSV outputs
+const jobStoreFile = "0000_JOBS.db"
+
+// handleServeCommand implements "serve -dir <directory>": it watches the directory for new or
+// changed DXF files, extracts them one by one and serves a status page with the job queue.
+// It runs on the job queue, watcher and job runner of the daemon, with one watched directory
+// and the status page instead of the job API. The page has no authentication, so it listens
+// on localhost unless -addr says otherwise.
+func handleServeCommand() {
+	fs := newCommandFlagSet("serve", "dxf_parser serve -dir <directory> [-addr 127.0.0.1:8080] [-interval 5s] [-weld] [-debug]")
+	directory := fs.String("dir", "", "Directory to watch for DXF files (required)")
+	addr := fs.String("addr", "127.0.0.1:8080", "Address of the status web UI")
+	interval := fs.Duration("interval", 5*time.Second, "How often the directory is scanned for new files")
+	weld := fs.Bool("weld", false, "Also count weld symbols")
+	debug := fs.Bool("debug", false, "Enable debug output")
+	checkArgCount(fs, parseCommandArgs(fs, os.Args[2:]), 0, 0, "")
+
+	if *directory == "" {
+		usageError(fs, msg("serve.dir_required"))
+	}
+	if *interval <= 0 {
+		usageError(fs, "Error: -interval must be positive")
+	}
+	if _, err := os.Stat(*directory); err != nil {
+		fmt.Println(msg("bom.dir_missing", *directory))
+		os.Exit(1)
+	}
+
+	debugMode = *debug
+	useProjectConfig(*directory, nil)
+
+	queue, err := openDaemonQueue(filepath.Join(*directory, jobStoreFile))
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer queue.Close()
+
+	mux := http.NewServeMux()
+	mux.Handle("/", uiHandler())
+	mux.HandleFunc("/api/status", func(w http.ResponseWriter, r *http.Request) {
+		status, err := queueStatus(queue, *directory, 50)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(status)
+	})
+
+	fmt.Printf("Watching %s, status UI on http://%s/\n", *directory, displayAddr(*addr))
+	watch := []DaemonJob{{Name: "watch " + *directory, OutputDir: *directory, Weld: *weld}}
+	if err := runJobService(queue, &http.Server{Addr: *addr, Handler: mux}, watch, *interval); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		queue.Close()
+		os.Exit(1)
+	}
+}
+
+// uiHandler serves the embedded status page
+func uiHandler() http.Handler {
+	sub, err := fs.Sub(uiFiles, "ui")
+	if err != nil {
+		panic(err) // the ui directory is embedded at build time
+	}
+	return http.FileServer(http.FS(sub))
+}
+
+// displayAddr turns a listen address like ":8080" into something a browser can open
+func displayAddr(addr string) string {
+	if strings.HasPrefix(addr, ":") {
+		return "localhost" + addr
+	}
+	return addr
+}
+
+// Job is one file of the job queue as shown on the status page
+type Job struct {
+	ID         int64     `json:"id"` // of the batch the file is in
+	FilePath   string    `json:"file_path"`
+	Status     string    `json:"status"`
+	QueuedAt   time.Time `json:"queued_at"`
+	StartedAt  time.Time `json:"started_at,omitempty"`
+	FinishedAt time.Time `json:"finished_at,omitempty"`
+	DrawingNo  string    `json:"drawing_no,omitempty"`
+	PipeClass  string    `json:"pipe_class,omitempty"`
+	MatRows    int       `json:"mat_rows"`
+	CutRows    int       `json:"cut_rows"`
+	WeldCount  int       `json:"weld_count"`
+	Duration   float64   `json:"duration"`
+	Error      string    `json:"error,omitempty"`
+	Warnings   []string  `json:"warnings,omitempty"`
+}
+
+// JobStatus is a snapshot of the queue for the web UI
+type JobStatus struct {
+	Directory string    `json:"directory"`
+	Updated   time.Time `json:"updated"`
+	Queued    []Job     `json:"queued"`
+	Running   []Job     `json:"running"`
+	Recent    []Job     `json:"recent"`   // finished files, newest first
+	Failures  []Job     `json:"failures"` // failed files, newest first
+	Done      int       `json:"done"`
+	Failed    int       `json:"failed"`
+}
+
+// queueStatus returns the files of the jobs in queue: queued and running ones, and the latest
+// limit finished and failed ones. The files of a job not started yet are its inputs; those of
+// a canceled job that were not processed are left out.
+func queueStatus(queue *DaemonQueue, directory string, limit int) (JobStatus, error) {
+	jobs, err := queue.jobs(true)
+	if err != nil {
+		return JobStatus{}, err
+	}
+
+	status := JobStatus{Directory: directory, Updated: time.Now()}
+	for i := len(jobs) - 1; i >= 0; i-- {
+		job := jobs[i]
+		files := job.Files
+		if files == nil {
+			state := JobQueued
+			if job.Status == JobFailed {
+				state = JobFailed
+			}
+			for _, input := range job.Inputs {
+				files = append(files, DaemonFile{Path: input, Status: state, Error: job.Error, FinishedAt: job.FinishedAt})
+			}
+		}
+
+		// The running file is the first one not processed yet; it started when the one before finished
+		current, started := -1, job.StartedAt
+		if job.Status == JobRunning {
+			for j, file := range files {
+				if file.Status == JobQueued {
+					current = j
+					break
+				}
+				if file.FinishedAt.After(started) {
+					started = file.FinishedAt
+				}
+			}
+		}
+
+		for j := len(files) - 1; j >= 0; j-- {
+			file := files[j]
+			row := Job{
+				ID: job.ID, FilePath: file.Path, Status: file.Status, QueuedAt: job.SubmittedAt,
+				FinishedAt: file.FinishedAt, DrawingNo: file.DrawingNo, PipeClass: file.PipeClass,
+				MatRows: file.MatRows, CutRows: file.CutRows, WeldCount: file.WeldCount,
+				Duration: file.Duration, Error: file.Error, Warnings: file.Warnings,
+			}
+			switch {
+			case j == current:
+				row.Status, row.StartedAt = JobRunning, started
+				status.Running = append(status.Running, row)
+			case file.Status == JobQueued:
+				if job.Status == JobQueued || job.Status == JobRunning {
+					status.Queued = append([]Job{row}, status.Queued...)
+				}
+			case file.Status == JobDone:
+				status.Done++
+				if len(status.Recent) < limit {
+					status.Recent = append(status.Recent, row)
+				}
+			case file.Status == JobFailed:
+				status.Failed++
+				if len(status.Recent) < limit {
+					status.Recent = append(status.Recent, row)
+				}
+				if len(status.Failures) < limit {
+					status.Failures = append(status.Failures, row)
+				}
+			}
+		}
+	}
+	return status, nil
+}
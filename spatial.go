@@ -1,13 +1,21 @@
-package main
+package dxfparser
 
 import (
 	"math"
+	"runtime"
 	"sort"
+	"sync"
+
+	"github.com/jeffcall-ch/dxf_parser_go/internal/geometry"
 )
 
 // SpatialAnalyzer provides spatial analysis functions for text entities
 type SpatialAnalyzer struct {
 	entities []TextEntity
+
+	// Grid index shared by radius/nearest queries, built lazily on first use
+	index     *spatialGrid
+	indexOnce sync.Once
 }
 
 // NewSpatialAnalyzer creates a new spatial analyzer with the given entities
@@ -15,13 +23,6 @@ func NewSpatialAnalyzer(entities []TextEntity) *SpatialAnalyzer {
 	return &SpatialAnalyzer{entities: entities}
 }
 
-// Distance calculates the Euclidean distance between two points
-func Distance(x1, y1, x2, y2 float64) float64 {
-	dx := x2 - x1
-	dy := y2 - y1
-	return math.Sqrt(dx*dx + dy*dy)
-}
-
 // BoundingBox represents a rectangular boundary
 type BoundingBox struct {
 	MinX, MinY, MaxX, MaxY float64
@@ -61,31 +62,24 @@ func (sa *SpatialAnalyzer) GetBoundingBox() BoundingBox {
 // FindEntitiesInRange returns all text entities within the specified coordinate range
 func (sa *SpatialAnalyzer) FindEntitiesInRange(minX, minY, maxX, maxY float64) []TextEntity {
 	var result []TextEntity
-	
+
 	for _, entity := range sa.entities {
 		if entity.X >= minX && entity.X <= maxX && entity.Y >= minY && entity.Y <= maxY {
 			result = append(result, entity)
 		}
 	}
-	
+
 	return result
 }
 
 // FindEntitiesInRadius returns all text entities within the specified radius of a point
 func (sa *SpatialAnalyzer) FindEntitiesInRadius(centerX, centerY, radius float64) []TextEntity {
 	var result []TextEntity
-	radiusSquared := radius * radius
-	
-	for _, entity := range sa.entities {
-		dx := entity.X - centerX
-		dy := entity.Y - centerY
-		distanceSquared := dx*dx + dy*dy
-		
-		if distanceSquared <= radiusSquared {
-			result = append(result, entity)
-		}
+
+	for _, i := range sa.getIndex().radius(sa.entities, centerX, centerY, radius) {
+		result = append(result, sa.entities[i])
 	}
-	
+
 	return result
 }
 
@@ -101,82 +95,293 @@ func (sa *SpatialAnalyzer) FindNearestEntities(x, y float64, n int) []EntityWith
 		return nil
 	}
 
-	// Calculate distances for all entities
-	entitiesWithDistance := make([]EntityWithDistance, len(sa.entities))
-	for i, entity := range sa.entities {
-		distance := Distance(x, y, entity.X, entity.Y)
-		entitiesWithDistance[i] = EntityWithDistance{
-			Entity:   entity,
-			Distance: distance,
-		}
-	}
-
-	// Sort by distance
-	sort.Slice(entitiesWithDistance, func(i, j int) bool {
-		return entitiesWithDistance[i].Distance < entitiesWithDistance[j].Distance
-	})
-
-	// Return the first N entities
-	if n > len(entitiesWithDistance) {
-		n = len(entitiesWithDistance)
-	}
-	
-	return entitiesWithDistance[:n]
+	return sa.BatchFindNearestEntities([]NearestQuery{{X: x, Y: y, N: n}})[0]
 }
 
 // FindEntitiesNearText finds all entities within a specified distance of entities containing the given text
 func (sa *SpatialAnalyzer) FindEntitiesNearText(searchText string, maxDistance float64) []EntityWithDistance {
 	var referenceEntities []TextEntity
 	var result []EntityWithDistance
-	
+
 	// Find all entities containing the search text
 	for _, entity := range sa.entities {
 		if containsText(entity.Content, searchText) {
 			referenceEntities = append(referenceEntities, entity)
 		}
 	}
-	
+
 	if len(referenceEntities) == 0 {
 		return result
 	}
-	
-	// Find entities near any of the reference entities
+
+	// Find entities near any of the reference entities in one batch
+	queries := make([]RadiusQuery, len(referenceEntities))
+	for i, refEntity := range referenceEntities {
+		queries[i] = RadiusQuery{X: refEntity.X, Y: refEntity.Y, Radius: maxDistance}
+	}
+
 	seen := make(map[int]bool) // To avoid duplicates
-	
-	for _, refEntity := range referenceEntities {
-		for i, entity := range sa.entities {
-			if seen[i] {
+
+	for _, matches := range sa.batchRadiusIndices(queries) {
+		for _, match := range matches {
+			if seen[match.index] {
 				continue
 			}
-			
-			distance := Distance(refEntity.X, refEntity.Y, entity.X, entity.Y)
-			if distance <= maxDistance {
-				result = append(result, EntityWithDistance{
-					Entity:   entity,
-					Distance: distance,
-				})
-				seen[i] = true
-			}
+			result = append(result, EntityWithDistance{
+				Entity:   sa.entities[match.index],
+				Distance: match.distance,
+			})
+			seen[match.index] = true
 		}
 	}
-	
+
 	// Sort by distance
 	sort.Slice(result, func(i, j int) bool {
 		return result[i].Distance < result[j].Distance
 	})
-	
+
+	return result
+}
+
+// RadiusQuery describes a single "entities within radius of point" query
+type RadiusQuery struct {
+	X, Y   float64
+	Radius float64
+}
+
+// NearestQuery describes a single "N nearest entities to point" query
+type NearestQuery struct {
+	X, Y float64
+	N    int
+}
+
+// BatchFindEntitiesInRadius answers many radius queries in one call.
+// Results are returned in query order; each result is sorted by distance.
+func (sa *SpatialAnalyzer) BatchFindEntitiesInRadius(queries []RadiusQuery) [][]EntityWithDistance {
+	indexed := sa.batchRadiusIndices(queries)
+
+	results := make([][]EntityWithDistance, len(queries))
+	for q, matches := range indexed {
+		for _, match := range matches {
+			results[q] = append(results[q], EntityWithDistance{
+				Entity:   sa.entities[match.index],
+				Distance: match.distance,
+			})
+		}
+	}
+	return results
+}
+
+// BatchFindNearestEntities answers many nearest-neighbour queries in one call.
+// Results are returned in query order.
+func (sa *SpatialAnalyzer) BatchFindNearestEntities(queries []NearestQuery) [][]EntityWithDistance {
+	results := make([][]EntityWithDistance, len(queries))
+	if len(sa.entities) == 0 {
+		return results
+	}
+	grid := sa.getIndex()
+
+	parallelFor(len(queries), func(q int) {
+		query := queries[q]
+		if query.N <= 0 {
+			return
+		}
+		for _, match := range grid.nearest(sa.entities, query.X, query.Y, query.N) {
+			results[q] = append(results[q], EntityWithDistance{
+				Entity:   sa.entities[match.index],
+				Distance: match.distance,
+			})
+		}
+	})
+	return results
+}
+
+// batchRadiusIndices runs radius queries in parallel against the shared index
+func (sa *SpatialAnalyzer) batchRadiusIndices(queries []RadiusQuery) [][]indexMatch {
+	results := make([][]indexMatch, len(queries))
+	if len(sa.entities) == 0 {
+		return results
+	}
+	grid := sa.getIndex()
+
+	parallelFor(len(queries), func(q int) {
+		query := queries[q]
+		matches := make([]indexMatch, 0)
+		for _, i := range grid.radius(sa.entities, query.X, query.Y, query.Radius) {
+			matches = append(matches, indexMatch{
+				index:    i,
+				distance: geometry.Distance(query.X, query.Y, sa.entities[i].X, sa.entities[i].Y),
+			})
+		}
+		// Stable, so entities at the same distance stay in index order
+		sort.SliceStable(matches, func(i, j int) bool {
+			return matches[i].distance < matches[j].distance
+		})
+		results[q] = matches
+	})
+	return results
+}
+
+// parallelFor calls fn for 0..n-1 spread over the available CPUs.
+// Small batches are run inline since goroutine overhead would dominate.
+func parallelFor(n int, fn func(i int)) {
+	workers := runtime.NumCPU()
+	if n < 64 || workers <= 1 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+	if workers > n {
+		workers = n
+	}
+
+	var wg sync.WaitGroup
+	next := make(chan int, n)
+	for i := 0; i < n; i++ {
+		next <- i
+	}
+	close(next)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// getIndex returns the grid index, building it on first use
+func (sa *SpatialAnalyzer) getIndex() *spatialGrid {
+	sa.indexOnce.Do(func() {
+		sa.index = newSpatialGrid(sa.entities, sa.GetBoundingBox())
+	})
+	return sa.index
+}
+
+// indexMatch is an entity index with its distance to the query point
+type indexMatch struct {
+	index    int
+	distance float64
+}
+
+// spatialGrid is a uniform grid over entity insertion points.
+// It is read-only after construction and safe for concurrent queries.
+type spatialGrid struct {
+	bbox     BoundingBox
+	cellSize float64
+	cells    map[[2]int][]int
+}
+
+// newSpatialGrid buckets entities into cells sized for roughly a few entities per cell
+func newSpatialGrid(entities []TextEntity, bbox BoundingBox) *spatialGrid {
+	width := bbox.MaxX - bbox.MinX
+	height := bbox.MaxY - bbox.MinY
+
+	cellSize := 1.0
+	if len(entities) > 0 && width*height > 0 {
+		cellSize = math.Sqrt(width * height / float64(len(entities)) * 4)
+	} else if math.Max(width, height) > 0 {
+		cellSize = math.Max(width, height) / 16
+	}
+
+	grid := &spatialGrid{
+		bbox:     bbox,
+		cellSize: cellSize,
+		cells:    make(map[[2]int][]int),
+	}
+	for i, entity := range entities {
+		key := grid.cellOf(entity.X, entity.Y)
+		grid.cells[key] = append(grid.cells[key], i)
+	}
+	return grid
+}
+
+// cellOf returns the grid cell containing a point
+func (g *spatialGrid) cellOf(x, y float64) [2]int {
+	return [2]int{
+		int(math.Floor((x - g.bbox.MinX) / g.cellSize)),
+		int(math.Floor((y - g.bbox.MinY) / g.cellSize)),
+	}
+}
+
+// radius returns indices of entities within radius of (x, y) in index order
+func (g *spatialGrid) radius(entities []TextEntity, x, y, radius float64) []int {
+	var result []int
+	if radius < 0 {
+		return result
+	}
+
+	// Clamp the searched square to the populated area
+	minCell := g.cellOf(math.Max(x-radius, g.bbox.MinX), math.Max(y-radius, g.bbox.MinY))
+	maxCell := g.cellOf(math.Min(x+radius, g.bbox.MaxX), math.Min(y+radius, g.bbox.MaxY))
+	radiusSquared := radius * radius
+
+	for cx := minCell[0]; cx <= maxCell[0]; cx++ {
+		for cy := minCell[1]; cy <= maxCell[1]; cy++ {
+			for _, i := range g.cells[[2]int{cx, cy}] {
+				dx := entities[i].X - x
+				dy := entities[i].Y - y
+				if dx*dx+dy*dy <= radiusSquared {
+					result = append(result, i)
+				}
+			}
+		}
+	}
+
+	sort.Ints(result)
 	return result
 }
 
+// nearest returns the n entities closest to (x, y), growing the search radius until
+// the n-th candidate is guaranteed to be inside the searched circle
+func (g *spatialGrid) nearest(entities []TextEntity, x, y float64, n int) []indexMatch {
+	if n > len(entities) {
+		n = len(entities)
+	}
+
+	// Farthest possible distance from the query point to any entity
+	maxRadius := math.Max(
+		geometry.Distance(x, y, g.bbox.MinX, g.bbox.MinY),
+		math.Max(geometry.Distance(x, y, g.bbox.MaxX, g.bbox.MaxY),
+			math.Max(geometry.Distance(x, y, g.bbox.MinX, g.bbox.MaxY), geometry.Distance(x, y, g.bbox.MaxX, g.bbox.MinY))))
+
+	for radius := g.cellSize; ; radius *= 2 {
+		if radius >= maxRadius {
+			// Every entity is in the last circle; rounding of maxRadius must not drop the farthest
+			radius = math.Inf(1)
+		}
+
+		indices := g.radius(entities, x, y, radius)
+		if len(indices) >= n || math.IsInf(radius, 1) {
+			matches := make([]indexMatch, len(indices))
+			for k, i := range indices {
+				matches[k] = indexMatch{index: i, distance: geometry.Distance(x, y, entities[i].X, entities[i].Y)}
+			}
+			sort.SliceStable(matches, func(a, b int) bool {
+				return matches[a].distance < matches[b].distance
+			})
+			if len(matches) > n {
+				matches = matches[:n]
+			}
+			return matches
+		}
+	}
+}
+
 // GetQuadrant returns entities in a specific quadrant relative to a reference point
 // quadrant: 1=top-right, 2=top-left, 3=bottom-left, 4=bottom-right
 func (sa *SpatialAnalyzer) GetQuadrant(refX, refY float64, quadrant int) []TextEntity {
 	var result []TextEntity
-	
+
 	for _, entity := range sa.entities {
 		dx := entity.X - refX
 		dy := entity.Y - refY
-		
+
 		switch quadrant {
 		case 1: // Top-right
 			if dx >= 0 && dy >= 0 {
@@ -196,7 +401,7 @@ func (sa *SpatialAnalyzer) GetQuadrant(refX, refY float64, quadrant int) []TextE
 			}
 		}
 	}
-	
+
 	return result
 }
 
@@ -204,7 +409,7 @@ func (sa *SpatialAnalyzer) GetQuadrant(refX, refY float64, quadrant int) []TextE
 func (sa *SpatialAnalyzer) FindEntitiesInTopRightQuadrant(searchText string) []TextEntity {
 	var result []TextEntity
 	seen := make(map[int]bool)
-	
+
 	// Find all entities containing the search text
 	for _, refEntity := range sa.entities {
 		if containsText(refEntity.Content, searchText) {
@@ -219,7 +424,7 @@ func (sa *SpatialAnalyzer) FindEntitiesInTopRightQuadrant(searchText string) []T
 			}
 		}
 	}
-	
+
 	return result
 }
 
@@ -232,41 +437,49 @@ func (sa *SpatialAnalyzer) GetEntityStats() map[string]interface{} {
 	}
 
 	bbox := sa.GetBoundingBox()
-	
+
 	// Count by entity type
 	textCount := 0
 	mtextCount := 0
+	attributeCount := 0
+	dimensionCount := 0
 	totalHeight := 0.0
 	heightCount := 0
-	
+
 	layerCounts := make(map[string]int)
-	
+
 	for _, entity := range sa.entities {
 		if entity.EntityType == "TEXT" {
 			textCount++
 		} else if entity.EntityType == "MTEXT" {
 			mtextCount++
+		} else if entity.isAttribute() {
+			attributeCount++
+		} else if entity.EntityType == "DIMENSION" {
+			dimensionCount++
 		}
-		
+
 		if entity.Height > 0 {
 			totalHeight += entity.Height
 			heightCount++
 		}
-		
+
 		if entity.Layer != "" {
 			layerCounts[entity.Layer]++
 		}
 	}
-	
+
 	avgHeight := 0.0
 	if heightCount > 0 {
 		avgHeight = totalHeight / float64(heightCount)
 	}
-	
+
 	return map[string]interface{}{
 		"total_entities":     len(sa.entities),
 		"text_entities":      textCount,
 		"mtext_entities":     mtextCount,
+		"attribute_entities": attributeCount,
+		"dimension_entities": dimensionCount,
 		"bounding_box":       bbox,
 		"average_height":     avgHeight,
 		"layer_distribution": layerCounts,
@@ -277,8 +490,8 @@ func (sa *SpatialAnalyzer) GetEntityStats() map[string]interface{} {
 
 // containsText checks if the content contains the search text (case-insensitive)
 func containsText(content, searchText string) bool {
-	return len(content) > 0 && len(searchText) > 0 && 
-		   stringContainsIgnoreCase(content, searchText)
+	return len(content) > 0 && len(searchText) > 0 &&
+		stringContainsIgnoreCase(content, searchText)
 }
 
 // stringContainsIgnoreCase performs case-insensitive substring search
@@ -309,7 +522,7 @@ func stringContains(s, substr string) bool {
 	if len(substr) > len(s) {
 		return false
 	}
-	
+
 	for i := 0; i <= len(s)-len(substr); i++ {
 		if s[i:i+len(substr)] == substr {
 			return true
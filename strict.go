@@ -0,0 +1,90 @@
+package dxfparser
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ParseError is the first structural problem of a file that fails a strict parse
+// (ParseOptions.Strict)
+type ParseError struct {
+	Line   int    // 1-based line of the group code
+	Code   string // group code as read
+	Reason string // e.g. "unknown section FOO"
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d, group code %s: %s", e.Line, e.Code, e.Reason)
+}
+
+// knownSections are the sections of the DXF reference
+var knownSections = map[string]bool{
+	"HEADER": true, "CLASSES": true, "TABLES": true, "BLOCKS": true,
+	"ENTITIES": true, "OBJECTS": true, "THUMBNAILIMAGE": true, "ACDSDATA": true,
+}
+
+// validateDXF checks DXF content for a strict parse and returns its first problem as a
+// *ParseError: a group code that is no number, a non-numeric value of a floating point code, an
+// unknown section, SECTION and ENDSEC out of balance, or content ending without the EOF record,
+// e.g. inside an entity. maxLine is the longest line kept, in bytes. Content after EOF is not read.
+func validateDXF(r io.Reader, maxLine int) error {
+	var sections sectionTracker
+	record, recordLine := "", 0 // last record (group code 0) and its line
+	scanner := newLineScanner(r, maxLine)
+	for scanner.Scan() {
+		code := strings.TrimSpace(scanner.Text())
+		line := scanner.Line()
+		if !isGroupCode(code) {
+			return &ParseError{Line: line, Code: code, Reason: "group code is not a number"}
+		}
+		if !scanner.Scan() {
+			if scanner.Err() == nil {
+				return &ParseError{Line: line, Code: code, Reason: "group code without value at the end"}
+			}
+			break
+		}
+		value := strings.TrimSpace(scanner.Text())
+		if isFloatCode(code) {
+			if _, err := parseDXFFloat(value); err != nil {
+				return &ParseError{Line: line, Code: code, Reason: err.Error()}
+			}
+		}
+
+		switch {
+		case code == "0" && sections.opening:
+			return &ParseError{Line: line, Code: code, Reason: fmt.Sprintf("SECTION at line %d without name", recordLine)}
+		case code == "0" && value == "SECTION" && sections.name != "":
+			return &ParseError{Line: line, Code: code, Reason: fmt.Sprintf("SECTION inside %s, which has no ENDSEC", sections.name)}
+		case code == "0" && value == "ENDSEC" && sections.name == "":
+			return &ParseError{Line: line, Code: code, Reason: "ENDSEC outside a section"}
+		case code == "0" && value == "EOF" && sections.name != "":
+			return &ParseError{Line: line, Code: code, Reason: fmt.Sprintf("EOF inside %s, which has no ENDSEC", sections.name)}
+		case code == "0" && value == "EOF":
+			return nil
+		case code == "2" && sections.opening && !knownSections[strings.ToUpper(value)]:
+			return &ParseError{Line: line, Code: code, Reason: fmt.Sprintf("unknown section %s", value)}
+		}
+		if code == "0" {
+			record, recordLine = value, line
+		}
+		sections.pair(code, value)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading file: %w", err)
+	}
+	if record == "" {
+		return &ParseError{Line: scanner.Line(), Code: "0", Reason: "no records and no EOF"}
+	}
+	return &ParseError{Line: recordLine, Code: "0", Reason: fmt.Sprintf("content ends inside %s without EOF", record)}
+}
+
+// validateFile is validateDXF of a file
+func (p *DXFParser) validateFile(filename string) error {
+	file, err := openInput(filename)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+	return validateDXF(file, p.scanBuffer)
+}
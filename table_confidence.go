@@ -0,0 +1,152 @@
+package dxfparser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TableConfidence rates how plausible an extracted table is, each figure from 0 to 1.
+// Downstream loaders can quarantine drawings whose Score is below their threshold.
+type TableConfidence struct {
+	RowTypes        float64 `json:"row_types"`        // rows whose typed columns hold values of the right type
+	ColumnStability float64 `json:"column_stability"` // rows with the most common number of filled columns
+	HeaderMatch     float64 `json:"header_match"`     // expected header names found in the header
+	Score           float64 `json:"score"`            // mean of the three figures
+}
+
+// columnCheck is a typed column of a table, found by a header name fragment
+type columnCheck struct {
+	header string
+	valid  func(value string) bool
+}
+
+var (
+	nominalSizePattern = regexp.MustCompile(`^\d+(\.\d+)?(\s*x\s*\d+(\.\d+)?)?$`)
+	quantityPattern    = regexp.MustCompile(`^\d+(\.\d+)?\s*(M|m)?$`)
+)
+
+func validInteger(value string) bool {
+	return numberPattern.MatchString(value) && !strings.Contains(value, ".")
+}
+func validNumber(value string) bool      { return numberPattern.MatchString(value) }
+func validNominalSize(value string) bool { return nominalSizePattern.MatchString(value) }
+func validQuantity(value string) bool    { return quantityPattern.MatchString(value) }
+func validWeight(value string) bool      { return value == "---" || numberPattern.MatchString(value) }
+func validText(value string) bool        { return value != "" }
+
+// Typed columns per table; their header names are also the expected header of the table
+var tableColumnChecks = map[string][]columnCheck{
+	"ERECTION MATERIALS": {
+		{"PT NO", validInteger},
+		{"DESCRIPTION", validText},
+		{"N.S.", validNominalSize},
+		{"QTY", validQuantity},
+		{"WEIGHT", validWeight},
+		{"CATEGORY", validText},
+	},
+	"CUT PIPE LENGTH": {
+		{"PIECE NO", isPieceNumber},
+		{"CUT LENGTH", validNumber},
+		{"N.S.", validNominalSize},
+		{"REMARKS", nil},
+	},
+}
+
+// scoreTable computes the confidence of a table in the output format (header names as written
+// to the CSV files). Weight total lines are not scored. Returns nil for an empty table.
+func scoreTable(table string, header []string, rows [][]string) *TableConfidence {
+	checks := tableColumnChecks[table]
+	if len(rows) == 0 || len(checks) == 0 {
+		return nil
+	}
+
+	// Header match quality: expected names found, in any position
+	columns := make([]int, len(checks))
+	matched := 0
+	for i, check := range checks {
+		columns[i] = -1
+		for c, name := range header {
+			if strings.Contains(strings.ToUpper(name), check.header) {
+				columns[i] = c
+				matched++
+				break
+			}
+		}
+	}
+
+	// Row type checks over the typed columns found in the header
+	validRows, scoredRows := 0, 0
+	filledCounts := make(map[int]int)
+	for _, row := range rows {
+		if isTotalRow(row) {
+			continue
+		}
+		scoredRows++
+
+		valid := true
+		for i, check := range checks {
+			if check.valid == nil || columns[i] == -1 {
+				continue
+			}
+			value := ""
+			if columns[i] < len(row) {
+				value = strings.TrimSpace(row[columns[i]])
+			}
+			if !check.valid(value) {
+				valid = false
+				break
+			}
+		}
+		if valid {
+			validRows++
+		}
+
+		// Optional columns such as REMARKS are not counted
+		filled := 0
+		for i, c := range columns {
+			if checks[i].valid != nil && c != -1 && c < len(row) && strings.TrimSpace(row[c]) != "" {
+				filled++
+			}
+		}
+		filledCounts[filled]++
+	}
+
+	// Column count stability: share of rows with the most common number of filled columns
+	modal := 0
+	for _, count := range filledCounts {
+		if count > modal {
+			modal = count
+		}
+	}
+
+	if scoredRows == 0 {
+		return nil
+	}
+
+	confidence := &TableConfidence{
+		RowTypes:        float64(validRows) / float64(scoredRows),
+		ColumnStability: float64(modal) / float64(scoredRows),
+		HeaderMatch:     float64(matched) / float64(len(checks)),
+	}
+	confidence.Score = (confidence.RowTypes + confidence.ColumnStability + confidence.HeaderMatch) / 3
+	return confidence
+}
+
+// isTotalRow reports whether row is a weight total line (TOTAL WEIGHT, TOTAL ERECTION WEIGHT)
+func isTotalRow(row []string) bool {
+	for _, cell := range row {
+		if strings.HasPrefix(strings.ToUpper(strings.TrimSpace(cell)), "TOTAL ") {
+			return true
+		}
+	}
+	return false
+}
+
+// formatConfidence formats a confidence score for the CSV outputs, "" for a missing table
+func formatConfidence(confidence *TableConfidence) string {
+	if confidence == nil {
+		return ""
+	}
+	return fmt.Sprintf("%.2f", confidence.Score)
+}
@@ -1,4 +1,4 @@
-package main
+package dxfparser
 
 import (
 	"fmt"
@@ -26,17 +26,57 @@ type TableCell struct {
 	Text string
 }
 
+// Guard rails against pathological drawings (e.g. thousands of texts on one baseline)
+const (
+	maxTableCols = 20
+	maxTableRows = 100
+)
+
+// RawTableRow is a row of the unprocessed row/column reconstruction of a table page
+type RawTableRow struct {
+	Page  int
+	Y     float64
+	Cells []string
+}
+
+// TableExtraction holds a processed table together with its diagnostics
+type TableExtraction struct {
+	Header   []string
+	Rows     [][]string
+	Warnings []string      // row/column limits that were hit
+	RawRows  []RawTableRow // rows before header merging, category moves, corrections and filtering
+}
+
+// cellXs returns the X positions of a row of cells
+func cellXs(cells []TableCell) []float64 {
+	xs := make([]float64, len(cells))
+	for i, cell := range cells {
+		xs[i] = cell.X
+	}
+	return xs
+}
+
 func extractTable(textEntities []TextEntity, tableTitle string) ([]string, [][]string) {
-	const maxCols = 20
-	const maxRows = 100
+	table := extractTableDetailed(textEntities, tableTitle)
+	return table.Header, table.Rows
+}
+
+// extractTableDetailed extracts a table and reports where the row/column limits were hit.
+// Rows wider than maxTableCols keep their leftmost cells; tables longer than maxTableRows are cut.
+func extractTableDetailed(textEntities []TextEntity, tableTitle string) TableExtraction {
+	var warnings []string
+	var rawRows []RawTableRow
+
+	// A table drawn rotated as a whole is read in its own frame
+	textEntities = rotatedTableEntities(textEntities, tableTitle)
 
 	// Step 1: Find ALL table locations to determine pages
 	var allTableYCoords []float64
 	var allTableXCoords []float64
-	
+
 	for i := range textEntities {
 		entity := &textEntities[i]
-		if strings.Contains(strings.ToLower(entity.Content), strings.ToLower(tableTitle)) {
+		if matchesTableTitle(entity.Content, tableTitle) {
 			allTableYCoords = append(allTableYCoords, entity.Y)
 			allTableXCoords = append(allTableXCoords, entity.X)
 			debugPrint(fmt.Sprintf("[DEBUG] Table title '%s' found at X=%f, Y=%f, text='%s'", tableTitle, entity.X, entity.Y, entity.Content))
@@ -45,7 +85,7 @@ func extractTable(textEntities []TextEntity, tableTitle string) ([]string, [][]s
 
 	if len(allTableYCoords) == 0 {
 		debugPrint(fmt.Sprintf("[DEBUG] Table title '%s' not found.", tableTitle))
-		return []string{}, [][]string{}
+		return TableExtraction{Header: []string{}, Rows: [][]string{}}
 	}
 
 	// Step 2: Sort table Y coordinates (highest to lowest - top to bottom)
@@ -53,12 +93,12 @@ func extractTable(textEntities []TextEntity, tableTitle string) ([]string, [][]s
 		Y float64
 		X float64
 	}
-	
+
 	var tableLocations []tableLocation
 	for i := range allTableYCoords {
 		tableLocations = append(tableLocations, tableLocation{Y: allTableYCoords[i], X: allTableXCoords[i]})
 	}
-	
+
 	sort.Slice(tableLocations, func(i, j int) bool {
 		return tableLocations[i].Y > tableLocations[j].Y // Descending Y (top to bottom)
 	})
@@ -68,10 +108,10 @@ func extractTable(textEntities []TextEntity, tableTitle string) ([]string, [][]s
 	// Step 3: Process each page and combine results
 	var allHeaders []string
 	var allDataRows [][]string
-	
+
 	for pageNum, tableLocation := range tableLocations {
 		debugPrint(fmt.Sprintf("[DEBUG] Processing page %d/%d at Y=%f", pageNum+1, len(tableLocations), tableLocation.Y))
-		
+
 		// Define page boundaries
 		pageStartY := tableLocation.Y
 		var pageEndY *float64
@@ -79,16 +119,16 @@ func extractTable(textEntities []TextEntity, tableTitle string) ([]string, [][]s
 			nextY := tableLocations[pageNum+1].Y
 			pageEndY = &nextY
 		}
-		
+
 		// Create page-specific entity bucket
 		var pageEntities []TextEntity
 		minX := tableLocation.X
-		
+
 		if strings.ToLower(tableTitle) == "cut pipe length" {
 			// Allow data to the left of the title for cut pipe length table
 			minX = tableLocation.X - 50
 		}
-		
+
 		for _, entity := range textEntities {
 			// Include entity if it's within this page's Y range and X range
 			if entity.Y <= pageStartY { // At or below page start
@@ -99,30 +139,50 @@ func extractTable(textEntities []TextEntity, tableTitle string) ([]string, [][]s
 				}
 			}
 		}
-		
+
 		debugPrint(fmt.Sprintf("[DEBUG] Page %d has %d entities", pageNum+1, len(pageEntities)))
-		
+
 		// Process this page using existing extraction logic
-		pageHeaders, pageRows := extractTableFromPageEntities(pageEntities, tableTitle, tableLocation.Y, tableLocation.X)
-		
+		pageHeaders, pageRows, pageRaw, pageWarnings := extractTableFromPageEntities(pageEntities, tableTitle, tableLocation.Y, tableLocation.X)
+		for _, warning := range pageWarnings {
+			warnings = append(warnings, fmt.Sprintf("%s page %d: %s", tableTitle, pageNum+1, warning))
+		}
+		for _, raw := range pageRaw {
+			raw.Page = pageNum + 1
+			rawRows = append(rawRows, raw)
+		}
+
 		// For the first page, use its headers
 		if pageNum == 0 {
 			allHeaders = pageHeaders
 		}
-		
+
 		// Add all page data rows
 		allDataRows = append(allDataRows, pageRows...)
-		
+
 		debugPrint(fmt.Sprintf("[DEBUG] Page %d contributed %d data rows", pageNum+1, len(pageRows)))
 	}
 
 	debugPrint(fmt.Sprintf("[DEBUG] Total combined rows from all pages: %d", len(allDataRows)))
-	
-	return allHeaders, allDataRows
+
+	if len(allDataRows) > maxTableRows {
+		warnings = append(warnings, fmt.Sprintf("%s: %d rows exceed the limit of %d, table truncated", tableTitle, len(allDataRows), maxTableRows))
+		allDataRows = allDataRows[:maxTableRows]
+	}
+
+	return TableExtraction{
+		Header:   allHeaders,
+		Rows:     allDataRows,
+		Warnings: warnings,
+		RawRows:  rawRows,
+	}
 }
 
 // extractTableFromPageEntities processes entities from a single page using the original extraction logic
-func extractTableFromPageEntities(pageEntities []TextEntity, tableTitle string, titleY, titleX float64) ([]string, [][]string) {
+func extractTableFromPageEntities(pageEntities []TextEntity, tableTitle string, titleY, titleX float64) ([]string, [][]string, []RawTableRow, []string) {
+	var warnings []string
+	var rawRows []RawTableRow
+
 	// Group entities by Y coordinate (rows)
 	rowsDict := make(map[float64][]TableCell)
 	for _, entity := range pageEntities {
@@ -132,7 +192,8 @@ func extractTableFromPageEntities(pageEntities []TextEntity, tableTitle string,
 			if _, exists := rowsDict[yKey]; !exists {
 				rowsDict[yKey] = []TableCell{}
 			}
-			rowsDict[yKey] = append(rowsDict[yKey], TableCell{X: entity.X, Text: entity.Content})
+			// A multi-paragraph MTEXT cell is one line of the CSV
+			rowsDict[yKey] = append(rowsDict[yKey], TableCell{X: entity.X, Text: strings.ReplaceAll(entity.Content, "\n", " ")})
 		}
 	}
 
@@ -141,24 +202,36 @@ func extractTableFromPageEntities(pageEntities []TextEntity, tableTitle string,
 		y     float64
 		cells []TableCell
 	}
-	
+
 	sortedRows := []rowData{}
 	for y, cells := range rowsDict {
 		sortedRows = append(sortedRows, rowData{y: y, cells: cells})
 	}
-	
+
 	sort.Slice(sortedRows, func(i, j int) bool {
 		return sortedRows[i].y > sortedRows[j].y // Descending Y
 	})
 
 	// For each row, sort cells by X coordinate (left to right)
 	tableRows := [][]string{}
+	tableCells := [][]TableCell{} // positioned cells of tableRows, used for column assignment
+	oversizedRows := 0
+	widestRow := 0
 	for idx, row := range sortedRows {
 		// Sort cells by X coordinate
 		sort.Slice(row.cells, func(i, j int) bool {
 			return row.cells[i].X < row.cells[j].X
 		})
 
+		// Keep only the leftmost cells of monster rows
+		if len(row.cells) > maxTableCols {
+			oversizedRows++
+			if len(row.cells) > widestRow {
+				widestRow = len(row.cells)
+			}
+			row.cells = row.cells[:maxTableCols]
+		}
+
 		// Extract text content
 		rowTexts := make([]string, len(row.cells))
 		for i, cell := range row.cells {
@@ -181,15 +254,23 @@ func extractTableFromPageEntities(pageEntities []TextEntity, tableTitle string,
 		}
 
 		tableRows = append(tableRows, rowTexts)
+		tableCells = append(tableCells, row.cells)
+		rawRows = append(rawRows, RawTableRow{Y: row.y, Cells: append([]string(nil), rowTexts...)})
 	}
 
 	if strings.ToLower(tableTitle) == "cut pipe length" {
 		debugPrint(fmt.Sprintf("[DEBUG] Total rows extracted for 'CUT PIPE LENGTH': %d", len(tableRows)))
 	}
 
+	if oversizedRows > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d rows wider than %d columns (widest %d) truncated", oversizedRows, maxTableCols, widestRow))
+	}
+
 	// Process headers - merge first two rows
 	var header []string
 	var dataRows [][]string
+	var dataCells [][]TableCell
+	var headerXs []float64
 
 	if len(tableRows) >= 2 {
 		// Merge first two rows as header
@@ -219,12 +300,23 @@ func extractTableFromPageEntities(pageEntities []TextEntity, tableTitle string,
 			}
 		}
 		dataRows = tableRows[2:]
+		dataCells = tableCells[2:]
+		headerXs = cellXs(tableCells[0])
+
+		// Tables continued in a second column block repeat the header lines mid-table
+		var dropped int
+		dataRows, dataCells, dropped = dropRepeatedHeaderRows(tableRows[:2], dataRows, dataCells)
+		if dropped > 0 {
+			debugPrint(fmt.Sprintf("[DEBUG] Dropped %d repeated header rows from '%s'", dropped, tableTitle))
+		}
 	} else {
 		if len(tableRows) > 0 {
 			header = tableRows[0]
+			headerXs = cellXs(tableCells[0])
 		}
 		if len(tableRows) > 1 {
 			dataRows = tableRows[1:]
+			dataCells = tableCells[1:]
 		}
 	}
 
@@ -233,7 +325,7 @@ func extractTableFromPageEntities(pageEntities []TextEntity, tableTitle string,
 		// FIRST: Merge multi-line rows before processing categories
 		dataRows = mergeMultiLineRows(dataRows)
 		debugPrint(fmt.Sprintf("[DEBUG] After multi-line merging: %d rows", len(dataRows)))
-		
+
 		dataRows = processErectionMaterialsTable(dataRows)
 		// Update header to include the new CATEGORY column
 		if len(header) > 0 {
@@ -251,10 +343,12 @@ func extractTableFromPageEntities(pageEntities []TextEntity, tableTitle string,
 	// For CUT PIPE LENGTH, filter rows with '<' and apply validation
 	if strings.ToLower(tableTitle) == "cut pipe length" {
 		keptRows := [][]string{}
-		for _, row := range dataRows {
+		keptCells := [][]TableCell{}
+		for i, row := range dataRows {
 			rowStr := strings.Join(row, "")
 			if strings.Contains(rowStr, "<") {
 				keptRows = append(keptRows, row)
+				keptCells = append(keptCells, dataCells[i])
 			}
 		}
 		debugPrint(fmt.Sprintf("[DEBUG] Kept rows for 'CUT PIPE LENGTH':"))
@@ -265,10 +359,14 @@ func extractTableFromPageEntities(pageEntities []TextEntity, tableTitle string,
 		}
 		dataRows = keptRows
 
-		// Apply column validation and correction for CUT PIPE LENGTH
+		// Apply column validation and correction for CUT PIPE LENGTH: place cells by the
+		// X positions of the header columns, fall back to classifying the cell contents
 		correctedRows := [][]string{}
-		for _, row := range dataRows {
-			correctedRow := validateAndCorrectCutLengthRow(row)
+		for i, row := range dataRows {
+			correctedRow, ok := splitCutLengthRowByColumns(keptCells[i], headerXs)
+			if !ok {
+				correctedRow = validateAndCorrectCutLengthRow(row)
+			}
 			correctedRows = append(correctedRows, correctedRow)
 		}
 		dataRows = correctedRows
@@ -307,7 +405,68 @@ func extractTableFromPageEntities(pageEntities []TextEntity, tableTitle string,
 		paddedRows = append(paddedRows, paddedRow)
 	}
 
-	return header, paddedRows
+	return header, paddedRows, rawRows, warnings
+}
+
+// dropRepeatedHeaderRows removes data rows that repeat a header line: rows equal to one of the
+// header lines, or with at least two cells that all occur in the header lines. Returns the
+// remaining rows and cells and the number of rows dropped.
+func dropRepeatedHeaderRows(headerLines [][]string, rows [][]string, cells [][]TableCell) ([][]string, [][]TableCell, int) {
+	normalize := func(text string) string {
+		return strings.ToUpper(strings.Join(strings.Fields(text), " "))
+	}
+	joinCells := func(row []string) (string, int) {
+		var parts []string
+		for _, cell := range row {
+			if text := normalize(cell); text != "" {
+				parts = append(parts, text)
+			}
+		}
+		return strings.Join(parts, "|"), len(parts)
+	}
+
+	headerCells := make(map[string]bool)
+	headerRows := make(map[string]bool)
+	for _, line := range headerLines {
+		for _, cell := range line {
+			if text := normalize(cell); text != "" {
+				headerCells[text] = true
+			}
+		}
+		if joined, n := joinCells(line); n > 0 {
+			headerRows[joined] = true
+		}
+	}
+
+	isHeader := func(row []string) bool {
+		joined, n := joinCells(row)
+		if n == 0 {
+			return false
+		}
+		if headerRows[joined] {
+			return true
+		}
+		if n < 2 {
+			return false
+		}
+		for _, cell := range row {
+			if text := normalize(cell); text != "" && !headerCells[text] {
+				return false
+			}
+		}
+		return true
+	}
+
+	keptRows := rows[:0:0]
+	keptCells := cells[:0:0]
+	for i, row := range rows {
+		if isHeader(row) {
+			continue
+		}
+		keptRows = append(keptRows, row)
+		keptCells = append(keptCells, cells[i])
+	}
+	return keptRows, keptCells, len(rows) - len(keptRows)
 }
 
 func mergeHeaderForCutPipeLength(h1, h2 string) string {
@@ -400,7 +559,7 @@ func processErectionMaterialsTable(dataRows [][]string) [][]string {
 			if isTotalRow {
 				// For total rows, move the total type to column F and weight value to column E
 				newRow := make([]string, 6) // Create exactly 6 columns (A-F)
-				
+
 				totalType := row[0] // Save the total type
 				weightValue := ""
 				if len(row) > 1 {
@@ -408,13 +567,13 @@ func processErectionMaterialsTable(dataRows [][]string) [][]string {
 				}
 
 				// Leave columns A-D empty, put weight in E (index 4), total type in F (index 5)
-				newRow[0] = "" // Column A
-				newRow[1] = "" // Column B  
-				newRow[2] = "" // Column C
-				newRow[3] = "" // Column D
+				newRow[0] = ""          // Column A
+				newRow[1] = ""          // Column B
+				newRow[2] = ""          // Column C
+				newRow[3] = ""          // Column D
 				newRow[4] = weightValue // Column E (WEIGHT)
 				newRow[5] = totalType   // Column F (CATEGORY)
-				
+
 				processedRows = append(processedRows, newRow)
 			} else {
 				// Regular category header
@@ -432,19 +591,19 @@ func processErectionMaterialsTable(dataRows [][]string) [][]string {
 			// Detect and fix missing N.S. column issue (conservative approach)
 			// Expected structure: PT_NO | DESCRIPTION | N.S. | QTY | WEIGHT | CATEGORY
 			//                    [0]   | [1]         | [2]  | [3] | [4]    | [5]
-			
+
 			// Only attempt correction if we have exactly 5 columns (missing one column)
 			// and the row appears to be a component row (not a category header)
 			if len(newRow) == 5 && newRow[0] != "" && newRow[1] != "" {
-				
+
 				// Working backwards from the structure:
 				// newRow[4] should be WEIGHT (always present - number or "---")
 				// newRow[3] should be QTY (always present)
 				// newRow[2] should be N.S. (sometimes missing)
-				
+
 				col2 := strings.TrimSpace(newRow[2]) // What should be N.S.
 				col4 := strings.TrimSpace(newRow[4]) // What should be WEIGHT
-				
+
 				// Check if col4 looks like a valid WEIGHT value
 				isCol4ValidWeight := false
 				if col4 == "---" || col4 == "" {
@@ -452,20 +611,20 @@ func processErectionMaterialsTable(dataRows [][]string) [][]string {
 				} else if val, err := strconv.ParseFloat(col4, 64); err == nil && val >= 0 {
 					isCol4ValidWeight = true
 				}
-				
+
 				// If col4 is valid weight, check if col2 looks like it contains QTY data
 				// instead of N.S. data (indicating missing N.S. column)
 				if isCol4ValidWeight {
 					// N.S. values are typically: empty, numbers like "25", or "number x number" format like "25 x 15"
 					// QTY values are typically: decimal numbers, numbers with "M" suffix, small integers
-					
+
 					isCol2LikelyQty := false
-					
+
 					// Check if col2 looks like QTY (pipe length with M suffix)
 					if strings.HasSuffix(col2, "M") {
 						isCol2LikelyQty = true
 					}
-					
+
 					// Check if col2 is a small integer that's likely QTY, not N.S.
 					// N.S. (nominal size) is typically 15, 25, 50, etc. (pipe sizes)
 					// QTY can be small numbers like 1, 2, 3, etc.
@@ -476,27 +635,27 @@ func processErectionMaterialsTable(dataRows [][]string) [][]string {
 							isCol2LikelyQty = true
 						}
 					}
-					
+
 					// Check if col2 contains decimal values which are more likely QTY than N.S.
 					if strings.Contains(col2, ".") {
 						if val, err := strconv.ParseFloat(col2, 64); err == nil && val > 0 {
 							isCol2LikelyQty = true
 						}
 					}
-					
+
 					// Only apply fix if we're confident this is a missing N.S. case
 					if isCol2LikelyQty {
 						debugPrint(fmt.Sprintf("[DEBUG] Detected missing N.S. column in row: %v (Category: %s)", newRow, currentCategory))
-						
+
 						// Shift data right to insert missing N.S. column
 						correctedRow := make([]string, 6)
 						correctedRow[0] = newRow[0] // PT_NO
-						correctedRow[1] = newRow[1] // DESCRIPTION  
+						correctedRow[1] = newRow[1] // DESCRIPTION
 						correctedRow[2] = ""        // N.S. (missing, leave empty)
 						correctedRow[3] = newRow[2] // QTY (was in N.S. position)
-						correctedRow[4] = newRow[3] // WEIGHT (was in QTY position) 
+						correctedRow[4] = newRow[3] // WEIGHT (was in QTY position)
 						correctedRow[5] = newRow[4] // Keep any additional data
-						
+
 						newRow = correctedRow
 						debugPrint(fmt.Sprintf("[DEBUG] Corrected row: %v", newRow))
 					}
@@ -516,7 +675,7 @@ func processErectionMaterialsTable(dataRows [][]string) [][]string {
 
 			// Put category in column F (index 5)
 			newRow[5] = currentCategory
-			
+
 			processedRows = append(processedRows, newRow)
 		}
 	}
@@ -531,24 +690,24 @@ func mergeMultiLineRows(dataRows [][]string) [][]string {
 	}
 
 	mergedRows := [][]string{}
-	
+
 	for _, row := range dataRows {
 		if len(row) == 0 {
 			continue
 		}
-		
+
 		// Check if this row starts with a PT NO (number)
 		// If it does, it's a legitimate new BOM row
 		// If it doesn't, it might be a continuation of the previous row
 		isNewBOMRow := false
-		
+
 		if len(row) > 0 && row[0] != "" {
 			// Check if first column contains a number (PT NO)
 			if _, err := strconv.Atoi(strings.TrimSpace(row[0])); err == nil {
 				isNewBOMRow = true
 			}
 		}
-		
+
 		if isNewBOMRow || len(mergedRows) == 0 {
 			// This is a new BOM row, add it as-is
 			mergedRows = append(mergedRows, row)
@@ -557,7 +716,7 @@ func mergeMultiLineRows(dataRows [][]string) [][]string {
 			// This might be a continuation row - check if it looks like wrapped text
 			// Continuation rows typically have text but are missing PT NO, N.S., QTY, WEIGHT
 			isContinuationRow := false
-			
+
 			// Check if this row has text content (in any column) but no numeric PT NO
 			hasTextContent := false
 			for _, cell := range row {
@@ -566,12 +725,12 @@ func mergeMultiLineRows(dataRows [][]string) [][]string {
 					break
 				}
 			}
-			
+
 			if hasTextContent {
 				// This row has text but no PT NO, likely a continuation
 				// Check if it looks like descriptive text (not numbers/measurements)
 				firstCell := strings.TrimSpace(row[0])
-				
+
 				// If the first cell contains text that looks like part of a description
 				// (no PT NO number, and contains words that suggest continuation)
 				if firstCell != "" {
@@ -585,12 +744,12 @@ func mergeMultiLineRows(dataRows [][]string) [][]string {
 					}
 				}
 			}
-			
+
 			if isContinuationRow && len(mergedRows) > 0 {
 				// Merge this row's text with the previous row's description
 				lastRowIdx := len(mergedRows) - 1
 				lastRow := mergedRows[lastRowIdx]
-				
+
 				// Find the continuation text (could be in any column)
 				continuationText := ""
 				for _, cell := range row {
@@ -599,11 +758,11 @@ func mergeMultiLineRows(dataRows [][]string) [][]string {
 						break
 					}
 				}
-				
+
 				if len(lastRow) > 1 && continuationText != "" {
 					// Append continuation text to previous row's description
 					mergedRows[lastRowIdx][1] = strings.TrimSpace(lastRow[1] + " " + continuationText)
-					debugPrint(fmt.Sprintf("[DEBUG] Merged continuation text: '%s' + '%s' = '%s'", 
+					debugPrint(fmt.Sprintf("[DEBUG] Merged continuation text: '%s' + '%s' = '%s'",
 						lastRow[1], continuationText, mergedRows[lastRowIdx][1]))
 				}
 			} else {
@@ -613,9 +772,7 @@ func mergeMultiLineRows(dataRows [][]string) [][]string {
 			}
 		}
 	}
-	
+
 	debugPrint(fmt.Sprintf("[DEBUG] Multi-line merging: %d rows -> %d rows", len(dataRows), len(mergedRows)))
 	return mergedRows
 }
-
-
@@ -0,0 +1,150 @@
+package dxfparser
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// tagRowTolerance is how far (drawing units) a tag may be above or below a table row's
+// PT NO text and still be read as written in that row
+const tagRowTolerance = 0.5
+
+// isTaggedCategory reports whether rows of a BOM category reference tagged items
+func isTaggedCategory(category string) bool {
+	category = strings.ToUpper(category)
+	return strings.Contains(category, "VALVE") || strings.Contains(category, "INSTRUMENT") ||
+		strings.Contains(category, "IN-LINE")
+}
+
+// tagText is one tag found in a text entity
+type tagText struct {
+	Tag  string
+	X, Y float64
+}
+
+// findTagTexts returns every tag matching pattern in the entities
+func findTagTexts(entities []TextEntity, pattern *regexp.Regexp) []tagText {
+	var tags []tagText
+	for _, entity := range entities {
+		for _, tag := range pattern.FindAllString(entity.Content, -1) {
+			tags = append(tags, tagText{Tag: tag, X: entity.X, Y: entity.Y})
+		}
+	}
+	return tags
+}
+
+// tableCellPoints returns the positions of the text entities that are cells of a table. Texts
+// there are never callouts, e.g. a QTY equal to a PT NO.
+func tableCellPoints(provenance []RowProvenance) map[[2]float64]bool {
+	points := make(map[[2]float64]bool)
+	for _, rowProv := range provenance {
+		for _, cell := range rowProv.Cells {
+			if cell.Found {
+				points[[2]float64{cell.X, cell.Y}] = true
+			}
+		}
+	}
+	return points
+}
+
+// assignTags returns the tags of each ERECTION MATERIALS row; rows outside the valve and
+// instrument categories get "". A tag is taken from the table row itself (a tag written
+// right of the PT NO on the same line) or, otherwise, from the nearest tag within radius of
+// each item callout on the drawing: a text equal to the row's PT NO outside the table. The
+// callouts of all rows are looked up in one batch of spatial queries.
+func assignTags(header []string, rows [][]string, entities []TextEntity, radius float64, pattern *regexp.Regexp) []string {
+	tags := make([]string, len(rows))
+
+	categoryIdx := -1
+	for i, column := range header {
+		if strings.EqualFold(strings.TrimSpace(column), "CATEGORY") {
+			categoryIdx = i
+		}
+	}
+	if categoryIdx == -1 {
+		return tags
+	}
+
+	tagTexts := findTagTexts(entities, pattern)
+	if len(tagTexts) == 0 {
+		return tags
+	}
+	provenance := buildProvenance("ERECTION MATERIALS", header, rows, entities, "", "")
+	tableCells := tableCellPoints(provenance)
+
+	rowTags := make([][]string, len(rows))
+	add := func(r int, tag string) {
+		for _, known := range rowTags[r] {
+			if known == tag {
+				return
+			}
+		}
+		rowTags[r] = append(rowTags[r], tag)
+	}
+
+	// Tag written in the table row
+	untagged := make(map[string][]int) // PT NO: rows without a tag in the table
+	for r, row := range rows {
+		if categoryIdx >= len(row) || !isTaggedCategory(row[categoryIdx]) || len(row) == 0 {
+			continue
+		}
+		ptNo := strings.TrimSpace(row[0])
+		if ptNo == "" {
+			continue
+		}
+		if anchor := provenance[r].Cells[0]; anchor.Found {
+			for _, tag := range tagTexts {
+				if tag.X > anchor.X && math.Abs(tag.Y-anchor.Y) <= tagRowTolerance {
+					add(r, tag.Tag)
+				}
+			}
+		}
+		if len(rowTags[r]) == 0 {
+			untagged[ptNo] = append(untagged[ptNo], r)
+		}
+	}
+
+	// Tag next to the item callouts on the drawing
+	var queries []RadiusQuery
+	var queryRows [][]int
+	for _, entity := range entities {
+		calloutRows := untagged[strings.TrimSpace(entity.Content)]
+		if len(calloutRows) == 0 || tableCells[[2]float64{entity.X, entity.Y}] {
+			continue
+		}
+		queries = append(queries, RadiusQuery{X: entity.X, Y: entity.Y, Radius: radius})
+		queryRows = append(queryRows, calloutRows)
+	}
+	if len(queries) > 0 {
+		tagEntities := make([]TextEntity, len(tagTexts))
+		for i, tag := range tagTexts {
+			tagEntities[i] = TextEntity{Content: tag.Tag, X: tag.X, Y: tag.Y}
+		}
+		for q, matches := range NewSpatialAnalyzer(tagEntities).BatchFindEntitiesInRadius(queries) {
+			if len(matches) == 0 {
+				continue
+			}
+			// Of equally near tags the one found last in the drawing
+			nearest := 0
+			for nearest+1 < len(matches) && matches[nearest+1].Distance == matches[0].Distance {
+				nearest++
+			}
+			for _, r := range queryRows[q] {
+				add(r, matches[nearest].Entity.Content)
+			}
+		}
+	}
+
+	for r := range rows {
+		if len(rowTags[r]) == 0 {
+			continue
+		}
+		sort.Strings(rowTags[r])
+		tags[r] = strings.Join(rowTags[r], "; ")
+		debugPrint(fmt.Sprintf("[DEBUG] PT NO %s: tags %s", strings.TrimSpace(rows[r][0]), tags[r]))
+	}
+	return tags
+}
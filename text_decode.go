@@ -0,0 +1,70 @@
+package dxfparser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// unicodeEscape matches the \U+xxxx escapes of characters outside the drawing's code page
+var unicodeEscape = regexp.MustCompile(`\\U\+([0-9A-Fa-f]{4})`)
+
+// controlCodes are the %% control sequences of TEXT (and MTEXT) content
+var controlCodes = map[byte]string{
+	'd': "°", // degree
+	'c': "Ø", // diameter
+	'p': "±", // plus/minus
+	'%': "%",
+	'o': "", // overline on / off
+	'u': "", // underline on / off
+	'k': "", // strike-through on / off
+}
+
+// decodeText decodes the escapes of a text value: Unicode escapes like \U+00B0 and the legacy
+// control sequences %%d (°), %%c (Ø), %%p (±), %%% (%) and %%nnn (character nnn). The
+// overline, underline and strike-through toggles %%o, %%u and %%k are dropped.
+func decodeText(text string) string {
+	if strings.Contains(text, `\U+`) {
+		text = unicodeEscape.ReplaceAllStringFunc(text, func(match string) string {
+			// Extract the hex code (remove \U+)
+			if codePoint, err := strconv.ParseInt(match[3:], 16, 32); err == nil {
+				return string(rune(codePoint))
+			}
+			return match
+		})
+	}
+	if strings.Contains(text, "%%") {
+		text = decodeControlCodes(text)
+	}
+	return text
+}
+
+// decodeControlCodes replaces the %% control sequences of text; unknown ones are kept
+func decodeControlCodes(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] != '%' || i+2 >= len(text) || text[i+1] != '%' {
+			b.WriteByte(text[i])
+			continue
+		}
+		code := text[i+2]
+		if code >= 'A' && code <= 'Z' {
+			code += 'a' - 'A'
+		}
+		if replacement, ok := controlCodes[code]; ok {
+			b.WriteString(replacement)
+			i += 2
+			continue
+		}
+		// %%nnn: a character by its three digit decimal code
+		if i+4 < len(text) {
+			if n, err := strconv.Atoi(text[i+2 : i+5]); err == nil && n > 0 {
+				b.WriteRune(rune(n))
+				i += 4
+				continue
+			}
+		}
+		b.WriteByte(text[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,80 @@
+package dxfparser
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Script values detected for text content
+const (
+	ScriptLatin    = "latin"
+	ScriptCyrillic = "cyrillic"
+	ScriptMixed    = "mixed"
+)
+
+// cyrillicToLatin maps Russian Cyrillic letters to Latin (GOST 7.79-2000 system B, simplified)
+var cyrillicToLatin = map[rune]string{
+	'А': "A", 'Б': "B", 'В': "V", 'Г': "G", 'Д': "D", 'Е': "E", 'Ё': "YO", 'Ж': "ZH",
+	'З': "Z", 'И': "I", 'Й': "J", 'К': "K", 'Л': "L", 'М': "M", 'Н': "N", 'О': "O",
+	'П': "P", 'Р': "R", 'С': "S", 'Т': "T", 'У': "U", 'Ф': "F", 'Х': "X", 'Ц': "C",
+	'Ч': "CH", 'Ш': "SH", 'Щ': "SHH", 'Ъ': "", 'Ы': "Y", 'Ь': "", 'Э': "E", 'Ю': "YU",
+	'Я': "YA",
+}
+
+// detectScript returns the writing system of the letters in text, or "" if it has no letters
+func detectScript(text string) string {
+	hasLatin := false
+	hasCyrillic := false
+
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Cyrillic, r):
+			hasCyrillic = true
+		case unicode.Is(unicode.Latin, r):
+			hasLatin = true
+		}
+	}
+
+	switch {
+	case hasLatin && hasCyrillic:
+		return ScriptMixed
+	case hasCyrillic:
+		return ScriptCyrillic
+	case hasLatin:
+		return ScriptLatin
+	}
+	return ""
+}
+
+// transliterate converts Cyrillic letters to upper-case Latin; other characters are kept
+func transliterate(text string) string {
+	var b strings.Builder
+	b.Grow(len(text))
+
+	for _, r := range text {
+		if unicode.Is(unicode.Cyrillic, r) {
+			if latin, ok := cyrillicToLatin[unicode.ToUpper(r)]; ok {
+				b.WriteString(latin)
+				continue
+			}
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// aggregationKey normalizes a description for grouping: transliterated (if enabled),
+// upper-cased and with collapsed whitespace
+func aggregationKey(description string, translit bool) string {
+	if !translit {
+		return description
+	}
+	return strings.Join(strings.Fields(strings.ToUpper(transliterate(description))), " ")
+}
+
+// annotateScripts sets the Script field of every entity
+func annotateScripts(entities []TextEntity) {
+	for i := range entities {
+		entities[i].Script = detectScript(entities[i].Content)
+	}
+}
@@ -0,0 +1,108 @@
+package dxfparser
+
+import (
+	"fmt"
+	"math"
+)
+
+// RotatedTextPolicy decides what the table extraction does with rotated text, e.g. vertical
+// column headers or a north arrow label, which would otherwise be grouped into rows by position
+// as if it were horizontal
+type RotatedTextPolicy string
+
+const (
+	RotatedTextKeep     RotatedTextPolicy = "keep"     // use rotated text where it is (earlier versions)
+	RotatedTextSkip     RotatedTextPolicy = "skip"     // leave rotated text out of the tables
+	RotatedTextUnrotate RotatedTextPolicy = "unrotate" // turn rotated text back about the origin by its rotation
+)
+
+// rotationTolerance is the deviation from horizontal, in degrees, up to which text counts as not rotated
+const rotationTolerance = 1.0
+
+// parseRotatedTextPolicy validates a -rotated-text value
+func parseRotatedTextPolicy(value string) (RotatedTextPolicy, error) {
+	switch policy := RotatedTextPolicy(value); policy {
+	case RotatedTextKeep, RotatedTextSkip, RotatedTextUnrotate:
+		return policy, nil
+	}
+	return "", fmt.Errorf("invalid rotated text policy '%s' (keep, skip, unrotate)", value)
+}
+
+// normalizeRotation returns an angle in degrees in the range 0-360
+func normalizeRotation(degrees float64) float64 {
+	degrees = math.Mod(degrees, 360)
+	if degrees < 0 {
+		degrees += 360
+	}
+	return degrees
+}
+
+// Rotated reports whether the text is rotated by more than rotationTolerance
+func (e TextEntity) Rotated() bool {
+	return e.Rotation > rotationTolerance && e.Rotation < 360-rotationTolerance
+}
+
+// sameRotation reports whether two rotations in degrees differ by at most rotationTolerance
+func sameRotation(a, b float64) bool {
+	diff := normalizeRotation(a - b)
+	return diff <= rotationTolerance || diff >= 360-rotationTolerance
+}
+
+// unrotate returns e turned back about the origin by rotation degrees
+func (e TextEntity) unrotate(rotation float64) TextEntity {
+	sin, cos := math.Sincos(rotation * math.Pi / 180)
+	e.X, e.Y = e.X*cos+e.Y*sin, e.Y*cos-e.X*sin
+	e.Rotation = normalizeRotation(e.Rotation - rotation)
+	return e
+}
+
+// rotatedTableEntities returns the entities of a table drawn rotated as a whole, e.g. a CUT
+// PIPE LENGTH table printed along the long side of the sheet, where the rows run along Y. If
+// the first title of the table is rotated, text of the same rotation is turned back by it
+// about the origin, so rows run along X again, and text of other rotations is left out;
+// otherwise entities are returned unchanged.
+func rotatedTableEntities(entities []TextEntity, tableTitle string) []TextEntity {
+	var title *TextEntity
+	for i := range entities {
+		if matchesTableTitle(entities[i].Content, tableTitle) {
+			title = &entities[i]
+			break
+		}
+	}
+	if title == nil || !title.Rotated() {
+		return entities
+	}
+	rotation := title.Rotation
+	table := make([]TextEntity, 0, len(entities))
+	for _, entity := range entities {
+		if sameRotation(entity.Rotation, rotation) {
+			table = append(table, entity.unrotate(rotation))
+		}
+	}
+	debugPrint(fmt.Sprintf("[DEBUG] Table '%s' is rotated by %.1f°, reading %d of %d text entities turned back", tableTitle, rotation, len(table), len(entities)))
+	return table
+}
+
+// tableEntities returns the entities the table extraction reads under policy. Text
+// that is not rotated keeps its position. Rotated text turned back by its rotation lines up
+// with text of the same rotation, so a table drawn rotated as a whole reads like a horizontal
+// one, though it may land among other text of the drawing.
+func tableEntities(entities []TextEntity, policy RotatedTextPolicy) []TextEntity {
+	if policy == RotatedTextKeep {
+		return entities
+	}
+	table := make([]TextEntity, 0, len(entities))
+	for _, entity := range entities {
+		if !entity.Rotated() {
+			table = append(table, entity)
+			continue
+		}
+		if policy == RotatedTextUnrotate {
+			table = append(table, entity.unrotate(entity.Rotation))
+		}
+	}
+	if dropped := len(entities) - len(table); dropped > 0 {
+		debugPrint(fmt.Sprintf("[DEBUG] Skipped %d rotated text entities in the table extraction", dropped))
+	}
+	return table
+}
@@ -0,0 +1,66 @@
+package dxfparser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// gdtSymbols are the characters of the GDT font of TOLERANCE frames and the Unicode symbols
+// they show
+var gdtSymbols = map[string]string{
+	"a": "∠", // angularity
+	"b": "⊥", // perpendicularity
+	"c": "⏥", // flatness
+	"d": "⌓", // profile of a surface
+	"e": "○", // circularity
+	"f": "∥", // parallelism
+	"g": "⌭", // cylindricity
+	"h": "↗", // circular runout
+	"i": "⌯", // symmetry
+	"j": "⌖", // position
+	"k": "⌒", // profile of a line
+	"l": "Ⓛ", // least material condition
+	"m": "Ⓜ", // maximum material condition
+	"n": "⌀", // diameter
+	"p": "Ⓟ", // projected tolerance zone
+	"r": "◎", // concentricity
+	"s": "Ⓢ", // regardless of feature size
+	"t": "⌰", // total runout
+	"u": "⏤", // straightness
+}
+
+// gdtFont matches a run of GDT font characters, e.g. {\Fgdt;j}
+var gdtFont = regexp.MustCompile(`(?i)\{\\Fgdt;([a-z]*)\}`)
+
+// toleranceCell matches the %%v separating the cells of a feature control frame
+var toleranceCell = regexp.MustCompile(`(?i)%%v`)
+
+// toleranceText returns the content (1) of a TOLERANCE, a feature control frame, as plain
+// text: GDT font characters as their symbols, cells separated by " | " and the frames of a
+// composite tolerance (^J) on lines of their own. Empty cells are left out.
+func toleranceText(content string) string {
+	content = gdtFont.ReplaceAllStringFunc(content, func(match string) string {
+		var b strings.Builder
+		for _, c := range strings.ToLower(gdtFont.FindStringSubmatch(match)[1]) {
+			if symbol, ok := gdtSymbols[string(c)]; ok {
+				b.WriteString(symbol)
+			} else {
+				b.WriteRune(c)
+			}
+		}
+		return b.String()
+	})
+	content = stripMTextFormat(content)
+
+	frames := strings.Split(content, "^J")
+	for i, frame := range frames {
+		var cells []string
+		for _, cell := range toleranceCell.Split(frame, -1) {
+			if cell = strings.TrimSpace(cell); cell != "" {
+				cells = append(cells, cell)
+			}
+		}
+		frames[i] = strings.Join(cells, " | ")
+	}
+	return strings.TrimSpace(strings.Join(frames, "\n"))
+}
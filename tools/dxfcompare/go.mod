@@ -0,0 +1,5 @@
+module github.com/jeffcall-ch/dxf_parser_go/tools/dxfcompare
+
+go 1.22
+
+require github.com/yofu/dxf v0.0.0-20250806094206-f3988c7f0176
@@ -0,0 +1,71 @@
+// Command dxfcompare is the reader adapter of "dxf_parser benchmark compare" for
+// github.com/yofu/dxf. It reads every file given as argument and prints one JSON line per file
+// with the parse time and the entity records read per type:
+//
+//	dxf_parser benchmark compare drawings/ -reader yofu="go run ./tools/dxfcompare"
+//
+// It is a separate module so the parser does not depend on the readers it is compared with.
+// Adapters for other readers only have to print the same lines.
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/yofu/dxf"
+	"github.com/yofu/dxf/entity"
+)
+
+// result is one line of the adapter protocol
+type result struct {
+	File     string         `json:"file"`
+	Seconds  float64        `json:"seconds"`
+	Entities map[string]int `json:"entities"`
+	Error    string         `json:"error,omitempty"`
+}
+
+func main() {
+	out := json.NewEncoder(os.Stdout)
+	for _, path := range os.Args[1:] {
+		r := result{File: path, Entities: make(map[string]int)}
+		start := time.Now()
+		drawing, err := dxf.FromFile(path)
+		r.Seconds = time.Since(start).Seconds()
+		if err != nil {
+			r.Error = err.Error()
+		} else {
+			for _, e := range drawing.Entities() {
+				r.Entities[typeName(e)]++
+			}
+		}
+		out.Encode(r)
+	}
+}
+
+// typeName returns the DXF record name of an entity
+func typeName(e entity.Entity) string {
+	switch e.(type) {
+	case *entity.Line:
+		return "LINE"
+	case *entity.ThreeDFace:
+		return "3DFACE"
+	case *entity.LwPolyline:
+		return "LWPOLYLINE"
+	case *entity.Circle:
+		return "CIRCLE"
+	case *entity.Polyline:
+		return "POLYLINE"
+	case *entity.Vertex:
+		return "VERTEX"
+	case *entity.Point:
+		return "POINT"
+	case *entity.Arc:
+		return "ARC"
+	case *entity.Text:
+		return "TEXT"
+	case *entity.Spline:
+		return "SPLINE"
+	}
+	return "OTHER"
+}
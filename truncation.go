@@ -0,0 +1,90 @@
+package dxfparser
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// truncationWindow is the size of the file tail searched first for the last record
+const truncationWindow = 64 * 1024
+
+// TruncationError reports a file that ends inside a record instead of with the EOF record,
+// e.g. after an interrupted copy or export. Offset is the byte offset of the code 0 line of the
+// incomplete record; everything before it is intact.
+type TruncationError struct {
+	Offset int64
+	Record string // type of the incomplete record, e.g. TEXT or ENDSEC
+}
+
+func (e *TruncationError) Error() string {
+	return fmt.Sprintf("file truncated at byte %d inside %s", e.Offset, e.Record)
+}
+
+// findTruncation returns a TruncationError if the size bytes of r do not end with the EOF
+// record, nil for a complete file. Only the tail is read: windows growing from the end are
+// searched for the last code 0 line followed by a record name, aligned like findSafeChunkEnd.
+// Content without any record is left to the parser.
+func findTruncation(r io.ReaderAt, size int64) (*TruncationError, error) {
+	for window := int64(truncationWindow); ; window *= 2 {
+		start := size - window
+		if start < 0 {
+			start = 0
+		}
+		offset, record, err := lastRecord(r, start, size)
+		if err != nil {
+			return nil, err
+		}
+		if record != "" {
+			if record == "EOF" {
+				return nil, nil
+			}
+			return &TruncationError{Offset: offset, Record: record}, nil
+		}
+		if start == 0 {
+			return nil, nil
+		}
+	}
+}
+
+// lastRecord returns the offset and name of the last record starting between start and size,
+// skipping the line start falls into
+func lastRecord(r io.ReaderAt, start, size int64) (int64, string, error) {
+	reader := bufio.NewReader(io.NewSectionReader(r, start, size-start))
+	offset := start
+	if start > 0 {
+		var previous [1]byte
+		if _, err := r.ReadAt(previous[:], start-1); err != nil {
+			return 0, "", fmt.Errorf("error reading file end: %w", err)
+		}
+		if previous[0] != '\n' {
+			skipped, err := reader.ReadString('\n')
+			offset += int64(len(skipped))
+			if err == io.EOF {
+				return 0, "", nil
+			} else if err != nil {
+				return 0, "", fmt.Errorf("error reading file end: %w", err)
+			}
+		}
+	}
+
+	lastOffset, lastName := int64(0), ""
+	prevLine, prevStart := "", int64(-1)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			trimmed := strings.TrimSpace(line)
+			if prevLine == "0" && isRecordName(trimmed) {
+				lastOffset, lastName = prevStart, trimmed
+			}
+			prevLine, prevStart = trimmed, offset
+			offset += int64(len(line))
+		}
+		if err == io.EOF {
+			return lastOffset, lastName, nil
+		} else if err != nil {
+			return 0, "", fmt.Errorf("error reading file end: %w", err)
+		}
+	}
+}
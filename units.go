@@ -0,0 +1,156 @@
+package dxfparser
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// UnitsAuto detects the units of every drawing from its HEADER; any other -units value names
+// the unit all drawings are read in
+const UnitsAuto = "auto"
+
+// unitCodes are the $INSUNITS codes of the -units values
+var unitCodes = map[string]int{
+	"mm": 4, "millimeters": 4,
+	"cm": 5, "centimeters": 5,
+	"m": 6, "meters": 6,
+	"in": 1, "inch": 1, "inches": 1,
+	"ft": 2, "feet": 2,
+}
+
+// parseUnits validates a -units value
+func parseUnits(value string) (string, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if _, ok := unitCodes[value]; ok || value == UnitsAuto {
+		return value, nil
+	}
+	return "", fmt.Errorf("invalid units '%s' (auto, mm, cm, m, inch, ft)", value)
+}
+
+// drawingUnits is the unit of the coordinates and lengths of a drawing
+type drawingUnits struct {
+	Name        string  // $INSUNITS name, e.g. "inches"
+	Millimeters float64 // size of one unit in millimeters
+	Source      string  // "-units", "$INSUNITS", "$MEASUREMENT" or "default"
+}
+
+// resolveUnits returns the units of a drawing with header (nil if it has none) under the
+// -units value override: the named unit, or detected from $INSUNITS, then $MEASUREMENT 0
+// (imperial) for inches, otherwise millimeters
+func resolveUnits(header *DXFHeader, override string) drawingUnits {
+	if code, ok := unitCodes[strings.ToLower(override)]; ok {
+		return drawingUnits{Name: insUnits[code].name, Millimeters: insUnits[code].millimeters, Source: "-units"}
+	}
+	if header != nil {
+		if size, ok := header.Millimeters(); ok {
+			return drawingUnits{Name: header.Units, Millimeters: size, Source: "$INSUNITS"}
+		}
+		if header.Measurement == 0 {
+			return drawingUnits{Name: insUnits[1].name, Millimeters: insUnits[1].millimeters, Source: "$MEASUREMENT"}
+		}
+	}
+	return drawingUnits{Name: insUnits[4].name, Millimeters: 1, Source: "default"}
+}
+
+// detectUnits returns the units of a drawing under the -units override; read returns its
+// HEADER and is only called to detect the units
+func detectUnits(override string, read func() (*DXFHeader, error)) drawingUnits {
+	if override != "" && override != UnitsAuto {
+		return resolveUnits(nil, override)
+	}
+	header, err := read()
+	if err != nil {
+		header = nil
+	}
+	return resolveUnits(header, override)
+}
+
+// scaled returns e with its coordinates and lengths in millimeters, given a unit of mm millimeters
+func (e TextEntity) scaled(mm float64) TextEntity {
+	e.X, e.Y, e.Z = e.X*mm, e.Y*mm, e.Z*mm
+	e.Height *= mm
+	e.Measurement *= mm
+	if len(e.Arrow) == 2 {
+		e.Arrow = []float64{e.Arrow[0] * mm, e.Arrow[1] * mm}
+	}
+	return e
+}
+
+// scaleEntities returns the entities in millimeters, entities itself for drawings in millimeters
+func scaleEntities(entities []TextEntity, units drawingUnits) []TextEntity {
+	if units.Millimeters == 1 {
+		return entities
+	}
+	scaled := newEntities(len(entities))
+	for _, e := range entities {
+		scaled = append(scaled, e.scaled(units.Millimeters))
+	}
+	return scaled
+}
+
+// scaled returns s with its end points and length in millimeters, given a unit of mm millimeters
+func (s PolylineSegment) scaled(mm float64) PolylineSegment {
+	s.X1, s.Y1, s.X2, s.Y2 = s.X1*mm, s.Y1*mm, s.X2*mm, s.Y2*mm
+	s.Z1, s.Z2 = s.Z1*mm, s.Z2*mm
+	s.Length *= mm
+	return s
+}
+
+// feetInches matches a length written in feet and inches, e.g. 12'-6", 12' 6 1/2" or 3'
+var feetInches = regexp.MustCompile(`^(\d+)'(?:\s*-?\s*(\d+)(?:\s+(\d+)/(\d+))?")?$`)
+
+// lengthMillimeters returns a printed length of a drawing in units as millimeters: a number
+// in drawing units, or feet and inches. ok is false for any other text.
+func lengthMillimeters(value string, units drawingUnits) (float64, bool) {
+	value = strings.TrimSpace(value)
+	if n, err := strconv.ParseFloat(value, 64); err == nil {
+		return n * units.Millimeters, true
+	}
+	m := feetInches.FindStringSubmatch(value)
+	if m == nil {
+		return 0, false
+	}
+	feet, _ := strconv.Atoi(m[1])
+	inches := float64(feet) * 12
+	if m[2] != "" {
+		whole, _ := strconv.Atoi(m[2])
+		inches += float64(whole)
+	}
+	if m[3] != "" {
+		num, _ := strconv.Atoi(m[3])
+		den, _ := strconv.Atoi(m[4])
+		if den == 0 {
+			return 0, false
+		}
+		inches += float64(num) / float64(den)
+	}
+	return inches * 25.4, true
+}
+
+// convertCutLengths writes the CUT LENGTH cells of rows in millimeters for a drawing not in
+// millimeters, rounded to 0.1 mm; cells that are no length are left as printed
+func convertCutLengths(header []string, rows [][]string, units drawingUnits) {
+	if units.Millimeters == 1 {
+		return
+	}
+	column := -1
+	for i, name := range header {
+		if name == "CUT LENGTH" {
+			column = i
+		}
+	}
+	if column < 0 {
+		return
+	}
+	for _, row := range rows {
+		if column >= len(row) {
+			continue
+		}
+		if mm, ok := lengthMillimeters(row[column], units); ok {
+			row[column] = strconv.FormatFloat(math.Round(mm*10)/10, 'f', -1, 64)
+		}
+	}
+}
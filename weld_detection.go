@@ -0,0 +1,392 @@
+package dxfparser
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/jeffcall-ch/dxf_parser_go/internal/geometry"
+)
+
+// WeldConfig holds the tuning parameters of the weld symbol detection
+type WeldConfig struct {
+	LengthPairs       [][2]float64       // segment length pairs that form a weld cross
+	LayerPairs        []LayerLengthPairs // symbol sets of particular layers; other layers use LengthPairs
+	LengthTolerance   float64            // absolute tolerance when matching segment lengths
+	CenterTolerance   float64            // max distance of the crossing from a segment midpoint, as share of its length
+	GroupTolerance    float64            // CenterTolerance of segments in the same GROUP object, if larger
+	DuplicateDistance float64            // symbols closer than this are reported once
+	LabelRadius       float64            // search radius for a text label next to the symbol (0 disables)
+	SegmentEpsilon    float64            // segments whose endpoints match within this distance are drawn twice
+	Explain           bool               // set WeldSymbol.Explanation, why the pair was accepted (off by default, it allocates per symbol)
+}
+
+// LayerLengthPairs is the symbol set of the layers matching Layer, e.g. field welds drawn with
+// a bigger cross on "WELD_F". Both segments of a cross must be on layers of the same set.
+type LayerLengthPairs struct {
+	Layer       string       `yaml:"layer"` // glob pattern (* and ?), matched case-insensitively
+	LengthPairs [][2]float64 `yaml:"length_pairs"`
+}
+
+// matchesLayer reports whether layer matches the pattern of the set
+func (l LayerLengthPairs) matchesLayer(layer string) bool {
+	ok, _ := path.Match(strings.ToUpper(l.Layer), strings.ToUpper(layer))
+	return ok
+}
+
+// validate checks the layer pattern and that the set has length pairs
+func (l LayerLengthPairs) validate() error {
+	if _, err := path.Match(l.Layer, ""); err != nil || l.Layer == "" {
+		return fmt.Errorf("invalid weld layer pattern '%s'", l.Layer)
+	}
+	if len(l.LengthPairs) == 0 {
+		return fmt.Errorf("weld layer '%s' has no length_pairs", l.Layer)
+	}
+	return nil
+}
+
+// weldPairRule is one length pair of a symbol set; Set is the index into LayerPairs, -1 for
+// the LengthPairs of all other layers
+type weldPairRule struct {
+	Lengths [2]float64
+	Set     int
+}
+
+// WeldDetection is the result of ExtractWeldSymbolsDetailed
+type WeldDetection struct {
+	Symbols           []WeldSymbol
+	DuplicateSegments int // segments dropped because the same segment was drawn again
+}
+
+// DefaultWeldConfig returns the configuration used by the BOM extraction, the weld section of
+// the embedded default config
+func DefaultWeldConfig() WeldConfig {
+	w := builtinConfig.Weld
+	return WeldConfig{
+		LengthPairs:       append([][2]float64(nil), w.LengthPairs...),
+		LayerPairs:        append([]LayerLengthPairs(nil), w.LayerPairs...),
+		LengthTolerance:   *w.LengthTolerance,
+		CenterTolerance:   *w.CenterTolerance,
+		GroupTolerance:    *w.GroupTolerance,
+		DuplicateDistance: *w.DuplicateDistance,
+		LabelRadius:       *w.LabelRadius,
+		SegmentEpsilon:    *w.SegmentEpsilon,
+	}
+}
+
+// layerSet returns the index of the first LayerPairs set matching layer, -1 if there is none
+func (c WeldConfig) layerSet(layer string) int {
+	for i, set := range c.LayerPairs {
+		if set.matchesLayer(layer) {
+			return i
+		}
+	}
+	return -1
+}
+
+// pairRules returns the length pairs of all symbol sets, LengthPairs first
+func (c WeldConfig) pairRules() []weldPairRule {
+	var rules []weldPairRule
+	for set := -1; set < len(c.LayerPairs); set++ {
+		for _, pair := range c.setPairs(set) {
+			rules = append(rules, weldPairRule{Lengths: pair, Set: set})
+		}
+	}
+	return rules
+}
+
+// setPairs returns the length pairs of a symbol set (-1: LengthPairs)
+func (c WeldConfig) setPairs(set int) [][2]float64 {
+	if set < 0 {
+		return c.LengthPairs
+	}
+	return c.LayerPairs[set].LengthPairs
+}
+
+// IsTargetLength checks if a segment length belongs to any configured weld length pair of any layer
+func (c WeldConfig) IsTargetLength(length float64) bool {
+	for set := -1; set < len(c.LayerPairs); set++ {
+		for _, pair := range c.setPairs(set) {
+			if math.Abs(length-pair[0]) <= c.LengthTolerance || math.Abs(length-pair[1]) <= c.LengthTolerance {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchingPair returns the configured length pair that the two segments form, in either order.
+// Both segments must be on layers of the same symbol set.
+func (c WeldConfig) matchingPair(seg1, seg2 PolylineSegment) ([2]float64, bool) {
+	set := c.layerSet(seg1.Layer)
+	if c.layerSet(seg2.Layer) != set {
+		return [2]float64{}, false
+	}
+	len1, len2 := seg1.Length, seg2.Length
+	for _, pair := range c.setPairs(set) {
+		if (math.Abs(len1-pair[0]) <= c.LengthTolerance && math.Abs(len2-pair[1]) <= c.LengthTolerance) ||
+			(math.Abs(len1-pair[1]) <= c.LengthTolerance && math.Abs(len2-pair[0]) <= c.LengthTolerance) {
+			return pair, true
+		}
+	}
+	return [2]float64{}, false
+}
+
+// ExtractWeldSymbols finds weld symbols (two crossed polyline segments with a matching length
+// pair, crossing near both midpoints) in already parsed geometry. It does no I/O and no printing.
+// entities are only used to attach the nearest text label; nil is allowed.
+func ExtractWeldSymbols(entities []TextEntity, segments []PolylineSegment, config WeldConfig) []WeldSymbol {
+	return ExtractWeldSymbolsDetailed(entities, segments, config).Symbols
+}
+
+// ExtractWeldSymbolsDetailed is ExtractWeldSymbols that also reports how many duplicate
+// segments (e.g. a weld cross drawn twice by the CAD export) were dropped before pair matching
+func ExtractWeldSymbolsDetailed(entities []TextEntity, segments []PolylineSegment, config WeldConfig) WeldDetection {
+	segments, duplicates := dedupSegments(segments, config.SegmentEpsilon)
+	if duplicates > 0 {
+		debugPrint(fmt.Sprintf("[DEBUG] Dropped %d duplicate segments before weld pair matching", duplicates))
+	}
+
+	rules := config.pairRules()
+	var weldSymbols []WeldSymbol
+	for _, candidate := range candidatePairs(segments, config) {
+		if symbol, ok := weldSymbolFor(segments, candidate.I, candidate.J, rules[candidate.Pair].Lengths, config); ok {
+			weldSymbols = append(weldSymbols, symbol)
+		}
+	}
+	return WeldDetection{Symbols: finishWeldSymbols(weldSymbols, entities, config), DuplicateSegments: duplicates}
+}
+
+// extractWeldSymbolsPairwise is the exhaustive O(n²) reference implementation of
+// ExtractWeldSymbols, kept to verify and benchmark the bucketed geometry core
+func extractWeldSymbolsPairwise(entities []TextEntity, segments []PolylineSegment, config WeldConfig) []WeldSymbol {
+	segments, _ = dedupSegments(segments, config.SegmentEpsilon)
+
+	var weldSymbols []WeldSymbol
+	for i := 0; i < len(segments); i++ {
+		for j := i + 1; j < len(segments); j++ {
+			// Check if lengths match known weld symbol pairs
+			pair, ok := config.matchingPair(segments[i], segments[j])
+			if !ok {
+				continue
+			}
+			if symbol, ok := weldSymbolFor(segments, i, j, pair, config); ok {
+				weldSymbols = append(weldSymbols, symbol)
+			}
+		}
+	}
+	return finishWeldSymbols(weldSymbols, entities, config)
+}
+
+// centerTolerance returns the center tolerance of a pair of segments. Segments the drafter
+// grouped together are taken to be one symbol, so their crossing may be further off center.
+func (c WeldConfig) centerTolerance(seg1, seg2 PolylineSegment) float64 {
+	if seg1.Group != "" && seg1.Group == seg2.Group {
+		return math.Max(c.CenterTolerance, c.GroupTolerance)
+	}
+	return c.CenterTolerance
+}
+
+// weldSymbolFor checks if segments i < j with matching lengths cross near both midpoints.
+// Segments of the same GROUP object use the group tolerance and are reported with confidence 1.
+func weldSymbolFor(segments []PolylineSegment, i, j int, pair [2]float64, config WeldConfig) (WeldSymbol, bool) {
+	seg1 := segments[i]
+	seg2 := segments[j]
+	centerTolerance := config.centerTolerance(seg1, seg2)
+
+	// Check if segments intersect (crossed)
+	ix, iy, intersects := linesIntersect(seg1, seg2)
+	if !intersects {
+		return WeldSymbol{}, false
+	}
+
+	// Intersection should be close to the midpoint of both segments
+	mid1X, mid1Y := geometry.Midpoint(seg1.X1, seg1.Y1, seg1.X2, seg1.Y2)
+	mid2X, mid2Y := geometry.Midpoint(seg2.X1, seg2.Y1, seg2.X2, seg2.Y2)
+
+	distToMid1 := geometry.Distance(ix, iy, mid1X, mid1Y)
+	distToMid2 := geometry.Distance(ix, iy, mid2X, mid2Y)
+
+	tolerance1 := seg1.Length * centerTolerance
+	tolerance2 := seg2.Length * centerTolerance
+
+	if distToMid1 > tolerance1 || distToMid2 > tolerance2 {
+		return WeldSymbol{}, false
+	}
+
+	// Confidence is 1 for a perfect cross and drops towards the tolerance limit
+	maxTolerance := math.Max(tolerance1, tolerance2)
+	maxDistToMid := math.Max(distToMid1, distToMid2)
+	confidence := 1.0
+	if maxTolerance > 0 {
+		confidence -= maxDistToMid / maxTolerance
+	}
+	group := ""
+	if seg1.Group != "" && seg1.Group == seg2.Group {
+		group = seg1.Group
+		confidence = 1
+	}
+	explanation := ""
+	if config.Explain {
+		explanation = fmt.Sprintf("segments %d and %d cross at (%.3f, %.3f); lengths %.4f/%.4f match pair %.4f/%.4f; midpoint offsets %.3f/%.3f within %.3f/%.3f",
+			i, j, ix, iy, seg1.Length, seg2.Length, pair[0], pair[1], distToMid1, distToMid2, tolerance1, tolerance2)
+		if group != "" {
+			explanation += "; both in group " + group
+		}
+	}
+
+	return WeldSymbol{
+		CenterX:     ix,
+		CenterY:     iy,
+		CenterZ:     (seg1.Z1 + seg1.Z2 + seg2.Z1 + seg2.Z2) / 4,
+		Length1:     seg1.Length,
+		Length2:     seg2.Length,
+		Layer:       symbolLayer(seg1.Layer, seg2.Layer),
+		Group:       group,
+		Handles:     symbolHandles(seg1, seg2),
+		Confidence:  confidence,
+		Explanation: explanation,
+	}, true
+}
+
+// symbolHandles are the handles of the polylines of a symbol's segments, one if they are the
+// same, nil if neither is known
+func symbolHandles(seg1, seg2 PolylineSegment) []string {
+	var handles []string
+	for _, handle := range []string{seg1.Handle, seg2.Handle} {
+		if handle != "" && (len(handles) == 0 || handles[0] != handle) {
+			handles = append(handles, handle)
+		}
+	}
+	return handles
+}
+
+// symbolLayer is the layer of a symbol: the common layer of its segments, or both layers
+func symbolLayer(layer1, layer2 string) string {
+	if layer1 == layer2 {
+		return layer1
+	}
+	return layer1 + ", " + layer2
+}
+
+// finishWeldSymbols removes duplicate symbols and attaches text labels
+func finishWeldSymbols(weldSymbols []WeldSymbol, entities []TextEntity, config WeldConfig) []WeldSymbol {
+	weldSymbols = removeDuplicateSymbols(weldSymbols, config.DuplicateDistance)
+
+	if config.LabelRadius > 0 && len(entities) > 0 {
+		for k := range weldSymbols {
+			labelWeldSymbol(&weldSymbols[k], entities, config.LabelRadius)
+		}
+	}
+
+	return weldSymbols
+}
+
+// dedupSegments drops segments whose endpoints (in either direction) match an earlier segment
+// within epsilon, keeping the first occurrence and the original order
+func dedupSegments(segments []PolylineSegment, epsilon float64) ([]PolylineSegment, int) {
+	if len(segments) < 2 {
+		return segments, 0
+	}
+
+	// Sweep over the segments sorted by their smaller X; only neighbours within epsilon can match
+	order := make([]int, len(segments))
+	minX := make([]float64, len(segments))
+	for i, seg := range segments {
+		order[i] = i
+		minX[i] = math.Min(seg.X1, seg.X2)
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return minX[order[a]] < minX[order[b]]
+	})
+
+	near := func(a, b float64) bool { return math.Abs(a-b) <= epsilon }
+	same := func(s, t PolylineSegment) bool {
+		return (near(s.X1, t.X1) && near(s.Y1, t.Y1) && near(s.X2, t.X2) && near(s.Y2, t.Y2)) ||
+			(near(s.X1, t.X2) && near(s.Y1, t.Y2) && near(s.X2, t.X1) && near(s.Y2, t.Y1))
+	}
+
+	dropped := make([]bool, len(segments))
+	duplicates := 0
+	for a := 0; a < len(order); a++ {
+		i := order[a]
+		if dropped[i] {
+			continue
+		}
+		for b := a + 1; b < len(order) && minX[order[b]]-minX[i] <= epsilon; b++ {
+			j := order[b]
+			if dropped[j] || !same(segments[i], segments[j]) {
+				continue
+			}
+			// Keep the segment that came first in the drawing
+			if j < i {
+				dropped[i] = true
+				duplicates++
+				break
+			}
+			dropped[j] = true
+			duplicates++
+		}
+	}
+
+	if duplicates == 0 {
+		return segments, 0
+	}
+	unique := make([]PolylineSegment, 0, len(segments)-duplicates)
+	for i, seg := range segments {
+		if !dropped[i] {
+			unique = append(unique, seg)
+		}
+	}
+	return unique, duplicates
+}
+
+// labelWeldSymbol attaches the closest non-empty text entity within radius to the symbol; a
+// MULTILEADER also counts at the arrowhead of its leader
+func labelWeldSymbol(symbol *WeldSymbol, entities []TextEntity, radius float64) {
+	best := radius
+	for _, entity := range entities {
+		text := strings.TrimSpace(entity.Content)
+		if text == "" {
+			continue
+		}
+		d := geometry.Distance(symbol.CenterX, symbol.CenterY, entity.X, entity.Y)
+		if len(entity.Arrow) == 2 {
+			// A leader callout labels the point its arrow refers to
+			d = math.Min(d, geometry.Distance(symbol.CenterX, symbol.CenterY, entity.Arrow[0], entity.Arrow[1]))
+		}
+		if d <= best {
+			best = d
+			symbol.Label = text
+		}
+	}
+	if symbol.Label != "" && symbol.Explanation != "" {
+		symbol.Explanation += fmt.Sprintf("; label %q at distance %.3f", symbol.Label, best)
+	}
+}
+
+// removeDuplicateSymbols keeps the first of several weld symbols closer than threshold to each other
+func removeDuplicateSymbols(symbols []WeldSymbol, threshold float64) []WeldSymbol {
+	if len(symbols) <= 1 {
+		return symbols
+	}
+
+	var unique []WeldSymbol
+	for _, symbol := range symbols {
+		isDuplicate := false
+		for _, existing := range unique {
+			if geometry.Distance(symbol.CenterX, symbol.CenterY, existing.CenterX, existing.CenterY) < threshold {
+				isDuplicate = true
+				break
+			}
+		}
+
+		if !isDuplicate {
+			unique = append(unique, symbol)
+		}
+	}
+
+	return unique
+}
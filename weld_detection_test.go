@@ -0,0 +1,159 @@
+package dxfparser
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// weldCross returns a horizontal segment of length h and a vertical one of length v on layer,
+// both centered on (x, y) except that the vertical one is moved up by offset
+func weldCross(x, y, h, v, offset float64, layer string) []PolylineSegment {
+	return []PolylineSegment{
+		{X1: x - h/2, Y1: y, X2: x + h/2, Y2: y, Length: h, Layer: layer},
+		{X1: x, Y1: y + offset - v/2, X2: x, Y2: y + offset + v/2, Length: v, Layer: layer},
+	}
+}
+
+// grouped puts segments into the GROUP object name
+func grouped(name string, segments []PolylineSegment) []PolylineSegment {
+	for i := range segments {
+		segments[i].Group = name
+	}
+	return segments
+}
+
+// joined concatenates the segment lists of several crosses
+func joined(lists ...[]PolylineSegment) []PolylineSegment {
+	var segments []PolylineSegment
+	for _, list := range lists {
+		segments = append(segments, list...)
+	}
+	return segments
+}
+
+func TestExtractWeldSymbols(t *testing.T) {
+	config := DefaultWeldConfig()
+	config.LayerPairs = []LayerLengthPairs{{Layer: "WELD_*", LengthPairs: [][2]float64{{8, 12}}}}
+	pair, other := config.LengthPairs[0], config.LengthPairs[1]
+	// Largest offset of the vertical segment accepted by the center tolerance
+	maxOffset := pair[1] * config.CenterTolerance
+
+	type symbol struct {
+		x, y       float64
+		confidence float64
+		label      string
+		group      string
+	}
+	cases := []struct {
+		name     string
+		segments []PolylineSegment
+		entities []TextEntity
+		want     []symbol
+	}{
+		{"no segments", nil, nil, nil},
+		{"cross", weldCross(10, 10, pair[0], pair[1], 0, "0"), nil, []symbol{{10, 10, 1, "", ""}}},
+		{"each length pair", joined(
+			weldCross(0, 0, config.LengthPairs[0][0], config.LengthPairs[0][1], 0, "0"),
+			weldCross(20, 0, config.LengthPairs[1][0], config.LengthPairs[1][1], 0, "0"),
+			weldCross(40, 0, config.LengthPairs[2][0], config.LengthPairs[2][1], 0, "0"),
+		), nil, []symbol{{0, 0, 1, "", ""}, {20, 0, 1, "", ""}, {40, 0, 1, "", ""}}},
+		{"lengths swapped", weldCross(10, 10, pair[1], pair[0], 0, "0"), nil, []symbol{{10, 10, 1, "", ""}}},
+		{"lengths within tolerance", weldCross(10, 10, pair[0]+0.005, pair[1]-0.005, 0, "0"), nil, []symbol{{10, 10, 1, "", ""}}},
+		{"length beyond tolerance", weldCross(10, 10, pair[0]+0.02, pair[1], 0, "0"), nil, nil},
+		{"lengths of two pairs", weldCross(10, 10, pair[0], other[1], 0, "0"), nil, nil},
+		{"not crossing", weldCross(10, 10, pair[0], pair[1], pair[1], "0"), nil, nil},
+		{"parallel", []PolylineSegment{
+			{X1: 0, Y1: 0, X2: pair[0], Y2: 0, Length: pair[0], Layer: "0"},
+			{X1: 0, Y1: 1, X2: pair[1], Y2: 1, Length: pair[1], Layer: "0"},
+		}, nil, nil},
+		{"off center", weldCross(10, 10, pair[0], pair[1], 1, "0"), nil,
+			[]symbol{{10, 10, 1 - 1/maxOffset, "", ""}}},
+		{"beyond the center tolerance", weldCross(10, 10, pair[0], pair[1], maxOffset+0.1, "0"), nil, nil},
+		{"group beyond the center tolerance", grouped("*A1", weldCross(10, 10, pair[0], pair[1], maxOffset+0.1, "0")), nil,
+			[]symbol{{10, 10, 1, "", "*A1"}}},
+		{"different groups beyond the center tolerance", joined(
+			grouped("*A1", weldCross(10, 10, pair[0], pair[1], maxOffset+0.1, "0")[:1]),
+			grouped("*A2", weldCross(10, 10, pair[0], pair[1], maxOffset+0.1, "0")[1:]),
+		), nil, nil},
+		{"cross drawn twice", joined(
+			weldCross(10, 10, pair[0], pair[1], 0, "0"),
+			weldCross(10, 10, pair[0], pair[1], 0, "0"),
+		), nil, []symbol{{10, 10, 1, "", ""}}},
+		{"crosses closer than the duplicate distance", joined(
+			weldCross(10, 10, pair[0], pair[1], 0, "0"),
+			weldCross(10+config.DuplicateDistance/2, 10, pair[0], pair[1], 0, "0"),
+		), nil, []symbol{{10, 10, 1, "", ""}}},
+		{"crosses apart", joined(
+			weldCross(10, 10, pair[0], pair[1], 0, "0"),
+			weldCross(30, 10, pair[0], pair[1], 0, "0"),
+		), nil, []symbol{{10, 10, 1, "", ""}, {30, 10, 1, "", ""}}},
+		{"layer pairs", weldCross(10, 10, 8, 12, 0, "weld_shop"), nil, []symbol{{10, 10, 1, "", ""}}},
+		{"default pair on a layer with pairs", weldCross(10, 10, pair[0], pair[1], 0, "WELD_SHOP"), nil, nil},
+		{"layer pair on another layer", weldCross(10, 10, 8, 12, 0, "0"), nil, nil},
+		{"segments on layers of different sets", joined(
+			weldCross(10, 10, pair[0], pair[1], 0, "0")[:1],
+			weldCross(10, 10, pair[0], pair[1], 0, "WELD_SHOP")[1:],
+		), nil, nil},
+		{"nearest label", weldCross(10, 10, pair[0], pair[1], 0, "0"), []TextEntity{
+			{Content: "W2", X: 16, Y: 10},
+			{Content: " W1 ", X: 13, Y: 10},
+			{Content: "", X: 10, Y: 10},
+			{Content: "FAR", X: 10, Y: 10 + config.LabelRadius + 1},
+		}, []symbol{{10, 10, 1, "W1", ""}}},
+		{"label beyond the radius", weldCross(10, 10, pair[0], pair[1], 0, "0"), []TextEntity{
+			{Content: "W1", X: 10 + config.LabelRadius + 1, Y: 10},
+		}, []symbol{{10, 10, 1, "", ""}}},
+		{"leader label", weldCross(10, 10, pair[0], pair[1], 0, "0"), []TextEntity{
+			{Content: "W1", X: 10, Y: 10 + config.LabelRadius/2},
+			{Content: "W7", X: 60, Y: 60, Arrow: []float64{10.5, 10}, EntityType: "MULTILEADER"},
+		}, []symbol{{10, 10, 1, "W7", ""}}},
+	}
+
+	for name, extract := range map[string]func([]TextEntity, []PolylineSegment, WeldConfig) []WeldSymbol{
+		"ExtractWeldSymbols":         ExtractWeldSymbols,
+		"extractWeldSymbolsPairwise": extractWeldSymbolsPairwise,
+	} {
+		for _, c := range cases {
+			symbols := extract(c.entities, c.segments, config)
+			if len(symbols) != len(c.want) {
+				t.Errorf("%s: %s: %d symbols, want %d: %+v", name, c.name, len(symbols), len(c.want), symbols)
+				continue
+			}
+			for i, s := range symbols {
+				w := c.want[i]
+				if math.Abs(s.CenterX-w.x) > 1e-9 || math.Abs(s.CenterY-w.y) > 1e-9 {
+					t.Errorf("%s: %s: symbol %d at (%v, %v), want (%v, %v)", name, c.name, i, s.CenterX, s.CenterY, w.x, w.y)
+				}
+				if math.Abs(s.Confidence-w.confidence) > 1e-9 {
+					t.Errorf("%s: %s: symbol %d confidence = %v, want %v", name, c.name, i, s.Confidence, w.confidence)
+				}
+				if s.Label != w.label || s.Group != w.group {
+					t.Errorf("%s: %s: symbol %d label, group = %q, %q, want %q, %q", name, c.name, i, s.Label, s.Group, w.label, w.group)
+				}
+			}
+		}
+	}
+}
+
+func TestExtractWeldSymbolsExplain(t *testing.T) {
+	config := DefaultWeldConfig()
+	pair := config.LengthPairs[0]
+	segments := weldCross(10, 10, pair[0], pair[1], 0, "0")
+	labels := []TextEntity{{Content: "W1", X: 12, Y: 10}}
+
+	for _, explain := range []bool{false, true} {
+		config.Explain = explain
+		symbols := ExtractWeldSymbols(labels, segments, config)
+		if len(symbols) != 1 {
+			t.Fatalf("Explain %v: %d symbols, want 1", explain, len(symbols))
+		}
+		got := symbols[0].Explanation
+		if explain != (got != "") {
+			t.Errorf("Explain %v: Explanation = %q", explain, got)
+		}
+		if explain && !strings.HasSuffix(got, `; label "W1" at distance 2.000`) {
+			t.Errorf("Explain %v: Explanation = %q, want the label at the end", explain, got)
+		}
+	}
+}
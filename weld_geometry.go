@@ -0,0 +1,232 @@
+package dxfparser
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/jeffcall-ch/dxf_parser_go/internal/geometry"
+)
+
+// segmentSoA stores segments as flat coordinate slices (struct-of-arrays) so the pair
+// checks run over contiguous float64 data without per-pair allocations or struct copies
+type segmentSoA struct {
+	X1, Y1, X2, Y2 []float64
+	MX, MY         []float64 // midpoints
+	Len            []float64
+	Index          []int // index into the original segment slice
+}
+
+// newSegmentSoA copies the selected segments into flat slices
+func newSegmentSoA(segments []PolylineSegment, indices []int) segmentSoA {
+	n := len(indices)
+	soa := segmentSoA{
+		X1: make([]float64, n), Y1: make([]float64, n),
+		X2: make([]float64, n), Y2: make([]float64, n),
+		MX: make([]float64, n), MY: make([]float64, n),
+		Len:   make([]float64, n),
+		Index: make([]int, n),
+	}
+	for k, i := range indices {
+		seg := segments[i]
+		soa.X1[k], soa.Y1[k], soa.X2[k], soa.Y2[k] = seg.X1, seg.Y1, seg.X2, seg.Y2
+		soa.MX[k], soa.MY[k] = geometry.Midpoint(seg.X1, seg.Y1, seg.X2, seg.Y2)
+		soa.Len[k] = seg.Length
+		soa.Index[k] = i
+	}
+	return soa
+}
+
+// segmentPair is a candidate weld cross: segments I < J matching length pair Pair
+type segmentPair struct {
+	I, J, Pair int
+}
+
+// crossingPairs returns every pair of a segment in a and a segment in b that intersects with
+// the crossing point close enough to both midpoints to be a weld symbol candidate.
+// The midpoint test is done first: if the crossing is within centerTolerance*L of both
+// midpoints, the midpoints are at most centerTolerance*(L1+L2) apart, which rejects most
+// pairs with three subtractions and a compare before any intersection math.
+func crossingPairs(a, b *segmentSoA, pair int, centerTolerance float64, out []segmentPair) []segmentPair {
+	for p := range a.X1 {
+		amx, amy, alen := a.MX[p], a.MY[p], a.Len[p]
+
+		for q := range b.X1 {
+			reach := centerTolerance*(alen+b.Len[q]) + 1e-9
+			dmx := amx - b.MX[q]
+			dmy := amy - b.MY[q]
+			if dmx*dmx+dmy*dmy > reach*reach {
+				continue
+			}
+
+			i, j := a.Index[p], b.Index[q]
+			if i == j {
+				continue
+			}
+
+			// Same operand order as linesIntersect(segments[i], segments[j]) with i < j,
+			// so boundary cases are decided exactly like the pairwise scan
+			x1, y1, x2, y2 := a.X1[p], a.Y1[p], a.X2[p], a.Y2[p]
+			x3, y3, x4, y4 := b.X1[q], b.Y1[q], b.X2[q], b.Y2[q]
+			if i > j {
+				i, j = j, i
+				x1, y1, x2, y2, x3, y3, x4, y4 = x3, y3, x4, y4, x1, y1, x2, y2
+			}
+			if !geometry.Crosses(x1, y1, x2, y2, x3, y3, x4, y4) {
+				continue
+			}
+
+			out = append(out, segmentPair{I: i, J: j, Pair: pair})
+		}
+	}
+	return out
+}
+
+// candidatePairs buckets the segments by configured length pair and returns the crossing
+// candidates in the same (i, j) order as an exhaustive pairwise scan, each pair once with
+// the first matching length pair. Pair indexes config.pairRules(); a rule of a layer set only
+// takes segments on layers of that set.
+func candidatePairs(segments []PolylineSegment, config WeldConfig) []segmentPair {
+	sets := make([]int, len(segments))
+	for i, seg := range segments {
+		sets[i] = config.layerSet(seg.Layer)
+	}
+
+	// Grouped segments may cross further off center; widen the prefilter for them
+	tolerance := config.CenterTolerance
+	for _, seg := range segments {
+		if seg.Group != "" {
+			tolerance = math.Max(tolerance, config.GroupTolerance)
+			break
+		}
+	}
+
+	var pairs []segmentPair
+	for k, rule := range config.pairRules() {
+		lengths := rule.Lengths
+		var first, second []int
+		for i, seg := range segments {
+			if sets[i] != rule.Set {
+				continue
+			}
+			if math.Abs(seg.Length-lengths[0]) <= config.LengthTolerance {
+				first = append(first, i)
+			}
+			if math.Abs(seg.Length-lengths[1]) <= config.LengthTolerance {
+				second = append(second, i)
+			}
+		}
+		if len(first) == 0 || len(second) == 0 {
+			continue
+		}
+		a := newSegmentSoA(segments, first)
+		b := newSegmentSoA(segments, second)
+		pairs = crossingPairs(&a, &b, k, tolerance, pairs)
+	}
+
+	sort.Slice(pairs, func(x, y int) bool {
+		if pairs[x].I != pairs[y].I {
+			return pairs[x].I < pairs[y].I
+		}
+		if pairs[x].J != pairs[y].J {
+			return pairs[x].J < pairs[y].J
+		}
+		return pairs[x].Pair < pairs[y].Pair
+	})
+
+	// Drop duplicates (a pair found from both buckets or for several length pairs)
+	unique := pairs[:0]
+	for _, pair := range pairs {
+		if len(unique) > 0 && pair.I == unique[len(unique)-1].I && pair.J == unique[len(unique)-1].J {
+			continue
+		}
+		unique = append(unique, pair)
+	}
+	return unique
+}
+
+// syntheticWeldSegments builds a dense drawing: symbols weld crosses plus noise segments with
+// weld symbol lengths at random positions and angles, in random order (fixed seed)
+func syntheticWeldSegments(symbols, noise int, config WeldConfig) []PolylineSegment {
+	rng := rand.New(rand.NewSource(42))
+	extent := math.Sqrt(float64(symbols+noise)) * 20
+
+	var segments []PolylineSegment
+	add := func(cx, cy, angle, length float64) {
+		dx, dy := math.Cos(angle)*length/2, math.Sin(angle)*length/2
+		segments = append(segments, PolylineSegment{X1: cx - dx, Y1: cy - dy, X2: cx + dx, Y2: cy + dy, Length: length, Layer: "0"})
+	}
+
+	for s := 0; s < symbols; s++ {
+		pair := config.LengthPairs[rng.Intn(len(config.LengthPairs))]
+		cx, cy, angle := rng.Float64()*extent, rng.Float64()*extent, rng.Float64()*math.Pi
+		add(cx, cy, angle, pair[0])
+		add(cx, cy, angle+math.Pi/2, pair[1])
+	}
+	for n := 0; n < noise; n++ {
+		pair := config.LengthPairs[rng.Intn(len(config.LengthPairs))]
+		add(rng.Float64()*extent, rng.Float64()*extent, rng.Float64()*math.Pi, pair[rng.Intn(2)])
+	}
+
+	rng.Shuffle(len(segments), func(i, j int) { segments[i], segments[j] = segments[j], segments[i] })
+	return segments
+}
+
+// handleWeldBenchmark implements "benchmark welds": it times the pair-checking stage of the
+// pairwise reference and the bucketed struct-of-arrays core on a synthetic dense drawing
+// and verifies that both find the same symbols
+func handleWeldBenchmark(args []string) {
+	fs := newCommandFlagSet("benchmark welds", "dxf_parser benchmark welds [-symbols 400] [-noise 4000] [-iterations 5]")
+	symbols := fs.Int("symbols", 400, "Number of weld crosses in the synthetic drawing")
+	noise := fs.Int("noise", 4000, "Number of additional segments with weld symbol lengths")
+	iterations := fs.Int("iterations", 5, "Runs per implementation")
+	checkArgCount(fs, parseCommandArgs(fs, args), 0, 0, "")
+	if *symbols < 0 || *noise < 0 || *symbols+*noise == 0 || *iterations <= 0 {
+		usageError(fs, "Error: -symbols and -noise must not be negative (and not both 0), -iterations must be positive")
+	}
+
+	config := DefaultWeldConfig()
+	config.LabelRadius = 0
+	segments := syntheticWeldSegments(*symbols, *noise, config)
+
+	fmt.Printf("Weld pair-checking benchmark: %d segments (%d symbols, %d noise)\n", len(segments), *symbols, *noise)
+	fmt.Println("=====================================")
+
+	measure := func(name string, extract func([]TextEntity, []PolylineSegment, WeldConfig) []WeldSymbol) (time.Duration, []WeldSymbol) {
+		var total time.Duration
+		var found []WeldSymbol
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		for i := 0; i < *iterations; i++ {
+			start := time.Now()
+			found = extract(nil, segments, config)
+			total += time.Since(start)
+		}
+		runtime.ReadMemStats(&after)
+		avg := total / time.Duration(*iterations)
+		fmt.Printf("  %-10s %12v/op %10d allocs/op  %d symbols\n", name, avg,
+			(after.Mallocs-before.Mallocs)/uint64(*iterations), len(found))
+		return avg, found
+	}
+
+	pairwiseTime, pairwise := measure("pairwise", extractWeldSymbolsPairwise)
+	coreTime, core := measure("soa-core", ExtractWeldSymbols)
+
+	fmt.Printf("  Speedup: %.2fx\n", float64(pairwiseTime)/float64(coreTime))
+
+	if len(pairwise) != len(core) {
+		fmt.Printf("MISMATCH: pairwise found %d symbols, core found %d\n", len(pairwise), len(core))
+		os.Exit(1)
+	}
+	for i := range pairwise {
+		if pairwise[i].CenterX != core[i].CenterX || pairwise[i].CenterY != core[i].CenterY {
+			fmt.Printf("MISMATCH at symbol %d: %+v vs %+v\n", i, pairwise[i], core[i])
+			os.Exit(1)
+		}
+	}
+	fmt.Println("  Results identical")
+}
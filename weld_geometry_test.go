@@ -0,0 +1,40 @@
+package dxfparser
+
+import "testing"
+
+// benchmarkPairCheck runs extract on the dense drawing of "benchmark welds" with its defaults
+func benchmarkPairCheck(b *testing.B, extract func([]TextEntity, []PolylineSegment, WeldConfig) []WeldSymbol) {
+	config := DefaultWeldConfig()
+	config.LabelRadius = 0
+	segments := syntheticWeldSegments(400, 4000, config)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		extract(nil, segments, config)
+	}
+}
+
+func BenchmarkPairCheckPairwise(b *testing.B) {
+	benchmarkPairCheck(b, extractWeldSymbolsPairwise)
+}
+
+func BenchmarkPairCheckBucketed(b *testing.B) {
+	benchmarkPairCheck(b, ExtractWeldSymbols)
+}
+
+// The bucketed pair check allocates per length pair and for its result slices, not per pair
+// of segments or per symbol
+func TestPairCheckAllocations(t *testing.T) {
+	config := DefaultWeldConfig()
+	config.LabelRadius = 0
+	segments := syntheticWeldSegments(400, 4000, config)
+
+	symbols := len(ExtractWeldSymbols(nil, segments, config))
+	allocs := testing.AllocsPerRun(3, func() {
+		ExtractWeldSymbols(nil, segments, config)
+	})
+	if allocs > float64(symbols)/2 {
+		t.Errorf("%.0f allocations for %d symbols, want at most %d", allocs, symbols, symbols/2)
+	}
+}
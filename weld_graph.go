@@ -0,0 +1,262 @@
+package dxfparser
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jeffcall-ch/dxf_parser_go/internal/geometry"
+)
+
+// Kinds of weld graph nodes
+const (
+	graphNodePiece   = "piece"
+	graphNodeFitting = "fitting"
+)
+
+// GraphNode is a cut piece or a fitting of a drawing. A piece is one node however many piece
+// number callouts mark it; a fitting is one node per item callout, as every callout of a PT NO
+// marks another instance (four elbows of PT NO 3 have four callouts).
+type GraphNode struct {
+	ID          string       `json:"id"`   // "piece:<1>" or "fitting:3#2", the second callout of PT NO 3
+	Kind        string       `json:"kind"` // "piece" or "fitting"
+	Label       string       `json:"label"`
+	Description string       `json:"description,omitempty"`
+	Size        string       `json:"size,omitempty"`   // N.S.
+	Length      string       `json:"length,omitempty"` // cut length of a piece
+	Category    string       `json:"category,omitempty"`
+	Callouts    [][2]float64 `json:"callouts"` // positions of the callouts marking it
+}
+
+// GraphEdge is a weld joining two nodes
+type GraphEdge struct {
+	ID     string     `json:"id"` // "weld:1", in the order of the detected symbols
+	Source string     `json:"source"`
+	Target string     `json:"target"`
+	Weld   WeldSymbol `json:"weld"`
+}
+
+// WeldGraph is the piece-to-weld adjacency graph of a drawing: which cut pieces and fittings
+// each detected weld joins. Welds without two nodes within the graph radius are open.
+type WeldGraph struct {
+	FilePath  string       `json:"file_path"`
+	DrawingNo string       `json:"drawing_no"`
+	Nodes     []GraphNode  `json:"nodes"`
+	Edges     []GraphEdge  `json:"edges"`
+	OpenWelds []WeldSymbol `json:"open_welds,omitempty"`
+}
+
+// graphNodes returns the nodes of a drawing from its extracted tables: a piece per cut length
+// row with a callout on the drawing, and a fitting per item callout of a material row outside
+// the PIPE category. cutRows are in single-row format. The texts of a table reconstructed from
+// text (textTable) are never item callouts; a table read from structured data has no such texts.
+func graphNodes(matHeader []string, matRows, cutRows [][]string, entities []TextEntity, textTable bool) []GraphNode {
+	var nodes []GraphNode
+	pieces := make(map[string]int)
+	for _, callout := range pieceCallouts(cutRows, entities) {
+		number := strings.TrimSpace(callout.Row[0])
+		i, ok := pieces[number]
+		if !ok {
+			i = len(nodes)
+			pieces[number] = i
+			node := GraphNode{ID: graphNodePiece + ":" + number, Kind: graphNodePiece, Label: number}
+			node.Length = strings.TrimSpace(callout.Row[1])
+			if len(callout.Row) > 2 {
+				node.Size = strings.TrimSpace(callout.Row[2])
+			}
+			if len(callout.Row) > 4 {
+				node.Description = strings.TrimSpace(callout.Row[4])
+			}
+			nodes = append(nodes, node)
+		}
+		nodes[i].Callouts = append(nodes[i].Callouts, [2]float64{callout.X, callout.Y})
+	}
+
+	column := func(name string) int {
+		for i, header := range matHeader {
+			if strings.EqualFold(strings.TrimSpace(header), name) {
+				return i
+			}
+		}
+		return -1
+	}
+	categoryIdx, descriptionIdx, sizeIdx := column("CATEGORY"), 1, 2
+	if categoryIdx < 0 {
+		return nodes
+	}
+	tableCells := make(map[[2]float64]bool)
+	if textTable {
+		tableCells = tableCellPoints(buildProvenance("ERECTION MATERIALS", matHeader, matRows, entities, "", ""))
+	}
+	for _, row := range matRows {
+		if len(row) <= categoryIdx || strings.EqualFold(strings.TrimSpace(row[categoryIdx]), "PIPE") {
+			continue
+		}
+		ptNo := strings.TrimSpace(row[0])
+		if ptNo == "" {
+			continue
+		}
+		instance := 0
+		for _, entity := range entities {
+			if strings.TrimSpace(entity.Content) != ptNo || tableCells[[2]float64{entity.X, entity.Y}] {
+				continue
+			}
+			instance++
+			nodes = append(nodes, GraphNode{
+				ID:          fmt.Sprintf("%s:%s#%d", graphNodeFitting, ptNo, instance),
+				Kind:        graphNodeFitting,
+				Label:       ptNo,
+				Description: strings.TrimSpace(row[descriptionIdx]),
+				Size:        strings.TrimSpace(row[sizeIdx]),
+				Category:    strings.TrimSpace(row[categoryIdx]),
+				Callouts:    [][2]float64{{entity.X, entity.Y}},
+			})
+		}
+	}
+	return nodes
+}
+
+// BuildWeldGraph joins nodes by welds: every weld becomes an edge between the two nodes with a
+// callout nearest to its center, both within radius. Welds with fewer nodes in reach are open.
+func BuildWeldGraph(nodes []GraphNode, welds []WeldSymbol, radius float64) WeldGraph {
+	graph := WeldGraph{Nodes: nodes, Edges: []GraphEdge{}}
+	if graph.Nodes == nil {
+		graph.Nodes = []GraphNode{}
+	}
+	for i, weld := range welds {
+		type reach struct {
+			node     int
+			distance float64
+		}
+		var near []reach
+		for n, node := range nodes {
+			best := math.Inf(1)
+			for _, callout := range node.Callouts {
+				best = math.Min(best, geometry.Distance(weld.CenterX, weld.CenterY, callout[0], callout[1]))
+			}
+			if best <= radius {
+				near = append(near, reach{n, best})
+			}
+		}
+		if len(near) < 2 {
+			graph.OpenWelds = append(graph.OpenWelds, weld)
+			continue
+		}
+		sort.SliceStable(near, func(a, b int) bool { return near[a].distance < near[b].distance })
+		graph.Edges = append(graph.Edges, GraphEdge{
+			ID:     "weld:" + strconv.Itoa(i+1),
+			Source: nodes[near[0].node].ID,
+			Target: nodes[near[1].node].ID,
+			Weld:   weld,
+		})
+	}
+	return graph
+}
+
+// writeWeldGraph writes the graphs of the drawings sorted by file path, as GraphML for a
+// .graphml file name and as a JSON array otherwise
+func writeWeldGraph(out *runOutputs, filename string, results []WeldResult) error {
+	var graphs []WeldGraph
+	for _, result := range results {
+		if result.Graph != nil {
+			graphs = append(graphs, *result.Graph)
+		}
+	}
+	sort.Slice(graphs, func(i, j int) bool {
+		return graphs[i].FilePath < graphs[j].FilePath
+	})
+
+	var data []byte
+	if strings.EqualFold(filepath.Ext(filename), ".graphml") {
+		data = weldGraphML(graphs)
+	} else {
+		var err error
+		if data, err = json.MarshalIndent(graphs, "", "  "); err != nil {
+			return err
+		}
+	}
+	if err := out.writeFile(filename, data); err != nil {
+		return fmt.Errorf("error writing weld graph: %v", err)
+	}
+	fmt.Printf("Wrote WELD GRAPH to: %s (%d drawings)\n", filename, len(graphs))
+	return nil
+}
+
+// weldGraphML returns the graphs as a GraphML document with one undirected graph per drawing.
+// Node and edge ids are prefixed with the graph id, as GraphML ids are unique per document.
+func weldGraphML(graphs []WeldGraph) []byte {
+	var b strings.Builder
+	escape := func(s string) string {
+		var e strings.Builder
+		xml.EscapeText(&e, []byte(s))
+		return e.String()
+	}
+	data := func(key, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, `      <data key="%s">%s</data>`+"\n", key, escape(value))
+		}
+	}
+	number := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">` + "\n")
+	for _, key := range []struct{ id, domain, name, kind string }{
+		{"drawing", "graph", "drawing_no", "string"},
+		{"file", "graph", "file_path", "string"},
+		{"kind", "node", "kind", "string"},
+		{"label", "node", "label", "string"},
+		{"description", "node", "description", "string"},
+		{"size", "node", "size", "string"},
+		{"length", "node", "length", "string"},
+		{"category", "node", "category", "string"},
+		{"x", "node", "x", "double"},
+		{"y", "node", "y", "double"},
+		{"weld_x", "edge", "x", "double"},
+		{"weld_y", "edge", "y", "double"},
+		{"weld_size", "edge", "size", "string"},
+		{"weld_label", "edge", "label", "string"},
+		{"confidence", "edge", "confidence", "double"},
+	} {
+		fmt.Fprintf(&b, `  <key id="%s" for="%s" attr.name="%s" attr.type="%s"/>`+"\n", key.id, key.domain, key.name, key.kind)
+	}
+	for g, graph := range graphs {
+		prefix := "g" + strconv.Itoa(g+1) + "/"
+		fmt.Fprintf(&b, `  <graph id="g%d" edgedefault="undirected">`+"\n", g+1)
+		data("drawing", graph.DrawingNo)
+		data("file", graph.FilePath)
+		for _, node := range graph.Nodes {
+			fmt.Fprintf(&b, `    <node id="%s">`+"\n", escape(prefix+node.ID))
+			data("kind", node.Kind)
+			data("label", node.Label)
+			data("description", node.Description)
+			data("size", node.Size)
+			data("length", node.Length)
+			data("category", node.Category)
+			if len(node.Callouts) > 0 {
+				data("x", number(node.Callouts[0][0]))
+				data("y", number(node.Callouts[0][1]))
+			}
+			b.WriteString("    </node>\n")
+		}
+		for _, edge := range graph.Edges {
+			fmt.Fprintf(&b, `    <edge id="%s" source="%s" target="%s">`+"\n",
+				escape(prefix+edge.ID), escape(prefix+edge.Source), escape(prefix+edge.Target))
+			data("weld_x", number(edge.Weld.CenterX))
+			data("weld_y", number(edge.Weld.CenterY))
+			data("weld_size", edge.Weld.Size)
+			data("weld_label", edge.Weld.Label)
+			data("confidence", number(edge.Weld.Confidence))
+			b.WriteString("    </edge>\n")
+		}
+		b.WriteString("  </graph>\n")
+	}
+	b.WriteString("</graphml>\n")
+	return []byte(b.String())
+}
@@ -1,67 +1,72 @@
-package main
+package dxfparser
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"math"
-	"os"
-	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
-)
 
-// Global weld symbol configuration (from original weld_detector.go)
-var weldLengthPairs = [][2]float64{
-	{4.0311, 6.9462},
-	{6.8964, 3.9446},
-	{6.9000, 4.0000},
-}
+	"github.com/jeffcall-ch/dxf_parser_go/internal/geometry"
+)
 
-var targetLengths = []float64{4.0311, 6.9462, 6.8964, 3.9446, 6.9000, 4.0000}
+// minSegmentLength is the length below which a segment is degenerate (duplicate vertices)
+const minSegmentLength = 1e-9
 
-// distance calculates the distance between two points
-func distance(x1, y1, x2, y2 float64) float64 {
-	dx := x2 - x1
-	dy := y2 - y1
-	return math.Sqrt(dx*dx + dy*dy)
-}
-
-// isTargetLength checks if a length matches any target length (with tolerance)
-func isTargetLength(length float64) bool {
-	tolerance := 0.01
-	for _, target := range targetLengths {
-		if math.Abs(length-target) <= tolerance {
+// isDegenerateSegment reports whether a segment has no direction or non-finite coordinates.
+// Such segments have no meaningful length or intersection and are dropped at parse time.
+func isDegenerateSegment(seg PolylineSegment) bool {
+	for _, v := range []float64{seg.X1, seg.Y1, seg.X2, seg.Y2} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
 			return true
 		}
 	}
-	return false
+	return !(seg.Length >= minSegmentLength) || math.IsInf(seg.Length, 0)
 }
 
-// FileCache stores parsed data for reuse in weld detection
+// FileCache stores parsed data for reuse in weld detection.
+//
+// Memory model: the file is read once; its polylines are parsed right away and only the
+// segments with weld symbol target lengths are kept, so the raw file content can be freed
+// before the next file is read. A cached file costs its text entities plus a few hundred
+// bytes per candidate segment instead of the full file size.
 type FileCache struct {
 	TextEntities []TextEntity
-	RawContent   []byte
+	Segments     []PolylineSegment // polyline segments already filtered by weldConfig.IsTargetLength
+	SegmentError string            // set if the polylines could not be parsed
 	FilePath     string
 	FileName     string
 	DrawingNo    string
 	PipeClass    string
+	SizeCallouts []sizedCallout // cut piece callouts with their N.S., for weld sizes
+	PipeSizes    []string       // distinct N.S. of the cut pieces
+	GraphNodes   []GraphNode    // cut pieces and fittings, with the weld graph enabled
 }
 
 // WeldResult represents the result of weld detection for a single file
 type WeldResult struct {
-	FilePath           string  `json:"file_path"`
-	FileName           string  `json:"file_name"`
-	DrawingNo          string  `json:"drawing_no"`
-	PipeClass          string  `json:"pipe_class"`
-	PipeNS             string  `json:"pipe_ns"`
-	PipeDescription    string  `json:"pipe_description"`
-	MultiplePipeNS     string  `json:"multiple_pipe_ns"`
-	WeldCount          int     `json:"weld_count"`
-	ProcessingTime     float64 `json:"processing_time"`
-	Error              string  `json:"error"`
+	FilePath          string       `json:"file_path"`
+	FileName          string       `json:"file_name"`
+	DrawingNo         string       `json:"drawing_no"`
+	PipeClass         string       `json:"pipe_class"`
+	PipeNS            string       `json:"pipe_ns"`
+	PipeDescription   string       `json:"pipe_description"`
+	MultiplePipeNS    string       `json:"multiple_pipe_ns"`
+	WeldCount         int          `json:"weld_count"`
+	DuplicateSegments int          `json:"duplicate_segments"`
+	ProcessingTime    float64      `json:"processing_time"`
+	Error             string       `json:"error"`
+	Source            string       `json:"source"`
+	Welds             []WeldSymbol `json:"welds,omitempty"`      // only with weld details enabled
+	PipeWelds         []PipeWelds  `json:"pipe_welds,omitempty"` // welds per pipe, unless the pipe policy is "all"
+	WeldsBySize       []SizeCount  `json:"welds_by_size,omitempty"`
+	Graph             *WeldGraph   `json:"-"` // only with the weld graph enabled
 }
 
 // WorkerContext holds per-worker cache and results
@@ -75,16 +80,27 @@ type WorkerContext struct {
 // PolylineSegment represents a line segment from polyline parsing
 type PolylineSegment struct {
 	X1, Y1, X2, Y2 float64
+	Z1, Z2         float64 // elevations of the end points, 0 in 2D drawings; Length is measured in plan
 	Length         float64
 	Layer          string
+	Group          string // name of the GROUP object the polyline belongs to, "" if none
+	Handle         string // handle of the polyline (5), of the outermost INSERT for block content
 }
 
 // WeldSymbol represents a detected weld symbol
 type WeldSymbol struct {
-	CenterX, CenterY float64
-	Length1, Length2 float64
-	Layer            string
-	Confidence       float64
+	CenterX     float64  `json:"center_x"`
+	CenterY     float64  `json:"center_y"`
+	CenterZ     float64  `json:"center_z,omitempty"` // mean elevation of the segment midpoints
+	Length1     float64  `json:"length1"`
+	Length2     float64  `json:"length2"`
+	Layer       string   `json:"layer"`
+	Group       string   `json:"group,omitempty"`   // GROUP object holding both segments, if any
+	Handles     []string `json:"handles,omitempty"` // handles of the polylines of the segments
+	Confidence  float64  `json:"confidence"`
+	Label       string   `json:"label,omitempty"`       // nearest text entity within WeldConfig.LabelRadius, if any
+	Size        string   `json:"size,omitempty"`        // N.S. of the nearest cut piece callout, see attributeWeldSizes
+	Explanation string   `json:"explanation,omitempty"` // why the segment pair was accepted as a weld symbol
 }
 
 // Performance constants
@@ -105,26 +121,26 @@ func createWorkerCaches(numWorkers int) []map[string]FileCache {
 // mergeWorkerCaches combines all worker caches into a single cache
 func mergeWorkerCaches(workerCaches []map[string]FileCache) map[string]FileCache {
 	globalCache := make(map[string]FileCache)
-	
+
 	for _, workerCache := range workerCaches {
 		for filePath, fileCache := range workerCache {
 			globalCache[filePath] = fileCache
 		}
 	}
-	
+
 	return globalCache
 }
 
 // estimateMemoryUsage calculates approximate memory usage of cache
 func estimateMemoryUsage(cache map[string]FileCache) int64 {
 	var totalBytes int64
-	
+
 	for _, fileCache := range cache {
-		// Rough estimate: text entities + raw content
-		totalBytes += int64(len(fileCache.RawContent))
+		// Rough estimate: text entities + filtered segments
+		totalBytes += int64(len(fileCache.Segments) * 64)      // 5 float64 + layer string header
 		totalBytes += int64(len(fileCache.TextEntities) * 200) // rough estimate per TextEntity
 	}
-	
+
 	return totalBytes / (1024 * 1024) // Convert to MB
 }
 
@@ -135,70 +151,87 @@ func cleanupFileCache(cache map[string]FileCache) {
 	}
 }
 
-// extractPipeInfoFromEntities extracts pipe N.S., descriptions, and multiple pipes flag
-func extractPipeInfoFromEntities(textEntities []TextEntity) (string, string, string) {
+// extractPipeInfoFromEntities extracts pipe N.S., descriptions, and multiple pipes flag.
+// N.S. and descriptions are those of the pipes selected by policy; the selected pipes are
+// also returned for weld attribution (nil for PipePolicyAll, which keeps every pipe unweighted).
+func extractPipeInfoFromEntities(textEntities []TextEntity, policy PipePolicy) (string, string, string, []PipeRow) {
 	// Look for BOM data that contains pipe information
 	matHeader, matRows := extractTable(textEntities, "ERECTION MATERIALS")
-	
+
 	if len(matRows) == 0 {
-		return "", "", ""
+		return "", "", "", nil
 	}
-	
-	// Find N.S. and Description column indices
+
+	// Find N.S., Description and QTY column indices
 	nsIndex := -1
 	descIndex := -1
-	
+	qtyIndex := -1
+
 	for i, header := range matHeader {
+		if strings.TrimSpace(strings.ToUpper(header)) == "QTY" {
+			qtyIndex = i
+		}
 		if strings.Contains(strings.ToUpper(header), "N.S.") {
 			nsIndex = i
 		}
-		if strings.Contains(strings.ToUpper(header), "DESCRIPTION") || 
-		   strings.Contains(strings.ToUpper(header), "COMPONENT") {
+		if strings.Contains(strings.ToUpper(header), "DESCRIPTION") ||
+			strings.Contains(strings.ToUpper(header), "COMPONENT") {
 			descIndex = i
 		}
 	}
-	
+
 	if nsIndex == -1 || descIndex == -1 {
-		return "", "", ""
+		return "", "", "", nil
 	}
-	
+
 	// Extract pipe rows data
-	var pipeRows []map[string]string
-	nsSet := make(map[string]bool)
-	descSet := make(map[string]bool)
-	
+	var pipeRows []PipeRow
+
 	for _, row := range matRows {
 		if len(row) > nsIndex && len(row) > descIndex {
 			description := strings.TrimSpace(row[descIndex])
-			
+
 			// Check if this row is a pipe row (contains "Pipe" in description)
 			if strings.Contains(strings.ToUpper(description), "PIPE") {
 				ns := strings.TrimSpace(row[nsIndex])
 				if ns != "" && !strings.Contains(ns, "N.S.") {
 					// Store pipe row info
-					pipeRows = append(pipeRows, map[string]string{
-						"ns":   ns,
-						"desc": description,
-					})
-					
-					// Handle multi-size NS like "40 x 25" - split and add both
-					parts := strings.Split(ns, "x")
-					for _, part := range parts {
-						cleanNS := strings.TrimSpace(part)
-						if cleanNS != "" {
-							nsSet[cleanNS] = true
-						}
-					}
-					
-					// Add description
-					if description != "" {
-						descSet[description] = true
+					pipe := PipeRow{Description: description, NS: ns}
+					if qtyIndex >= 0 && qtyIndex < len(row) {
+						pipe.Qty = parseQuantity(row[qtyIndex])
 					}
+					pipeRows = append(pipeRows, pipe)
 				}
 			}
 		}
 	}
-	
+
+	var selected []PipeRow
+	if policy != PipePolicyAll {
+		selected = selectPipes(pipeRows, policy)
+	}
+	nsSet := make(map[string]bool)
+	descSet := make(map[string]bool)
+	for _, pipe := range pipeRows {
+		if selected != nil && !containsPipe(selected, pipe) {
+			continue
+		}
+
+		// Handle multi-size NS like "40 x 25" - split and add both
+		parts := strings.Split(pipe.NS, "x")
+		for _, part := range parts {
+			cleanNS := strings.TrimSpace(part)
+			if cleanNS != "" {
+				nsSet[cleanNS] = true
+			}
+		}
+
+		// Add description
+		if pipe.Description != "" {
+			descSet[pipe.Description] = true
+		}
+	}
+
 	// Convert NS to sorted slice
 	var nsValues []string
 	for ns := range nsSet {
@@ -214,36 +247,64 @@ func extractPipeInfoFromEntities(textEntities []TextEntity) (string, string, str
 		// Fall back to string comparison
 		return nsValues[i] < nsValues[j]
 	})
-	
+
 	// Convert descriptions to sorted slice
 	var descValues []string
 	for desc := range descSet {
 		descValues = append(descValues, desc)
 	}
 	sort.Strings(descValues)
-	
+
 	// Determine multiple pipes flag
 	multiplePipes := ""
 	if len(pipeRows) > 1 {
 		multiplePipes = "Yes"
 	}
-	
+
 	// Join results
 	pipeNS := strings.Join(nsValues, ", ")
 	pipeDescription := strings.Join(descValues, ", ")
-	
-	return pipeNS, pipeDescription, multiplePipes
+	if selected != nil {
+		pipeDescription = describePipes(selected, policy, ", ")
+	}
+
+	return pipeNS, pipeDescription, multiplePipes, selected
+}
+
+// containsPipe reports whether pipes has a row with the description and N.S. of pipe
+func containsPipe(pipes []PipeRow, pipe PipeRow) bool {
+	for _, p := range pipes {
+		if p.Description == pipe.Description && p.NS == pipe.NS {
+			return true
+		}
+	}
+	return false
+}
+
+// PipeWelds is the part of a drawing's welds attributed to one of its pipes
+type PipeWelds struct {
+	PipeRow
+	Welds float64 `json:"welds"` // weld count times the pipe's share
+}
+
+// attributeWelds splits a drawing's weld count over the pipes selected by the pipe policy
+func attributeWelds(selected []PipeRow, weldCount int) []PipeWelds {
+	var attributed []PipeWelds
+	for _, pipe := range selected {
+		attributed = append(attributed, PipeWelds{PipeRow: pipe, Welds: float64(weldCount) * pipe.Share})
+	}
+	return attributed
 }
 
 // extractPipeNSFromEntities extracts unique pipe N.S. values from text entities
 func extractPipeNSFromEntities(textEntities []TextEntity) string {
 	// Look for BOM data that contains pipe information
 	matHeader, matRows := extractTable(textEntities, "ERECTION MATERIALS")
-	
+
 	if len(matRows) == 0 {
 		return ""
 	}
-	
+
 	// Find N.S. column index
 	nsIndex := -1
 	for i, header := range matHeader {
@@ -252,26 +313,26 @@ func extractPipeNSFromEntities(textEntities []TextEntity) string {
 			break
 		}
 	}
-	
+
 	if nsIndex == -1 {
 		return ""
 	}
-	
+
 	// Extract unique N.S. values from pipe rows
 	nsSet := make(map[string]bool)
-	
+
 	for _, row := range matRows {
 		if len(row) > nsIndex {
 			// Check if this row is a pipe row (contains "Pipe" in description)
 			descIndex := -1
 			for i, header := range matHeader {
-				if strings.Contains(strings.ToUpper(header), "DESCRIPTION") || 
-				   strings.Contains(strings.ToUpper(header), "COMPONENT") {
+				if strings.Contains(strings.ToUpper(header), "DESCRIPTION") ||
+					strings.Contains(strings.ToUpper(header), "COMPONENT") {
 					descIndex = i
 					break
 				}
 			}
-			
+
 			if descIndex != -1 && len(row) > descIndex {
 				description := strings.ToUpper(row[descIndex])
 				if strings.Contains(description, "PIPE") {
@@ -290,13 +351,13 @@ func extractPipeNSFromEntities(textEntities []TextEntity) string {
 			}
 		}
 	}
-	
+
 	// Convert to sorted slice
 	var nsValues []string
 	for ns := range nsSet {
 		nsValues = append(nsValues, ns)
 	}
-	
+
 	// Sort the values
 	sort.Slice(nsValues, func(i, j int) bool {
 		// Try to sort numerically if possible
@@ -308,465 +369,395 @@ func extractPipeNSFromEntities(textEntities []TextEntity) string {
 		// Fall back to string comparison
 		return nsValues[i] < nsValues[j]
 	})
-	
+
 	// Join with commas
 	return strings.Join(nsValues, ", ")
 }
 
-// processWeldDetection processes cached files for weld detection
-func processWeldDetection(fileCache map[string]FileCache) []WeldResult {
+// processWeldDetection processes cached files for weld detection with the settings of run.
+// With details every result also lists its weld symbols with their text labels. When ctx is
+// done the results of the files detected so far are returned with an error wrapping ctx.Err().
+func processWeldDetection(ctx context.Context, fileCache map[string]FileCache, details bool, run *runConfig) ([]WeldResult, error) {
 	var results []WeldResult
-	
-	for filePath, cache := range fileCache {
-		start := time.Now()
-		result := WeldResult{
-			FilePath: filePath,
-			FileName: cache.FileName,
-		}
-		
-		// Extract drawing number and pipe class from cached text entities
-		result.DrawingNo = findDrawingNoFromEntities(cache.TextEntities)
-		result.PipeClass = findPipeClassFromEntities(cache.TextEntities)
-		
-		// Extract pipe information (NS, Description, Multiple flag)
-		result.PipeNS, result.PipeDescription, result.MultiplePipeNS = extractPipeInfoFromEntities(cache.TextEntities)
-		
-		// Process weld detection safely with error capture
-		if weldCount, err := extractWeldsFromRawContent(cache.RawContent); err != nil {
-			result.Error = fmt.Sprintf("Weld detection failed: %v", err)
-			result.WeldCount = 0
-		} else {
-			result.WeldCount = weldCount
-		}
-		
-		result.ProcessingTime = time.Since(start).Seconds()
-		results = append(results, result)
-	}
-	
-	return results
-}
 
-// findDrawingNoFromEntities extracts drawing number from text entities
-func findDrawingNoFromEntities(entities []TextEntity) string {
-	// First find ERECTION MATERIALS position to establish search area
-	var erectionX, erectionY *float64
-	for _, entity := range entities {
-		if strings.Contains(strings.ToUpper(entity.Content), "ERECTION MATERIALS") {
-			erectionX = &entity.X
-			erectionY = &entity.Y
-			break
-		}
-	}
-
-	type candidate struct {
-		value string
-		x     float64
-		y     float64
-		absY  float64
-	}
-	candidates := []candidate{}
-
-	for _, entity := range entities {
-		match := kksPattern.FindString(entity.Content)
-		if match != "" {
-			// If we found ERECTION MATERIALS, filter by position (below and to the right)
-			if erectionX != nil && erectionY != nil {
-				if entity.X >= *erectionX && entity.Y <= *erectionY { // Right and below
-					candidates = append(candidates, candidate{match, entity.X, entity.Y, abs(entity.Y)})
-				}
-			} else {
-				// If no ERECTION MATERIALS found, consider all KKS codes
-				candidates = append(candidates, candidate{match, entity.X, entity.Y, abs(entity.Y)})
-			}
-		}
-	}
-
-	if len(candidates) > 0 {
-		// Sort by Y coordinate (lowest Y = bottom of drawing) and pick the first one
-		for i := 0; i < len(candidates)-1; i++ {
-			for j := i + 1; j < len(candidates); j++ {
-				if candidates[i].absY > candidates[j].absY {
-					candidates[i], candidates[j] = candidates[j], candidates[i]
-				}
-			}
+	for filePath, cache := range fileCache {
+		if ctx.Err() != nil {
+			return results, fmt.Errorf("weld detection interrupted after %d of %d files: %w", len(results), len(fileCache), ctx.Err())
 		}
-		return candidates[0].value
+		results = append(results, detectFileWelds(filePath, cache, details, run))
 	}
 
-	return ""
+	return results, nil
 }
 
-// findPipeClassFromEntities extracts pipe class from text entities
-func findPipeClassFromEntities(entities []TextEntity) string {
-	// Look for 'Pipe class:' label first
-	var pipeClassLabelY, pipeClassLabelX *float64
-
-	for _, entity := range entities {
-		textClean := strings.ToLower(strings.ReplaceAll(strings.ReplaceAll(entity.Content, " ", ""), "\n", ""))
-		// More flexible matching for pipe class label
-		if strings.Contains(textClean, "pipeclass") ||
-			strings.Contains(textClean, "pipe_class") ||
-			(strings.Contains(strings.ToLower(entity.Content), "pipe") && strings.Contains(strings.ToLower(entity.Content), "class")) {
-			pipeClassLabelX = &entity.X
-			pipeClassLabelY = &entity.Y
-			break
-		}
+// detectFileWelds runs the weld detection of one cached file. A panic fails only that file.
+func detectFileWelds(filePath string, cache FileCache, details bool, run *runConfig) (result WeldResult) {
+	start := time.Now()
+	result = WeldResult{
+		FilePath: filePath,
+		FileName: cache.FileName,
 	}
 
-	if pipeClassLabelY != nil {
-		// Look for 4-letter codes near the label
-		type candidate struct {
-			value    string
-			distance float64
-		}
-		candidates := []candidate{}
-
-		for _, entity := range entities {
-			// Look for text near the label (horizontally close, similar Y level)
-			if abs(entity.Y-*pipeClassLabelY) < 20 && // Same row or close
-				entity.X > *pipeClassLabelX && // To the right of label
-				abs(entity.X-*pipeClassLabelX) < 200 { // Not too far horizontally
-				textClean := strings.TrimSpace(entity.Content)
-				match := pipeClassPattern.FindString(textClean)
-				if match != "" {
-					distance := abs(entity.X - *pipeClassLabelX)
-					candidates = append(candidates, candidate{match, distance})
-				}
-			}
-		}
-
-		if len(candidates) > 0 {
-			// Sort by distance from label and pick the closest one
-			for i := 0; i < len(candidates)-1; i++ {
-				for j := i + 1; j < len(candidates); j++ {
-					if candidates[i].distance > candidates[j].distance {
-						candidates[i], candidates[j] = candidates[j], candidates[i]
-					}
-				}
-			}
-			return candidates[0].value
-		}
-	}
-
-	// Alternative approach: Look for DESIGN DATA section first, then find pipe class within it
-	var designDataY *float64
-	for _, entity := range entities {
-		if strings.Contains(strings.ToUpper(entity.Content), "DESIGN DATA") {
-			designDataY = &entity.Y
-			break
-		}
-	}
+	// Drawing number and pipe class were found with the run's patterns during extraction
+	result.DrawingNo = cache.DrawingNo
+	result.PipeClass = cache.PipeClass
+	defer run.recoverCrash(filePath, "weld detection", func(message string) {
+		result = WeldResult{FilePath: filePath, FileName: cache.FileName, DrawingNo: cache.DrawingNo, PipeClass: cache.PipeClass, Error: message}
+		result.ProcessingTime = time.Since(start).Seconds()
+	})
 
-	if designDataY != nil {
-		// Look for 4-letter codes within DESIGN DATA area (below the title)
-		for _, entity := range entities {
-			if entity.Y < *designDataY && entity.Y > *designDataY-150 { // Within 150 units below DESIGN DATA
-				textClean := strings.TrimSpace(entity.Content)
-				match := pipeClassPattern.FindString(textClean)
-				if match != "" {
-					return match
-				}
-			}
+	// Extract pipe information (NS, Description, Multiple flag)
+	var pipes []PipeRow
+	result.PipeNS, result.PipeDescription, result.MultiplePipeNS, pipes = extractPipeInfoFromEntities(cache.TextEntities, run.pipePolicy)
+
+	// Process weld detection safely with error capture
+	if cache.SegmentError != "" {
+		result.Error = fmt.Sprintf("Weld detection failed: %s", cache.SegmentError)
+		result.WeldCount = 0
+	} else {
+		var labels []TextEntity
+		if details {
+			labels = cache.TextEntities
 		}
-	}
-
-	// Fallback: look for 4-letter codes in bottom center area
-	bottomEntities := []TextEntity{}
-	for _, entity := range entities {
-		if entity.Y < 100 { // Y < 100 (bottom area)
-			bottomEntities = append(bottomEntities, entity)
+		config := weldConfig
+		config.Explain = details
+		detection := ExtractWeldSymbolsDetailed(labels, cache.Segments, config)
+		result.WeldCount = len(detection.Symbols)
+		result.DuplicateSegments = detection.DuplicateSegments
+		result.PipeWelds = attributeWelds(pipes, result.WeldCount)
+		result.WeldsBySize = attributeWeldSizes(detection.Symbols, cache.SizeCallouts, cache.PipeSizes)
+		if run.weldGraph {
+			graph := BuildWeldGraph(cache.GraphNodes, detection.Symbols, run.weldGraphRadius)
+			graph.FilePath, graph.DrawingNo = filePath, cache.DrawingNo
+			result.Graph = &graph
 		}
-	}
-
-	if len(bottomEntities) == 0 {
-		// Use bottom half if no entities found below Y=100
-		for i := 0; i < len(entities)-1; i++ {
-			for j := i + 1; j < len(entities); j++ {
-				if entities[i].Y > entities[j].Y {
-					entities[i], entities[j] = entities[j], entities[i]
-				}
-			}
+		if details {
+			result.Welds = detection.Symbols
 		}
-		midPoint := len(entities) / 2
-		bottomEntities = entities[:midPoint]
-	}
-
-	// Look for candidates in center area (avoid far right where revision notes might be)
-	type centerCandidate struct {
-		value string
-		x     float64
-		y     float64
 	}
-	centerCandidates := []centerCandidate{}
 
-	for _, entity := range bottomEntities {
-		if entity.X < 500 { // Avoid far right area where revision notes typically are
-			match := pipeClassPattern.FindString(strings.TrimSpace(entity.Content))
-			if match != "" {
-				centerCandidates = append(centerCandidates, centerCandidate{match, entity.X, entity.Y})
-			}
-		}
-	}
+	result.ProcessingTime = time.Since(start).Seconds()
+	return result
+}
 
-	if len(centerCandidates) > 0 {
-		// Prefer candidates in the center-left area (where DESIGN DATA typically is)
-		for i := 0; i < len(centerCandidates)-1; i++ {
-			for j := i + 1; j < len(centerCandidates); j++ {
-				if centerCandidates[i].x > centerCandidates[j].x {
-					centerCandidates[i], centerCandidates[j] = centerCandidates[j], centerCandidates[i]
-				}
-			}
-		}
-		return centerCandidates[0].value
-	}
+// linesIntersect checks if two line segments intersect and returns intersection point
+func linesIntersect(seg1, seg2 PolylineSegment) (float64, float64, bool) {
+	return geometry.Intersect(seg1.X1, seg1.Y1, seg1.X2, seg1.Y2, seg2.X1, seg2.Y1, seg2.X2, seg2.Y2)
+}
 
-	return ""
+// ParseSegments returns the polyline segments of DXF content in millimeters, the input of
+// ExtractWeldSymbols together with the text entities of the drawing
+func ParseSegments(content []byte) ([]PolylineSegment, error) {
+	return parsePolylineSegments(content, nil, defaultRunConfig())
 }
 
-// extractWeldsFromRawContent parses polylines and detects weld symbols
-func extractWeldsFromRawContent(rawContent []byte) (int, error) {
-	segments, err := parsePolylineSegmentsOptimized(string(rawContent))
-	if err != nil {
-		return 0, err
-	}
-	
-	weldSymbols := detectWeldSymbols(segments)
-	return len(weldSymbols), nil
+// parsePolylineSegmentsOptimized extracts polyline segments from DXF content
+// keeping only segments with weld symbol target lengths of the active weld configuration
+func parsePolylineSegmentsOptimized(content []byte, run *runConfig) ([]PolylineSegment, error) {
+	return parsePolylineSegments(content, weldConfig.IsTargetLength, run)
 }
 
-// lengthsMatch checks if two lengths match any known weld symbol pair
-func lengthsMatch(len1, len2 float64) bool {
-	tolerance := 0.01 // Allow small floating point variations
-	
-	for _, pair := range weldLengthPairs {
-		// Check both orders: (len1, len2) and (len2, len1)
-		if (math.Abs(len1-pair[0]) <= tolerance && math.Abs(len2-pair[1]) <= tolerance) ||
-		   (math.Abs(len1-pair[1]) <= tolerance && math.Abs(len2-pair[0]) <= tolerance) {
-			return true
+// parsePolylineSegments extracts polyline segments from DXF content: of POLYLINE with its
+// VERTEX records, the only polyline of R12 files, and of the LWPOLYLINE that R14 and later
+// write for 2D polylines. If keep is non-nil only segments whose length it accepts are
+// returned. Segments of polylines that are members of a GROUP object carry its name (from the
+// ACAD_GROUP dictionary, or the group's handle for a group without entry). Segments of block definitions are returned at every
+// INSERT of the block, nested blocks up to the block depth of run. Polylines and block records
+// are only read in the sections of the scope of run. Segments are returned in millimeters,
+// converted from the units of the drawing (see detectUnits).
+func parsePolylineSegments(content []byte, keep func(length float64) bool, run *runConfig) ([]PolylineSegment, error) {
+	segments := newSegments()
+	var segmentHandles []string // handle of the polyline of every segment
+	degenerate := 0
+	units := detectUnits(run.units, func() (*DXFHeader, error) {
+		return readHeader(bytes.NewReader(content), defaultScanBuffer)
+	})
+	blocks := newSegmentBlocks(func(segment PolylineSegment, handle string) {
+		segment = segment.scaled(units.Millimeters)
+		// Duplicate vertices would match length windows near zero by accident
+		if isDegenerateSegment(segment) {
+			degenerate++
+			return
 		}
-	}
-	return false
-}
+		// Only keep segments accepted by the filter
+		if keep == nil || keep(segment.Length) {
+			segments = append(segments, segment)
+			segmentHandles = append(segmentHandles, handle)
+		}
+	}, run.parse.BlockDepth, run.parse.Layout)
 
-// linesIntersect checks if two line segments intersect and returns intersection point
-func linesIntersect(seg1, seg2 PolylineSegment) (float64, float64, bool) {
-	x1, y1, x2, y2 := seg1.X1, seg1.Y1, seg1.X2, seg1.Y2
-	x3, y3, x4, y4 := seg2.X1, seg2.Y1, seg2.X2, seg2.Y2
-	
-	denom := (x1-x2)*(y3-y4) - (y1-y2)*(x3-x4)
-	if math.Abs(denom) < 1e-10 {
-		return 0, 0, false // Lines are parallel
-	}
-	
-	t := ((x1-x3)*(y3-y4) - (y1-y3)*(x3-x4)) / denom
-	u := -((x1-x2)*(y1-y3) - (y1-y2)*(x1-x3)) / denom
-	
-	if t >= 0 && t <= 1 && u >= 0 && u <= 1 {
-		// Lines intersect
-		ix := x1 + t*(x2-x1)
-		iy := y1 + t*(y2-y1)
-		return ix, iy, true
-	}
-	
-	return 0, 0, false
-}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
 
-// parsePolylineSegmentsOptimized extracts polyline segments from DXF content
-func parsePolylineSegmentsOptimized(content string) ([]PolylineSegment, error) {
-	var segments []PolylineSegment
-	
-	scanner := bufio.NewScanner(strings.NewReader(content))
-	
 	var currentLayer string
-	var vertices [][]float64
+	var vertices [][3]float64 // of the polyline read, reused for the next
 	inPolyline := false
 	inVertex := false
 	expectingValue := false
 	lastGroupCode := ""
 	var currentX, currentY float64
+	elevation := 0.0 // of a 2D POLYLINE (30 of its dummy point); 3D polylines have a Z per vertex
+	var groups appGroupFilter
+
+	// GROUP objects: handle of the record being read, members and dictionary entry names
+	record, recordHandle, polylineHandle, entryName := "", "", "", ""
+	polylinePaper, polylineLayout := false, "" // space (67) and layout (410) of the POLYLINE
+	var sections sectionTracker
+	var proxies proxySkipper
+	groupMembers := make(map[string]string) // member entity handle -> group handle
+	entryNames := make(map[string]string)   // object handle -> dictionary entry name
+
+	// emitPolyline passes the segments of the polyline read to the blocks
+	emitPolyline := func() {
+		if len(vertices) < 2 || !blocks.drawn(polylinePaper, polylineLayout) {
+			return
+		}
+		for i := 0; i < len(vertices)-1; i++ {
+			segment := PolylineSegment{
+				X1:     vertices[i][0],
+				Y1:     vertices[i][1],
+				X2:     vertices[i+1][0],
+				Y2:     vertices[i+1][1],
+				Z1:     vertices[i][2],
+				Z2:     vertices[i+1][2],
+				Layer:  currentLayer,
+				Handle: polylineHandle,
+			}
+			segment.Length = geometry.Distance(segment.X1, segment.Y1, segment.X2, segment.Y2)
+			blocks.add(segment, polylineHandle)
+		}
+	}
 
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
-		
+		if proxies.active {
+			if proxies.skip(line) {
+				continue
+			}
+			// The record after the proxy
+			lastGroupCode, expectingValue = "0", true
+		}
+
 		if !expectingValue {
+			if proxies.payload(line) {
+				continue
+			}
 			lastGroupCode = line
 			expectingValue = true
 		} else {
 			expectingValue = false
-			
+			sections.pair(lastGroupCode, line)
+			if groups.skip(lastGroupCode, line) {
+				continue
+			}
+			if lastGroupCode != "0" && blocks.setGroup(lastGroupCode, line) {
+				continue
+			}
+
 			switch lastGroupCode {
 			case "0": // Entity type
-				if line == "POLYLINE" {
+				if record == "LWPOLYLINE" && inPolyline {
+					// An LWPOLYLINE has its vertices in its own groups and ends at the next record
+					emitPolyline()
+					inPolyline, inVertex = false, false
+				}
+				record, recordHandle, entryName = line, "", ""
+				proxies.enter(line)
+				blocks.endRecord()
+				if !run.parse.Scope.allows(line, sections.name) {
+					inPolyline, inVertex = false, false
+					break
+				}
+				if isBlockRecord(line) {
+					blocks.startRecord(line)
+				}
+				if isPolylineRecord(line) {
+					polylineHandle = ""
+					polylinePaper, polylineLayout = false, ""
 					inPolyline = true
-					vertices = nil
+					vertices = vertices[:0]
+					elevation = 0
 				} else if line == "SEQEND" && inPolyline {
 					// End of POLYLINE, process vertices but only keep target-length segments
-					if len(vertices) >= 2 {
-						for i := 0; i < len(vertices)-1; i++ {
-							segment := PolylineSegment{
-								X1:    vertices[i][0],
-								Y1:    vertices[i][1],
-								X2:    vertices[i+1][0],
-								Y2:    vertices[i+1][1],
-								Layer: currentLayer,
-							}
-							segment.Length = distance(segment.X1, segment.Y1, segment.X2, segment.Y2)
-							
-							// Only keep segments with target lengths
-							if isTargetLength(segment.Length) {
-								segments = append(segments, segment)
-							}
-						}
-					}
+					emitPolyline()
 					inPolyline = false
 					inVertex = false
 				} else if line == "VERTEX" && inPolyline {
 					inVertex = true
 				}
-				
+
+			case "5": // Handle
+				recordHandle = strings.ToUpper(line)
+				if isPolylineRecord(record) {
+					polylineHandle = recordHandle
+				}
+
+			case "67": // Space: 1 = paper space
+				if isPolylineRecord(record) {
+					polylinePaper = line == "1"
+				}
+
+			case "410": // Layout name
+				if isPolylineRecord(record) {
+					polylineLayout = line
+				}
+
+			case "3": // Dictionary entry name
+				if record == "DICTIONARY" {
+					entryName = line
+				}
+
+			case "350": // Dictionary entry
+				if record == "DICTIONARY" && entryName != "" {
+					entryNames[strings.ToUpper(line)] = entryName
+				}
+
+			case "340": // Group member
+				if record == "GROUP" && recordHandle != "" {
+					groupMembers[strings.ToUpper(line)] = recordHandle
+				}
+
 			case "8": // Layer name
 				if inPolyline {
 					currentLayer = line
 				}
-				
-			case "10": // X coordinate
-				if inPolyline && inVertex {
-					if val, err := strconv.ParseFloat(line, 64); err == nil {
+
+			case "10": // X coordinate; every vertex of an LWPOLYLINE starts with one
+				if inPolyline && (inVertex || record == "LWPOLYLINE") {
+					if val, ok := parseGroupFloat(lastGroupCode, line); ok {
 						currentX = val
+						inVertex = true
 					}
 				}
-				
+
 			case "20": // Y coordinate
 				if inPolyline && inVertex {
-					if val, err := strconv.ParseFloat(line, 64); err == nil {
+					if val, ok := parseGroupFloat(lastGroupCode, line); ok {
 						currentY = val
-						vertices = append(vertices, []float64{currentX, currentY})
+						vertices = append(vertices, [3]float64{currentX, currentY, elevation})
 						inVertex = false
 					}
 				}
+
+			case "30": // Z coordinate
+				if z, ok := parseGroupFloat(lastGroupCode, line); ok && inPolyline {
+					switch {
+					case record == "POLYLINE":
+						elevation = z
+					case record == "VERTEX" && len(vertices) > 0:
+						vertices[len(vertices)-1][2] = elevation + z
+					}
+				}
+
+			case "38": // Elevation of an LWPOLYLINE
+				if z, ok := parseGroupFloat(lastGroupCode, line); ok && inPolyline && record == "LWPOLYLINE" {
+					elevation = z
+				}
 			}
 		}
 	}
-	
-	return segments, scanner.Err()
-}
 
-// detectWeldSymbols finds pairs of crossed polyline segments with matching lengths
-func detectWeldSymbols(segments []PolylineSegment) []WeldSymbol {
-	var weldSymbols []WeldSymbol
-	
-	if len(segments) == 0 {
-		return weldSymbols
-	}
-	
-	// Check all pairs of segments (already filtered to target lengths)
-	for i := 0; i < len(segments); i++ {
-		for j := i + 1; j < len(segments); j++ {
-			seg1 := segments[i]
-			seg2 := segments[j]
-			
-			// Check if lengths match known weld symbol pairs
-			if !lengthsMatch(seg1.Length, seg2.Length) {
-				continue
-			}
-			
-			// Check if segments intersect (crossed)
-			ix, iy, intersects := linesIntersect(seg1, seg2)
-			if !intersects {
-				continue
-			}
-			
-			// Check if intersection is roughly in the middle of both segments
-			mid1X, mid1Y := (seg1.X1+seg1.X2)/2, (seg1.Y1+seg1.Y2)/2
-			mid2X, mid2Y := (seg2.X1+seg2.X2)/2, (seg2.Y1+seg2.Y2)/2
-			
-			distToMid1 := distance(ix, iy, mid1X, mid1Y)
-			distToMid2 := distance(ix, iy, mid2X, mid2Y)
-			
-			// Intersection should be close to midpoint of both segments
-			tolerance1 := seg1.Length * 0.3 // 30% tolerance
-			tolerance2 := seg2.Length * 0.3
-			
-			if distToMid1 > tolerance1 || distToMid2 > tolerance2 {
-				continue // Segments don't cross in the middle
-			}
-			
-			// Calculate confidence based on how close to perfect cross it is
-			maxTolerance := math.Max(tolerance1, tolerance2)
-			maxDistToMid := math.Max(distToMid1, distToMid2)
-			confidence := 1.0 - (maxDistToMid / maxTolerance)
-			
-			// Create weld symbol
-			weldSymbol := WeldSymbol{
-				CenterX:    ix,
-				CenterY:    iy,
-				Length1:    seg1.Length,
-				Length2:    seg2.Length,
-				Layer:      seg1.Layer,
-				Confidence: confidence,
-			}
-			
-			weldSymbols = append(weldSymbols, weldSymbol)
-		}
+	blocks.endRecord()
+	for _, warning := range blocks.nesting.warnings {
+		debugPrint("[DEBUG] Segments: " + warning)
+	}
+	if degenerate > 0 {
+		debugPrint(fmt.Sprintf("[DEBUG] Dropped %d degenerate polyline segments", degenerate))
 	}
-	
-	// Remove duplicates (same location)
-	return removeDuplicateSymbols(weldSymbols)
-}
 
-// removeDuplicateSymbols removes weld symbols that are too close to each other
-func removeDuplicateSymbols(symbols []WeldSymbol) []WeldSymbol {
-	if len(symbols) <= 1 {
-		return symbols
-	}
-	
-	var unique []WeldSymbol
-	duplicateThreshold := 5.0 // Symbols closer than this are considered duplicates
-	
-	for _, symbol := range symbols {
-		isDuplicate := false
-		for _, existing := range unique {
-			if distance(symbol.CenterX, symbol.CenterY, existing.CenterX, existing.CenterY) < duplicateThreshold {
-				isDuplicate = true
-				break
-			}
+	// GROUP objects follow the entities in the OBJECTS section
+	grouped := 0
+	for i, handle := range segmentHandles {
+		group, ok := groupMembers[handle]
+		if handle == "" || !ok {
+			continue
 		}
-		
-		if !isDuplicate {
-			unique = append(unique, symbol)
+		if name, ok := entryNames[group]; ok {
+			group = name
 		}
+		segments[i].Group = group
+		grouped++
 	}
-	
-	return unique
+	if grouped > 0 {
+		debugPrint(fmt.Sprintf("[DEBUG] %d candidate segments belong to GROUP objects", grouped))
+	}
+	return segments, scanner.Err()
+}
+
+// isPolylineRecord reports whether a record is a POLYLINE or an LWPOLYLINE
+func isPolylineRecord(record string) bool {
+	return record == "POLYLINE" || record == "LWPOLYLINE"
 }
 
 // writeWeldCSVs generates weld detection CSV files
-func writeWeldCSVs(results []WeldResult, outputDir string) error {
+func writeWeldCSVs(out *runOutputs, results []WeldResult, outputDir string) error {
 	// Write weld counts CSV
-	weldCountsFile := filepath.Join(outputDir, "0005_WELD_COUNTS.csv")
-	if err := writeWeldCountsCSV(weldCountsFile, results); err != nil {
+	weldCountsFile := out.path(outputDir, "0005_WELD_COUNTS.csv")
+	if err := writeWeldCountsCSV(out, weldCountsFile, results); err != nil {
 		return fmt.Errorf("error writing weld counts CSV: %v", err)
 	}
-	
+
 	fmt.Printf("Wrote WELD COUNTS data to: %s (%d files)\n", weldCountsFile, len(results))
+
+	weldSizesFile := out.path(outputDir, "0006_WELDS_BY_SIZE.csv")
+	rows := weldsBySizeRows(results)
+	if err := writeCSVFile(out, weldSizesFile, []string{"FilePath", "DrawingNo", "Size", "WeldCount"}, rows); err != nil {
+		return fmt.Errorf("error writing welds by size CSV: %v", err)
+	}
+	fmt.Printf("Wrote WELDS BY SIZE data to: %s (%d rows)\n", weldSizesFile, len(rows))
+	return nil
+}
+
+// weldsBySizeRows returns one row per drawing and attributed size, drawings sorted by file path
+func weldsBySizeRows(results []WeldResult) [][]string {
+	sorted := make([]WeldResult, len(results))
+	copy(sorted, results)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].FilePath < sorted[j].FilePath
+	})
+
+	var rows [][]string
+	for _, result := range sorted {
+		for _, count := range result.WeldsBySize {
+			rows = append(rows, []string{result.FilePath, result.DrawingNo, count.Size, strconv.Itoa(count.Welds)})
+		}
+	}
+	return rows
+}
+
+// writeWeldJSON writes the weld results as a JSON array sorted by file path
+func writeWeldJSON(out *runOutputs, filename string, results []WeldResult) error {
+	sorted := make([]WeldResult, len(results))
+	copy(sorted, results)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].FilePath < sorted[j].FilePath
+	})
+
+	data, err := json.MarshalIndent(sorted, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := out.writeFile(filename, data); err != nil {
+		return fmt.Errorf("error writing weld JSON: %v", err)
+	}
+
+	fmt.Printf("Wrote WELD RESULTS JSON to: %s (%d files)\n", filename, len(results))
 	return nil
 }
 
 // writeWeldCountsCSV writes the weld counts CSV file
-func writeWeldCountsCSV(filename string, results []WeldResult) error {
-	file, err := os.Create(filename)
+func writeWeldCountsCSV(out *runOutputs, filename string, results []WeldResult) error {
+	file, err := out.create(filename)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 
 	writer := csv.NewWriter(file)
-	defer writer.Flush()
 
 	// Write header
 	header := []string{
 		"FilePath", "FileName", "DrawingNo", "PipeClass", "PipeNS", "PipeDescription", "MultiplePipeNS",
-		"WeldCount", "ProcessingTime", "Error",
+		"WeldCount", "DuplicateSegments", "ProcessingTime", "Error", "Source",
 	}
 	if err := writer.Write(header); err != nil {
 		return err
@@ -783,13 +774,19 @@ func writeWeldCountsCSV(filename string, results []WeldResult) error {
 			result.PipeDescription,
 			result.MultiplePipeNS,
 			strconv.Itoa(result.WeldCount),
+			strconv.Itoa(result.DuplicateSegments),
 			fmt.Sprintf("%.3f", result.ProcessingTime),
 			result.Error,
+			result.Source,
 		}
 		if err := writer.Write(record); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return file.Commit()
 }
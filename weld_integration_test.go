@@ -0,0 +1,150 @@
+package dxfparser
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+// finite reports whether none of values is NaN or infinite
+func finite(values ...float64) bool {
+	for _, v := range values {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// lwpolyline returns the DXF records of an LWPOLYLINE through the points x1, y1, x2, y2, ...,
+// given as the group values are written
+func lwpolyline(layer string, points ...string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "0\nLWPOLYLINE\n8\n%s\n90\n%d\n", layer, len(points)/2)
+	for i := 0; i+1 < len(points); i += 2 {
+		fmt.Fprintf(&b, "10\n%s\n20\n%s\n", points[i], points[i+1])
+	}
+	return b.String()
+}
+
+func TestIsDegenerateSegment(t *testing.T) {
+	nan, inf := math.NaN(), math.Inf(1)
+	cases := []struct {
+		name    string
+		segment PolylineSegment
+		want    bool
+	}{
+		{"regular", PolylineSegment{X1: 0, Y1: 0, X2: 3, Y2: 4, Length: 5}, false},
+		{"short", PolylineSegment{X1: 0, Y1: 0, X2: 1e-6, Y2: 0, Length: 1e-6}, false},
+		{"zero length", PolylineSegment{X1: 2, Y1: 2, X2: 2, Y2: 2, Length: 0}, true},
+		{"NaN X1", PolylineSegment{X1: nan, Y1: 0, X2: 3, Y2: 4, Length: 5}, true},
+		{"NaN Y2", PolylineSegment{X1: 0, Y1: 0, X2: 3, Y2: nan, Length: 5}, true},
+		{"Inf Y1", PolylineSegment{X1: 0, Y1: inf, X2: 3, Y2: 4, Length: 5}, true},
+		{"-Inf X2", PolylineSegment{X1: 0, Y1: 0, X2: -inf, Y2: 4, Length: 5}, true},
+		{"NaN length", PolylineSegment{X1: 0, Y1: 0, X2: 3, Y2: 4, Length: nan}, true},
+		{"Inf length", PolylineSegment{X1: 0, Y1: 0, X2: 3, Y2: 4, Length: inf}, true},
+	}
+	for _, c := range cases {
+		if got := isDegenerateSegment(c.segment); got != c.want {
+			t.Errorf("%s: isDegenerateSegment(%+v) = %v, want %v", c.name, c.segment, got, c.want)
+		}
+	}
+}
+
+func TestLinesIntersectNonFinite(t *testing.T) {
+	nan, inf := math.NaN(), math.Inf(1)
+	cross := PolylineSegment{X1: -1, Y1: 0, X2: 1, Y2: 0}
+	cases := []struct {
+		name    string
+		segment PolylineSegment
+		want    bool
+	}{
+		{"crossing", PolylineSegment{X1: 0, Y1: -1, X2: 0, Y2: 1}, true},
+		{"NaN end", PolylineSegment{X1: 0, Y1: -1, X2: nan, Y2: 1}, false},
+		{"NaN start", PolylineSegment{X1: nan, Y1: nan, X2: 0, Y2: 1}, false},
+		{"Inf end", PolylineSegment{X1: 0, Y1: -1, X2: 0, Y2: inf}, false},
+		{"huge", PolylineSegment{X1: 0, Y1: -math.MaxFloat64, X2: 0, Y2: math.MaxFloat64}, false},
+	}
+	for _, c := range cases {
+		x, y, ok := linesIntersect(cross, c.segment)
+		if ok != c.want {
+			t.Errorf("%s: linesIntersect(%+v) = %v, want %v", c.name, c.segment, ok, c.want)
+		}
+		if ok && !finite(x, y) {
+			t.Errorf("%s: intersection (%v, %v) is not finite", c.name, x, y)
+		}
+	}
+}
+
+func TestParseSegmentsSkipsNonFiniteCoordinates(t *testing.T) {
+	content := "0\nSECTION\n2\nENTITIES\n" +
+		lwpolyline("REGULAR", "0", "0", "4.0311", "0") +
+		lwpolyline("NAN", "NaN", "0", "4", "0") +
+		lwpolyline("INF", "0", "inf", "6.9462", "0") +
+		lwpolyline("RANGE", "1e400", "0", "5", "5") +
+		lwpolyline("MIXED", "-Infinity", "1", "2", "3", "4", "5") +
+		lwpolyline("DUPLICATE", "7", "7", "7", "7") +
+		"0\nENDSEC\n0\nEOF\n"
+
+	segments, err := ParseSegments([]byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	layers := make(map[string]int)
+	for _, s := range segments {
+		if !finite(s.X1, s.Y1, s.X2, s.Y2, s.Length) || !(s.Length > 0) {
+			t.Errorf("segment %+v has non-finite or zero geometry", s)
+		}
+		layers[s.Layer]++
+	}
+	// The MIXED polyline keeps the segment between its finite vertices
+	want := map[string]int{"REGULAR": 1, "MIXED": 1}
+	if fmt.Sprint(layers) != fmt.Sprint(want) {
+		t.Errorf("segments by layer = %v, want %v", layers, want)
+	}
+}
+
+func TestExtractWeldSymbolsIgnoresNonFiniteSegments(t *testing.T) {
+	config := DefaultWeldConfig()
+	config.LabelRadius = 0
+	pair := config.LengthPairs[0]
+	nan, inf := math.NaN(), math.Inf(1)
+
+	// One weld cross at (10, 10)
+	segments := []PolylineSegment{
+		{X1: 10 - pair[0]/2, Y1: 10, X2: 10 + pair[0]/2, Y2: 10, Length: pair[0], Layer: "0"},
+		{X1: 10, Y1: 10 - pair[1]/2, X2: 10, Y2: 10 + pair[1]/2, Length: pair[1], Layer: "0"},
+	}
+	// Segments with weld symbol lengths but NaN or infinite coordinates, crossing the symbol
+	// and each other where their coordinates are finite
+	for _, bad := range []PolylineSegment{
+		{X1: nan, Y1: 10, X2: 10 + pair[0]/2, Y2: 10, Length: pair[0]},
+		{X1: 10, Y1: nan, X2: 10, Y2: nan, Length: pair[1]},
+		{X1: 10 - pair[0]/2, Y1: 10, X2: inf, Y2: 10, Length: pair[0]},
+		{X1: 10, Y1: -inf, X2: 10, Y2: 10 + pair[1]/2, Length: pair[1]},
+		{X1: 50 - pair[0]/2, Y1: 50, X2: 50 + pair[0]/2, Y2: 50, Length: nan},
+		{X1: 50, Y1: 50 - pair[1]/2, X2: 50, Y2: 50 + pair[1]/2, Length: inf},
+	} {
+		bad.Layer = "0"
+		segments = append(segments, bad)
+	}
+
+	for name, extract := range map[string]func([]TextEntity, []PolylineSegment, WeldConfig) []WeldSymbol{
+		"ExtractWeldSymbols":         ExtractWeldSymbols,
+		"extractWeldSymbolsPairwise": extractWeldSymbolsPairwise,
+	} {
+		symbols := extract(nil, segments, config)
+		if len(symbols) != 1 {
+			t.Errorf("%s: %d symbols, want 1: %+v", name, len(symbols), symbols)
+			continue
+		}
+		s := symbols[0]
+		if !finite(s.CenterX, s.CenterY, s.Length1, s.Length2, s.Confidence) {
+			t.Errorf("%s: symbol %+v has non-finite values", name, s)
+		}
+		if math.Abs(s.CenterX-10) > 1e-9 || math.Abs(s.CenterY-10) > 1e-9 {
+			t.Errorf("%s: symbol at (%v, %v), want (10, 10)", name, s.CenterX, s.CenterY)
+		}
+	}
+}
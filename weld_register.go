@@ -0,0 +1,244 @@
+package dxfparser
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// weldRegisterHeader is the weld book layout of the QA department. Only weld number, drawing,
+// line, pipe class and size are filled; joint type, welding and NDT columns (ISO 9712 method
+// codes) are left empty for QA.
+var weldRegisterHeader = []string{
+	"Weld No", "Drawing", "Line No", "Pipe Class", "Size", "Joint Type", "WPS", "Welder ID", "Weld Date",
+	"VT", "PT", "MT", "RT", "UT", "NDT Report No", "Accepted", "Remarks",
+}
+
+// weldRegisterColumns is weldRegisterHeader, with a Handles column of the weld symbol polylines
+// with handles
+func weldRegisterColumns(handles bool) []string {
+	if !handles {
+		return weldRegisterHeader
+	}
+	return append(append([]string(nil), weldRegisterHeader...), "Handles")
+}
+
+// weldRegisterRows returns one register row per detected weld, drawings sorted by drawing number.
+// Welds are numbered W01, W02, ... per drawing; drawings whose weld detection failed get a
+// single row with the error in Remarks so they are not silently missing from the register.
+func weldRegisterRows(results []WeldResult, handles bool) [][]string {
+	sorted := make([]WeldResult, len(results))
+	copy(sorted, results)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].DrawingNo != sorted[j].DrawingNo {
+			return sorted[i].DrawingNo < sorted[j].DrawingNo
+		}
+		return sorted[i].FilePath < sorted[j].FilePath
+	})
+
+	var rows [][]string
+	for _, result := range sorted {
+		drawing := strings.TrimSuffix(filepath.Base(result.FilePath), filepath.Ext(result.FilePath))
+		row := func(weldNo, remarks string) []string {
+			r := make([]string, len(weldRegisterColumns(handles)))
+			r[0], r[1], r[2], r[3], r[4] = weldNo, drawing, result.DrawingNo, result.PipeClass, result.PipeNS
+			r[len(weldRegisterHeader)-1] = remarks
+			return r
+		}
+
+		if result.Error != "" {
+			rows = append(rows, row("", result.Error))
+			continue
+		}
+		for i, weld := range result.Welds {
+			r := row(fmt.Sprintf("W%02d", i+1), "")
+			if handles {
+				r[len(r)-1] = strings.Join(weld.Handles, " ")
+			}
+			rows = append(rows, r)
+		}
+	}
+	return rows
+}
+
+// writeWeldRegister writes the weld register as XLSX if filename ends in .xlsx, otherwise as CSV
+func writeWeldRegister(out *runOutputs, filename string, results []WeldResult, handles bool) error {
+	header, rows := weldRegisterColumns(handles), weldRegisterRows(results, handles)
+
+	var err error
+	if strings.EqualFold(filepath.Ext(filename), ".xlsx") {
+		err = writeXLSX(out, filename, "Weld Register", header, rows)
+	} else {
+		err = writeCSVFile(out, filename, header, rows)
+	}
+	if err != nil {
+		return fmt.Errorf("error writing weld register: %v", err)
+	}
+
+	fmt.Printf("Wrote WELD REGISTER to: %s (%d rows)\n", filename, len(rows))
+	return nil
+}
+
+// writeCSVFile writes header and rows to a new CSV file
+func writeCSVFile(out *runOutputs, filename string, header []string, rows [][]string) error {
+	file, err := out.create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+	return file.Commit()
+}
+
+// XLSX package parts that do not depend on the sheets; the only style is the bold header font
+const (
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+	xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>
+<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>
+<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>
+</styleSheet>`
+)
+
+// xlsxSheet is one sheet of a workbook written by writeXLSXSheets
+type xlsxSheet struct {
+	Name    string
+	Header  []string
+	Rows    [][]string
+	Numbers bool // write row cells that hold a number as numbers instead of text
+}
+
+// writeXLSX writes header and rows as text cells to a single sheet workbook with a frozen,
+// bold header row
+func writeXLSX(out *runOutputs, filename, sheetName string, header []string, rows [][]string) error {
+	return writeXLSXSheets(out, filename, []xlsxSheet{{Name: sheetName, Header: header, Rows: rows}})
+}
+
+// writeXLSXSheets writes a workbook with one sheet per entry of sheets, each with a frozen,
+// bold header row
+func writeXLSXSheets(out *runOutputs, filename string, sheets []xlsxSheet) error {
+	file, err := out.create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var contentTypes, workbook, workbookRels strings.Builder
+	contentTypes.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>
+`)
+	workbook.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets>`)
+	workbookRels.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rIdStyles" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+`)
+	parts := []struct{ name, content string }{}
+	for s, sheet := range sheets {
+		fmt.Fprintf(&contentTypes, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`+"\n", s+1)
+		workbook.WriteString(`<sheet name="`)
+		xml.EscapeText(&workbook, []byte(sheet.Name))
+		fmt.Fprintf(&workbook, `" sheetId="%d" r:id="rId%d"/>`, s+1, s+1)
+		fmt.Fprintf(&workbookRels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`+"\n", s+1, s+1)
+		parts = append(parts, struct{ name, content string }{fmt.Sprintf("xl/worksheets/sheet%d.xml", s+1), xlsxSheetXML(sheet)})
+	}
+	contentTypes.WriteString(`</Types>`)
+	workbook.WriteString("</sheets>\n</workbook>")
+	workbookRels.WriteString(`</Relationships>`)
+
+	archive := zip.NewWriter(file)
+	for _, part := range append([]struct{ name, content string }{
+		{"[Content_Types].xml", contentTypes.String()},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", workbook.String()},
+		{"xl/_rels/workbook.xml.rels", workbookRels.String()},
+		{"xl/styles.xml", xlsxStyles},
+	}, parts...) {
+		w, err := archive.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write([]byte(part.content)); err != nil {
+			return err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	return file.Commit()
+}
+
+// xlsxNumber reports whether a cell value can be written as a number. NaN and Inf, which
+// strconv.ParseFloat accepts, and hexadecimal floats make Excel reject the workbook.
+func xlsxNumber(value string) bool {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return false
+	}
+	return !strings.ContainsAny(value, "xX")
+}
+
+// xlsxSheetXML returns the worksheet part of sheet
+func xlsxSheetXML(sheet xlsxSheet) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	b.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	b.WriteString(`<sheetData>`)
+	writeRow := func(index int, values []string, style int, numbers bool) {
+		fmt.Fprintf(&b, `<row r="%d">`, index)
+		for c, value := range values {
+			if value == "" {
+				continue
+			}
+			if numbers && xlsxNumber(value) {
+				fmt.Fprintf(&b, `<c r="%s%d" s="%d"><v>%s</v></c>`, xlsxColumn(c), index, style, value)
+				continue
+			}
+			fmt.Fprintf(&b, `<c r="%s%d" t="inlineStr" s="%d"><is><t xml:space="preserve">`, xlsxColumn(c), index, style)
+			xml.EscapeText(&b, []byte(value))
+			b.WriteString(`</t></is></c>`)
+		}
+		b.WriteString(`</row>`)
+	}
+	writeRow(1, sheet.Header, 1, false)
+	for i, row := range sheet.Rows {
+		writeRow(i+2, row, 0, sheet.Numbers)
+	}
+	b.WriteString(`</sheetData></worksheet>`)
+	return b.String()
+}
+
+// xlsxColumn returns the column letters of a zero-based column index (0 -> A, 26 -> AA)
+func xlsxColumn(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
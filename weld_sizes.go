@@ -0,0 +1,133 @@
+package dxfparser
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// sizedCallout is a piece number callout on the drawing with the N.S. of its cut piece
+type sizedCallout struct {
+	Size string
+	X, Y float64
+}
+
+// SizeCount is the number of welds attributed to one pipe size of a drawing
+type SizeCount struct {
+	Size  string `json:"size"` // N.S. (MM) of the cut piece, "" if no size could be attributed
+	Welds int    `json:"welds"`
+}
+
+// pieceCallout is a piece number callout on the drawing with the cut length row of its piece
+type pieceCallout struct {
+	Row  []string // PIECE NO | CUT LENGTH | N.S. (MM) | ...
+	X, Y float64
+}
+
+// pieceSizeCallouts returns the piece number callouts whose piece has an N.S. in the cut
+// lengths, with that N.S.
+func pieceSizeCallouts(cutRows [][]string, entities []TextEntity) []sizedCallout {
+	var sized []sizedCallout
+	for _, callout := range pieceCallouts(cutRows, entities) {
+		if len(callout.Row) > 2 && strings.TrimSpace(callout.Row[2]) != "" {
+			sized = append(sized, sizedCallout{Size: strings.TrimSpace(callout.Row[2]), X: callout.X, Y: callout.Y})
+		}
+	}
+	return sized
+}
+
+// pieceCallouts returns the piece number callouts (<1>, <2>, ...) outside the CUT PIPE LENGTH
+// table, whose cut lengths are in single-row format (PIECE NO | CUT LENGTH | N.S. (MM) | ...).
+// The callouts mark the pipe runs on the isometric.
+func pieceCallouts(cutRows [][]string, entities []TextEntity) []pieceCallout {
+	pieces := make(map[string][]string)
+	for _, row := range cutRows {
+		if len(row) > 1 && strings.TrimSpace(row[0]) != "" {
+			pieces[strings.TrimSpace(row[0])] = row
+		}
+	}
+	if len(pieces) == 0 {
+		return nil
+	}
+
+	// A piece number with its cut length right of it on the same line is a table cell
+	inTable := func(entity TextEntity, length string) bool {
+		for _, other := range entities {
+			if other.X > entity.X && math.Abs(other.Y-entity.Y) <= tagRowTolerance && strings.TrimSpace(other.Content) == length {
+				return true
+			}
+		}
+		return false
+	}
+
+	var callouts []pieceCallout
+	for _, entity := range entities {
+		row, ok := pieces[strings.TrimSpace(entity.Content)]
+		if !ok || inTable(entity, strings.TrimSpace(row[1])) {
+			continue
+		}
+		callouts = append(callouts, pieceCallout{Row: row, X: entity.X, Y: entity.Y})
+	}
+	return callouts
+}
+
+// cutPieceSizes returns the distinct N.S. values of single-row format cut lengths
+func cutPieceSizes(cutRows [][]string) []string {
+	seen := make(map[string]bool)
+	var sizes []string
+	for _, row := range cutRows {
+		if len(row) > 2 {
+			if size := strings.TrimSpace(row[2]); size != "" && !seen[size] {
+				seen[size] = true
+				sizes = append(sizes, size)
+			}
+		}
+	}
+	return sizes
+}
+
+// attributeWeldSizes sets the Size of every symbol to the size of the nearest piece callout.
+// Without callouts, a drawing with a single pipe size gets that size for all welds. Returns the
+// weld count per size, numeric sizes ascending and unattributed welds ("") last.
+func attributeWeldSizes(symbols []WeldSymbol, callouts []sizedCallout, sizes []string) []SizeCount {
+	calloutEntities := make([]TextEntity, len(callouts))
+	for i, callout := range callouts {
+		calloutEntities[i] = TextEntity{Content: callout.Size, X: callout.X, Y: callout.Y}
+	}
+	queries := make([]NearestQuery, len(symbols))
+	for i, symbol := range symbols {
+		queries[i] = NearestQuery{X: symbol.CenterX, Y: symbol.CenterY, N: 1}
+	}
+	nearest := NewSpatialAnalyzer(calloutEntities).BatchFindNearestEntities(queries)
+
+	counts := make(map[string]int)
+	for i := range symbols {
+		symbol := &symbols[i]
+		if len(nearest[i]) > 0 {
+			symbol.Size = nearest[i][0].Entity.Content
+		}
+		if len(callouts) == 0 && len(sizes) == 1 {
+			symbol.Size = sizes[0]
+		}
+		counts[symbol.Size]++
+	}
+
+	result := make([]SizeCount, 0, len(counts))
+	for size, welds := range counts {
+		result = append(result, SizeCount{Size: size, Welds: welds})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i].Size, result[j].Size
+		if (a == "") != (b == "") {
+			return b == ""
+		}
+		numA, errA := strconv.ParseFloat(a, 64)
+		numB, errB := strconv.ParseFloat(b, 64)
+		if errA == nil && errB == nil && numA != numB {
+			return numA < numB
+		}
+		return a < b
+	})
+	return result
+}
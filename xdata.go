@@ -0,0 +1,86 @@
+package dxfparser
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// XData is the extended data (XDATA) applications attach to an entity, keyed by the registered
+// application name (1001) in upper case. Each application has its groups 1000 to 1071 in file
+// order, control strings (1002 "{" and "}") included. Plant 3D, for example, keeps line
+// numbers and spec data there.
+type XData map[string][]GroupValue
+
+// Apps returns the application names of the data, sorted
+func (x XData) Apps() []string {
+	apps := make([]string, 0, len(x))
+	for app := range x {
+		apps = append(apps, app)
+	}
+	sort.Strings(apps)
+	return apps
+}
+
+// Value returns the first value of a group code in the data of application appid
+func (x XData) Value(appid string, code int) (string, bool) {
+	for _, g := range x[strings.ToUpper(appid)] {
+		if g.Code == code {
+			return g.Value, true
+		}
+	}
+	return "", false
+}
+
+// Strings returns the string values (1000) of application appid
+func (x XData) Strings(appid string) []string {
+	var values []string
+	for _, g := range x[strings.ToUpper(appid)] {
+		if g.Code == 1000 {
+			values = append(values, g.Value)
+		}
+	}
+	return values
+}
+
+// isXDataCode reports whether a group code is one of the XDATA codes 1000 to 1071
+func isXDataCode(code string) bool {
+	if len(code) != 4 || !strings.HasPrefix(code, "10") {
+		return false
+	}
+	n, err := strconv.Atoi(code)
+	return err == nil && n <= 1071
+}
+
+// setXDataGroup applies an XDATA group to the entity and reports whether the code is one. A
+// 1001 group names the application the following groups belong to; groups before the first
+// are dropped.
+func (e *TextEntity) setXDataGroup(code, value string) bool {
+	if !isXDataCode(code) {
+		return false
+	}
+	if code == "1001" {
+		e.xdataApp = strings.ToUpper(value)
+		if e.XData == nil {
+			e.XData = make(XData)
+		}
+		if _, ok := e.XData[e.xdataApp]; !ok {
+			e.XData[e.xdataApp] = []GroupValue{}
+		}
+		return true
+	}
+	if e.xdataApp != "" {
+		n, _ := strconv.Atoi(code)
+		e.XData[e.xdataApp] = append(e.XData[e.xdataApp], GroupValue{Code: n, Value: value})
+	}
+	return true
+}
+
+// XData returns the extended data of the entity
+func (e Entity) XData() XData {
+	var text TextEntity
+	for _, g := range e.Groups {
+		text.setXDataGroup(strconv.Itoa(g.Code), g.Value)
+	}
+	return text.XData
+}
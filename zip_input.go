@@ -0,0 +1,92 @@
+package dxfparser
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// zipEntrySep separates the archive from the entry in the path of a drawing inside a ZIP
+// archive, e.g. "package.zip!/ISO/drawing.dxf". Such paths are read without extraction.
+const zipEntrySep = "!/"
+
+// isZip reports whether path has the .zip extension
+func isZip(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".zip")
+}
+
+// splitZipPath splits the path of a ZIP entry into archive and entry name
+func splitZipPath(p string) (archive, entry string, ok bool) {
+	i := strings.Index(strings.ToLower(p), ".zip"+zipEntrySep)
+	if i < 0 {
+		return "", "", false
+	}
+	archive = p[:i+len(".zip")]
+	return archive, p[len(archive)+len(zipEntrySep):], true
+}
+
+// zipEntries returns the paths of the DXF entries (plain or gzip-compressed) of a ZIP archive, sorted by entry name.
+// With withDWG, DWG entries are included; skippedDWG counts those left out.
+func zipEntries(archive string, withDWG bool) (paths []string, skippedDWG int, err error) {
+	reader, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open archive %s: %v", archive, err)
+	}
+	defer reader.Close()
+
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		switch {
+		case isDXF(file.Name):
+			paths = append(paths, archive+zipEntrySep+file.Name)
+		case isDWG(file.Name):
+			if withDWG {
+				paths = append(paths, archive+zipEntrySep+file.Name)
+			} else {
+				skippedDWG++
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths, skippedDWG, nil
+}
+
+// zipEntryReader is an open ZIP entry that closes its archive
+type zipEntryReader struct {
+	io.ReadCloser
+	archive *zip.ReadCloser
+}
+
+func (z zipEntryReader) Close() error {
+	err := z.ReadCloser.Close()
+	if closeErr := z.archive.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// openZipEntry opens an entry of a ZIP archive, given as "<archive>!/<entry>"
+func openZipEntry(p, archive, entry string) (io.ReadCloser, error) {
+	reader, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range reader.File {
+		if file.Name != entry {
+			continue
+		}
+		content, err := file.Open()
+		if err != nil {
+			reader.Close()
+			return nil, fmt.Errorf("%s: %v", p, err)
+		}
+		return zipEntryReader{ReadCloser: content, archive: reader}, nil
+	}
+	reader.Close()
+	return nil, fmt.Errorf("%s: no such entry in %s", entry, archive)
+}